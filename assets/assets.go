@@ -25,3 +25,9 @@ var SoundError []byte
 
 //go:embed sounds/processing_tick.wav
 var SoundProcessingTick []byte
+
+//go:embed sounds/auth_error.wav
+var SoundAuthError []byte
+
+//go:embed sounds/retry.wav
+var SoundRetry []byte
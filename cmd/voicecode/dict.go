@@ -0,0 +1,231 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/noricha-vr/voicecode/internal/core/history"
+	"github.com/noricha-vr/voicecode/internal/core/prompt"
+)
+
+const dictUsage = `Usage: voicecode dict import --format=google-ime|ms-ime [--profile name] [--dry-run] <file>
+       voicecode dict lint [--profile name] [--budget tokens]
+       voicecode dict suggest [--min-count N] [--profile name] [--apply]`
+
+// runDict dispatches the dictionary maintenance subcommands.
+func runDict(args []string, stdout io.Writer) error {
+	if len(args) < 1 {
+		return &usageError{dictUsage}
+	}
+	switch args[0] {
+	case "import":
+		return runDictImport(args[1:], stdout)
+	case "lint":
+		return runDictLint(args[1:], stdout)
+	case "suggest":
+		return runDictSuggest(args[1:], stdout)
+	}
+	return &usageError{fmt.Sprintf("Unknown dict command: %s\n%s", args[0], dictUsage)}
+}
+
+// runDictImport appends the conversions of an IME user dictionary export to
+// the user dictionary (or a profile), skipping entries it already has.
+func runDictImport(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("dict import", flag.ContinueOnError)
+	formatName := fs.String("format", "", "export format: google-ime or ms-ime")
+	profile := fs.String("profile", "", "import into the named dictionary profile")
+	dryRun := fs.Bool("dry-run", false, "report what would be imported without writing")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return &usageError{dictUsage}
+	}
+	format, err := prompt.ParseIMEFormat(*formatName)
+	if err != nil {
+		return &usageError{fmt.Sprintf("%v\n%s", err, dictUsage)}
+	}
+	src := fs.Arg(0)
+
+	base := prompt.DictionaryPath()
+	target := base
+	if *profile != "" {
+		if target, err = prompt.ProfilePath(base, *profile); err != nil {
+			return fmt.Errorf("invalid profile: %w", err)
+		}
+	}
+
+	imported, err := prompt.ParseIMEDictionary(src, format)
+	if imported == nil && err != nil {
+		return fmt.Errorf("failed to read %s: %w", src, err)
+	}
+	if err != nil {
+		log.Printf("Skipped malformed lines: %v", err)
+	}
+	// De-duplicate against everything active for the target: a profile
+	// import also skips readings the base dictionary already covers.
+	existing, err := prompt.LoadProfile(base, *profile)
+	if err != nil {
+		return fmt.Errorf("failed to load dictionary: %w", err)
+	}
+
+	plan := prompt.PlanImport(existing.Entries, imported)
+	for _, c := range plan.Conflicts {
+		log.Printf("Conflict: %s -> %s (kept %s from %s)", c.Reading, c.Imported, c.Existing, c.Source)
+	}
+	for _, e := range plan.Invalid {
+		log.Printf("Skipped line %d: %q -> %q cannot be written as a dictionary entry", e.Line, e.Reading, e.Surface)
+	}
+	log.Printf("%d entries read: %d new, %d duplicates, %d conflicts, %d invalid", len(imported), len(plan.Added), plan.Duplicates, len(plan.Conflicts), len(plan.Invalid))
+
+	if *dryRun {
+		for _, e := range plan.Added {
+			fmt.Fprintf(stdout, "%s\t%s\n", e.Reading, e.Notation)
+		}
+		return nil
+	}
+	if len(plan.Added) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return fmt.Errorf("failed to create dictionary directory: %w", err)
+	}
+	header := fmt.Sprintf("Imported from %s (%s)", filepath.Base(src), format)
+	if err := prompt.AppendEntries(target, header, plan.Added); err != nil {
+		return fmt.Errorf("import failed: %w", err)
+	}
+	log.Printf("Added %d entries to %s", len(plan.Added), target)
+	return nil
+}
+
+// runDictLint reports dictionary problems and the estimated size of the
+// composed system prompt. It returns errCheckFailed on lint errors or when
+// the estimate exceeds the token budget.
+func runDictLint(args []string, stdout io.Writer) error {
+	cfg := loadSettings()
+	fs := flag.NewFlagSet("dict lint", flag.ContinueOnError)
+	profile := fs.String("profile", cfg.DictionaryProfile, "dictionary profile to check (default: the active one)")
+	budget := fs.Int("budget", cfg.PromptTokenBudget, "maximum estimated system prompt tokens")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return &usageError{dictUsage}
+	}
+
+	d, err := prompt.LoadProfile(prompt.DictionaryPath(), *profile)
+	if err != nil {
+		return fmt.Errorf("failed to load dictionary: %w", err)
+	}
+
+	failed := false
+	issues := prompt.Lint(d)
+	for _, i := range issues {
+		fmt.Fprintln(stdout, i)
+		if i.Severity == prompt.SeverityError {
+			failed = true
+		}
+	}
+
+	base := prompt.EstimateTokens(prompt.SystemPrompt)
+	total := prompt.EstimateTokens(prompt.ComposeSystemPrompt(d))
+	fmt.Fprintf(stdout, "%d entries (%d conversions, %d hints), %d issues\n", d.Terms(), d.Conversions, d.Hints, len(issues))
+	fmt.Fprintf(stdout, "System prompt: ~%d tokens (base ~%d + dictionary ~%d), budget %d\n", total, base, total-base, *budget)
+	if total > *budget {
+		fmt.Fprintf(stdout, "Over budget by ~%d tokens: remove rarely used entries or move project terms into profiles\n", total-*budget)
+		failed = true
+	}
+	if failed {
+		return errCheckFailed
+	}
+	return nil
+}
+
+// runDictSuggest proposes conversion entries from the corrections recorded
+// with "history correct", and appends them with --apply.
+func runDictSuggest(args []string, stdout io.Writer) error {
+	cfg := loadSettings()
+	fs := flag.NewFlagSet("dict suggest", flag.ContinueOnError)
+	minCount := fs.Int("min-count", 1, "only suggest edits made at least this many times")
+	profile := fs.String("profile", cfg.DictionaryProfile, "dictionary profile to compare against and --apply to")
+	apply := fs.Bool("apply", false, "append the suggestions (except conflicts) to the dictionary")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return &usageError{dictUsage}
+	}
+	base := prompt.DictionaryPath()
+	target := base
+	if *profile != "" {
+		var err error
+		if target, err = prompt.ProfilePath(base, *profile); err != nil {
+			return fmt.Errorf("invalid profile: %w", err)
+		}
+	}
+
+	entries, err := history.List()
+	if err != nil {
+		return fmt.Errorf("failed to read history: %w", err)
+	}
+	var corrections []prompt.Correction
+	translated := 0
+	for _, e := range entries {
+		original, corrected, ok := e.DictionaryCorrection()
+		if !ok {
+			if e.CorrectedText != "" {
+				translated++
+			}
+			continue
+		}
+		corrections = append(corrections, prompt.Correction{Original: original, Corrected: corrected})
+	}
+	if translated > 0 {
+		log.Printf("Skipped %d corrected entries whose translation was pasted", translated)
+	}
+	if len(corrections) == 0 {
+		fmt.Fprintln(stdout, "No corrections recorded. Use: voicecode history correct <id|last> <text>")
+		return nil
+	}
+
+	d, err := prompt.LoadProfile(base, *profile)
+	if err != nil {
+		return fmt.Errorf("failed to load dictionary: %w", err)
+	}
+
+	var add []prompt.Entry
+	for _, s := range prompt.Suggest(corrections, d.Entries) {
+		if s.Count < *minCount {
+			continue
+		}
+		// Printed in dictionary format so the output can be appended as is.
+		if s.Existing != "" {
+			fmt.Fprintf(stdout, "# %s -> %s: corrected %d times in %d entries, but the dictionary maps it to %s\n", s.From, s.To, s.Count, s.Entries, s.Existing)
+			continue
+		}
+		e := prompt.Entry{Reading: s.From, Notation: s.To, Context: prompt.DefaultContext}
+		if err := prompt.ValidateEntry(e); err != nil {
+			fmt.Fprintf(stdout, "# %q -> %q: corrected %d times in %d entries, but %v\n", s.From, s.To, s.Count, s.Entries, err)
+			continue
+		}
+		fmt.Fprintf(stdout, "# corrected %d times in %d entries\n%s\t%s\n", s.Count, s.Entries, s.From, s.To)
+		add = append(add, e)
+	}
+	log.Printf("%d corrections, %d suggestions", len(corrections), len(add))
+
+	if !*apply || len(add) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return fmt.Errorf("failed to create dictionary directory: %w", err)
+	}
+	if err := prompt.AppendEntries(target, "Suggested from corrected history", add); err != nil {
+		return fmt.Errorf("failed to update dictionary: %w", err)
+	}
+	log.Printf("Added %d entries to %s", len(add), target)
+	return nil
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/noricha-vr/voicecode/internal/core/history"
+	"github.com/noricha-vr/voicecode/internal/core/settings"
+)
+
+func TestRunDictImport(t *testing.T) {
+	const export = "# Google IME\nくばねてぃす\tKubernetes\t名詞\nごー\tGo\t名詞\nはっしゅ\t#tag\t名詞\n"
+	tests := []struct {
+		name     string
+		args     []string // the export file is appended
+		wantExit int
+		wantOut  string
+		wantDict string // relative to ~/.voicecoding
+		want     string
+	}{
+		{"base", []string{"import", "--format=google-ime"}, 0, "", "dictionary.txt",
+			"ゴー\tGo\n\n# Imported from ime.txt (google-ime)\nクバネティス\tKubernetes\n"},
+		{"profile", []string{"import", "--format=google-ime", "--profile", "k8s"}, 0, "", "dictionaries/k8s.txt",
+			"# Imported from ime.txt (google-ime)\nクバネティス\tKubernetes\n"},
+		{"dry run", []string{"import", "--format=google-ime", "--dry-run"}, 0, "クバネティス\tKubernetes\n", "dictionary.txt", "ゴー\tGo\n"},
+		{"unknown format", []string{"import", "--format=atok"}, 2, "", "dictionary.txt", "ゴー\tGo\n"},
+		{"missing format", []string{"import"}, 2, "", "dictionary.txt", "ゴー\tGo\n"},
+		{"invalid profile", []string{"import", "--format=google-ime", "--profile", "../x"}, 1, "", "dictionary.txt", "ゴー\tGo\n"},
+		{"bad flag", []string{"import", "--dry-run=maybe"}, 2, "", "dictionary.txt", "ゴー\tGo\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := withTempHome(t)
+			writeHomeFile(t, home, ".voicecoding/dictionary.txt", "ゴー\tGo\n")
+			src := writeHomeFile(t, home, "ime.txt", export)
+
+			var out strings.Builder
+			err := runDict(append(tt.args, src), &out)
+			if got := exitCode(err); got != tt.wantExit {
+				t.Fatalf("runDict(%q) = %v, exit %d; want %d", tt.args, err, got, tt.wantExit)
+			}
+			if out.String() != tt.wantOut {
+				t.Errorf("output = %q, want %q", out.String(), tt.wantOut)
+			}
+			data, _ := os.ReadFile(filepath.Join(home, ".voicecoding", filepath.FromSlash(tt.wantDict)))
+			if string(data) != tt.want {
+				t.Errorf("%s = %q, want %q", tt.wantDict, data, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunDictArguments(t *testing.T) {
+	withTempHome(t)
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"no subcommand", nil},
+		{"unknown subcommand", []string{"export"}},
+		{"import without file", []string{"import", "--format=google-ime"}},
+		{"import with two files", []string{"import", "--format=google-ime", "a.txt", "b.txt"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := runDict(tt.args, &strings.Builder{})
+			if got := exitCode(err); got != 2 {
+				t.Errorf("runDict(%q) = %v, exit %d; want 2", tt.args, err, got)
+			}
+		})
+	}
+}
+
+func TestRunDictLint(t *testing.T) {
+	tests := []struct {
+		name     string
+		dict     string
+		budget   int // saved prompt_token_budget; 0 keeps the default
+		args     []string
+		wantExit int
+		wantOut  string
+	}{
+		{"clean", "クバネティス\tKubernetes\n", 0, nil, 0, "System prompt: ~"},
+		{"warnings only", "Go\nGo\n", 0, nil, 0, "duplicate hint word"},
+		{"conflict", "ゴー\tGo\nゴー\tGolang\n", 0, nil, 1, "conflicting conversion"},
+		{"over --budget", "Go\n", 0, []string{"--budget", "10"}, 1, "Over budget"},
+		{"over saved budget", "Go\n", 10, nil, 1, "Over budget"},
+		{"--budget overrides settings", "Go\n", 10, []string{"--budget", "100000"}, 0, "budget 100000"},
+		{"profile", "Go\n", 0, []string{"--profile", "bad"}, 1, "conflicting conversion"},
+		{"bad budget", "Go\n", 0, []string{"--budget", "lots"}, 2, ""},
+		{"extra argument", "Go\n", 0, []string{"dictionary.txt"}, 2, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := withTempHome(t)
+			writeHomeFile(t, home, ".voicecoding/dictionary.txt", tt.dict)
+			writeHomeFile(t, home, ".voicecoding/dictionaries/bad.txt", "ゴー\tGo\nゴー\tGolang\n")
+			if tt.budget > 0 {
+				cfg := settings.Default()
+				cfg.PromptTokenBudget = tt.budget
+				if err := cfg.Save(); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var out strings.Builder
+			err := runDict(append([]string{"lint"}, tt.args...), &out)
+			if got := exitCode(err); got != tt.wantExit {
+				t.Fatalf("dict lint %q = %v, exit %d; want %d\n%s", tt.args, err, got, tt.wantExit, out.String())
+			}
+			if tt.wantExit == 1 && !errors.Is(err, errCheckFailed) {
+				t.Errorf("error = %v, want errCheckFailed", err)
+			}
+			if !strings.Contains(out.String(), tt.wantOut) {
+				t.Errorf("output = %q, want it to contain %q", out.String(), tt.wantOut)
+			}
+		})
+	}
+}
+
+func TestRunDictSuggest(t *testing.T) {
+	const suggestion = "# corrected 1 times in 1 entries\nクバネティス\tKubernetes\n"
+	tests := []struct {
+		name      string
+		args      []string
+		corrected bool
+		wantExit  int
+		wantOut   string
+		wantDict  string // relative to ~/.voicecoding
+		want      string
+	}{
+		{"print", []string{"suggest"}, true, 0, suggestion, "dictionary.txt", "ゴー\tGo\n"},
+		{"apply", []string{"suggest", "--apply"}, true, 0, suggestion, "dictionary.txt",
+			"ゴー\tGo\n\n# Suggested from corrected history\nクバネティス\tKubernetes\n"},
+		{"apply to profile", []string{"suggest", "--apply", "--profile", "k8s"}, true, 0, suggestion, "dictionaries/k8s.txt",
+			"# Suggested from corrected history\nクバネティス\tKubernetes\n"},
+		{"below min count", []string{"suggest", "--min-count", "2", "--apply"}, true, 0, "", "dictionary.txt", "ゴー\tGo\n"},
+		{"no corrections", []string{"suggest", "--apply"}, false, 0, "No corrections recorded. Use: voicecode history correct <id|last> <text>\n", "dictionary.txt", "ゴー\tGo\n"},
+		{"invalid profile", []string{"suggest", "--apply", "--profile", "../x"}, true, 1, "", "dictionary.txt", "ゴー\tGo\n"},
+		{"bad min count", []string{"suggest", "--min-count", "often"}, true, 2, "", "dictionary.txt", "ゴー\tGo\n"},
+		{"extra argument", []string{"suggest", "now"}, true, 2, "", "dictionary.txt", "ゴー\tGo\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := withTempHome(t)
+			writeHomeFile(t, home, ".voicecoding/dictionary.txt", "ゴー\tGo\n")
+			e := history.Entry{ProcessedText: "クバネティスにデプロイする"}
+			if tt.corrected {
+				e.CorrectedText = "Kubernetesにデプロイする"
+			}
+			writeHistory(t, home, "2026-01-01_090000", e)
+
+			var out strings.Builder
+			err := runDict(tt.args, &out)
+			if got := exitCode(err); got != tt.wantExit {
+				t.Fatalf("runDict(%q) = %v, exit %d; want %d", tt.args, err, got, tt.wantExit)
+			}
+			if out.String() != tt.wantOut {
+				t.Errorf("output = %q, want %q", out.String(), tt.wantOut)
+			}
+			data, _ := os.ReadFile(filepath.Join(home, ".voicecoding", filepath.FromSlash(tt.wantDict)))
+			if string(data) != tt.want {
+				t.Errorf("%s = %q, want %q", tt.wantDict, data, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/noricha-vr/voicecode/internal/core/eval"
+	"github.com/noricha-vr/voicecode/internal/core/prompt"
+	"github.com/noricha-vr/voicecode/internal/core/transcriber"
+)
+
+const evalUsage = `Usage: voicecode eval [--model m] [--mode name] [--compare-model m] [--compare-mode name]
+                     [--profile name] [--fixtures file | --record file] [--json] [--max-cer rate] <corpus-dir>`
+
+// runEval transcribes a corpus of WAV files with expected text and reports
+// the character error rate, optionally for two variants side by side. It
+// returns errCheckFailed when a variant's CER exceeds --max-cer.
+func runEval(args []string, stdout io.Writer) error {
+	cfg := loadSettings()
+	fs := flag.NewFlagSet("eval", flag.ContinueOnError)
+	model := fs.String("model", "", "pin requests to this model (default: the configured model and fallback)")
+	mode := fs.String("mode", cfg.Mode, "mode from settings to evaluate")
+	compareModel := fs.String("compare-model", "", "also evaluate with this model and compare")
+	compareMode := fs.String("compare-mode", "", `also evaluate in this mode ("default" for the built-in prompts) and compare`)
+	profile := fs.String("profile", cfg.DictionaryProfile, "dictionary profile for the backend prompt and the local replacement")
+	fixtures := fs.String("fixtures", "", "replay transcriptions recorded with --record instead of calling the backend")
+	record := fs.String("record", "", "record the transcriptions to this fixture file")
+	jsonOut := fs.Bool("json", false, "print the reports as JSON")
+	maxCER := fs.Float64("max-cer", -1, "exit with status 1 when a variant's CER exceeds this rate (e.g. 0.05)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 || *fixtures != "" && *record != "" {
+		return &usageError{evalUsage}
+	}
+
+	variants := []eval.Variant{{Model: *model, Mode: *mode}}
+	if *compareModel != "" || *compareMode != "" {
+		b := variants[0]
+		if *compareModel != "" {
+			b.Model = *compareModel
+		}
+		if *compareMode == "default" {
+			b.Mode = ""
+		} else if *compareMode != "" {
+			b.Mode = *compareMode
+		}
+		variants = append(variants, b)
+	}
+	for i := range variants {
+		v := &variants[i]
+		if v.Mode != "" {
+			if _, ok := cfg.FindMode(v.Mode); !ok {
+				return &usageError{fmt.Sprintf("Unknown mode %q (define it in settings.json \"modes\")", v.Mode)}
+			}
+		}
+		v.Label = variantLabel(*v)
+	}
+	if len(variants) == 2 && variants[0].Label == variants[1].Label {
+		return &usageError{fmt.Sprintf("Nothing to compare: both variants are %s", variants[0].Label)}
+	}
+
+	cases, err := eval.LoadCorpus(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("failed to load corpus: %w", err)
+	}
+	dict, err := prompt.LoadProfile(prompt.DictionaryPath(), *profile)
+	if err != nil {
+		log.Printf("Dictionary load failed, skipping local replacement: %v", err)
+	}
+
+	ctx := context.Background()
+	var b transcriber.Backend
+	var recorder *eval.Recorder
+	switch {
+	case *fixtures != "":
+		recorded, err := eval.LoadFixtures(*fixtures)
+		if err != nil {
+			return err
+		}
+		b = eval.NewFixtureBackend(recorded)
+	default:
+		cfg.DictionaryProfile = *profile
+		live, err := transcriber.NewBackend(ctx, cfg)
+		if err != nil {
+			return fmt.Errorf("failed to initialize transcriber: %w", err)
+		}
+		defer live.Close()
+		b = live
+		if *record != "" {
+			existing, err := eval.LoadFixtures(*record)
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+			recorder = eval.NewRecorder(live, existing)
+			b = recorder
+		}
+	}
+
+	var reports []eval.Report
+	for _, v := range variants {
+		log.Printf("Evaluating %d cases: %s", len(cases), v.Label)
+		reports = append(reports, eval.Run(ctx, b, dict.Replacer(), cases, v))
+	}
+	if recorder != nil {
+		if err := recorder.Save(*record); err != nil {
+			return err
+		}
+		log.Printf("Recorded fixtures to %s", *record)
+	}
+
+	if *jsonOut {
+		err = eval.WriteJSON(stdout, reports)
+	} else {
+		err = eval.WriteText(stdout, reports)
+	}
+	if err != nil {
+		return fmt.Errorf("write failed: %w", err)
+	}
+
+	if *maxCER < 0 {
+		return nil
+	}
+	failed := false
+	for _, r := range reports {
+		if r.CER > *maxCER {
+			log.Printf("%s: CER %.1f%% exceeds %.1f%%", r.Variant, r.CER*100, *maxCER*100)
+			failed = true
+		}
+	}
+	if failed {
+		return errCheckFailed
+	}
+	return nil
+}
+
+// variantLabel names a variant by what it overrides.
+func variantLabel(v eval.Variant) string {
+	var parts []string
+	if v.Model != "" {
+		parts = append(parts, "model="+v.Model)
+	}
+	parts = append(parts, "mode="+modeLabel(v.Mode))
+	return strings.Join(parts, " ")
+}
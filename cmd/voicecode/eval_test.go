@@ -0,0 +1,99 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/noricha-vr/voicecode/internal/core/audio"
+	"github.com/noricha-vr/voicecode/internal/core/eval"
+)
+
+// writeEvalCorpus creates a one-case corpus and a fixture file answering
+// it: one character short for the default variant, exact for model m2.
+func writeEvalCorpus(t *testing.T) (corpus, fixtures string) {
+	t.Helper()
+	corpus = t.TempDir()
+	wavPath := filepath.Join(corpus, "deploy.wav")
+	if err := audio.WriteWAV(wavPath, make([]int16, 1600), eval.SampleRate); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(corpus, "deploy.txt"), []byte("Kubernetesにデプロイ\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The hash of the audio as eval uploads it.
+	w, err := audio.ReadWAV(wavPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	upload, err := audio.EncodeWAV(w.PCM16(eval.SampleRate), eval.SampleRate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(upload)
+	data, err := json.Marshal(map[string][]eval.Fixture{"fixtures": {
+		{AudioSHA256: hex.EncodeToString(sum[:]), Text: "Kubernetesにデプロ"},
+		{AudioSHA256: hex.EncodeToString(sum[:]), Model: "m2", Text: "Kubernetesにデプロイ"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixtures = filepath.Join(t.TempDir(), "fixtures.json")
+	if err := os.WriteFile(fixtures, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return corpus, fixtures
+}
+
+func TestRunEval(t *testing.T) {
+	corpus, fixtures := writeEvalCorpus(t)
+	replay := []string{"--fixtures", fixtures}
+
+	tests := []struct {
+		name     string
+		args     []string
+		wantExit int
+		wantOut  []string
+	}{
+		{"report", append(replay, corpus), 0, []string{"FAIL deploy", "1 cases: CER 6.7%"}},
+		{"compare model", append(replay, "--compare-model", "m2", corpus), 0, []string{"== mode=default", "== model=m2 mode=default", "ok   deploy"}},
+		{"under max CER", append(replay, "--max-cer", "0.1", corpus), 0, nil},
+		{"over max CER", append(replay, "--max-cer", "0.05", corpus), 1, []string{"1 cases: CER 6.7%"}},
+		{"one variant over max CER", append(replay, "--compare-model", "m2", "--max-cer", "0.05", corpus), 1, nil},
+		{"profile replacement", append(replay, "--profile", "k8s", "--max-cer", "0", corpus), 0, []string{"1 cases: CER 0.0%"}},
+		{"json", append(replay, "--json", corpus), 0, []string{`"cer"`}},
+		{"missing fixtures", []string{"--fixtures", filepath.Join(corpus, "none.json"), corpus}, 1, nil},
+		{"missing corpus", append(replay, filepath.Join(corpus, "none")), 1, nil},
+		{"no corpus", replay, 2, nil},
+		{"fixtures and record", append(replay, "--record", "out.json", corpus), 2, nil},
+		{"bad max CER", append(replay, "--max-cer", "low", corpus), 2, nil},
+		{"unknown mode", append(replay, "--mode", "memo", corpus), 2, nil},
+		{"nothing to compare", append(replay, "--compare-mode", "default", corpus), 2, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := withTempHome(t)
+			writeHomeFile(t, home, ".voicecoding/dictionaries/k8s.txt", "デプロ\tデプロイ\n")
+
+			var out strings.Builder
+			err := runEval(tt.args, &out)
+			if got := exitCode(err); got != tt.wantExit {
+				t.Fatalf("runEval(%q) = %v, exit %d; want %d\n%s", tt.args, err, got, tt.wantExit, out.String())
+			}
+			if strings.Contains(tt.name, "max CER") && tt.wantExit == 1 && !errors.Is(err, errCheckFailed) {
+				t.Errorf("error = %v, want errCheckFailed", err)
+			}
+			for _, want := range tt.wantOut {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("output does not contain %q:\n%s", want, out.String())
+				}
+			}
+		})
+	}
+}
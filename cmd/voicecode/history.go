@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/noricha-vr/voicecode/internal/core/history"
+)
+
+const historyUsage = `Usage: voicecode history list [-n count]
+       voicecode history correct <id|last> [corrected text]`
+
+// runHistory dispatches the history subcommands.
+func runHistory(args []string, stdin io.Reader, stdout io.Writer) error {
+	if len(args) < 1 {
+		return &usageError{historyUsage}
+	}
+	switch args[0] {
+	case "list":
+		return runHistoryList(args[1:], stdout)
+	case "correct":
+		return runHistoryCorrect(args[1:], stdin)
+	}
+	return &usageError{fmt.Sprintf("Unknown history command: %s\n%s", args[0], historyUsage)}
+}
+
+// runHistoryList prints the most recent entries with their IDs.
+func runHistoryList(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("history list", flag.ContinueOnError)
+	n := fs.Int("n", 20, "number of entries to show")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return &usageError{historyUsage}
+	}
+
+	entries, err := history.List()
+	if err != nil {
+		return fmt.Errorf("failed to read history: %w", err)
+	}
+	if *n > 0 && len(entries) > *n {
+		entries = entries[len(entries)-*n:]
+	}
+	for _, e := range entries {
+		mark := " "
+		if e.CorrectedText != "" {
+			mark = "*"
+		}
+		text := oneLine(e.ProcessedText)
+		if e.Mode != "" {
+			text = "[" + e.Mode + "] " + text
+		}
+		fmt.Fprintf(stdout, "%s %s  %s\n", e.ID, mark, text)
+	}
+	return nil
+}
+
+// runHistoryCorrect records what the user changed a pasted transcription
+// to. The text comes from the arguments or, without any, from stdin.
+func runHistoryCorrect(args []string, stdin io.Reader) error {
+	if len(args) < 1 {
+		return &usageError{historyUsage}
+	}
+	id := args[0]
+	if id == "last" {
+		e, err := history.Latest()
+		if err != nil {
+			return fmt.Errorf("failed to read history: %w", err)
+		}
+		id = e.ID
+	}
+	e, err := history.Load(id)
+	if err != nil {
+		return fmt.Errorf("failed to load history entry: %w", err)
+	}
+
+	text := strings.Join(args[1:], " ")
+	if len(args) == 1 {
+		fmt.Fprintf(os.Stderr, "Transcription: %s\nEnter the corrected text (Ctrl-D to finish):\n", e.ProcessedText)
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			return fmt.Errorf("failed to read corrected text: %w", err)
+		}
+		text = string(data)
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return errors.New("corrected text is empty")
+	}
+	if text == e.ProcessedText {
+		log.Printf("Corrected text matches the transcription; nothing to learn")
+	}
+
+	if _, err := history.Correct(id, text); err != nil {
+		return fmt.Errorf("failed to save correction: %w", err)
+	}
+	log.Printf("Saved correction for %s", id)
+	return nil
+}
+
+func oneLine(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > 60 {
+		return string(r[:60]) + "…"
+	}
+	return s
+}
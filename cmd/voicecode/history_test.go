@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/noricha-vr/voicecode/internal/core/history"
+)
+
+// writeHistory saves e as the history entry id under the temporary home.
+func writeHistory(t *testing.T, home, id string, e history.Entry) {
+	t.Helper()
+	data, err := json.Marshal(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeHomeFile(t, home, ".voicecoding/history/"+id+".json", string(data))
+}
+
+func TestRunHistoryList(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		wantExit int
+		wantOut  string
+	}{
+		{"all", []string{"list"}, 0, "2026-01-01_090000    first\n2026-01-02_090000 *  second\n2026-01-03_090000    [memo] third line\n"},
+		{"last two", []string{"list", "-n", "2"}, 0, "2026-01-02_090000 *  second\n2026-01-03_090000    [memo] third line\n"},
+		{"bad count", []string{"list", "-n", "two"}, 2, ""},
+		{"extra argument", []string{"list", "all"}, 2, ""},
+		{"no subcommand", nil, 2, ""},
+		{"unknown subcommand", []string{"show"}, 2, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := withTempHome(t)
+			writeHistory(t, home, "2026-01-01_090000", history.Entry{ProcessedText: "first"})
+			writeHistory(t, home, "2026-01-02_090000", history.Entry{ProcessedText: "second", CorrectedText: "Second"})
+			writeHistory(t, home, "2026-01-03_090000", history.Entry{ProcessedText: "third\nline", Mode: "memo"})
+
+			var out strings.Builder
+			err := runHistory(tt.args, strings.NewReader(""), &out)
+			if got := exitCode(err); got != tt.wantExit {
+				t.Fatalf("runHistory(%q) = %v, exit %d; want %d", tt.args, err, got, tt.wantExit)
+			}
+			if out.String() != tt.wantOut {
+				t.Errorf("output = %q, want %q", out.String(), tt.wantOut)
+			}
+		})
+	}
+}
+
+func TestRunHistoryListEmpty(t *testing.T) {
+	withTempHome(t)
+	var out strings.Builder
+	if err := runHistory([]string{"list"}, strings.NewReader(""), &out); err != nil || out.Len() != 0 {
+		t.Errorf("runHistory(list) without history = %q, %v; want no output", out.String(), err)
+	}
+}
+
+func TestRunHistoryCorrect(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		stdin     string
+		noHistory bool
+		wantExit  int
+		wantID    string
+		wantText  string
+	}{
+		{"by id", []string{"correct", "2026-01-01_090000", "get", "state"}, "", false, 0, "2026-01-01_090000", "get state"},
+		{"last", []string{"correct", "last", "getState"}, "", false, 0, "2026-01-02_090000", "getState"},
+		{"stdin", []string{"correct", "last"}, "  getState を追加\n", false, 0, "2026-01-02_090000", "getState を追加"},
+		{"empty stdin", []string{"correct", "last"}, " \n", false, 1, "2026-01-02_090000", ""},
+		{"unknown id", []string{"correct", "2026-12-31_000000", "x"}, "", false, 1, "", ""},
+		{"invalid id", []string{"correct", "../settings", "x"}, "", false, 1, "", ""},
+		{"last without history", []string{"correct", "last", "x"}, "", true, 1, "", ""},
+		{"no id", []string{"correct"}, "", false, 2, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := withTempHome(t)
+			if !tt.noHistory {
+				writeHistory(t, home, "2026-01-01_090000", history.Entry{ProcessedText: "ゲットステート"})
+				writeHistory(t, home, "2026-01-02_090000", history.Entry{ProcessedText: "ゲットステートを追加"})
+			}
+
+			err := runHistory(tt.args, strings.NewReader(tt.stdin), &strings.Builder{})
+			if got := exitCode(err); got != tt.wantExit {
+				t.Fatalf("runHistory(%q) = %v, exit %d; want %d", tt.args, err, got, tt.wantExit)
+			}
+			if tt.wantID == "" {
+				return
+			}
+			e, err := history.Load(tt.wantID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if e.CorrectedText != tt.wantText {
+				t.Errorf("CorrectedText = %q, want %q", e.CorrectedText, tt.wantText)
+			}
+		})
+	}
+}
@@ -2,13 +2,21 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/noricha-vr/voicecode/internal/app"
+	"github.com/noricha-vr/voicecode/internal/core/audio"
+	"github.com/noricha-vr/voicecode/internal/core/prompt"
 	"github.com/noricha-vr/voicecode/internal/core/settings"
 	"github.com/noricha-vr/voicecode/internal/core/transcriber"
+	"github.com/noricha-vr/voicecode/internal/core/vocab"
 	"github.com/noricha-vr/voicecode/internal/platform/clipboard"
 	"github.com/noricha-vr/voicecode/internal/platform/hotkey"
 	"github.com/noricha-vr/voicecode/internal/platform/overlay"
@@ -21,11 +29,22 @@ func main() {
 	if len(os.Args) >= 2 {
 		switch os.Args[1] {
 		case "transcribe":
-			if len(os.Args) < 3 {
-				fmt.Fprintln(os.Stderr, "Usage: voicecode transcribe <wav-file>")
-				os.Exit(1)
-			}
-			runTranscribe(os.Args[2])
+			exit(runTranscribe(os.Args[2:], os.Stdout))
+			return
+		case "profile":
+			exit(runProfile(os.Args[2:], os.Stdout))
+			return
+		case "dict":
+			exit(runDict(os.Args[2:], os.Stdout))
+			return
+		case "history":
+			exit(runHistory(os.Args[2:], os.Stdin, os.Stdout))
+			return
+		case "eval":
+			exit(runEval(os.Args[2:], os.Stdout))
+			return
+		case "vocab":
+			exit(runVocab(os.Args[2:], os.Stdout))
 			return
 		case "help", "-h", "--help":
 			printUsage()
@@ -33,7 +52,7 @@ func main() {
 		default:
 			fmt.Fprintf(os.Stderr, "Unknown command: %s\n", os.Args[1])
 			printUsage()
-			os.Exit(1)
+			os.Exit(2)
 		}
 	}
 
@@ -45,30 +64,423 @@ func printUsage() {
 	fmt.Println("Usage: voicecode [command]")
 	fmt.Println()
 	fmt.Println("Commands:")
-	fmt.Println("  transcribe <wav-file>   Transcribe a WAV file")
+	fmt.Println("  transcribe [--stream] <wav-file>")
+	fmt.Println("                          Transcribe a WAV or FLAC file (--stream prints partial text as it arrives)")
+	fmt.Println("  transcribe --profile <name> <wav-file>")
+	fmt.Println("                          Transcribe with a dictionary profile instead of the configured one")
+	fmt.Println("  transcribe --mode <name> <wav-file>")
+	fmt.Println("                          Transcribe in a mode from settings instead of the configured one")
+	fmt.Println("  transcribe --language ja|en|auto --translate <lang> <wav-file>")
+	fmt.Println("                          Override the spoken language and print a translation")
+	fmt.Println("  transcribe --context <file> <wav-file>")
+	fmt.Println("                          Send the file as reference context for spelling identifiers")
+	fmt.Println("  profile                 List dictionary profiles (* marks the active one)")
+	fmt.Println("  profile <name>          Activate a dictionary profile")
+	fmt.Println("  profile --base          Use the base dictionary only")
+	fmt.Println("  dict import --format=google-ime|ms-ime <file>")
+	fmt.Println("                          Add IME user dictionary entries to the dictionary")
+	fmt.Println("                          (--profile name, --dry-run to preview)")
+	fmt.Println("  dict lint [--profile name] [--budget tokens]")
+	fmt.Println("                          Check the dictionary and the system prompt token budget")
+	fmt.Println("  dict suggest [--min-count N] [--apply]")
+	fmt.Println("                          Propose conversions from corrected history entries")
+	fmt.Println("  history list [-n count]  List recent transcriptions with their IDs")
+	fmt.Println("  history correct <id|last> [text]")
+	fmt.Println("                          Record the corrected text of a transcription (stdin without text)")
+	fmt.Println("  eval [options] <corpus-dir>")
+	fmt.Println("                          Score transcriptions of WAV files against .txt expected text (CER)")
+	fmt.Println("                          (--compare-model/--compare-mode, --fixtures/--record, --json, --max-cer)")
+	fmt.Println("  vocab scan [options] <dir>")
+	fmt.Println("                          Extract identifiers and terms from a source tree as hint words")
+	fmt.Println("                          (-o file or --profile name to write a dictionary; default stdout)")
 	fmt.Println("  help                    Show this help message")
 	fmt.Println()
 	fmt.Println("Without a command, starts in GUI mode with system tray.")
 }
 
-func runTranscribe(wavPath string) {
+// logHealth prints the per-model circuit breaker state when the backend tracks it.
+func logHealth(b transcriber.Backend) {
+	hr, ok := b.(transcriber.HealthReporter)
+	if !ok {
+		return
+	}
+	for _, h := range hr.Health() {
+		log.Printf("Health: %s", h)
+	}
+}
+
+// usageError reports invalid arguments. exit prints usage, when set, and
+// ends with status 2, the status the flag package uses for unknown flags.
+type usageError struct {
+	usage string // "" when the flag set already printed its own
+}
+
+func (e *usageError) Error() string {
+	if e.usage == "" {
+		return "invalid arguments"
+	}
+	return e.usage
+}
+
+// errCheckFailed is returned by commands that printed their findings and
+// failed a check the caller asked for, like dict lint or eval --max-cer.
+var errCheckFailed = errors.New("check failed")
+
+// exitCode returns the process status for the result of a subcommand: 0 on
+// success or -h, 2 for invalid arguments and 1 for anything else.
+func exitCode(err error) int {
+	var ue *usageError
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.As(err, &ue):
+		return 2
+	}
+	return 1
+}
+
+// exit reports the error of a subcommand on stderr and exits with its status.
+func exit(err error) {
+	code := exitCode(err)
+	if code == 0 {
+		return
+	}
+	var ue *usageError
+	switch {
+	case errors.As(err, &ue):
+		if ue.usage != "" {
+			fmt.Fprintln(os.Stderr, ue.usage)
+		}
+	case !errors.Is(err, errCheckFailed):
+		log.Printf("%v", err)
+	}
+	os.Exit(code)
+}
+
+// parseFlags parses args with a flag set that returns its errors, after
+// printing them, as a usageError.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return &usageError{}
+	}
+	return nil
+}
+
+// loadSettings returns the saved settings, or the defaults when they cannot
+// be read.
+func loadSettings() *settings.Settings {
+	cfg, err := settings.Load()
+	if err != nil {
+		log.Printf("Settings load failed, using defaults: %v", err)
+		cfg = settings.Default()
+	}
+	return cfg
+}
+
+const profileUsage = "Usage: voicecode profile [name | --base]"
+
+// runProfile lists or selects the dictionary profile stored in settings.json.
+func runProfile(args []string, stdout io.Writer) error {
+	if len(args) > 1 {
+		return &usageError{profileUsage}
+	}
+	cfg := loadSettings()
+	base := prompt.DictionaryPath()
+
+	if len(args) == 0 {
+		profiles, err := prompt.ListProfiles(base)
+		if err != nil {
+			return fmt.Errorf("failed to list profiles: %w", err)
+		}
+		mark := func(name string) string {
+			if name == cfg.DictionaryProfile {
+				return "*"
+			}
+			return " "
+		}
+		fmt.Fprintf(stdout, "%s (base only)\n", mark(""))
+		for _, name := range profiles {
+			fmt.Fprintf(stdout, "%s %s\n", mark(name), name)
+		}
+		if len(profiles) == 0 {
+			fmt.Fprintf(stdout, "No profiles found. Create %s\n", filepath.Join(prompt.ProfileDir(base), "<name>.txt"))
+		}
+		return nil
+	}
+
+	name := args[0]
+	switch {
+	case name == "--base":
+		name = ""
+	case strings.HasPrefix(name, "-"):
+		return &usageError{profileUsage}
+	default:
+		path, err := prompt.ProfilePath(base, name)
+		if err != nil {
+			return fmt.Errorf("invalid profile: %w", err)
+		}
+		if _, err := os.Stat(path); err != nil {
+			log.Printf("Warning: profile file not found yet: %s", path)
+		}
+	}
+	cfg.DictionaryProfile = name
+	if err := cfg.Save(); err != nil {
+		return fmt.Errorf("failed to save settings: %w", err)
+	}
+	if name == "" {
+		fmt.Fprintln(stdout, "Dictionary profile: base only")
+		return nil
+	}
+	fmt.Fprintf(stdout, "Dictionary profile: %s\n", name)
+	return nil
+}
+
+const vocabUsage = "Usage: voicecode vocab scan [--limit N] [--min-count N] [-o file | --profile name] [--force] <dir>"
+
+// runVocab dispatches the vocab subcommands.
+func runVocab(args []string, stdout io.Writer) error {
+	if len(args) < 1 || args[0] != "scan" {
+		return &usageError{vocabUsage}
+	}
+	return runVocabScan(args[1:], stdout)
+}
+
+// runVocabScan writes the vocabulary of a source tree as a hint dictionary,
+// to stdout unless -o or --profile names a file.
+func runVocabScan(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("vocab scan", flag.ContinueOnError)
+	limit := fs.Int("limit", vocab.DefaultLimit, "maximum number of hint words")
+	minCount := fs.Int("min-count", vocab.DefaultMinCount, "minimum occurrences of a word (package names are always kept)")
+	out := fs.String("o", "", "output file (default stdout)")
+	profile := fs.String("profile", "", "write to the named dictionary profile")
+	force := fs.Bool("force", false, "overwrite an existing output file")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 || *out != "" && *profile != "" {
+		return &usageError{vocabUsage}
+	}
+	root := fs.Arg(0)
+
+	terms, stats, err := vocab.Scan(root, vocab.Options{Limit: *limit, MinCount: *minCount})
+	if err != nil {
+		return fmt.Errorf("scan failed: %w", err)
+	}
+	log.Printf("Scanned %d files: %d candidate words, %d kept", stats.Files, stats.Words, len(terms))
+	if stats.Skipped > 0 {
+		log.Printf("Skipped %d unreadable files or directories", stats.Skipped)
+	}
+
+	path := *out
+	if *profile != "" {
+		path, err = prompt.ProfilePath(prompt.DictionaryPath(), *profile)
+		if err != nil {
+			return fmt.Errorf("invalid profile: %w", err)
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return fmt.Errorf("failed to create profile directory: %w", err)
+		}
+	}
+	if path == "" {
+		if err := vocab.WriteDictionary(stdout, root, terms); err != nil {
+			return fmt.Errorf("write failed: %w", err)
+		}
+		return nil
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if *force {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	f, err := os.OpenFile(path, flags, 0o644)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("%s already exists (use --force to overwrite)", path)
+		}
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	if err := vocab.WriteDictionary(f, root, terms); err != nil {
+		f.Close()
+		return fmt.Errorf("write failed: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("write failed: %w", err)
+	}
+	log.Printf("Wrote %d hint words to %s", len(terms), path)
+	return nil
+}
+
+const transcribeUsage = `Usage: voicecode transcribe [--stream] [--profile name] [--mode name] [--language ja|en|auto]
+                           [--translate lang] [--context file] <wav-file>`
+
+// transcribeSampleRate is the rate the recorder captures at. WAV input is
+// converted to it so files from other tools are sent like recordings.
+const transcribeSampleRate = 16000
+
+// runTranscribe transcribes a WAV or FLAC file and prints the result,
+// translated when a translation target is configured or given. Flags
+// override the settings for one run.
+func runTranscribe(args []string, stdout io.Writer) error {
+	cfg := loadSettings()
+	fs := flag.NewFlagSet("transcribe", flag.ContinueOnError)
+	stream := fs.Bool("stream", false, "print partial text as it arrives")
+	profile := fs.String("profile", cfg.DictionaryProfile, "dictionary profile instead of the configured one")
+	mode := fs.String("mode", cfg.Mode, "mode from settings to transcribe in")
+	language := fs.String("language", cfg.Language, "spoken language: ja, en or auto")
+	translate := fs.String("translate", cfg.TranslateTo, "translate the result into this language")
+	contextFile := fs.String("context", "", "send this file as reference context, redacted and truncated like the clipboard")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return &usageError{transcribeUsage}
+	}
+	wavPath := fs.Arg(0)
 	if _, err := os.Stat(wavPath); os.IsNotExist(err) {
-		log.Fatalf("File not found: %s", wavPath)
+		return fmt.Errorf("file not found: %s", wavPath)
 	}
 
-	ctx := context.Background()
-	t, err := transcriber.New(ctx, "")
+	cfg.DictionaryProfile = *profile
+	if *mode != "" {
+		if _, ok := cfg.FindMode(*mode); !ok {
+			return &usageError{fmt.Sprintf("Unknown mode %q (define it in settings.json \"modes\")", *mode)}
+		}
+	}
+	switch *language {
+	case "ja", "en", settings.LanguageAuto:
+		cfg.Language = *language
+	default:
+		return &usageError{fmt.Sprintf("Unknown language %q (want ja, en or auto)", *language)}
+	}
+	cfg.TranslateTo = strings.ToLower(*translate)
+	target := cfg.TranslationTarget()
+
+	ctx := transcriber.WithMode(context.Background(), *mode)
+	if *contextFile != "" {
+		data, err := os.ReadFile(*contextFile)
+		if err != nil {
+			return fmt.Errorf("failed to read context file: %w", err)
+		}
+		refContext, redactions := prompt.ReferenceContext(string(data), cfg.ContextMaxChars)
+		log.Printf("Reference context: %d chars, %d redacted", len([]rune(refContext)), redactions)
+		ctx = transcriber.WithReferenceContext(ctx, refContext)
+	}
+	t, err := transcriber.NewBackend(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("failed to initialize transcriber: %w", err)
+	}
+	defer t.Close()
+
+	dict, err := prompt.LoadProfile(prompt.DictionaryPath(), cfg.DictionaryProfile)
+	if err != nil {
+		log.Printf("Dictionary load failed, skipping local replacement: %v", err)
+	}
+	replacer := dict.Replacer()
+
+	uploadPath, cleanup, err := normalizeAudio(wavPath, audioFormat(cfg.UploadFormat))
+	if err != nil {
+		return fmt.Errorf("failed to read audio: %w", err)
+	}
+
+	if *stream && target != "" {
+		log.Printf("Streaming is disabled while translating")
+	}
+	if *stream && target == "" {
+		sr := replacer.Stream()
+		_, elapsed, err := transcriber.TranscribeFileStream(ctx, t, uploadPath, func(delta string) {
+			fmt.Fprint(stdout, sr.Write(delta))
+		})
+		cleanup()
+		fmt.Fprintln(stdout, sr.Flush())
+		logReplacements(sr.Replacements())
+		logHealth(t)
+		if err != nil {
+			return fmt.Errorf("transcription failed: %w", err)
+		}
+		log.Printf("Elapsed: %.2fs, Backend: %s, Model: %s, Mode: %s (stream)", elapsed, t.Name(), t.ModelName(), modeLabel(*mode))
+		return nil
+	}
+
+	text, elapsed, err := transcriber.TranscribeFile(ctx, t, uploadPath)
+	cleanup()
+	logHealth(t)
+	if err != nil {
+		return fmt.Errorf("transcription failed: %w", err)
+	}
+
+	text, fired := replacer.Apply(text)
+	logReplacements(fired)
+	if target != "" && text != "" {
+		translated, err := transcriber.TranslateText(ctx, t, text, target)
+		if err != nil {
+			return fmt.Errorf("translation failed: %w", err)
+		}
+		log.Printf("Source: %s", text)
+		text = translated
+	}
+	fmt.Fprintln(stdout, text)
+	log.Printf("Elapsed: %.2fs, Backend: %s, Model: %s, Mode: %s", elapsed, t.Name(), t.ModelName(), modeLabel(*mode))
+	return nil
+}
+
+// normalizeAudio converts a WAV file in any encoding, rate and channel
+// layout audio.DecodeWAV supports to 16 kHz mono in the upload format,
+// written to a temporary file that cleanup removes. Other files are sent as
+// they are.
+func normalizeAudio(path string, format audio.Format) (string, func(), error) {
+	if f, _ := audio.FormatOf(path); f != audio.FormatWAV {
+		return path, func() {}, nil
+	}
+	w, err := audio.ReadWAV(path)
+	if err != nil {
+		return "", nil, err
+	}
+	data, err := audio.Encode(format, w.PCM16(transcribeSampleRate), transcribeSampleRate)
 	if err != nil {
-		log.Fatalf("Failed to initialize transcriber: %v", err)
+		return "", nil, err
 	}
+	tmp, err := os.CreateTemp("", "voicecode-transcribe-*"+format.Ext())
+	if err != nil {
+		return "", nil, err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", nil, fmt.Errorf("writing converted audio: %w", err)
+	}
+	log.Printf("Audio: %s, %.1fs -> %d Hz, 1 ch, %s (%d bytes)", w, w.Duration(), transcribeSampleRate, format, len(data))
+	return tmp.Name(), func() { os.Remove(tmp.Name()) }, nil
+}
 
-	text, elapsed, err := t.Transcribe(ctx, wavPath)
+// audioFormat returns the audio format named by a setting, falling back to
+// WAV for an unknown name.
+func audioFormat(name string) audio.Format {
+	f, err := audio.ParseFormat(name)
 	if err != nil {
-		log.Fatalf("Transcription failed: %v", err)
+		log.Printf("%v, using %s", err, audio.FormatWAV)
+		return audio.FormatWAV
 	}
+	return f
+}
 
-	fmt.Println(text)
-	log.Printf("Elapsed: %.2fs, Model: %s", elapsed, t.ModelName())
+func modeLabel(mode string) string {
+	if mode == "" {
+		return "default"
+	}
+	return mode
+}
+
+// logReplacements prints the dictionary conversions applied locally.
+func logReplacements(fired []prompt.Replacement) {
+	for _, r := range fired {
+		log.Printf("Replaced: %s -> %s (x%d)", r.From, r.To, r.Count)
+	}
 }
 
 func runGUI() {
@@ -82,10 +494,12 @@ func runGUI() {
 	}
 
 	// Initialize transcriber
-	tr, err := transcriber.New(ctx, "")
+	tr, err := transcriber.NewBackend(ctx, cfg)
 	if err != nil {
 		log.Fatalf("[Init] Transcriber init failed: %v", err)
 	}
+	defer tr.Close()
+	log.Printf("[Init] Transcription backend: %s (model=%s)", tr.Name(), tr.ModelName())
 
 	// Initialize platform adapters
 	rec, err := recorder.NewRecorder()
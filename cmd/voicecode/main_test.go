@@ -0,0 +1,223 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/noricha-vr/voicecode/internal/core/settings"
+)
+
+// withTempHome points the settings, dictionary and history paths at a
+// temporary home directory and returns it.
+func withTempHome(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	return home
+}
+
+// writeHomeFile writes a file under the temporary home directory.
+func writeHomeFile(t *testing.T, home, rel, content string) string {
+	t.Helper()
+	path := filepath.Join(home, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"success", nil, 0},
+		{"help", flag.ErrHelp, 0},
+		{"usage", &usageError{"Usage: x"}, 2},
+		{"flag error", &usageError{}, 2},
+		{"check failed", errCheckFailed, 1},
+		{"wrapped check failed", fmt.Errorf("lint: %w", errCheckFailed), 1},
+		{"failure", errors.New("boom"), 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCode(tt.err); got != tt.want {
+				t.Errorf("exitCode(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFlags(t *testing.T) {
+	newFlags := func() *flag.FlagSet {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		fs.Int("n", 0, "")
+		return fs
+	}
+	if err := parseFlags(newFlags(), []string{"-n", "3", "x"}); err != nil {
+		t.Errorf("valid flags: %v", err)
+	}
+	if err := parseFlags(newFlags(), []string{"-n", "three"}); exitCode(err) != 2 {
+		t.Errorf("bad value: err = %v, exit %d; want 2", err, exitCode(err))
+	}
+	if err := parseFlags(newFlags(), []string{"--unknown"}); exitCode(err) != 2 {
+		t.Errorf("unknown flag: err = %v, exit %d; want 2", err, exitCode(err))
+	}
+	if err := parseFlags(newFlags(), []string{"-h"}); !errors.Is(err, flag.ErrHelp) || exitCode(err) != 0 {
+		t.Errorf("-h: err = %v, exit %d; want flag.ErrHelp, 0", err, exitCode(err))
+	}
+}
+
+func TestRunProfile(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		wantExit    int
+		wantOut     string
+		wantProfile string
+	}{
+		{"list", nil, 0, "* (base only)\n  backend\n", ""},
+		{"activate", []string{"backend"}, 0, "Dictionary profile: backend\n", "backend"},
+		{"activate missing file", []string{"frontend"}, 0, "Dictionary profile: frontend\n", "frontend"},
+		{"base", []string{"--base"}, 0, "Dictionary profile: base only\n", ""},
+		{"invalid name", []string{"../x"}, 1, "", "old"},
+		{"unknown flag", []string{"--bogus"}, 2, "", "old"},
+		{"too many arguments", []string{"a", "b"}, 2, "", "old"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := withTempHome(t)
+			writeHomeFile(t, home, ".voicecoding/dictionaries/backend.txt", "Go\n")
+			if tt.args != nil {
+				cfg := settings.Default()
+				cfg.DictionaryProfile = "old"
+				if err := cfg.Save(); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var out strings.Builder
+			err := runProfile(tt.args, &out)
+			if got := exitCode(err); got != tt.wantExit {
+				t.Fatalf("runProfile(%q) = %v, exit %d; want %d", tt.args, err, got, tt.wantExit)
+			}
+			if out.String() != tt.wantOut {
+				t.Errorf("output = %q, want %q", out.String(), tt.wantOut)
+			}
+			if tt.args == nil {
+				return
+			}
+			cfg, err := settings.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.DictionaryProfile != tt.wantProfile {
+				t.Errorf("saved profile = %q, want %q", cfg.DictionaryProfile, tt.wantProfile)
+			}
+		})
+	}
+}
+
+func TestRunVocab(t *testing.T) {
+	src := t.TempDir()
+	if err := os.WriteFile(filepath.Join(src, "widget.go"), []byte("package widget\n\nfunc renderWidget() {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		args     func(home string) []string
+		wantExit int
+		wantFile string // relative to home; "" checks stdout instead
+	}{
+		{"stdout", func(string) []string { return []string{"scan", src} }, 0, ""},
+		{"output file", func(home string) []string { return []string{"scan", "-o", filepath.Join(home, "vocab.txt"), src} }, 0, "vocab.txt"},
+		{"profile", func(string) []string { return []string{"scan", "--profile", "widget", src} }, 0, ".voicecoding/dictionaries/widget.txt"},
+		{"existing file", func(home string) []string {
+			return []string{"scan", "-o", writeHomeFile(t, home, "old.txt", "keep\n"), src}
+		}, 1, ""},
+		{"force", func(home string) []string {
+			return []string{"scan", "--force", "-o", writeHomeFile(t, home, "old.txt", "keep\n"), src}
+		}, 0, "old.txt"},
+		{"invalid profile", func(string) []string { return []string{"scan", "--profile", "../x", src} }, 1, ""},
+		{"missing directory", func(home string) []string { return []string{"scan", filepath.Join(home, "missing")} }, 1, ""},
+		{"no subcommand", func(string) []string { return nil }, 2, ""},
+		{"unknown subcommand", func(string) []string { return []string{"list", src} }, 2, ""},
+		{"no directory", func(string) []string { return []string{"scan"} }, 2, ""},
+		{"output and profile", func(home string) []string { return []string{"scan", "-o", "x.txt", "--profile", "p", src} }, 2, ""},
+		{"bad limit", func(string) []string { return []string{"scan", "--limit", "many", src} }, 2, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := withTempHome(t)
+			var out strings.Builder
+			err := runVocab(tt.args(home), &out)
+			if got := exitCode(err); got != tt.wantExit {
+				t.Fatalf("runVocab() = %v, exit %d; want %d", err, got, tt.wantExit)
+			}
+			if tt.wantExit != 0 {
+				return
+			}
+			written := out.String()
+			if tt.wantFile != "" {
+				data, err := os.ReadFile(filepath.Join(home, filepath.FromSlash(tt.wantFile)))
+				if err != nil {
+					t.Fatal(err)
+				}
+				written = string(data)
+				if out.Len() != 0 {
+					t.Errorf("stdout = %q, want nothing", out.String())
+				}
+			}
+			if !strings.HasPrefix(written, "# Generated by voicecode vocab scan") || !strings.Contains(written, "\nwidget\n") {
+				t.Errorf("dictionary = %q, want a generated header and widget", written)
+			}
+		})
+	}
+}
+
+func TestRunTranscribeArguments(t *testing.T) {
+	wav := filepath.Join(t.TempDir(), "in.wav")
+	if err := os.WriteFile(wav, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(t.TempDir(), "missing.wav")
+
+	tests := []struct {
+		name     string
+		args     []string
+		wantExit int
+	}{
+		{"no file", nil, 2},
+		{"two files", []string{wav, wav}, 2},
+		{"bad flag", []string{"--stream=often", wav}, 2},
+		{"unknown mode", []string{"--mode", "memo", wav}, 2},
+		{"unknown language", []string{"--language", "fr", wav}, 2},
+		{"missing file", []string{missing}, 1},
+		{"missing context file", []string{"--context", missing, wav}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withTempHome(t)
+			var out strings.Builder
+			err := runTranscribe(tt.args, &out)
+			if got := exitCode(err); got != tt.wantExit {
+				t.Errorf("runTranscribe(%q) = %v, exit %d; want %d", tt.args, err, got, tt.wantExit)
+			}
+			if out.Len() != 0 {
+				t.Errorf("output = %q, want nothing", out.String())
+			}
+		})
+	}
+}
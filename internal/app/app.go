@@ -2,6 +2,8 @@ package app
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"os"
 	"path/filepath"
@@ -10,6 +12,7 @@ import (
 
 	"github.com/noricha-vr/voicecode/internal/core/audio"
 	"github.com/noricha-vr/voicecode/internal/core/history"
+	"github.com/noricha-vr/voicecode/internal/core/prompt"
 	"github.com/noricha-vr/voicecode/internal/core/settings"
 	"github.com/noricha-vr/voicecode/internal/core/trace"
 	"github.com/noricha-vr/voicecode/internal/core/transcriber"
@@ -25,12 +28,15 @@ const (
 	audioSampleRateHz  = 16000
 	trimSilenceEnvVar  = "VOICECODE_TRIM_SILENCE"
 	minUsefulTrimDelta = 300 * time.Millisecond
+	// pasteSettleDelay is how long the target application is given to read
+	// a paste before the clipboard is overwritten with the next one.
+	pasteSettleDelay = 200 * time.Millisecond
 )
 
 // App is the main application orchestrator.
 type App struct {
 	settings    *settings.Settings
-	transcriber *transcriber.Transcriber
+	transcriber transcriber.Backend
 	recorder    recorder.Recorder
 	clipboard   clipboard.Clipboard
 	sound       sound.Player
@@ -43,18 +49,27 @@ type App struct {
 	cancelTimer context.CancelFunc
 	currentRun  *recordingRun
 
-	processMu sync.Mutex // guards processRecording from concurrent execution
+	reloadMu            sync.Mutex // serializes dictionary reloads from the watcher and the tray
+	dictionaryPath      string
+	dictionaryFiles     []string         // base, profile and #include files being watched
+	replacer            *prompt.Replacer // local conversion pass; reloaded with the dictionary
+	stopDictionaryWatch context.CancelFunc
+
+	processMu   sync.Mutex    // guards processRecording from concurrent execution
+	pasteSettle time.Duration // wait between consecutive pastes; see pasteText
+	lastPasteAt time.Time
 }
 
 type recordingRun struct {
 	tl                 *trace.Timeline
 	recordingStartedAt time.Time
+	mode               string // settings.Mode name; "" for the default prompts
 }
 
 // New creates a new App with all dependencies.
 func New(
 	cfg *settings.Settings,
-	tr *transcriber.Transcriber,
+	tr transcriber.Backend,
 	rec recorder.Recorder,
 	clip clipboard.Clipboard,
 	snd sound.Player,
@@ -63,14 +78,16 @@ func New(
 	tm tray.Manager,
 ) *App {
 	return &App{
-		settings:    cfg,
-		transcriber: tr,
-		recorder:    rec,
-		clipboard:   clip,
-		sound:       snd,
-		overlay:     ov,
-		hotkey:      hk,
-		tray:        tm,
+		settings:       cfg,
+		transcriber:    tr,
+		recorder:       rec,
+		clipboard:      clip,
+		sound:          snd,
+		overlay:        ov,
+		hotkey:         hk,
+		tray:           tm,
+		dictionaryPath: prompt.DictionaryPath(),
+		pasteSettle:    pasteSettleDelay,
 	}
 }
 
@@ -81,6 +98,8 @@ func (a *App) Run() {
 		OnHotkeyChange:     a.onHotkeyChange,
 		OnDurationChange:   a.onDurationChange,
 		OnPushToTalkToggle: a.onPushToTalkToggle,
+		OnProfileChange:    a.onProfileChange,
+		OnModeChange:       a.onModeChange,
 	})
 
 	a.tray.Run(func() {
@@ -91,13 +110,26 @@ func (a *App) Run() {
 		// Sync tray menu with current settings
 		a.tray.UpdateSettings(a.settings.Hotkey, a.settings.MaxRecordingDuration, a.settings.PushToTalk)
 
+		a.refreshModes()
+
 		a.registerHotkey()
-		log.Printf("[App] Hotkey registered: %s (push-to-talk: %v)", a.settings.Hotkey, a.settings.PushToTalk)
+		log.Printf("[App] Hotkey registered: %s (push-to-talk: %v, mode: %s)", a.settings.Hotkey, a.settings.PushToTalk, modeLabel(a.settings.Mode))
+
+		if _, _, err := a.loadDictionary(a.profile()); err != nil {
+			log.Printf("[App] 辞書の読み込みに失敗したためローカル置換を無効化します: %v", err)
+		}
+		a.refreshProfiles()
+		a.startDictionaryWatch()
 	}, func() {
 		// onQuit
 		log.Println("[App] Shutting down")
 		a.hotkey.Unregister()
 		a.mu.Lock()
+		if a.stopDictionaryWatch != nil {
+			a.stopDictionaryWatch()
+		}
+		a.mu.Unlock()
+		a.mu.Lock()
 		recording := a.isRecording
 		a.mu.Unlock()
 		if recording {
@@ -106,11 +138,28 @@ func (a *App) Run() {
 	})
 }
 
+// registerHotkey registers the main hotkey and the hotkey of each mode that
+// has one. Every hotkey stops a recording in progress.
 func (a *App) registerHotkey() {
+	var onRelease func()
 	if a.settings.PushToTalk {
-		a.hotkey.Register(a.settings.Hotkey, a.onHotkeyPress, a.onHotkeyRelease)
-	} else {
-		a.hotkey.Register(a.settings.Hotkey, a.onHotkeyPress, nil)
+		onRelease = a.onHotkeyRelease
+	}
+	a.hotkey.Register(a.settings.Hotkey, a.onHotkeyPress, onRelease)
+
+	for _, m := range a.settings.Modes {
+		if m.Hotkey == "" {
+			continue
+		}
+		if m.Hotkey == a.settings.Hotkey {
+			log.Printf("[App] モード %s のホットキー %s はメインのホットキーと重複するため登録しません", m.Name, m.Hotkey)
+			continue
+		}
+		if err := a.hotkey.Register(m.Hotkey, a.onModeHotkeyPress(m.Name), onRelease); err != nil {
+			log.Printf("[App] モード %s のホットキー %s を登録できません: %v", m.Name, m.Hotkey, err)
+			continue
+		}
+		log.Printf("[App] Hotkey registered: %s (mode: %s)", m.Hotkey, m.Name)
 	}
 }
 
@@ -151,15 +200,67 @@ func (a *App) onPushToTalkToggle(enabled bool) {
 	log.Printf("[App] Push-to-talk: %v", enabled)
 }
 
+// onModeChange selects the mode used by the main hotkey.
+func (a *App) onModeChange(mode string) {
+	a.mu.Lock()
+	if _, ok := a.settings.FindMode(mode); mode != "" && !ok {
+		a.mu.Unlock()
+		log.Printf("[App] 未定義のモードです: %s", mode)
+		return
+	}
+	a.settings.Mode = mode
+	if err := a.settings.Save(); err != nil {
+		log.Printf("[App] Failed to save settings: %v", err)
+	}
+	a.mu.Unlock()
+	a.refreshModes()
+	a.tray.SetStatus("モード: " + modeLabel(mode))
+	log.Printf("[App] Mode changed to: %s", modeLabel(mode))
+}
+
+// refreshModes lists the configured modes in the tray menu.
+func (a *App) refreshModes() {
+	a.mu.Lock()
+	names := make([]string, 0, len(a.settings.Modes))
+	for _, m := range a.settings.Modes {
+		names = append(names, m.Name)
+	}
+	current := a.settings.Mode
+	a.mu.Unlock()
+	a.tray.SetModes(names, current)
+}
+
+func modeLabel(mode string) string {
+	if mode == "" {
+		return "default"
+	}
+	return mode
+}
+
 func (a *App) onHotkeyPress() {
 	triggeredAt := time.Now()
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
+	a.toggleRecording(triggeredAt, a.settings.Mode)
+}
+
+// onModeHotkeyPress returns the press handler of a mode's own hotkey.
+func (a *App) onModeHotkeyPress(mode string) func() {
+	return func() {
+		triggeredAt := time.Now()
+		a.mu.Lock()
+		defer a.mu.Unlock()
+
+		a.toggleRecording(triggeredAt, mode)
+	}
+}
+
+func (a *App) toggleRecording(triggeredAt time.Time, mode string) {
 	if a.isRecording {
 		a.stopAndProcess(triggeredAt)
 	} else {
-		a.startRecording(triggeredAt)
+		a.startRecording(triggeredAt, mode)
 	}
 }
 
@@ -173,9 +274,11 @@ func (a *App) onHotkeyRelease() {
 	}
 }
 
-func (a *App) startRecording(triggeredAt time.Time) {
+func (a *App) startRecording(triggeredAt time.Time, mode string) {
 	tl := trace.NewWithStart("gui", triggeredAt)
-	tl.Eventf("hotkey.start key=%s push_to_talk=%v max_recording_duration=%ds restore_clipboard=%v", a.settings.Hotkey, a.settings.PushToTalk, a.settings.MaxRecordingDuration, a.settings.RestoreClipboard)
+	tl.Eventf("hotkey.start key=%s mode=%s push_to_talk=%v hands_free=%v max_recording_duration=%ds restore_clipboard=%v", a.settings.Hotkey, modeLabel(mode), a.settings.PushToTalk, a.settings.HandsFree.Enabled, a.settings.MaxRecordingDuration, a.settings.RestoreClipboard)
+
+	voiceEnded := a.setVoiceMonitor()
 
 	recStartDone := tl.Step("recorder.Start")
 	if err := a.recorder.Start(); err != nil {
@@ -188,13 +291,18 @@ func (a *App) startRecording(triggeredAt time.Time) {
 	recStartDone(nil)
 
 	a.isRecording = true
-	a.currentRun = &recordingRun{tl: tl, recordingStartedAt: time.Now()}
+	run := &recordingRun{tl: tl, recordingStartedAt: time.Now(), mode: mode}
+	a.currentRun = run
 
 	sndStartDone := tl.Step("sound.Play(Start)")
 	sndStartDone(a.sound.Play(sound.Start))
 
 	ovShowDone := tl.Step("overlay.Show")
-	ovShowDone(a.overlay.Show("Recording..."))
+	overlayText := "Recording..."
+	if mode != "" {
+		overlayText = fmt.Sprintf("Recording (%s)...", mode)
+	}
+	ovShowDone(a.overlay.Show(overlayText))
 
 	trayRecDone := tl.Step("tray.SetState(Recording)")
 	trayRecDone(a.tray.SetState(tray.Recording))
@@ -206,6 +314,14 @@ func (a *App) startRecording(triggeredAt time.Time) {
 		select {
 		case <-ctx.Done():
 			return
+		case speech := <-voiceEnded:
+			a.mu.Lock()
+			defer a.mu.Unlock()
+			if a.isRecording && a.currentRun == run {
+				log.Printf("[App] 発話の終了を検出したため録音を停止します (発話 %s)", speech.Truncate(time.Millisecond))
+				run.tl.Eventf("recording.voice_ended speech=%s silence=%.1fs", speech.Truncate(time.Millisecond), a.settings.HandsFree.SilenceSec)
+				a.stopAndProcess(time.Now())
+			}
 		case <-time.After(time.Duration(a.settings.MaxRecordingDuration) * time.Second):
 			log.Printf("[App] Recording timeout (%ds)", a.settings.MaxRecordingDuration)
 			a.mu.Lock()
@@ -226,8 +342,10 @@ func (a *App) stopAndProcess(triggeredAt time.Time) {
 
 	var tl *trace.Timeline
 	var talkDuration time.Duration
+	var mode string
 	if run != nil {
 		tl = run.tl
+		mode = run.mode
 		if !run.recordingStartedAt.IsZero() {
 			talkDuration = triggeredAt.Sub(run.recordingStartedAt)
 		}
@@ -250,6 +368,9 @@ func (a *App) stopAndProcess(triggeredAt time.Time) {
 	samples, err := a.recorder.Stop()
 	stopDone(err)
 	a.isRecording = false
+	if m, ok := a.recorder.(recorder.Monitor); ok {
+		m.SetMonitor(nil)
+	}
 
 	sndStopDone := recStopDone.Step("sound.Play(Stop)")
 	sndStopDone(a.sound.Play(sound.Stop))
@@ -274,10 +395,46 @@ func (a *App) stopAndProcess(triggeredAt time.Time) {
 	if tl != nil {
 		tl.Eventf("processing.spawn samples=%d", len(samples))
 	}
-	go a.processRecording(samples, tl, talkDuration)
+	go a.processRecording(samples, tl, talkDuration, mode)
 }
 
-func (a *App) processRecording(samples []int16, tl *trace.Timeline, talkDuration time.Duration) {
+// setVoiceMonitor installs the hands-free voice detector on the recorder. The
+// returned channel receives the speech duration once the speaker has
+// finished; it is nil when hands-free is off, push-to-talk is on or the
+// recorder cannot be monitored.
+func (a *App) setVoiceMonitor() <-chan time.Duration {
+	m, ok := a.recorder.(recorder.Monitor)
+	hf := a.settings.HandsFree
+	if !hf.Enabled || a.settings.PushToTalk {
+		if ok {
+			m.SetMonitor(nil)
+		}
+		return nil
+	}
+	if !ok {
+		log.Printf("[App] この録音デバイスはハンズフリーに対応していません")
+		return nil
+	}
+	seconds := func(sec float64) time.Duration { return time.Duration(sec * float64(time.Second)) }
+	detector := audio.NewVoiceDetector(audioSampleRateHz, seconds(hf.SilenceSec), seconds(hf.MinSpeechSec))
+	ended := make(chan time.Duration, 1)
+	notified := false
+	m.SetMonitor(func(samples []int16) {
+		// Runs on the audio thread only, so the detector needs no lock.
+		if !notified && detector.Write(samples) {
+			notified = true
+			ended <- detector.SpeechDuration()
+		}
+	})
+	return ended
+}
+
+func (a *App) processRecording(samples []int16, tl *trace.Timeline, talkDuration time.Duration, mode string) {
+	// The tray callbacks update the settings under a.mu while this runs.
+	a.mu.Lock()
+	cfg := *a.settings
+	a.mu.Unlock()
+
 	if tl != nil {
 		tl.Eventf("processing.start samples=%d talk_duration=%s", len(samples), talkDuration.Truncate(time.Millisecond))
 	}
@@ -301,9 +458,10 @@ func (a *App) processRecording(samples []int16, tl *trace.Timeline, talkDuration
 		setIdleDone(a.tray.SetState(tray.Idle))
 	}()
 
-	// Save WAV to temp file
+	// Save audio to temp file in the upload format
+	uploadFormat := audioFormat(cfg.UploadFormat)
 	tmpDir := os.TempDir()
-	wavPath := filepath.Join(tmpDir, "voicecode_recording.wav")
+	audioPath := filepath.Join(tmpDir, "voicecode_recording"+uploadFormat.Ext())
 	wavWriteDone := (*trace.Timeline)(nil)
 	if tl != nil {
 		wavWriteDone = tl
@@ -351,23 +509,27 @@ func (a *App) processRecording(samples []int16, tl *trace.Timeline, talkDuration
 		}
 	}
 
-	writeDone := wavWriteDone.Step("audio.WriteWAV")
-	if err := audio.WriteWAV(wavPath, samples, audioSampleRateHz); err != nil {
-		writeDone(err)
-		log.Printf("[App] Failed to write WAV: %v", err)
+	writeDone := wavWriteDone.Step("audio.Encode(" + string(uploadFormat) + ")")
+	audioData, writeErr := audio.Encode(uploadFormat, samples, audioSampleRateHz)
+	if writeErr == nil {
+		writeErr = os.WriteFile(audioPath, audioData, 0o644)
+	}
+	if writeErr != nil {
+		writeDone(writeErr)
+		log.Printf("[App] Failed to write audio: %v", writeErr)
 		a.sound.Play(sound.Error)
 		if tl != nil {
-			tl.Finishf("aborted: audio.WriteWAV failed")
+			tl.Finishf("aborted: audio write failed")
 		}
 		return
 	}
 	writeDone(nil)
-	defer os.Remove(wavPath)
+	defer os.Remove(audioPath)
+	if tl != nil {
+		tl.Eventf("audio.encoded format=%s bytes=%d pcm_bytes=%d", uploadFormat, len(audioData), 2*len(samples))
+	}
 
-	// Get duration
-	getDurDone := wavWriteDone.Step("audio.GetDuration")
-	duration, durErr := audio.GetDuration(wavPath)
-	getDurDone(durErr)
+	duration := float64(len(samples)) / audioSampleRateHz
 
 	// Transcribe
 	if a.transcriber == nil {
@@ -379,19 +541,129 @@ func (a *App) processRecording(samples []int16, tl *trace.Timeline, talkDuration
 		return
 	}
 
+	// Save original clipboard. Streaming pastes while transcribing, so it must
+	// be captured before the first delta arrives.
+	var originalClip string
+	clipSaved := false
+	saveOriginalClip := func() {
+		if !cfg.RestoreClipboard || clipSaved {
+			return
+		}
+		clipSaved = true
+		clipGetDone := wavWriteDone.Step("clipboard.GetText(original)")
+		var clipErr error
+		originalClip, clipErr = a.clipboard.GetText()
+		clipGetDone(clipErr)
+		if clipErr != nil {
+			log.Printf("[App] Failed to get clipboard: %v", clipErr)
+		}
+	}
+
 	ctx := trace.WithTimeline(context.Background(), tl)
-	txDone := wavWriteDone.Step("transcriber.Transcribe")
-	text, elapsed, err := a.transcriber.Transcribe(ctx, wavPath)
-	txDone(err)
+	if mode != "" {
+		ctx = transcriber.WithMode(ctx, mode)
+	}
+	if src := cfg.ContextSource; src != "" {
+		// Read before anything is pasted: the clipboard is about to be
+		// overwritten with the transcription.
+		readDone := wavWriteDone.Step("context.read(" + src + ")")
+		raw, readErr := a.readReferenceContext(src, func() (string, error) {
+			if cfg.RestoreClipboard {
+				saveOriginalClip()
+				return originalClip, nil
+			}
+			return a.clipboard.GetText()
+		})
+		readDone(readErr)
+		if readErr != nil {
+			log.Printf("[App] 参照コンテキストを取得できません (%s): %v", src, readErr)
+		}
+		refContext, redactions := prompt.ReferenceContext(raw, cfg.ContextMaxChars)
+		if tl != nil {
+			tl.Eventf("context source=%s raw_chars=%d sent_chars=%d redactions=%d", src, len([]rune(raw)), len([]rune(refContext)), redactions)
+		}
+		if refContext != "" {
+			ctx = transcriber.WithReferenceContext(ctx, refContext)
+		}
+	}
+	var (
+		text     string
+		elapsed  float64
+		err      error
+		streamed bool
+	)
+	var pasteErr error
+	pasteDelta := func(delta string) {
+		if pasteErr != nil {
+			return
+		}
+		if pasteErr = a.pasteText(delta); pasteErr != nil {
+			log.Printf("[App] Failed to paste streamed text: %v", pasteErr)
+			return
+		}
+		streamed = true
+	}
+	// A translation is pasted as a whole once the transcription is done.
+	target := cfg.TranslationTarget()
+	streaming := cfg.Streaming && target == ""
+	var emit func(string)
+	var streamReplacer *prompt.StreamReplacer
+	if streaming {
+		saveOriginalClip()
+		// Deltas go through the dictionary pass before they are pasted; it
+		// holds back text that may still become a match.
+		streamReplacer = a.currentReplacer().Stream()
+		emit = func(delta string) {
+			if out := streamReplacer.Write(delta); out != "" {
+				pasteDelta(out)
+			}
+		}
+	}
+
+	chunkCfg := transcriber.ChunkConfig{
+		MaxChunkSec: cfg.Chunking.MaxChunkSec,
+		Concurrency: cfg.Chunking.Concurrency,
+		Retries:     cfg.Chunking.Retries,
+		Format:      uploadFormat,
+	}
+	switch {
+	case chunkCfg.MaxChunkSec > 0 && float64(len(samples)) > chunkCfg.MaxChunkSec*audioSampleRateHz:
+		txDone := wavWriteDone.Step("transcriber.TranscribeSamples(chunked)")
+		start := time.Now()
+		text, err = transcriber.TranscribeSamples(ctx, a.transcriber, samples, audioSampleRateHz, chunkCfg, emit)
+		elapsed = time.Since(start).Seconds()
+		txDone(err)
+	case streaming:
+		txDone := wavWriteDone.Step("transcriber.TranscribeStream")
+		text, elapsed, err = transcriber.TranscribeFileStream(ctx, a.transcriber, audioPath, emit)
+		txDone(err)
+	default:
+		txDone := wavWriteDone.Step("transcriber.Transcribe")
+		text, elapsed, err = transcriber.TranscribeFile(ctx, a.transcriber, audioPath)
+		txDone(err)
+	}
+	if err == nil && streamReplacer != nil {
+		if tail := streamReplacer.Flush(); tail != "" {
+			pasteDelta(tail)
+		}
+	}
+	if err == nil {
+		err = pasteErr
+	}
 	if err != nil {
-		log.Printf("[App] Transcription failed: %v", err)
-		a.sound.Play(sound.Error)
+		a.reportTranscribeError(err)
+		if streamed {
+			a.restoreClipboard(tl, originalClip)
+		}
 		if tl != nil {
 			tl.Finishf("aborted: transcribe failed")
 		}
 		return
 	}
 
+	rawText := text
+	text, replacements := a.applyReplacements(rawText)
+
 	if text == "" {
 		log.Println("[App] Empty transcription result (silence/hallucination)")
 		a.sound.Play(sound.Success)
@@ -401,42 +673,52 @@ func (a *App) processRecording(samples []int16, tl *trace.Timeline, talkDuration
 		return
 	}
 
-	// Save original clipboard
-	var originalClip string
-	if a.settings.RestoreClipboard {
-		clipGetDone := wavWriteDone.Step("clipboard.GetText(original)")
-		var clipErr error
-		originalClip, clipErr = a.clipboard.GetText()
-		clipGetDone(clipErr)
-		if clipErr != nil {
-			log.Printf("[App] Failed to get clipboard: %v", clipErr)
+	pasted := text
+	var translated string
+	if target != "" {
+		trDone := wavWriteDone.Step("transcriber.Translate")
+		var trErr error
+		translated, trErr = transcriber.TranslateText(ctx, a.transcriber, text, target)
+		trDone(trErr)
+		switch {
+		case trErr != nil:
+			log.Printf("[App] 翻訳に失敗したため原文をペーストします: %v", trErr)
+			translated = ""
+		case translated == "":
+			log.Printf("[App] 翻訳結果が空のため原文をペーストします")
+		default:
+			pasted = translated
 		}
 	}
 
-	// Set text and paste
-	clipSetDone := wavWriteDone.Step("clipboard.SetText(result)")
-	if err := a.clipboard.SetText(text); err != nil {
-		clipSetDone(err)
-		log.Printf("[App] Failed to set clipboard: %v", err)
-		a.sound.Play(sound.Error)
-		if tl != nil {
-			tl.Finishf("aborted: clipboard.SetText failed")
+	if !streamed {
+		saveOriginalClip()
+
+		// Set text and paste
+		clipSetDone := wavWriteDone.Step("clipboard.SetText(result)")
+		if err := a.clipboard.SetText(pasted); err != nil {
+			clipSetDone(err)
+			log.Printf("[App] Failed to set clipboard: %v", err)
+			a.sound.Play(sound.Error)
+			if tl != nil {
+				tl.Finishf("aborted: clipboard.SetText failed")
+			}
+			return
 		}
-		return
-	}
-	clipSetDone(nil)
+		clipSetDone(nil)
 
-	pasteDone := wavWriteDone.Step("clipboard.Paste")
-	if err := a.clipboard.Paste(); err != nil {
-		pasteDone(err)
-		log.Printf("[App] Failed to paste: %v", err)
-		a.sound.Play(sound.Error)
-		if tl != nil {
-			tl.Finishf("aborted: clipboard.Paste failed")
+		pasteDone := wavWriteDone.Step("clipboard.Paste")
+		if err := a.clipboard.Paste(); err != nil {
+			pasteDone(err)
+			log.Printf("[App] Failed to paste: %v", err)
+			a.sound.Play(sound.Error)
+			if tl != nil {
+				tl.Finishf("aborted: clipboard.Paste failed")
+			}
+			return
 		}
-		return
+		pasteDone(nil)
 	}
-	pasteDone(nil)
 
 	readyAt := time.Duration(0)
 	if tl != nil {
@@ -448,36 +730,134 @@ func (a *App) processRecording(samples []int16, tl *trace.Timeline, talkDuration
 	sndSuccessDone(a.sound.Play(sound.Success))
 
 	// Restore clipboard after delay
-	if a.settings.RestoreClipboard && originalClip != "" {
-		if tl != nil {
-			tl.Eventf("async.clipboard.restore scheduled (500ms)")
+	a.restoreClipboard(tl, originalClip)
+
+	// Save to history, re-encoding only when its format differs from the
+	// upload's
+	historyFormat := audioFormat(cfg.HistoryFormat)
+	historyData := audioData
+	var encErr error
+	if historyFormat != uploadFormat {
+		encHistDone := wavWriteDone.Step("audio.Encode(" + string(historyFormat) + ")")
+		historyData, encErr = audio.Encode(historyFormat, samples, audioSampleRateHz)
+		encHistDone(encErr)
+		if encErr != nil {
+			log.Printf("[App] Failed to encode history audio: %v", encErr)
 		}
-		go func() {
-			time.Sleep(500 * time.Millisecond)
-			if tl != nil {
-				restoreDone := tl.Step("async.clipboard.SetText(restore)")
-				restoreDone(a.clipboard.SetText(originalClip))
-				return
-			}
-			a.clipboard.SetText(originalClip)
-		}()
 	}
-
-	// Save to history
-	readHistDone := wavWriteDone.Step("os.ReadFile(wav)")
-	wavData, err := os.ReadFile(wavPath)
-	readHistDone(err)
-	if err == nil {
-		saveHistDone := wavWriteDone.Step("history.Save")
-		_, saveErr := history.Save(wavData, text, text, duration)
+	if encErr == nil {
+		saveHistDone := wavWriteDone.Step("history.SaveEntry")
+		entry := history.Entry{
+			RawTranscription: rawText,
+			ProcessedText:    text,
+			DurationSec:      duration,
+			Replacements:     replacements,
+			Mode:             mode,
+			Language:         cfg.Language,
+		}
+		if translated != "" {
+			entry.TranslatedText, entry.TranslatedTo = translated, target
+		}
+		_, saveErr := history.SaveEntry(historyData, historyFormat.Ext(), entry)
 		saveHistDone(saveErr)
 		if saveErr != nil {
 			log.Printf("[App] Failed to save history: %v", saveErr)
 		}
 	}
 
-	log.Printf("[App] Done: %q (%.2fs)", text, elapsed)
+	log.Printf("[App] Done: %q (%.2fs)", pasted, elapsed)
+	if tl != nil {
+		tl.Finishf("ok text_len=%d gemini_elapsed=%.2fs result_ready=%s", len(pasted), elapsed, readyAt.Truncate(time.Millisecond))
+	}
+}
+
+// audioFormat returns the audio format named by a setting, falling back to
+// WAV for an empty or unknown name.
+func audioFormat(name string) audio.Format {
+	f, err := audio.ParseFormat(name)
+	if err != nil {
+		log.Printf("[App] %v, using %s", err, audio.FormatWAV)
+		return audio.FormatWAV
+	}
+	return f
+}
+
+// reportTranscribeError logs a transcription failure and plays a sound that
+// tells the user whether to fix the configuration or simply try again.
+func (a *App) reportTranscribeError(err error) {
+	e := transcriber.Classify(err)
+	switch e.Kind {
+	case transcriber.KindAuth:
+		log.Printf("[App] Transcription failed: API キーが無効か権限がありません。設定を確認してください: %v", err)
+		a.sound.Play(sound.AuthError)
+	case transcriber.KindQuota:
+		log.Printf("[App] Transcription failed: API の利用上限に達しました。プランまたはクォータを確認してください: %v", err)
+		a.sound.Play(sound.AuthError)
+	case transcriber.KindRateLimit, transcriber.KindTransient:
+		if e.RetryAfter > 0 {
+			log.Printf("[App] Transcription failed: 一時的なエラーです。%s 後に再試行してください: %v", e.RetryAfter, err)
+		} else {
+			log.Printf("[App] Transcription failed: 一時的なエラーです。もう一度試してください: %v", err)
+		}
+		a.sound.Play(sound.Retry)
+	case transcriber.KindCancelled:
+		log.Printf("[App] Transcription cancelled: %v", err)
+	default:
+		log.Printf("[App] Transcription failed (%s): %v", e.Kind, err)
+		a.sound.Play(sound.Error)
+	}
+}
+
+// readReferenceContext returns the raw text of the reference context source:
+// the clipboard through readClipboard, or the primary selection when the
+// clipboard supports it.
+func (a *App) readReferenceContext(source string, readClipboard func() (string, error)) (string, error) {
+	if source != settings.ContextSelection {
+		return readClipboard()
+	}
+	sr, ok := a.clipboard.(clipboard.SelectionReader)
+	if !ok {
+		return "", errors.New("primary selection is not supported on this platform")
+	}
+	return sr.GetSelection()
+}
+
+// pasteText puts text on the clipboard and pastes it into the active window.
+func (a *App) pasteText(text string) error {
+	// The target application reads a paste asynchronously. Overwriting the
+	// clipboard right away could make it paste the next delta twice and
+	// lose this one, so wait like restoreClipboard does.
+	if !a.lastPasteAt.IsZero() {
+		if wait := a.pasteSettle - time.Since(a.lastPasteAt); wait > 0 {
+			time.Sleep(wait)
+		}
+	}
+	if err := a.clipboard.SetText(text); err != nil {
+		return fmt.Errorf("set clipboard: %w", err)
+	}
+	if err := a.clipboard.Paste(); err != nil {
+		return fmt.Errorf("paste: %w", err)
+	}
+	a.lastPasteAt = time.Now()
+	return nil
+}
+
+// restoreClipboard puts originalClip back on the clipboard after a short delay
+// so the target application has time to consume the paste.
+func (a *App) restoreClipboard(tl *trace.Timeline, originalClip string) {
+	if !a.settings.RestoreClipboard || originalClip == "" {
+		return
+	}
 	if tl != nil {
-		tl.Finishf("ok text_len=%d gemini_elapsed=%.2fs result_ready=%s", len(text), elapsed, readyAt.Truncate(time.Millisecond))
+		tl.Eventf("async.clipboard.restore scheduled (500ms)")
 	}
+	go func() {
+		time.Sleep(500 * time.Millisecond)
+		if tl != nil {
+			restoreDone := tl.Step("async.clipboard.SetText(restore)")
+			restoreDone(a.clipboard.SetText(originalClip))
+			return
+		}
+		a.clipboard.SetText(originalClip)
+	}()
 }
@@ -1,10 +1,19 @@
 package app
 
 import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/noricha-vr/voicecode/internal/core/history"
 	"github.com/noricha-vr/voicecode/internal/core/settings"
+	"github.com/noricha-vr/voicecode/internal/core/transcriber"
+	"github.com/noricha-vr/voicecode/internal/platform/clipboard"
 	"github.com/noricha-vr/voicecode/internal/platform/sound"
 	"github.com/noricha-vr/voicecode/internal/platform/tray"
 )
@@ -23,13 +32,37 @@ func (m *mockRecorder) Stop() ([]int16, error) {
 }
 func (m *mockRecorder) IsRecording() bool { return m.recording }
 
+// mockMonitorRecorder is a mockRecorder that supports recorder.Monitor.
+type mockMonitorRecorder struct {
+	mockRecorder
+	mu      sync.Mutex
+	monitor func([]int16)
+}
+
+func (m *mockMonitorRecorder) SetMonitor(fn func([]int16)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.monitor = fn
+}
+
+// feed passes samples to the monitor in recorder-sized frames.
+func (m *mockMonitorRecorder) feed(samples []int16) {
+	m.mu.Lock()
+	fn := m.monitor
+	m.mu.Unlock()
+	for i := 0; fn != nil && i < len(samples); i += 1024 {
+		fn(samples[i:min(i+1024, len(samples))])
+	}
+}
+
 type mockClipboard struct {
-	text string
+	text   string
+	pasted []string
 }
 
 func (m *mockClipboard) GetText() (string, error) { return m.text, nil }
 func (m *mockClipboard) SetText(t string) error   { m.text = t; return nil }
-func (m *mockClipboard) Paste() error             { return nil }
+func (m *mockClipboard) Paste() error             { m.pasted = append(m.pasted, m.text); return nil }
 
 type mockSound struct {
 	lastPlayed sound.SoundType
@@ -46,16 +79,21 @@ func (m *mockOverlay) Show(t string) error { m.visible = true; m.text = t; retur
 func (m *mockOverlay) Hide() error         { m.visible = false; return nil }
 
 type mockHotkey struct {
-	onPress   func()
-	onRelease func()
+	onPress    func()
+	onRelease  func()
+	pressByKey map[string]func()
 }
 
 func (m *mockHotkey) Register(key string, onPress func(), onRelease func()) error {
 	m.onPress = onPress
 	m.onRelease = onRelease
+	if m.pressByKey == nil {
+		m.pressByKey = make(map[string]func())
+	}
+	m.pressByKey[key] = onPress
 	return nil
 }
-func (m *mockHotkey) Unregister() error { return nil }
+func (m *mockHotkey) Unregister() error { m.pressByKey = nil; return nil }
 
 type mockTray struct {
 	state     tray.State
@@ -65,6 +103,11 @@ type mockTray struct {
 	curHotkey string
 	curDur    int
 	curPTT    bool
+	status    string
+	profiles  []string
+	curProf   string
+	modes     []string
+	curMode   string
 }
 
 func (m *mockTray) Run(onReady func(), onQuit func()) {
@@ -76,12 +119,66 @@ func (m *mockTray) Run(onReady func(), onQuit func()) {
 }
 func (m *mockTray) SetState(state tray.State) error                { m.state = state; return nil }
 func (m *mockTray) SetSettingsCallbacks(cb tray.SettingsCallbacks) { m.cb = cb }
+func (m *mockTray) SetStatus(status string)                        { m.status = status }
+func (m *mockTray) SetProfiles(profiles []string, current string) {
+	m.profiles = profiles
+	m.curProf = current
+}
+func (m *mockTray) SetModes(modes []string, current string) {
+	m.modes = modes
+	m.curMode = current
+}
 func (m *mockTray) UpdateSettings(hotkey string, maxDuration int, pushToTalk bool) {
 	m.curHotkey = hotkey
 	m.curDur = maxDuration
 	m.curPTT = pushToTalk
 }
 
+type mockBackend struct {
+	mu       sync.Mutex
+	text     string
+	err      error
+	calls    int
+	gotAudio []byte
+	gotOpts  transcriber.Options
+}
+
+func (m *mockBackend) Transcribe(ctx context.Context, audio []byte, opts transcriber.Options) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls++
+	m.gotAudio = audio
+	m.gotOpts = opts
+	return m.text, m.err
+}
+func (m *mockBackend) Name() string      { return "mock" }
+func (m *mockBackend) ModelName() string { return "mock-model" }
+func (m *mockBackend) Close() error      { return nil }
+
+type mockStreamingBackend struct {
+	mockBackend
+	deltas []string
+}
+
+func (m *mockStreamingBackend) TranscribeStream(ctx context.Context, audio []byte, opts transcriber.Options, emit func(string)) (string, error) {
+	m.calls++
+	var text string
+	for _, d := range m.deltas {
+		emit(d)
+		text += d
+	}
+	return text, m.err
+}
+
+// speechSamples returns 1s of constant-amplitude audio that survives silence trimming.
+func speechSamples() []int16 {
+	samples := make([]int16, 16000)
+	for i := range samples {
+		samples[i] = 2000
+	}
+	return samples
+}
+
 func TestNewApp(t *testing.T) {
 	cfg := settings.Default()
 	snd := &mockSound{}
@@ -195,7 +292,7 @@ func TestStartStopRecording(t *testing.T) {
 
 	// Start recording
 	a.mu.Lock()
-	a.startRecording(time.Now())
+	a.startRecording(time.Now(), "")
 	a.mu.Unlock()
 
 	if !a.isRecording {
@@ -220,3 +317,446 @@ func TestStartStopRecording(t *testing.T) {
 		t.Error("expected overlay to be hidden")
 	}
 }
+
+func TestProcessRecordingWithBackend(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	cfg := settings.Default()
+	backend := &mockBackend{text: "ReactのuseStateを使う"}
+	clip := &mockClipboard{}
+	snd := &mockSound{}
+	tr := &mockTray{}
+	a := New(cfg, backend, &mockRecorder{}, clip, snd, &mockOverlay{}, &mockHotkey{}, tr)
+
+	a.processRecording(speechSamples(), nil, time.Second, "")
+
+	if backend.calls != 1 {
+		t.Fatalf("backend calls = %d, want 1", backend.calls)
+	}
+	if string(backend.gotAudio[:4]) != "RIFF" {
+		t.Errorf("backend should receive WAV data, got prefix %q", backend.gotAudio[:4])
+	}
+	if clip.text != backend.text {
+		t.Errorf("clipboard = %q, want %q", clip.text, backend.text)
+	}
+	if snd.lastPlayed != sound.Success {
+		t.Errorf("expected Success sound, got %v", snd.lastPlayed)
+	}
+	if tr.state != tray.Idle {
+		t.Errorf("tray state = %v, want Idle", tr.state)
+	}
+
+	entries, err := filepath.Glob(filepath.Join(home, ".voicecoding", "history", "*.json"))
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected 1 history entry, got %v (err=%v)", entries, err)
+	}
+}
+
+// TestProcessRecordingDuringSettingsChange is meant for go test -race.
+func TestProcessRecordingDuringSettingsChange(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	a := New(settings.Default(), &mockBackend{text: "テスト"}, &mockRecorder{}, &mockClipboard{}, &mockSound{}, &mockOverlay{}, &mockHotkey{}, &mockTray{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		a.processRecording(speechSamples(), nil, time.Second, "")
+	}()
+	for _, d := range []int{30, 60, 90} {
+		a.onDurationChange(d)
+	}
+	<-done
+}
+
+func TestProcessRecordingBackendError(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	tests := []struct {
+		name      string
+		err       error
+		wantSound sound.SoundType
+	}{
+		{"unclassified", errors.New("boom"), sound.Error},
+		{"invalid api key", &transcriber.APIError{Kind: transcriber.KindAuth, Err: errors.New("API key not valid")}, sound.AuthError},
+		{"quota", &transcriber.APIError{Kind: transcriber.KindQuota, Err: errors.New("insufficient_quota")}, sound.AuthError},
+		{"rate limited", &transcriber.APIError{Kind: transcriber.KindRateLimit, Err: errors.New("429")}, sound.Retry},
+		{"timeout", os.ErrDeadlineExceeded, sound.Retry},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := settings.Default()
+			backend := &mockBackend{err: tt.err}
+			clip := &mockClipboard{text: "original"}
+			snd := &mockSound{}
+			a := New(cfg, backend, &mockRecorder{}, clip, snd, &mockOverlay{}, &mockHotkey{}, &mockTray{})
+
+			a.processRecording(speechSamples(), nil, time.Second, "")
+
+			if snd.lastPlayed != tt.wantSound {
+				t.Errorf("sound = %v, want %v", snd.lastPlayed, tt.wantSound)
+			}
+			if clip.text != "original" {
+				t.Errorf("clipboard should be untouched on failure, got %q", clip.text)
+			}
+		})
+	}
+}
+
+func TestProcessRecordingStreaming(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	tests := []struct {
+		name       string
+		backend    transcriber.Backend
+		wantPasted []string
+	}{
+		{
+			name:       "streaming backend pastes each delta",
+			backend:    &mockStreamingBackend{deltas: []string{"Reactの", "useStateを", "使う"}},
+			wantPasted: []string{"Reactの", "useStateを", "使う"},
+		},
+		{
+			name:       "non-streaming backend pastes once",
+			backend:    &mockBackend{text: "ReactのuseStateを使う"},
+			wantPasted: []string{"ReactのuseStateを使う"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := settings.Default()
+			cfg.Streaming = true
+			cfg.RestoreClipboard = false
+			clip := &mockClipboard{}
+			snd := &mockSound{}
+			a := New(cfg, tt.backend, &mockRecorder{}, clip, snd, &mockOverlay{}, &mockHotkey{}, &mockTray{})
+
+			a.processRecording(speechSamples(), nil, time.Second, "")
+
+			if len(clip.pasted) != len(tt.wantPasted) {
+				t.Fatalf("pasted = %q, want %q", clip.pasted, tt.wantPasted)
+			}
+			for i := range tt.wantPasted {
+				if clip.pasted[i] != tt.wantPasted[i] {
+					t.Errorf("pasted[%d] = %q, want %q", i, clip.pasted[i], tt.wantPasted[i])
+				}
+			}
+			if snd.lastPlayed != sound.Success {
+				t.Errorf("expected Success sound, got %v", snd.lastPlayed)
+			}
+		})
+	}
+}
+
+// orderClipboard records each SetText and Paste call with its time.
+type orderClipboard struct {
+	mockClipboard
+	ops   []string
+	times []time.Time
+}
+
+func (m *orderClipboard) SetText(t string) error {
+	m.ops, m.times = append(m.ops, "set:"+t), append(m.times, time.Now())
+	return m.mockClipboard.SetText(t)
+}
+
+func (m *orderClipboard) Paste() error {
+	m.ops, m.times = append(m.ops, "paste"), append(m.times, time.Now())
+	return m.mockClipboard.Paste()
+}
+
+func TestStreamingWaitsForEachPaste(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	cfg := settings.Default()
+	cfg.Streaming = true
+	cfg.RestoreClipboard = false
+	clip := &orderClipboard{}
+	backend := &mockStreamingBackend{deltas: []string{"一", "二", "三"}}
+	a := New(cfg, backend, &mockRecorder{}, clip, &mockSound{}, &mockOverlay{}, &mockHotkey{}, &mockTray{})
+	a.pasteSettle = 30 * time.Millisecond
+
+	a.processRecording(speechSamples(), nil, time.Second, "")
+
+	want := []string{"set:一", "paste", "set:二", "paste", "set:三", "paste"}
+	if strings.Join(clip.ops, " ") != strings.Join(want, " ") {
+		t.Fatalf("clipboard calls = %q, want %q", clip.ops, want)
+	}
+	for i := 2; i < len(clip.ops); i += 2 {
+		if gap := clip.times[i].Sub(clip.times[i-1]); gap < a.pasteSettle {
+			t.Errorf("SetText %d came %s after the previous Paste, want >= %s", i/2, gap, a.pasteSettle)
+		}
+	}
+}
+
+func TestProcessRecordingChunked(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	cfg := settings.Default()
+	cfg.Chunking.MaxChunkSec = 0.4
+	backend := &mockBackend{text: "テスト"}
+	clip := &mockClipboard{}
+	a := New(cfg, backend, &mockRecorder{}, clip, &mockSound{}, &mockOverlay{}, &mockHotkey{}, &mockTray{})
+
+	a.processRecording(speechSamples(), nil, time.Second, "")
+
+	if backend.calls != 3 {
+		t.Fatalf("backend calls = %d, want 3 chunks for 1s at 0.4s/chunk", backend.calls)
+	}
+	if clip.text != "テストテストテスト" {
+		t.Errorf("clipboard = %q, want stitched chunks", clip.text)
+	}
+}
+
+func TestModes(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	cfg := settings.Default()
+	cfg.Modes = []settings.Mode{{Name: "commit", Hotkey: "f16"}, {Name: "english"}}
+	backend := &mockBackend{text: "fix: handle empty input"}
+	hk := &mockHotkey{}
+	tm := &mockTray{}
+	ov := &mockOverlay{}
+	a := New(cfg, backend, &mockRecorder{samples: speechSamples()}, &mockClipboard{}, &mockSound{}, ov, hk, tm)
+	a.Run()
+
+	if strings.Join(tm.modes, ",") != "commit,english" || tm.curMode != "" {
+		t.Errorf("tray modes = %v (current %q), want [commit english] (current default)", tm.modes, tm.curMode)
+	}
+	press, ok := hk.pressByKey["f16"]
+	if !ok || hk.pressByKey["f15"] == nil {
+		t.Fatalf("registered hotkeys = %v, want f15 and the commit mode's f16", hk.pressByKey)
+	}
+	press()
+	a.mu.Lock()
+	run := a.currentRun
+	a.mu.Unlock()
+	if run == nil || run.mode != "commit" || ov.text != "Recording (commit)..." {
+		t.Errorf("mode hotkey should start recording in commit mode, got run %+v overlay %q", run, ov.text)
+	}
+	a.mu.Lock()
+	a.isRecording, a.currentRun = false, nil
+	a.mu.Unlock()
+
+	a.processRecording(speechSamples(), nil, time.Second, "commit")
+	if backend.gotOpts.Mode != "commit" {
+		t.Errorf("Options.Mode = %q, want commit", backend.gotOpts.Mode)
+	}
+	e, err := history.Latest()
+	if err != nil || e.Mode != "commit" {
+		t.Errorf("history.Latest() = %+v, %v; want the mode recorded", e, err)
+	}
+
+	tm.cb.OnModeChange("english")
+	if cfg.Mode != "english" || tm.curMode != "english" {
+		t.Errorf("after OnModeChange: settings %q, tray %q, want english", cfg.Mode, tm.curMode)
+	}
+	tm.cb.OnModeChange("missing")
+	if cfg.Mode != "english" {
+		t.Errorf("unknown mode should be ignored, got %q", cfg.Mode)
+	}
+}
+
+type mockTranslatingBackend struct {
+	mockStreamingBackend
+	translated string
+	err        error
+	gotTarget  string
+}
+
+func (m *mockTranslatingBackend) Translate(ctx context.Context, text, target string) (string, error) {
+	m.gotTarget = target
+	return m.translated, m.err
+}
+
+func TestProcessRecordingTranslates(t *testing.T) {
+	tests := []struct {
+		name       string
+		translated string
+		err        error
+		wantPasted string
+		wantStored string
+	}{
+		{"translated", "Add tests for the parser.", nil, "Add tests for the parser.", "Add tests for the parser."},
+		{"failure pastes source", "", errors.New("boom"), "パーサーのテストを追加して", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("HOME", t.TempDir())
+
+			cfg := settings.Default()
+			cfg.Streaming = true
+			cfg.TranslateTo = "en"
+			backend := &mockTranslatingBackend{translated: tt.translated, err: tt.err}
+			backend.text = "パーサーのテストを追加して"
+			backend.deltas = []string{"パーサーの", "テストを追加して"}
+			clip := &mockClipboard{}
+			a := New(cfg, backend, &mockRecorder{}, clip, &mockSound{}, &mockOverlay{}, &mockHotkey{}, &mockTray{})
+
+			a.processRecording(speechSamples(), nil, time.Second, "")
+
+			if backend.gotTarget != "en" {
+				t.Errorf("Translate target = %q, want en", backend.gotTarget)
+			}
+			if len(clip.pasted) != 1 || clip.pasted[0] != tt.wantPasted {
+				t.Errorf("pasted = %q, want a single paste of %q (no streaming while translating)", clip.pasted, tt.wantPasted)
+			}
+			e, err := history.Latest()
+			if err != nil {
+				t.Fatalf("history.Latest() error: %v", err)
+			}
+			if e.ProcessedText != backend.text || e.TranslatedText != tt.wantStored || e.Language != "ja" {
+				t.Errorf("history = %+v, want source %q and translation %q", e, backend.text, tt.wantStored)
+			}
+		})
+	}
+}
+
+func TestProcessRecordingAudioFormats(t *testing.T) {
+	tests := []struct {
+		name        string
+		upload      string
+		history     string
+		wantMIME    string
+		wantPrefix  string
+		wantHistory string
+	}{
+		{"wav", "wav", "wav", "audio/wav", "RIFF", "RIFF"},
+		{"flac", "flac", "flac", "audio/flac", "fLaC", "fLaC"},
+		{"flac upload, wav history", "flac", "wav", "audio/flac", "fLaC", "RIFF"},
+		{"wav upload, flac history", "wav", "flac", "audio/wav", "RIFF", "fLaC"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("HOME", t.TempDir())
+
+			cfg := settings.Default()
+			cfg.UploadFormat = tt.upload
+			cfg.HistoryFormat = tt.history
+			backend := &mockBackend{text: "テスト"}
+			a := New(cfg, backend, &mockRecorder{}, &mockClipboard{}, &mockSound{}, &mockOverlay{}, &mockHotkey{}, &mockTray{})
+
+			a.processRecording(speechSamples(), nil, time.Second, "")
+
+			if backend.gotOpts.MIMEType != tt.wantMIME || !strings.HasPrefix(string(backend.gotAudio), tt.wantPrefix) {
+				t.Errorf("upload = %s starting %q, want %s starting %q", backend.gotOpts.MIMEType, backend.gotAudio[:4], tt.wantMIME, tt.wantPrefix)
+			}
+			e, err := history.Latest()
+			if err != nil {
+				t.Fatalf("history.Latest() error: %v", err)
+			}
+			if filepath.Ext(e.AudioFile) != "."+tt.history {
+				t.Errorf("history AudioFile = %q, want a .%s file", e.AudioFile, tt.history)
+			}
+			data, err := os.ReadFile(filepath.Join(history.HistoryDir(), e.AudioFile))
+			if err != nil || !strings.HasPrefix(string(data), tt.wantHistory) {
+				t.Errorf("history audio starts %q (err=%v), want %q", data[:min(4, len(data))], err, tt.wantHistory)
+			}
+		})
+	}
+}
+
+type mockSelectionClipboard struct {
+	mockClipboard
+	selection string
+}
+
+func (m *mockSelectionClipboard) GetSelection() (string, error) { return m.selection, nil }
+
+func TestProcessRecordingReferenceContext(t *testing.T) {
+	tests := []struct {
+		name        string
+		source      string
+		restore     bool
+		clip        clipboard.Clipboard
+		wantContext string
+	}{
+		{"off", "", true, &mockClipboard{text: "parseConfig"}, ""},
+		{"clipboard", settings.ContextClipboard, true, &mockClipboard{text: "parseConfig(token=abc123)"}, "parseConfig(token=[REDACTED])"},
+		{"clipboard without restore", settings.ContextClipboard, false, &mockClipboard{text: "parseConfig"}, "parseConfig"},
+		{"selection", settings.ContextSelection, true, &mockSelectionClipboard{mockClipboard{text: "clip"}, "loadDictionary"}, "loadDictionary"},
+		{"selection unsupported", settings.ContextSelection, true, &mockClipboard{text: "clip"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("HOME", t.TempDir())
+
+			cfg := settings.Default()
+			cfg.ContextSource = tt.source
+			cfg.RestoreClipboard = tt.restore
+			backend := &mockBackend{text: "parseConfig を呼ぶ"}
+			a := New(cfg, backend, &mockRecorder{}, tt.clip, &mockSound{}, &mockOverlay{}, &mockHotkey{}, &mockTray{})
+
+			a.processRecording(speechSamples(), nil, time.Second, "")
+
+			if backend.gotOpts.Context != tt.wantContext {
+				t.Errorf("Options.Context = %q, want %q", backend.gotOpts.Context, tt.wantContext)
+			}
+			e, err := history.Latest()
+			if err != nil {
+				t.Fatalf("history.Latest() error: %v", err)
+			}
+			if e.ProcessedText != backend.text {
+				t.Errorf("history ProcessedText = %q, want %q", e.ProcessedText, backend.text)
+			}
+		})
+	}
+}
+
+func TestHandsFreeStopsAfterSilence(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	cfg := settings.Default()
+	cfg.HandsFree.Enabled = true
+	cfg.HandsFree.SilenceSec = 0.5
+	// Stop returns silence so the spawned processing ends without a request.
+	rec := &mockMonitorRecorder{mockRecorder: mockRecorder{samples: make([]int16, 16000)}}
+	a := New(cfg, &mockBackend{}, rec, &mockClipboard{}, &mockSound{}, &mockOverlay{}, &mockHotkey{}, &mockTray{})
+
+	a.onHotkeyPress()
+	rec.feed(make([]int16, 8000))
+	rec.feed(speechSamples())
+	rec.feed(make([]int16, 4000))
+	if !isRecording(a) {
+		t.Fatal("recording stopped before the trailing silence was long enough")
+	}
+	rec.feed(make([]int16, 8000))
+
+	deadline := time.Now().Add(2 * time.Second)
+	for isRecording(a) && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if isRecording(a) {
+		t.Fatal("hands-free recording did not stop after trailing silence")
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if rec.monitor != nil {
+		t.Error("monitor should be removed when the recording stops")
+	}
+}
+
+func TestHandsFreeIgnoredWithPushToTalk(t *testing.T) {
+	cfg := settings.Default()
+	cfg.HandsFree.Enabled = true
+	cfg.PushToTalk = true
+	rec := &mockMonitorRecorder{}
+	rec.monitor = func([]int16) {}
+	a := New(cfg, &mockBackend{}, rec, &mockClipboard{}, &mockSound{}, &mockOverlay{}, &mockHotkey{}, &mockTray{})
+
+	a.onHotkeyPress()
+	if rec.monitor != nil {
+		t.Error("push-to-talk recordings should not be monitored")
+	}
+	a.mu.Lock()
+	a.cancelTimer()
+	a.mu.Unlock()
+}
+
+func isRecording(a *App) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.isRecording
+}
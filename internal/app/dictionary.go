@@ -0,0 +1,182 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/noricha-vr/voicecode/internal/core/history"
+	"github.com/noricha-vr/voicecode/internal/core/prompt"
+	"github.com/noricha-vr/voicecode/internal/core/transcriber"
+)
+
+const dictionaryPollInterval = 2 * time.Second
+
+// loadDictionary reads the base dictionary with profile for the local
+// replacement pass and records which files to watch. On error the previous
+// replacer is kept. filesChanged reports whether the watch list differs from
+// before (e.g. an #include was added or the profile changed).
+func (a *App) loadDictionary(profile string) (d prompt.Dictionary, filesChanged bool, err error) {
+	d, err = prompt.LoadProfile(a.dictionaryPath, profile)
+	if err != nil {
+		return d, false, err
+	}
+
+	files := []string{a.dictionaryPath}
+	if profile != "" {
+		if p, err := prompt.ProfilePath(a.dictionaryPath, profile); err == nil {
+			files = append(files, p)
+		}
+	}
+	for _, f := range d.Files {
+		if !slices.Contains(files, f) {
+			files = append(files, f)
+		}
+	}
+
+	a.mu.Lock()
+	a.replacer = d.Replacer()
+	filesChanged = !slices.Equal(files, a.dictionaryFiles)
+	a.dictionaryFiles = files
+	a.mu.Unlock()
+	return d, filesChanged, nil
+}
+
+// profile returns the selected dictionary profile.
+func (a *App) profile() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.settings.DictionaryProfile
+}
+
+func (a *App) currentReplacer() *prompt.Replacer {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.replacer
+}
+
+// startDictionaryWatch (re)starts polling the dictionary files; a change
+// reloads the dictionary. Reloads run beside an in-flight transcription: the
+// backend keeps the superseded prompt cache alive until that request finishes.
+func (a *App) startDictionaryWatch() {
+	ctx, cancel := context.WithCancel(context.Background())
+	a.mu.Lock()
+	if a.stopDictionaryWatch != nil {
+		a.stopDictionaryWatch()
+	}
+	a.stopDictionaryWatch = cancel
+	files := slices.Clone(a.dictionaryFiles)
+	a.mu.Unlock()
+
+	log.Printf("[App] 辞書の変更を監視します: %s", strings.Join(files, ", "))
+	go prompt.WatchFiles(ctx, files, dictionaryPollInterval, func() {
+		a.reloadDictionary(ctx)
+	})
+}
+
+// reloadDictionary reloads the dictionary after a watched file changed. The
+// backend gets the dictionary parsed here instead of reading it again.
+func (a *App) reloadDictionary(ctx context.Context) {
+	a.reloadMu.Lock()
+	defer a.reloadMu.Unlock()
+	if ctx.Err() != nil {
+		// A profile change restarted the watch while this reload waited.
+		return
+	}
+	profile := a.profile()
+	local, filesChanged, err := a.loadDictionary(profile)
+	if err != nil {
+		log.Printf("[App] 辞書の再読み込みに失敗しました。以前の辞書を使い続けます: %v", err)
+		a.tray.SetStatus("辞書の再読み込みに失敗しました")
+		return
+	}
+	if filesChanged {
+		// Restarting cancels ctx, so it must come after the backend reload.
+		defer a.startDictionaryWatch()
+	}
+
+	r, ok := a.transcriber.(transcriber.DictionaryReloader)
+	if !ok {
+		log.Printf("[App] 辞書を再読み込みしました: 変換 %d 件", local.Conversions)
+		a.tray.SetStatus(fmt.Sprintf("辞書を再読み込みしました (変換 %d 件)", local.Conversions))
+		return
+	}
+	changed, err := r.SetDictionary(ctx, profile, local)
+	if err != nil {
+		log.Printf("[App] 辞書の再読み込みに失敗しました。以前の辞書を使い続けます: %v", err)
+		a.tray.SetStatus("辞書の再読み込みに失敗しました")
+		return
+	}
+	if !changed {
+		return
+	}
+	log.Printf("[App] 辞書を再読み込みしました: 変換 %d 件, ヒント %d 件 (hash=%s)", local.Conversions, local.Hints, local.Hash())
+	a.tray.SetStatus(fmt.Sprintf("辞書を再読み込みしました (%d 語)", local.Terms()))
+}
+
+// refreshProfiles lists the available dictionary profiles in the tray.
+func (a *App) refreshProfiles() {
+	profiles, err := prompt.ListProfiles(a.dictionaryPath)
+	if err != nil {
+		log.Printf("[App] 辞書プロファイルの一覧を取得できません: %v", err)
+	}
+	a.tray.SetProfiles(profiles, a.profile())
+}
+
+func (a *App) onProfileChange(profile string) {
+	a.mu.Lock()
+	if profile == a.settings.DictionaryProfile {
+		a.mu.Unlock()
+		return
+	}
+	a.settings.DictionaryProfile = profile
+	if err := a.settings.Save(); err != nil {
+		log.Printf("[App] Failed to save settings: %v", err)
+	}
+	a.mu.Unlock()
+	log.Printf("[App] Dictionary profile changed to: %q", profile)
+	a.refreshProfiles()
+
+	a.reloadMu.Lock()
+	defer a.reloadMu.Unlock()
+	local, _, err := a.loadDictionary(profile)
+	if err != nil {
+		log.Printf("[App] 辞書プロファイルの読み込みに失敗しました: %v", err)
+		a.tray.SetStatus("辞書プロファイルの読み込みに失敗しました")
+		return
+	}
+	if r, ok := a.transcriber.(transcriber.DictionaryReloader); ok {
+		if _, err := r.SetDictionary(context.Background(), profile, local); err != nil {
+			log.Printf("[App] 辞書プロファイルの切り替えに失敗しました: %v", err)
+			a.tray.SetStatus("辞書プロファイルの切り替えに失敗しました")
+			return
+		}
+	}
+	a.startDictionaryWatch()
+
+	label := profile
+	if label == "" {
+		label = "base"
+	}
+	a.tray.SetStatus(fmt.Sprintf("辞書プロファイル: %s (%d 語)", label, local.Terms()))
+}
+
+// applyReplacements runs the deterministic dictionary pass over the final
+// transcription and returns the text with the conversions that fired.
+func (a *App) applyReplacements(raw string) (string, []history.Replacement) {
+	text, fired := a.currentReplacer().Apply(raw)
+	if len(fired) == 0 {
+		return text, nil
+	}
+	out := make([]history.Replacement, len(fired))
+	parts := make([]string, len(fired))
+	for i, f := range fired {
+		out[i] = history.Replacement{From: f.From, To: f.To, Count: f.Count}
+		parts[i] = fmt.Sprintf("%s→%s×%d", f.From, f.To, f.Count)
+	}
+	log.Printf("[App] 辞書置換: %s", strings.Join(parts, ", "))
+	return text, out
+}
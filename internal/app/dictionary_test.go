@@ -0,0 +1,223 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/noricha-vr/voicecode/internal/core/history"
+	"github.com/noricha-vr/voicecode/internal/core/prompt"
+	"github.com/noricha-vr/voicecode/internal/core/settings"
+	"github.com/noricha-vr/voicecode/internal/core/transcriber"
+)
+
+type mockReloadingBackend struct {
+	mockBackend
+	dict    prompt.Dictionary
+	changed bool
+	err     error
+	reloads int
+	profile string
+	got     prompt.Dictionary // last dictionary passed to SetDictionary
+}
+
+func (m *mockReloadingBackend) ReloadDictionary(ctx context.Context) (prompt.Dictionary, bool, error) {
+	m.reloads++
+	return m.dict, m.changed, m.err
+}
+
+func (m *mockReloadingBackend) SetDictionaryProfile(ctx context.Context, profile string) (prompt.Dictionary, bool, error) {
+	m.profile = profile
+	return m.ReloadDictionary(ctx)
+}
+
+func (m *mockReloadingBackend) SetDictionary(ctx context.Context, profile string, d prompt.Dictionary) (bool, error) {
+	m.reloads++
+	m.profile, m.got = profile, d
+	return m.changed, m.err
+}
+
+func newDictionaryApp(t *testing.T, cfg *settings.Settings, backend transcriber.Backend, clip *mockClipboard, tm *mockTray, dictionary string) *App {
+	t.Helper()
+	a := New(cfg, backend, &mockRecorder{}, clip, &mockSound{}, &mockOverlay{}, &mockHotkey{}, tm)
+	a.dictionaryPath = filepath.Join(t.TempDir(), "dictionary.txt")
+	if err := os.WriteFile(a.dictionaryPath, []byte(dictionary), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := a.loadDictionary(a.profile()); err != nil {
+		t.Fatalf("loadDictionary() error: %v", err)
+	}
+	return a
+}
+
+func TestReloadDictionary(t *testing.T) {
+	tests := []struct {
+		name       string
+		backend    transcriber.Backend
+		wantStatus string
+	}{
+		{"changed", &mockReloadingBackend{changed: true}, "辞書を再読み込みしました (1 語)"},
+		{"unchanged", &mockReloadingBackend{}, ""},
+		{"backend error", &mockReloadingBackend{err: errors.New("permission denied")}, "辞書の再読み込みに失敗しました"},
+		{"backend without prompt dictionary", &mockBackend{}, "辞書を再読み込みしました (変換 1 件)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tm := &mockTray{}
+			a := newDictionaryApp(t, settings.Default(), tt.backend, &mockClipboard{}, tm, "")
+			if err := os.WriteFile(a.dictionaryPath, []byte("クバネティス\tKubernetes\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			a.reloadDictionary(context.Background())
+			if r, ok := tt.backend.(*mockReloadingBackend); ok && (r.reloads != 1 || r.got.Conversions != 1) {
+				t.Errorf("reloads = %d with %d conversions, want the parsed dictionary passed once", r.reloads, r.got.Conversions)
+			}
+			if tm.status != tt.wantStatus {
+				t.Errorf("tray status = %q, want %q", tm.status, tt.wantStatus)
+			}
+			if a.currentReplacer().Len() != 1 {
+				t.Errorf("replacer rules = %d, want 1 after reload", a.currentReplacer().Len())
+			}
+		})
+	}
+}
+
+func TestOnProfileChange(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	tm := &mockTray{}
+	backend := &mockReloadingBackend{changed: true}
+	a := newDictionaryApp(t, settings.Default(), backend, &mockClipboard{}, tm, "クバネティス\tKubernetes\n")
+	dir := prompt.ProfileDir(a.dictionaryPath)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "infra.txt"), []byte("テラフォーム\tTerraform\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	a.onProfileChange("infra")
+	defer a.stopDictionaryWatch()
+
+	if a.settings.DictionaryProfile != "infra" {
+		t.Errorf("settings profile = %q, want infra", a.settings.DictionaryProfile)
+	}
+	if backend.profile != "infra" {
+		t.Errorf("backend profile = %q, want infra", backend.profile)
+	}
+	if strings.Join(tm.profiles, ",") != "infra" || tm.curProf != "infra" {
+		t.Errorf("tray profiles = %v (current %q), want [infra] (current infra)", tm.profiles, tm.curProf)
+	}
+	if backend.got.Profile != "infra" || backend.got.Conversions != 2 {
+		t.Errorf("backend dictionary = profile %q with %d conversions, want infra with 2", backend.got.Profile, backend.got.Conversions)
+	}
+	if tm.status != "辞書プロファイル: infra (2 語)" {
+		t.Errorf("tray status = %q", tm.status)
+	}
+	if got, _ := a.applyReplacements("テラフォームとクバネティス"); got != "TerraformとKubernetes" {
+		t.Errorf("applyReplacements() = %q, want profile and base conversions", got)
+	}
+	if len(a.dictionaryFiles) != 2 {
+		t.Errorf("watched files = %v, want base and profile", a.dictionaryFiles)
+	}
+
+	a.onProfileChange("")
+	if backend.profile != "" || tm.curProf != "" {
+		t.Errorf("after switching back: backend %q, tray %q, want base", backend.profile, tm.curProf)
+	}
+	if got, _ := a.applyReplacements("テラフォーム"); got != "テラフォーム" {
+		t.Errorf("applyReplacements() = %q, want profile conversions dropped", got)
+	}
+}
+
+// TestLoadDictionaryDuringProfileChange is meant for go test -race.
+func TestLoadDictionaryDuringProfileChange(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	a := newDictionaryApp(t, settings.Default(), &mockBackend{}, &mockClipboard{}, &mockTray{}, "クバネティス\tKubernetes\n")
+	dir := prompt.ProfileDir(a.dictionaryPath)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "infra.txt"), []byte("テラフォーム\tTerraform\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		a.onProfileChange("infra")
+	}()
+	for i := 0; i < 10; i++ {
+		if _, _, err := a.loadDictionary(a.profile()); err != nil {
+			t.Errorf("loadDictionary() error: %v", err)
+		}
+	}
+	<-done
+	a.stopDictionaryWatch()
+}
+
+func TestProcessRecordingAppliesDictionary(t *testing.T) {
+	const dictionary = "クバネティス\tKubernetes\nドッカー\tDocker\n"
+
+	tests := []struct {
+		name       string
+		streaming  bool
+		backend    transcriber.Backend
+		wantPasted []string
+	}{
+		{
+			name:       "plain",
+			backend:    &mockBackend{text: "クバネティスにドッカーでデプロイ"},
+			wantPasted: []string{"KubernetesにDockerでデプロイ"},
+		},
+		{
+			// Nothing is pasted until more than the longest reading follows.
+			name:       "streaming holds back a reading split across deltas",
+			streaming:  true,
+			backend:    &mockStreamingBackend{deltas: []string{"クバネ", "ティスにドッカー", "でデプロイ"}},
+			wantPasted: []string{"Kubernetes", "にDocker", "でデプロイ"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := t.TempDir()
+			t.Setenv("HOME", home)
+			cfg := settings.Default()
+			cfg.Streaming = tt.streaming
+			cfg.RestoreClipboard = false
+			clip := &mockClipboard{}
+			a := newDictionaryApp(t, cfg, tt.backend, clip, &mockTray{}, dictionary)
+
+			a.processRecording(speechSamples(), nil, time.Second, "")
+
+			if strings.Join(clip.pasted, "|") != strings.Join(tt.wantPasted, "|") {
+				t.Errorf("pasted = %q, want %q", clip.pasted, tt.wantPasted)
+			}
+
+			entries, _ := filepath.Glob(filepath.Join(home, ".voicecoding", "history", "*.json"))
+			if len(entries) != 1 {
+				t.Fatalf("history entries = %v, want 1", entries)
+			}
+			data, err := os.ReadFile(entries[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			var entry history.Entry
+			if err := json.Unmarshal(data, &entry); err != nil {
+				t.Fatal(err)
+			}
+			if entry.RawTranscription != "クバネティスにドッカーでデプロイ" || entry.ProcessedText != "KubernetesにDockerでデプロイ" {
+				t.Errorf("history raw=%q processed=%q", entry.RawTranscription, entry.ProcessedText)
+			}
+			wantHits := []history.Replacement{{From: "クバネティス", To: "Kubernetes", Count: 1}, {From: "ドッカー", To: "Docker", Count: 1}}
+			if len(entry.Replacements) != 2 || entry.Replacements[0] != wantHits[0] || entry.Replacements[1] != wantHits[1] {
+				t.Errorf("Replacements = %+v, want %+v", entry.Replacements, wantHits)
+			}
+		})
+	}
+}
@@ -0,0 +1,390 @@
+package audio
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+)
+
+const (
+	// flacBlockSize is the number of samples per FLAC frame.
+	flacBlockSize = 4096
+	// flacMaxFixedOrder is the highest fixed predictor order tried.
+	flacMaxFixedOrder = 4
+	// flacMaxPartitionOrder bounds the Rice partitions tried per subframe.
+	flacMaxPartitionOrder = 6
+	// flacMaxRiceParam is the largest 4-bit Rice parameter (15 is the escape code).
+	flacMaxRiceParam = 14
+)
+
+// WriteFLAC writes PCM 16-bit mono audio data to a FLAC file.
+func WriteFLAC(path string, samples []int16, sampleRate int) error {
+	data, err := EncodeFLAC(samples, sampleRate)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("writing FLAC file: %w", err)
+	}
+	return nil
+}
+
+// EncodeFLAC returns PCM 16-bit mono audio data as an in-memory FLAC file.
+// Each frame uses the smallest of a constant, verbatim or fixed-predictor
+// (orders 0-4) subframe with Rice-coded residuals, which is lossless and
+// typically about half the size of WAV for speech.
+func EncodeFLAC(samples []int16, sampleRate int) ([]byte, error) {
+	if sampleRate <= 0 || sampleRate >= 1<<20 {
+		return nil, fmt.Errorf("unsupported FLAC sample rate %d", sampleRate)
+	}
+
+	var frames []byte
+	minFrame, maxFrame := 0, 0
+	for i, n := 0, 0; i < len(samples); i, n = i+flacBlockSize, n+1 {
+		block := samples[i:min(i+flacBlockSize, len(samples))]
+		frame := encodeFLACFrame(block, sampleRate, uint64(n))
+		if minFrame == 0 || len(frame) < minFrame {
+			minFrame = len(frame)
+		}
+		maxFrame = max(maxFrame, len(frame))
+		frames = append(frames, frame...)
+	}
+
+	pcm := make([]byte, 2*len(samples))
+	for i, s := range samples {
+		binary.LittleEndian.PutUint16(pcm[2*i:], uint16(s))
+	}
+	sum := md5.Sum(pcm)
+
+	blockSize := min(flacBlockSize, max(len(samples), 16))
+	var w bitWriter
+	w.write(0x664C6143, 32) // "fLaC"
+	w.write(1, 1)           // last metadata block
+	w.write(0, 7)           // STREAMINFO
+	w.write(34, 24)
+	w.write(uint64(blockSize), 16) // min block size; the last block may be shorter
+	w.write(uint64(blockSize), 16) // max block size
+	w.write(uint64(minFrame), 24)
+	w.write(uint64(maxFrame), 24)
+	w.write(uint64(sampleRate), 20)
+	w.write(0, 3)  // channels - 1
+	w.write(15, 5) // bits per sample - 1
+	w.write(uint64(len(samples)), 36)
+	for _, b := range sum {
+		w.write(uint64(b), 8)
+	}
+	return append(w.bytes(), frames...), nil
+}
+
+// encodeFLACFrame encodes one block as a FLAC frame with a fixed block size
+// strategy and frame number n.
+func encodeFLACFrame(block []int16, sampleRate int, n uint64) []byte {
+	var w bitWriter
+	w.write(0x3FFE, 14) // sync code
+	w.write(0, 1)       // reserved
+	w.write(0, 1)       // fixed block size
+
+	bsCode, bsExtra, bsBits := flacBlockSizeCode(len(block))
+	srCode, srExtra, srBits := flacSampleRateCode(sampleRate)
+	w.write(bsCode, 4)
+	w.write(srCode, 4)
+	w.write(0, 4) // mono
+	w.write(4, 3) // 16 bits per sample
+	w.write(0, 1) // reserved
+	w.writeUTF8(n)
+	if bsBits > 0 {
+		w.write(bsExtra, bsBits)
+	}
+	if srBits > 0 {
+		w.write(srExtra, srBits)
+	}
+	w.write(uint64(crc8(w.bytes())), 8)
+
+	encodeFLACSubframe(&w, block)
+	w.align()
+	frame := w.bytes()
+	crc := crc16(frame)
+	return append(frame, byte(crc>>8), byte(crc))
+}
+
+// flacBlockSizeCode returns the frame header block size code and the extra
+// bits that follow the frame number, if any.
+func flacBlockSizeCode(n int) (code, extra uint64, bits int) {
+	for c := uint64(8); c <= 15; c++ {
+		if n == 256<<(c-8) {
+			return c, 0, 0
+		}
+	}
+	if n <= 256 {
+		return 6, uint64(n - 1), 8
+	}
+	return 7, uint64(n - 1), 16
+}
+
+// flacSampleRateCode returns the frame header sample rate code and the
+// extra bits that follow the block size, if any.
+func flacSampleRateCode(rate int) (code, extra uint64, bits int) {
+	switch rate {
+	case 88200:
+		return 1, 0, 0
+	case 176400:
+		return 2, 0, 0
+	case 192000:
+		return 3, 0, 0
+	case 8000:
+		return 4, 0, 0
+	case 16000:
+		return 5, 0, 0
+	case 22050:
+		return 6, 0, 0
+	case 24000:
+		return 7, 0, 0
+	case 32000:
+		return 8, 0, 0
+	case 44100:
+		return 9, 0, 0
+	case 48000:
+		return 10, 0, 0
+	case 96000:
+		return 11, 0, 0
+	}
+	switch {
+	case rate%1000 == 0 && rate/1000 <= 255:
+		return 12, uint64(rate / 1000), 8
+	case rate <= 65535:
+		return 13, uint64(rate), 16
+	case rate%10 == 0 && rate/10 <= 65535:
+		return 14, uint64(rate / 10), 16
+	}
+	return 0, 0, 0 // taken from STREAMINFO
+}
+
+// encodeFLACSubframe writes the smallest subframe encoding of block.
+func encodeFLACSubframe(w *bitWriter, block []int16) {
+	constant := true
+	for _, s := range block[1:] {
+		if s != block[0] {
+			constant = false
+			break
+		}
+	}
+	if constant {
+		w.write(0, 8) // padding bit, type CONSTANT, no wasted bits
+		w.writeSigned(int64(block[0]), 16)
+		return
+	}
+
+	bestBits := 16 * len(block) // VERBATIM
+	bestOrder := -1
+	var best riceCoding
+	for order := 0; order <= flacMaxFixedOrder && order < len(block); order++ {
+		rc := planRice(fixedResidual(block, order), len(block), order)
+		if bits := 16*order + rc.bits; bits < bestBits {
+			bestBits, bestOrder, best = bits, order, rc
+		}
+	}
+
+	if bestOrder < 0 {
+		w.write(1<<1, 8) // type VERBATIM
+		for _, s := range block {
+			w.writeSigned(int64(s), 16)
+		}
+		return
+	}
+	w.write(uint64(8|bestOrder)<<1, 8) // type FIXED of bestOrder
+	for _, s := range block[:bestOrder] {
+		w.writeSigned(int64(s), 16)
+	}
+	w.write(0, 2) // Rice coding with 4-bit parameters
+	w.write(uint64(best.partitionOrder), 4)
+	for i, k := range best.params {
+		w.write(uint64(k), 4)
+		for _, u := range best.partition(i) {
+			w.writeRice(u, k)
+		}
+	}
+}
+
+// fixedResidual returns the zigzag-encoded residual of the fixed predictor
+// of the given order, for the samples after the warm-up samples.
+func fixedResidual(block []int16, order int) []uint64 {
+	res := make([]uint64, 0, len(block)-order)
+	for i := order; i < len(block); i++ {
+		x := func(j int) int64 { return int64(block[i-j]) }
+		var r int64
+		switch order {
+		case 0:
+			r = x(0)
+		case 1:
+			r = x(0) - x(1)
+		case 2:
+			r = x(0) - 2*x(1) + x(2)
+		case 3:
+			r = x(0) - 3*x(1) + 3*x(2) - x(3)
+		case 4:
+			r = x(0) - 4*x(1) + 6*x(2) - 4*x(3) + x(4)
+		}
+		res = append(res, uint64((r<<1)^(r>>63)))
+	}
+	return res
+}
+
+// riceCoding is a Rice partitioning of a residual and its size in bits,
+// including the residual header.
+type riceCoding struct {
+	residual       []uint64
+	blockSize      int
+	order          int // predictor order: the first partition is shorter by it
+	partitionOrder int
+	params         []int
+	bits           int
+}
+
+// partition returns the residual values of partition i.
+func (rc riceCoding) partition(i int) []uint64 {
+	size := rc.blockSize >> rc.partitionOrder
+	start, end := i*size-rc.order, (i+1)*size-rc.order
+	return rc.residual[max(start, 0):end]
+}
+
+// planRice picks the partition order and per-partition Rice parameters that
+// minimize the coded size of residual.
+func planRice(residual []uint64, blockSize, order int) riceCoding {
+	best := riceCoding{bits: math.MaxInt}
+	for po := 0; po <= flacMaxPartitionOrder; po++ {
+		if blockSize%(1<<po) != 0 || blockSize>>po <= order {
+			break
+		}
+		rc := riceCoding{residual: residual, blockSize: blockSize, order: order, partitionOrder: po, bits: 6}
+		for i := 0; i < 1<<po; i++ {
+			k, bits := bestRiceParam(rc.partition(i))
+			rc.params = append(rc.params, k)
+			rc.bits += 4 + bits
+		}
+		if rc.bits < best.bits {
+			best = rc
+		}
+	}
+	return best
+}
+
+// bestRiceParam returns the Rice parameter that codes values in the fewest
+// bits, and that number of bits.
+func bestRiceParam(values []uint64) (int, int) {
+	bestK, bestBits := 0, math.MaxInt
+	for k := 0; k <= flacMaxRiceParam; k++ {
+		bits := len(values) * (k + 1)
+		for _, u := range values {
+			bits += int(u >> k)
+			if bits >= bestBits {
+				break
+			}
+		}
+		if bits < bestBits {
+			bestK, bestBits = k, bits
+		}
+	}
+	return bestK, bestBits
+}
+
+// bitWriter appends big-endian bit fields to a byte slice.
+type bitWriter struct {
+	buf   []byte
+	acc   uint64
+	nbits int
+}
+
+// write appends the low n bits of v.
+func (w *bitWriter) write(v uint64, n int) {
+	for n > 32 {
+		n -= 32
+		w.write(v>>n, 32)
+	}
+	w.acc = w.acc<<n | v&(1<<n-1)
+	w.nbits += n
+	for w.nbits >= 8 {
+		w.nbits -= 8
+		w.buf = append(w.buf, byte(w.acc>>w.nbits))
+	}
+}
+
+func (w *bitWriter) writeSigned(v int64, n int) {
+	w.write(uint64(v), n)
+}
+
+// writeRice appends u as q zero bits, a one bit and the low k bits of u,
+// where q = u >> k.
+func (w *bitWriter) writeRice(u uint64, k int) {
+	for q := u >> k; q > 0; {
+		n := min(q, 32)
+		w.write(0, int(n))
+		q -= n
+	}
+	w.write(1, 1)
+	if k > 0 {
+		w.write(u, k)
+	}
+}
+
+// writeUTF8 appends v in the UTF-8-like variable length coding FLAC uses for
+// frame numbers.
+func (w *bitWriter) writeUTF8(v uint64) {
+	if v < 0x80 {
+		w.write(v, 8)
+		return
+	}
+	n := 2
+	for v >= 1<<(5*n+1) {
+		n++
+	}
+	w.write((0xFF00>>n)&0xFF|v>>(6*(n-1)), 8)
+	for i := n - 2; i >= 0; i-- {
+		w.write(0x80|(v>>(6*i))&0x3F, 8)
+	}
+}
+
+// align pads with zero bits to a byte boundary.
+func (w *bitWriter) align() {
+	if w.nbits > 0 {
+		w.write(0, 8-w.nbits)
+	}
+}
+
+// bytes returns the complete bytes written so far.
+func (w *bitWriter) bytes() []byte {
+	return w.buf
+}
+
+// crc8 is the FLAC frame header CRC (polynomial x^8 + x^2 + x + 1).
+func crc8(data []byte) byte {
+	var crc byte
+	for _, b := range data {
+		crc ^= b
+		for i := 0; i < 8; i++ {
+			if crc&0x80 != 0 {
+				crc = crc<<1 ^ 0x07
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// crc16 is the FLAC frame CRC (polynomial x^16 + x^15 + x^2 + 1).
+func crc16(data []byte) uint16 {
+	var crc uint16
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x8005
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
@@ -0,0 +1,399 @@
+package audio
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFLACCRC(t *testing.T) {
+	// Check values of CRC-8 (poly 0x07) and CRC-16/BUYPASS (poly 0x8005).
+	if got := crc8([]byte("123456789")); got != 0xF4 {
+		t.Errorf("crc8 = %#x, want 0xf4", got)
+	}
+	if got := crc16([]byte("123456789")); got != 0xFEE8 {
+		t.Errorf("crc16 = %#x, want 0xfee8", got)
+	}
+}
+
+// streamInfo holds the STREAMINFO fields of a FLAC file.
+type streamInfo struct {
+	minBlock, maxBlock int
+	minFrame, maxFrame int
+	sampleRate         int
+	channels           int
+	bitsPerSample      int
+	totalSamples       int
+	md5                [16]byte
+}
+
+// parseStreamInfo reads the STREAMINFO block, which must be the only
+// metadata block, and returns it with the offset of the first frame.
+func parseStreamInfo(data []byte) (streamInfo, int, error) {
+	var si streamInfo
+	if !bytes.HasPrefix(data, []byte("fLaC")) || len(data) < 42 {
+		return si, 0, fmt.Errorf("missing fLaC header")
+	}
+	r := &bitReader{data: data[4:]}
+	if last, typ, length := r.read(1), r.read(7), r.read(24); last != 1 || typ != 0 || length != 34 {
+		return si, 0, fmt.Errorf("unexpected metadata block last=%d type=%d len=%d", last, typ, length)
+	}
+	si.minBlock, si.maxBlock = int(r.read(16)), int(r.read(16))
+	si.minFrame, si.maxFrame = int(r.read(24)), int(r.read(24))
+	si.sampleRate = int(r.read(20))
+	si.channels = int(r.read(3)) + 1
+	si.bitsPerSample = int(r.read(5)) + 1
+	si.totalSamples = int(r.read(36))
+	for i := range si.md5 {
+		si.md5[i] = byte(r.read(8))
+	}
+	return si, 42, nil
+}
+
+// checkSingleFrame checks the CRC-8 at the end of the headerLen-byte frame
+// header and the CRC-16 footer of a file holding a single frame.
+func checkSingleFrame(t *testing.T, frame []byte, headerLen int) {
+	t.Helper()
+	if got, want := crc8(frame[:headerLen-1]), frame[headerLen-1]; got != want {
+		t.Errorf("frame header CRC-8 = %#02x, file has %#02x", got, want)
+	}
+	n := len(frame) - 2
+	if got, want := crc16(frame[:n]), binary.BigEndian.Uint16(frame[n:]); got != want {
+		t.Errorf("frame CRC-16 = %#04x, file has %#04x", got, want)
+	}
+}
+
+func TestFLACReferenceFile(t *testing.T) {
+	// The first decoding example of RFC 9639 Appendix D, written by the
+	// reference encoder: one 44.1 kHz stereo 16-bit sample (25588, 10416) in
+	// one frame with two verbatim subframes.
+	data, err := os.ReadFile(filepath.Join("testdata", "rfc9639_example1.flac"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	si, pos, err := parseStreamInfo(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := streamInfo{
+		minBlock: 4096, maxBlock: 4096,
+		minFrame: 15, maxFrame: 15,
+		sampleRate: 44100, channels: 2, bitsPerSample: 16, totalSamples: 1,
+		md5: pcmMD5(25588, 10416),
+	}
+	if si != want {
+		t.Errorf("STREAMINFO = %+v, want %+v", si, want)
+	}
+
+	// Header: sync, block size code 6 / sample rate 44.1 kHz, stereo 16-bit,
+	// frame number 0, block size - 1 = 0, CRC-8.
+	frame := data[pos:]
+	if !bytes.HasPrefix(frame, []byte{0xFF, 0xF8, 0x69, 0x18, 0x00, 0x00}) {
+		t.Fatalf("frame header = % x", frame[:7])
+	}
+	if len(frame) != si.maxFrame {
+		t.Fatalf("frame is %d bytes, STREAMINFO says %d", len(frame), si.maxFrame)
+	}
+	checkSingleFrame(t, frame, 7)
+}
+
+func TestEncodeFLACGolden(t *testing.T) {
+	// The mono counterpart of the RFC 9639 example: the same 16-bit samples
+	// in one frame with a verbatim subframe, laid out like the reference
+	// encoder's file. See TestFLACReferenceFile for the CRC algorithms.
+	samples := []int16{25588, 10416}
+	golden, err := os.ReadFile(filepath.Join("testdata", "two_samples.flac"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := EncodeFLAC(samples, 44100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, golden) {
+		t.Errorf("EncodeFLAC() =\n% x\nwant\n% x", data, golden)
+	}
+
+	si, pos, err := parseStreamInfo(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := streamInfo{
+		minBlock: 16, maxBlock: 16,
+		minFrame: 14, maxFrame: 14,
+		sampleRate: 44100, channels: 1, bitsPerSample: 16, totalSamples: 2,
+		md5: pcmMD5(samples...),
+	}
+	if si != want {
+		t.Errorf("STREAMINFO = %+v, want %+v", si, want)
+	}
+
+	// Header: sync, block size code 6 / sample rate 44.1 kHz, mono 16-bit,
+	// frame number 0, block size - 1 = 1, CRC-8. Then a verbatim subframe
+	// and the CRC-16.
+	frame := data[pos:]
+	wantFrame := []byte{0xFF, 0xF8, 0x69, 0x08, 0x00, 0x01, 0x1A, 0x02, 0x63, 0xF4, 0x28, 0xB0, 0x6A, 0x1F}
+	if !bytes.Equal(frame, wantFrame) {
+		t.Errorf("frame = % x, want % x", frame, wantFrame)
+	}
+	checkSingleFrame(t, frame, 7)
+}
+
+// pcmMD5 returns the STREAMINFO MD5 of interleaved 16-bit samples.
+func pcmMD5(samples ...int16) [16]byte {
+	pcm := make([]byte, 2*len(samples))
+	for i, s := range samples {
+		binary.LittleEndian.PutUint16(pcm[2*i:], uint16(s))
+	}
+	return md5.Sum(pcm)
+}
+
+func TestEncodeFLACRoundTrip(t *testing.T) {
+	const sr = 16000
+	rng := rand.New(rand.NewSource(1))
+	speechLike := make([]int16, 3*sr+123) // not a multiple of the block size
+	for i := range speechLike {
+		v := 8000*math.Sin(float64(i)*2*math.Pi*220/sr) + 3000*math.Sin(float64(i)*2*math.Pi*1375/sr) + rng.NormFloat64()*200
+		speechLike[i] = int16(v)
+	}
+	noise := make([]int16, 5000)
+	for i := range noise {
+		noise[i] = int16(rng.Intn(65536) - 32768)
+	}
+	extremes := []int16{math.MaxInt16, math.MinInt16, math.MaxInt16, math.MinInt16, 0, -1, 1}
+
+	tests := []struct {
+		name       string
+		samples    []int16
+		sampleRate int
+	}{
+		{"speech", speechLike, sr},
+		{"silence", make([]int16, sr), sr},
+		{"white noise", noise, sr},
+		{"extremes", extremes, sr},
+		{"single sample", []int16{42}, sr},
+		{"empty", nil, sr},
+		{"odd sample rate", speechLike[:9000], 11025},
+		{"many frames", make([]int16, 200*flacBlockSize), 44100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := EncodeFLAC(tt.samples, tt.sampleRate)
+			if err != nil {
+				t.Fatalf("EncodeFLAC() error: %v", err)
+			}
+			got, rate, err := decodeFLAC(data)
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if rate != tt.sampleRate {
+				t.Errorf("sample rate = %d, want %d", rate, tt.sampleRate)
+			}
+			if len(got) != len(tt.samples) {
+				t.Fatalf("decoded %d samples, want %d", len(got), len(tt.samples))
+			}
+			for i := range got {
+				if got[i] != tt.samples[i] {
+					t.Fatalf("sample %d = %d, want %d", i, got[i], tt.samples[i])
+				}
+			}
+		})
+	}
+}
+
+func TestEncodeFLACIsSmallerThanWAV(t *testing.T) {
+	const sr = 16000
+	samples := make([]int16, 2*sr)
+	for i := range samples {
+		samples[i] = int16(6000 * math.Sin(float64(i)*2*math.Pi*180/sr) * math.Sin(float64(i)*math.Pi/sr))
+	}
+	flac, err := EncodeFLAC(samples, sr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wav, err := EncodeWAV(samples, sr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(flac) >= len(wav)/2 {
+		t.Errorf("FLAC %d bytes, WAV %d bytes; want less than half", len(flac), len(wav))
+	}
+}
+
+// decodeFLAC is a minimal decoder for the subset EncodeFLAC writes (mono,
+// 16-bit, CONSTANT/VERBATIM/FIXED subframes, 4-bit Rice parameters). It
+// checks the frame CRCs and the STREAMINFO MD5.
+func decodeFLAC(data []byte) ([]int16, int, error) {
+	si, pos, err := parseStreamInfo(data)
+	if err != nil {
+		return nil, 0, err
+	}
+	if si.channels != 1 || si.bitsPerSample != 16 {
+		return nil, 0, fmt.Errorf("channels=%d bps=%d", si.channels, si.bitsPerSample)
+	}
+
+	var out []int16
+	for frame := 0; pos < len(data); frame++ {
+		r := &bitReader{data: data[pos:]}
+		if sync := r.read(14); sync != 0x3FFE {
+			return nil, 0, fmt.Errorf("frame %d: bad sync %#x", frame, sync)
+		}
+		r.read(2)
+		bsCode, srCode := r.read(4), r.read(4)
+		if ch, ss, res := r.read(4), r.read(3), r.read(1); ch != 0 || ss != 4 || res != 0 {
+			return nil, 0, fmt.Errorf("frame %d: channel/sample size %d/%d", frame, ch, ss)
+		}
+		if n := r.readUTF8(); n != uint64(frame) {
+			return nil, 0, fmt.Errorf("frame number %d, want %d", n, frame)
+		}
+		var blockSize int
+		switch {
+		case bsCode >= 8:
+			blockSize = 256 << (bsCode - 8)
+		case bsCode == 6:
+			blockSize = int(r.read(8)) + 1
+		case bsCode == 7:
+			blockSize = int(r.read(16)) + 1
+		default:
+			return nil, 0, fmt.Errorf("frame %d: block size code %d", frame, bsCode)
+		}
+		switch srCode {
+		case 12:
+			r.read(8)
+		case 13, 14:
+			r.read(16)
+		}
+		headerLen := r.pos / 8
+		if crc := byte(r.read(8)); crc != crc8(r.data[:headerLen]) {
+			return nil, 0, fmt.Errorf("frame %d: header CRC mismatch", frame)
+		}
+
+		block, err := decodeSubframe(r, blockSize)
+		if err != nil {
+			return nil, 0, fmt.Errorf("frame %d: %w", frame, err)
+		}
+		if r.pos%8 != 0 {
+			r.read(8 - r.pos%8)
+		}
+		end := r.pos / 8
+		if crc := uint16(r.read(16)); crc != crc16(r.data[:end]) {
+			return nil, 0, fmt.Errorf("frame %d: frame CRC mismatch", frame)
+		}
+		out = append(out, block...)
+		pos += end + 2
+	}
+	if len(out) != si.totalSamples {
+		return nil, 0, fmt.Errorf("decoded %d samples, STREAMINFO says %d", len(out), si.totalSamples)
+	}
+	if pcmMD5(out...) != si.md5 {
+		return nil, 0, fmt.Errorf("MD5 mismatch")
+	}
+	return out, si.sampleRate, nil
+}
+
+func decodeSubframe(r *bitReader, n int) ([]int16, error) {
+	header := r.read(8)
+	typ := header >> 1 & 0x3F
+	out := make([]int64, 0, n)
+	switch {
+	case typ == 0:
+		v := r.readSigned(16)
+		for range n {
+			out = append(out, v)
+		}
+	case typ == 1:
+		for range n {
+			out = append(out, r.readSigned(16))
+		}
+	case typ&0x38 == 8 && typ&7 <= 4:
+		order := int(typ & 7)
+		for range order {
+			out = append(out, r.readSigned(16))
+		}
+		if method := r.read(2); method != 0 {
+			return nil, fmt.Errorf("residual method %d", method)
+		}
+		po := r.read(4)
+		for p := 0; p < 1<<po; p++ {
+			k := int(r.read(4))
+			count := n >> po
+			if p == 0 {
+				count -= order
+			}
+			for range count {
+				q := uint64(0)
+				for r.read(1) == 0 {
+					q++
+				}
+				u := q<<k | r.read(k)
+				res := int64(u>>1) ^ -int64(u&1)
+				i := len(out)
+				x := func(j int) int64 { return out[i-j] }
+				var pred int64
+				switch order {
+				case 1:
+					pred = x(1)
+				case 2:
+					pred = 2*x(1) - x(2)
+				case 3:
+					pred = 3*x(1) - 3*x(2) + x(3)
+				case 4:
+					pred = 4*x(1) - 6*x(2) + 4*x(3) - x(4)
+				}
+				out = append(out, pred+res)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unexpected subframe type %#x", typ)
+	}
+	samples := make([]int16, len(out))
+	for i, v := range out {
+		samples[i] = int16(v)
+	}
+	return samples, nil
+}
+
+type bitReader struct {
+	data []byte
+	pos  int // in bits
+}
+
+func (r *bitReader) read(n int) uint64 {
+	var v uint64
+	for range n {
+		bit := uint64(0)
+		if r.pos/8 < len(r.data) {
+			bit = uint64(r.data[r.pos/8]>>(7-r.pos%8)) & 1
+		}
+		v = v<<1 | bit
+		r.pos++
+	}
+	return v
+}
+
+func (r *bitReader) readSigned(n int) int64 {
+	v := r.read(n)
+	return int64(v<<(64-n)) >> (64 - n)
+}
+
+func (r *bitReader) readUTF8() uint64 {
+	first := r.read(8)
+	if first < 0x80 {
+		return first
+	}
+	n := 0
+	for first&(0x80>>n) != 0 {
+		n++
+	}
+	v := first & (0xFF >> (n + 1))
+	for i := 1; i < n; i++ {
+		v = v<<6 | r.read(8)&0x3F
+	}
+	return v
+}
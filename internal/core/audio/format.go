@@ -0,0 +1,61 @@
+package audio
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Format is the file format audio is encoded in for upload or storage.
+type Format string
+
+const (
+	FormatWAV  Format = "wav"
+	FormatFLAC Format = "flac"
+)
+
+// ParseFormat validates a format name. Empty selects FormatWAV.
+func ParseFormat(name string) (Format, error) {
+	switch f := Format(strings.ToLower(strings.TrimSpace(name))); f {
+	case "":
+		return FormatWAV, nil
+	case FormatWAV, FormatFLAC:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown audio format %q (want %s or %s)", name, FormatWAV, FormatFLAC)
+}
+
+// FormatOf returns the format of a file from its extension.
+func FormatOf(path string) (Format, bool) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".wav":
+		return FormatWAV, true
+	case ".flac":
+		return FormatFLAC, true
+	}
+	return "", false
+}
+
+// MIMEType returns the MIME type of f.
+func (f Format) MIMEType() string {
+	if f == FormatFLAC {
+		return "audio/flac"
+	}
+	return "audio/wav"
+}
+
+// Ext returns the file extension of f, including the dot.
+func (f Format) Ext() string {
+	if f == FormatFLAC {
+		return ".flac"
+	}
+	return ".wav"
+}
+
+// Encode returns PCM 16-bit mono audio data as an in-memory file in format f.
+func Encode(f Format, samples []int16, sampleRate int) ([]byte, error) {
+	if f == FormatFLAC {
+		return EncodeFLAC(samples, sampleRate)
+	}
+	return EncodeWAV(samples, sampleRate)
+}
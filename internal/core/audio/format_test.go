@@ -0,0 +1,53 @@
+package audio
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Format
+		wantErr bool
+	}{
+		{"", FormatWAV, false},
+		{"wav", FormatWAV, false},
+		{" FLAC ", FormatFLAC, false},
+		{"mp3", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseFormat(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseFormat(%q) = %q, %v; want %q, wantErr %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestEncodeFormat(t *testing.T) {
+	samples := []int16{0, 100, -100, 200}
+	tests := []struct {
+		format     Format
+		wantPrefix string
+		wantMIME   string
+		wantExt    string
+	}{
+		{FormatWAV, "RIFF", "audio/wav", ".wav"},
+		{FormatFLAC, "fLaC", "audio/flac", ".flac"},
+	}
+	for _, tt := range tests {
+		data, err := Encode(tt.format, samples, 16000)
+		if err != nil {
+			t.Fatalf("Encode(%s) error: %v", tt.format, err)
+		}
+		if !bytes.HasPrefix(data, []byte(tt.wantPrefix)) {
+			t.Errorf("Encode(%s) starts with %q, want %q", tt.format, data[:4], tt.wantPrefix)
+		}
+		if tt.format.MIMEType() != tt.wantMIME || tt.format.Ext() != tt.wantExt {
+			t.Errorf("%s: MIME %q ext %q, want %q %q", tt.format, tt.format.MIMEType(), tt.format.Ext(), tt.wantMIME, tt.wantExt)
+		}
+		if f, ok := FormatOf("rec" + tt.wantExt); !ok || f != tt.format {
+			t.Errorf("FormatOf(rec%s) = %q, %v", tt.wantExt, f, ok)
+		}
+	}
+}
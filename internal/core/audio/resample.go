@@ -0,0 +1,103 @@
+package audio
+
+import (
+	"math"
+	"slices"
+)
+
+const (
+	// resampleZeros is the number of sinc zero crossings on each side of
+	// the interpolation filter; more gives a sharper cutoff.
+	resampleZeros = 32
+	// resampleCutoff is the filter cutoff as a fraction of the lower of the
+	// two Nyquist frequencies, leaving room for the transition band.
+	resampleCutoff = 0.95
+	// resampleKaiserBeta shapes the filter window for about 90 dB of
+	// stopband attenuation.
+	resampleKaiserBeta = 8.6
+)
+
+// Resample converts x from one sample rate to another with a Kaiser-windowed
+// sinc filter, which band-limits the signal below the new Nyquist frequency
+// when downsampling. The filter has one phase per output position between
+// two input samples (to/gcd(from, to) of them), so it is meant for the usual
+// audio rates rather than arbitrary ones.
+func Resample(x []float32, from, to int) []float32 {
+	if from == to || len(x) == 0 {
+		return slices.Clone(x)
+	}
+	g := gcd(from, to)
+	up, down := to/g, from/g // output j is at input position j*down/up
+
+	scale := min(1, float64(to)/float64(from)) * resampleCutoff
+	half := int(math.Ceil(resampleZeros / scale)) // taps on each side
+	taps := 2 * half
+	// Row p of filter weights the input samples around output positions
+	// p/up past an input sample, starting half-1 samples before it.
+	filter := make([]float32, up*taps)
+	norm := besselI0(resampleKaiserBeta)
+	weights := make([]float64, taps)
+	for p := 0; p < up; p++ {
+		row := filter[p*taps : (p+1)*taps]
+		frac := float64(p) / float64(up)
+		var sum float64
+		for k := range weights {
+			t := float64(k-half+1) - frac
+			r := t / float64(half)
+			window := besselI0(resampleKaiserBeta*math.Sqrt(max(0, 1-r*r))) / norm
+			weights[k] = scale * sinc(scale*t) * window
+			sum += weights[k]
+		}
+		for k, v := range weights {
+			row[k] = float32(v / sum) // unity gain at DC
+		}
+	}
+
+	out := make([]float32, int64(len(x))*int64(up)/int64(down))
+	for j := range out {
+		pos := int64(j) * int64(down)
+		i, p := int(pos/int64(up)), int(pos%int64(up))
+		row := filter[p*taps : (p+1)*taps]
+		start := i - half + 1
+		var acc float64
+		for k := max(0, -start); k < min(taps, len(x)-start); k++ {
+			acc += float64(row[k]) * float64(x[start+k])
+		}
+		out[j] = float32(acc)
+	}
+	return out
+}
+
+// ToInt16 converts samples in [-1, 1) to 16-bit PCM, clipping values
+// outside that range.
+func ToInt16(x []float32) []int16 {
+	out := make([]int16, len(x))
+	for i, v := range x {
+		out[i] = int16(max(math.MinInt16, min(math.MaxInt16, math.Round(float64(v)*(1<<15)))))
+	}
+	return out
+}
+
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+// besselI0 is the zeroth-order modified Bessel function of the first kind.
+func besselI0(x float64) float64 {
+	sum, term := 1.0, 1.0
+	for k := 1; term > sum*1e-12; k++ {
+		term *= (x / (2 * float64(k))) * (x / (2 * float64(k)))
+		sum += term
+	}
+	return sum
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
@@ -0,0 +1,247 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// wavFile builds a WAV file with the given fmt chunk body and data, with
+// extra chunks inserted before the data chunk.
+func wavFile(fmtBody, pcm []byte, extra ...[]byte) []byte {
+	var b bytes.Buffer
+	b.WriteString("RIFF")
+	binary.Write(&b, binary.LittleEndian, uint32(0)) // size is not checked
+	b.WriteString("WAVE")
+	chunk := func(id string, body []byte) {
+		b.WriteString(id)
+		binary.Write(&b, binary.LittleEndian, uint32(len(body)))
+		b.Write(body)
+		if len(body)%2 == 1 {
+			b.WriteByte(0)
+		}
+	}
+	chunk("fmt ", fmtBody)
+	for i := 0; i+1 < len(extra); i += 2 {
+		chunk(string(extra[i]), extra[i+1])
+	}
+	chunk("data", pcm)
+	return b.Bytes()
+}
+
+func fmtBody(tag, channels, rate, bits int) []byte {
+	var b bytes.Buffer
+	align := channels * bits / 8
+	for _, v := range []any{uint16(tag), uint16(channels), uint32(rate), uint32(rate * align), uint16(align), uint16(bits)} {
+		binary.Write(&b, binary.LittleEndian, v)
+	}
+	return b.Bytes()
+}
+
+func extensibleBody(subformat, channels, rate, bits int) []byte {
+	b := fmtBody(extensibleFormat, channels, rate, bits)
+	b = binary.LittleEndian.AppendUint16(b, 22) // cbSize
+	b = binary.LittleEndian.AppendUint16(b, uint16(bits))
+	b = binary.LittleEndian.AppendUint32(b, 0) // channel mask
+	b = binary.LittleEndian.AppendUint16(b, uint16(subformat))
+	return append(b, 0x00, 0x00, 0x00, 0x00, 0x10, 0x00, 0x80, 0x00, 0x00, 0xAA, 0x00, 0x38, 0x9B, 0x71)
+}
+
+func TestDecodeWAV(t *testing.T) {
+	f32 := func(vs ...float32) []byte {
+		var b []byte
+		for _, v := range vs {
+			b = binary.LittleEndian.AppendUint32(b, math.Float32bits(v))
+		}
+		return b
+	}
+	f64 := func(vs ...float64) []byte {
+		var b []byte
+		for _, v := range vs {
+			b = binary.LittleEndian.AppendUint64(b, math.Float64bits(v))
+		}
+		return b
+	}
+	tests := []struct {
+		name     string
+		data     []byte
+		channels int
+		want     []float32
+		desc     string
+	}{
+		{"8-bit", wavFile(fmtBody(pcmFormat, 1, 8000, 8), []byte{0x80, 0xC0, 0x00}), 1, []float32{0, 0.5, -1}, "8000 Hz, 1 ch, 8-bit PCM"},
+		{"16-bit", wavFile(fmtBody(pcmFormat, 1, 16000, 16), []byte{0x00, 0x40, 0x00, 0x80}), 1, []float32{0.5, -1}, "16000 Hz, 1 ch, 16-bit PCM"},
+		{"24-bit stereo", wavFile(fmtBody(pcmFormat, 2, 44100, 24), []byte{0x00, 0x00, 0x40, 0x00, 0x00, 0xC0}), 2, []float32{0.5, -0.5}, "44100 Hz, 2 ch, 24-bit PCM"},
+		{"32-bit", wavFile(fmtBody(pcmFormat, 1, 48000, 32), []byte{0x00, 0x00, 0x00, 0xC0}), 1, []float32{-0.5}, "48000 Hz, 1 ch, 32-bit PCM"},
+		{"float32", wavFile(fmtBody(floatFormat, 1, 48000, 32), f32(0.25, -0.75)), 1, []float32{0.25, -0.75}, "48000 Hz, 1 ch, 32-bit float"},
+		{"float64", wavFile(fmtBody(floatFormat, 1, 48000, 64), f64(0.125)), 1, []float32{0.125}, "48000 Hz, 1 ch, 64-bit float"},
+		{"extensible float", wavFile(extensibleBody(floatFormat, 2, 48000, 32), f32(0.5, 0.25)), 2, []float32{0.5, 0.25}, "48000 Hz, 2 ch, 32-bit float"},
+		{"odd chunk before data", wavFile(fmtBody(pcmFormat, 1, 16000, 16), []byte{0x00, 0x40}, []byte("LIST"), []byte("abc")), 1, []float32{0.5}, "16000 Hz, 1 ch, 16-bit PCM"},
+		{"truncated frame", wavFile(fmtBody(pcmFormat, 2, 16000, 16), []byte{0x00, 0x40, 0x00, 0x40, 0x00}), 2, []float32{0.5, 0.5}, "16000 Hz, 2 ch, 16-bit PCM"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := DecodeWAV(tt.data)
+			if err != nil {
+				t.Fatalf("DecodeWAV() error: %v", err)
+			}
+			if w.Channels != tt.channels || w.String() != tt.desc {
+				t.Errorf("format = %s, want %s", w, tt.desc)
+			}
+			if len(w.Samples) != len(tt.want) {
+				t.Fatalf("samples = %v, want %v", w.Samples, tt.want)
+			}
+			for i := range tt.want {
+				if math.Abs(float64(w.Samples[i]-tt.want[i])) > 1e-6 {
+					t.Errorf("samples = %v, want %v", w.Samples, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeWAVOversizedDataChunk(t *testing.T) {
+	data := wavFile(fmtBody(pcmFormat, 1, 16000, 16), []byte{0x00, 0x40, 0x00, 0xC0})
+	binary.LittleEndian.PutUint32(data[len(data)-8:], 0xFFFFFFFF) // streaming writers leave the size unset
+	w, err := DecodeWAV(data)
+	if err != nil {
+		t.Fatalf("DecodeWAV() error: %v", err)
+	}
+	if len(w.Samples) != 2 || w.Samples[0] != 0.5 || w.Samples[1] != -0.5 {
+		t.Errorf("samples = %v, want [0.5 -0.5]", w.Samples)
+	}
+}
+
+func TestDecodeWAVErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"not RIFF", []byte("not a wav file at all"), "not a RIFF file"},
+		{"no fmt", append([]byte("RIFF\x00\x00\x00\x00WAVEdata\x02\x00\x00\x00"), 0, 0), "fmt chunk not found"},
+		{"no data", wavFile(fmtBody(pcmFormat, 1, 16000, 16), nil)[:36], "data chunk not found"},
+		{"ADPCM", wavFile(fmtBody(2, 1, 16000, 4), []byte{0}), "format tag 0x0002"},
+		{"12-bit", wavFile(fmtBody(pcmFormat, 1, 16000, 12), []byte{0, 0}), "12-bit PCM"},
+		{"no channels", wavFile(fmtBody(pcmFormat, 0, 16000, 16), nil), "invalid WAV header"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DecodeWAV(tt.data)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("DecodeWAV() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadWAVRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "in.wav")
+	samples := []int16{0, 1, -1, 12345, -32768, 32767}
+	if err := WriteWAV(path, samples, 16000); err != nil {
+		t.Fatal(err)
+	}
+	w, err := ReadWAV(path)
+	if err != nil {
+		t.Fatalf("ReadWAV() error: %v", err)
+	}
+	got := w.PCM16(16000)
+	if len(got) != len(samples) {
+		t.Fatalf("PCM16() = %v, want %v", got, samples)
+	}
+	for i := range samples {
+		if got[i] != samples[i] {
+			t.Fatalf("PCM16() = %v, want 16 kHz mono 16-bit input unchanged (%v)", got, samples)
+		}
+	}
+}
+
+func TestWAVMono(t *testing.T) {
+	w := &WAV{SampleRate: 16000, Channels: 2, BitsPerSample: 16, Samples: []float32{0.5, -0.5, 1, 0, 0.25, 0.25}}
+	want := []float32{0, 0.5, 0.25}
+	got := w.Mono()
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Errorf("Mono() = %v, want %v", got, want)
+	}
+	if w.Frames() != 3 || math.Abs(w.Duration()-3.0/16000) > 1e-12 {
+		t.Errorf("Frames(), Duration() = %d, %v", w.Frames(), w.Duration())
+	}
+}
+
+func sine(freq float64, rate, n int, amp float64) []float32 {
+	x := make([]float32, n)
+	for i := range x {
+		x[i] = float32(amp * math.Sin(2*math.Pi*freq*float64(i)/float64(rate)))
+	}
+	return x
+}
+
+func TestResample(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to int
+		freq     float64
+		wantAmp  float64 // expected output amplitude relative to the input
+	}{
+		{"44.1k to 16k passband", 44100, 16000, 1000, 1},
+		{"48k to 16k passband", 48000, 16000, 3000, 1},
+		{"8k to 16k", 8000, 16000, 1000, 1},
+		{"22.05k to 16k", 22050, 16000, 440, 1},
+		{"48k to 16k stopband", 48000, 16000, 12000, 0},
+		{"44.1k to 16k stopband", 44100, 16000, 9000, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			const amp = 0.5
+			x := sine(tt.freq, tt.from, tt.from/2, amp) // 0.5s
+			y := Resample(x, tt.from, tt.to)
+			if want := tt.to / 2; len(y) != want {
+				t.Fatalf("len = %d, want %d", len(y), want)
+			}
+			// Skip the edges, where the filter runs off the input.
+			edge := tt.to / 20
+			if tt.wantAmp == 0 {
+				var peak float64
+				for _, v := range y[edge : len(y)-edge] {
+					peak = max(peak, math.Abs(float64(v)))
+				}
+				if peak > amp*1e-3 {
+					t.Errorf("peak above the new Nyquist frequency = %g, want < %g (aliasing)", peak, amp*1e-3)
+				}
+				return
+			}
+			ideal := sine(tt.freq, tt.to, len(y), amp)
+			var maxErr float64
+			for i := edge; i < len(y)-edge; i++ {
+				maxErr = max(maxErr, math.Abs(float64(y[i]-ideal[i])))
+			}
+			if maxErr > amp*1e-3 {
+				t.Errorf("max error = %g, want < %g", maxErr, amp*1e-3)
+			}
+		})
+	}
+}
+
+func TestResampleSameRate(t *testing.T) {
+	x := []float32{0.1, 0.2}
+	y := Resample(x, 16000, 16000)
+	y[0] = 1
+	if x[0] != 0.1 {
+		t.Error("Resample() at the same rate should return a copy")
+	}
+}
+
+func TestToInt16(t *testing.T) {
+	got := ToInt16([]float32{0, 0.5, -1, 1, 2, -2, 1.0 / 32768})
+	want := []int16{0, 16384, -32768, 32767, 32767, -32768, 1}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("ToInt16() = %v, want %v", got, want)
+			break
+		}
+	}
+}
@@ -39,37 +39,15 @@ func TrimSilence(samples []int16, sampleRate int) ([]int16, TrimInfo) {
 	}
 	info.WindowSamples = windowSamples
 
-	numWindows := (len(samples) + windowSamples - 1) / windowSamples
-	energies := make([]float64, 0, numWindows)
-
-	var maxEnergy float64
-	for i := 0; i < len(samples); i += windowSamples {
-		end := i + windowSamples
-		if end > len(samples) {
-			end = len(samples)
-		}
-		var sum float64
-		for _, s := range samples[i:end] {
-			sum += math.Abs(float64(s))
-		}
-		mean := sum / float64(end-i)
-		energies = append(energies, mean)
-		if mean > maxEnergy {
-			maxEnergy = mean
-		}
-	}
+	energies, maxEnergy := windowEnergies(samples, windowSamples)
 
 	if maxEnergy == 0 {
 		info.AllSilence = true
 		return nil, info
 	}
 
-	noiseFloor := percentile(energies, 0.10)
+	noiseFloor, threshold := speechThreshold(energies, maxEnergy)
 	info.NoiseFloor = noiseFloor
-
-	// Conservative threshold: depends on both noise floor and max energy.
-	threshold := math.Max(noiseFloor*3.0, maxEnergy*0.05)
-	threshold = math.Max(threshold, 80.0)
 	info.Threshold = threshold
 
 	startW := -1
@@ -125,6 +103,44 @@ func TrimSilence(samples []int16, sampleRate int) ([]int16, TrimInfo) {
 	return trimmed, info
 }
 
+// speechThreshold returns the noise floor (10th percentile of the window
+// energies) and the energy above which a window counts as speech.
+func speechThreshold(energies []float64, maxEnergy float64) (noiseFloor, threshold float64) {
+	noiseFloor = percentile(energies, 0.10)
+	return noiseFloor, thresholdFor(noiseFloor, maxEnergy)
+}
+
+// thresholdFor returns the speech threshold for a known noise floor.
+func thresholdFor(noiseFloor, maxEnergy float64) float64 {
+	// Conservative threshold: depends on both noise floor and max energy.
+	threshold := math.Max(noiseFloor*3.0, maxEnergy*0.05)
+	return math.Max(threshold, 80.0)
+}
+
+// windowEnergies returns the mean absolute amplitude of each window and the maximum of them.
+func windowEnergies(samples []int16, windowSamples int) ([]float64, float64) {
+	numWindows := (len(samples) + windowSamples - 1) / windowSamples
+	energies := make([]float64, 0, numWindows)
+
+	var maxEnergy float64
+	for i := 0; i < len(samples); i += windowSamples {
+		end := i + windowSamples
+		if end > len(samples) {
+			end = len(samples)
+		}
+		var sum float64
+		for _, s := range samples[i:end] {
+			sum += math.Abs(float64(s))
+		}
+		mean := sum / float64(end-i)
+		energies = append(energies, mean)
+		if mean > maxEnergy {
+			maxEnergy = mean
+		}
+	}
+	return energies, maxEnergy
+}
+
 func percentile(values []float64, p float64) float64 {
 	if len(values) == 0 {
 		return 0
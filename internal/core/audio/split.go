@@ -0,0 +1,84 @@
+package audio
+
+import "math"
+
+// Chunk is a half-open sample range [Start, End) of a recording.
+type Chunk struct {
+	Start int
+	End   int
+}
+
+// SplitAtSilence splits samples into chunks of at most maxChunkSec seconds,
+// cutting at the quietest point of each chunk's second half so that words
+// are not cut in the middle.
+//
+// Heuristic:
+// - Compute mean absolute amplitude per 10ms window (same as TrimSilence).
+// - Smooth energies over 300ms so a short gap between syllables does not win.
+// - For each chunk, cut at the lowest smoothed energy in [max/2, max].
+//
+// Recordings shorter than maxChunkSec are returned as a single chunk.
+func SplitAtSilence(samples []int16, sampleRate int, maxChunkSec float64) []Chunk {
+	if len(samples) == 0 {
+		return nil
+	}
+	maxChunk := int(maxChunkSec * float64(sampleRate))
+	if sampleRate <= 0 || maxChunk <= 0 || len(samples) <= maxChunk {
+		return []Chunk{{Start: 0, End: len(samples)}}
+	}
+
+	windowSamples := sampleRate / 100
+	if windowSamples < 1 {
+		windowSamples = 1
+	}
+	energies, _ := windowEnergies(samples, windowSamples)
+	smoothed := smoothEnergies(energies, 15) // ±150ms
+
+	var chunks []Chunk
+	start := 0
+	for len(samples)-start > maxChunk {
+		lo := (start + maxChunk/2) / windowSamples
+		hi := (start + maxChunk) / windowSamples
+		// Find the latest run of minimum-energy windows and cut at its middle,
+		// so chunks stay close to maxChunk and the cut lands inside the pause.
+		runStart, runEnd, bestEnergy := hi, hi, math.Inf(1)
+		for w := lo; w < hi && w < len(smoothed); w++ {
+			switch e := smoothed[w]; {
+			case e < bestEnergy:
+				runStart, runEnd, bestEnergy = w, w, e
+			case e == bestEnergy && w == runEnd+1:
+				runEnd = w
+			case e == bestEnergy:
+				runStart, runEnd = w, w
+			}
+		}
+		best := (runStart + runEnd) / 2
+		if runEnd >= hi-1 {
+			// The quietest run continues past the limit (or there is no
+			// quieter point at all): cutting at the limit is just as good.
+			best = hi
+		}
+		cut := best*windowSamples + windowSamples/2
+		if cut <= start || cut > start+maxChunk {
+			cut = start + maxChunk
+		}
+		chunks = append(chunks, Chunk{Start: start, End: cut})
+		start = cut
+	}
+	return append(chunks, Chunk{Start: start, End: len(samples)})
+}
+
+// smoothEnergies returns the moving average of energies over ±radius windows.
+func smoothEnergies(energies []float64, radius int) []float64 {
+	prefix := make([]float64, len(energies)+1)
+	for i, e := range energies {
+		prefix[i+1] = prefix[i] + e
+	}
+	out := make([]float64, len(energies))
+	for i := range energies {
+		lo := max(i-radius, 0)
+		hi := min(i+radius+1, len(energies))
+		out[i] = (prefix[hi] - prefix[lo]) / float64(hi-lo)
+	}
+	return out
+}
@@ -0,0 +1,62 @@
+package audio
+
+import "testing"
+
+func TestSplitAtSilence(t *testing.T) {
+	const sr = 16000
+
+	speech := func(sec float64) []int16 {
+		s := make([]int16, int(sec*sr))
+		for i := range s {
+			s[i] = 2000
+		}
+		return s
+	}
+	pause := func(sec float64) []int16 { return make([]int16, int(sec*sr)) }
+	join := func(parts ...[]int16) []int16 {
+		var out []int16
+		for _, p := range parts {
+			out = append(out, p...)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name       string
+		samples    []int16
+		maxSec     float64
+		wantChunks int
+		wantCutSec []float64 // approximate cut positions
+	}{
+		{"short recording is one chunk", speech(5), 10, 1, nil},
+		{"cuts at the pause", join(speech(7), pause(1), speech(7)), 10, 2, []float64{7.5}},
+		{"prefers the later pause", join(speech(5.5), pause(0.5), speech(3), pause(1), speech(5)), 10, 2, []float64{9.5}},
+		{"no pause cuts at max", speech(25), 10, 3, []float64{10, 20}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := SplitAtSilence(tt.samples, sr, tt.maxSec)
+			if len(chunks) != tt.wantChunks {
+				t.Fatalf("chunks = %+v, want %d chunks", chunks, tt.wantChunks)
+			}
+			if chunks[0].Start != 0 || chunks[len(chunks)-1].End != len(tt.samples) {
+				t.Fatalf("chunks %+v do not cover [0,%d)", chunks, len(tt.samples))
+			}
+			for i, c := range chunks {
+				if i > 0 && c.Start != chunks[i-1].End {
+					t.Errorf("chunk %d starts at %d, want %d", i, c.Start, chunks[i-1].End)
+				}
+				if float64(c.End-c.Start) > tt.maxSec*sr {
+					t.Errorf("chunk %d is %d samples, longer than max", i, c.End-c.Start)
+				}
+			}
+			for i, want := range tt.wantCutSec {
+				got := float64(chunks[i].End) / sr
+				if got < want-0.3 || got > want+0.3 {
+					t.Errorf("cut %d at %.2fs, want ~%.2fs", i, got, want)
+				}
+			}
+		})
+	}
+}
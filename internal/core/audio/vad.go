@@ -0,0 +1,96 @@
+package audio
+
+import "time"
+
+// vadHistoryWindows bounds the window energies a VoiceDetector re-evaluates
+// (30s of 10ms windows), so each Write stays cheap on long recordings.
+const vadHistoryWindows = 3000
+
+// vadFloorInterval is how many windows (0.5s) a VoiceDetector keeps its noise
+// floor before taking the percentile again. Sorting the history on every
+// recorder callback is too slow for the real-time path, and the floor of 30s
+// of audio barely moves in half a second.
+const vadFloorInterval = 50
+
+// VoiceDetector detects the end of an utterance in audio that arrives in
+// pieces while recording. It uses the windowed energy threshold of
+// TrimSilence over the audio so far, re-evaluated as audio arrives so speech
+// at the very start is recognized once the noise floor is known: the
+// utterance has ended once at least minSpeech of speech has been heard and
+// is followed by trailingSilence without speech. Requiring minSpeech keeps a
+// cough or a click from ending the recording before the user starts talking.
+type VoiceDetector struct {
+	sampleRate      int
+	windowSamples   int
+	minSpeech       int // windows
+	trailingSilence int // windows
+
+	pending    []int16
+	energies   []float64
+	maxEnergy  float64
+	noiseFloor float64
+	floorAge   int // windows added since noiseFloor was computed
+	speech     int // windows above the threshold in energies
+	ended      bool
+}
+
+// NewVoiceDetector returns a detector for audio at sampleRate.
+func NewVoiceDetector(sampleRate int, trailingSilence, minSpeech time.Duration) *VoiceDetector {
+	windowSamples := max(sampleRate/100, 1)
+	windows := func(d time.Duration) int {
+		return max(int(d.Seconds()*float64(sampleRate))/windowSamples, 1)
+	}
+	return &VoiceDetector{
+		sampleRate:      sampleRate,
+		windowSamples:   windowSamples,
+		minSpeech:       windows(minSpeech),
+		trailingSilence: windows(trailingSilence),
+	}
+}
+
+// Write feeds the next samples and reports whether the utterance has ended.
+// Once it reports true it keeps doing so. samples is not retained.
+func (d *VoiceDetector) Write(samples []int16) bool {
+	if d.ended {
+		return true
+	}
+	d.pending = append(d.pending, samples...)
+	n := len(d.pending) / d.windowSamples * d.windowSamples
+	if n == 0 {
+		return false
+	}
+	energies, maxEnergy := windowEnergies(d.pending[:n], d.windowSamples)
+	d.pending = append(d.pending[:0], d.pending[n:]...)
+
+	d.energies = append(d.energies, energies...)
+	if len(d.energies) > vadHistoryWindows {
+		d.energies = append(d.energies[:0], d.energies[len(d.energies)-vadHistoryWindows:]...)
+	}
+	d.maxEnergy = max(d.maxEnergy, maxEnergy)
+	d.floorAge += len(energies)
+	// Early on the floor is still settling and the history is short, so it
+	// is cheap to follow every Write.
+	if len(d.energies) <= vadFloorInterval || d.floorAge >= vadFloorInterval {
+		d.noiseFloor = percentile(d.energies, 0.10)
+		d.floorAge = 0
+	}
+	threshold := thresholdFor(d.noiseFloor, d.maxEnergy)
+
+	d.speech = 0
+	silence := 0
+	for _, e := range d.energies {
+		if e >= threshold {
+			d.speech++
+			silence = 0
+		} else {
+			silence++
+		}
+	}
+	d.ended = d.speech >= d.minSpeech && silence >= d.trailingSilence
+	return d.ended
+}
+
+// SpeechDuration returns how much speech has been heard so far.
+func (d *VoiceDetector) SpeechDuration() time.Duration {
+	return time.Duration(d.speech*d.windowSamples) * time.Second / time.Duration(d.sampleRate)
+}
@@ -0,0 +1,82 @@
+package audio
+
+import (
+	"testing"
+	"time"
+)
+
+// segment returns d of samples at a constant amplitude (0 is silence).
+func segment(sr int, d time.Duration, amplitude int16) []int16 {
+	s := make([]int16, int(d.Seconds()*float64(sr)))
+	for i := range s {
+		s[i] = amplitude
+	}
+	return s
+}
+
+func TestVoiceDetector(t *testing.T) {
+	const sr = 16000
+	tests := []struct {
+		name     string
+		segments [][]int16
+		want     bool
+	}{
+		{"silence only", [][]int16{segment(sr, 3*time.Second, 0)}, false},
+		{"speech then short pause", [][]int16{segment(sr, 500*time.Millisecond, 0), segment(sr, time.Second, 2000), segment(sr, time.Second, 0)}, false},
+		{"speech then trailing silence", [][]int16{segment(sr, 500*time.Millisecond, 0), segment(sr, time.Second, 2000), segment(sr, 1600*time.Millisecond, 0)}, true},
+		{"cough is ignored", [][]int16{segment(sr, 500*time.Millisecond, 0), segment(sr, 150*time.Millisecond, 3000), segment(sr, 3*time.Second, 0)}, false},
+		{"pause between phrases", [][]int16{segment(sr, time.Second, 2000), segment(sr, time.Second, 0), segment(sr, time.Second, 2000), segment(sr, time.Second, 0)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewVoiceDetector(sr, 1500*time.Millisecond, 300*time.Millisecond)
+			var samples []int16
+			for _, s := range tt.segments {
+				samples = append(samples, s...)
+			}
+			got := false
+			// Feed in recorder-sized frames that do not align with windows.
+			for i := 0; i < len(samples); i += 1024 {
+				got = d.Write(samples[i:min(i+1024, len(samples))])
+			}
+			if got != tt.want {
+				t.Errorf("ended = %v, want %v (speech %s)", got, tt.want, d.SpeechDuration())
+			}
+		})
+	}
+}
+
+func TestVoiceDetectorStaysEnded(t *testing.T) {
+	const sr = 16000
+	d := NewVoiceDetector(sr, 500*time.Millisecond, 300*time.Millisecond)
+	d.Write(segment(sr, time.Second, 2000))
+	if !d.Write(segment(sr, time.Second, 0)) {
+		t.Fatal("expected the utterance to end")
+	}
+	if !d.Write(segment(sr, time.Second, 2000)) {
+		t.Error("Write after the end should keep reporting true")
+	}
+	if got := d.SpeechDuration(); got != time.Second {
+		t.Errorf("SpeechDuration() = %s, want 1s", got)
+	}
+}
+
+func TestVoiceDetectorNoiseFloorInterval(t *testing.T) {
+	const sr = 16000
+	d := NewVoiceDetector(sr, 1500*time.Millisecond, 300*time.Millisecond)
+	d.Write(segment(sr, time.Second, 100))
+	if d.noiseFloor != 100 {
+		t.Fatalf("noiseFloor = %g, want 100", d.noiseFloor)
+	}
+	// A louder background is picked up at the next interval, not per Write.
+	d.Write(segment(sr, 100*time.Millisecond, 400))
+	if d.noiseFloor != 100 {
+		t.Errorf("noiseFloor = %g after 10 windows, want it kept at 100", d.noiseFloor)
+	}
+	for range 4 {
+		d.Write(segment(sr, 100*time.Millisecond, 400))
+	}
+	if d.floorAge != 0 {
+		t.Errorf("floorAge = %d after %d windows, want the floor recomputed", d.floorAge, vadFloorInterval)
+	}
+}
@@ -131,6 +131,18 @@ func GetDuration(path string) (float64, error) {
 // WriteWAV writes PCM 16-bit mono audio data to a WAV file.
 // All header and sample data are assembled in memory and written atomically.
 func WriteWAV(path string, samples []int16, sampleRate int) error {
+	data, err := EncodeWAV(samples, sampleRate)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("writing WAV file: %w", err)
+	}
+	return nil
+}
+
+// EncodeWAV returns PCM 16-bit mono audio data as an in-memory WAV file.
+func EncodeWAV(samples []int16, sampleRate int) ([]byte, error) {
 	numChannels := uint16(1)
 	bps := uint16(bitsPerSample)
 	dataSize := uint32(len(samples)) * uint32(bps/8) * uint32(numChannels)
@@ -160,11 +172,162 @@ func WriteWAV(path string, samples []int16, sampleRate int) error {
 	buf.WriteString("data")
 	binary.Write(&buf, binary.LittleEndian, dataSize)
 	if err := binary.Write(&buf, binary.LittleEndian, samples); err != nil {
-		return fmt.Errorf("encoding samples: %w", err)
+		return nil, fmt.Errorf("encoding samples: %w", err)
 	}
+	return buf.Bytes(), nil
+}
 
-	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
-		return fmt.Errorf("writing WAV file: %w", err)
+const (
+	floatFormat      = 3
+	extensibleFormat = 0xFFFE
+)
+
+// WAV is decoded WAV audio.
+type WAV struct {
+	SampleRate    int
+	Channels      int
+	BitsPerSample int
+	Float         bool
+	// Samples are interleaved by channel and scaled to [-1, 1).
+	Samples []float32
+}
+
+// ReadWAV reads and decodes a WAV file. See DecodeWAV.
+func ReadWAV(path string) (*WAV, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading WAV file: %w", err)
 	}
-	return nil
+	return DecodeWAV(data)
+}
+
+// DecodeWAV decodes an in-memory WAV file with 8, 16, 24 or 32-bit integer
+// PCM or 32 or 64-bit IEEE float samples and any number of channels,
+// including WAVE_FORMAT_EXTENSIBLE headers. A data chunk that claims more
+// bytes than the file has, as written by some streaming recorders, is read
+// up to the end of the file.
+func DecodeWAV(data []byte) (*WAV, error) {
+	if len(data) < riffHeaderSize || string(data[0:4]) != "RIFF" {
+		return nil, fmt.Errorf("not a RIFF file")
+	}
+	if string(data[8:12]) != "WAVE" {
+		return nil, fmt.Errorf("not a WAVE file")
+	}
+
+	var fmtChunk, pcm []byte
+	foundData := false
+	for pos := riffHeaderSize; pos+8 <= len(data); {
+		id := string(data[pos : pos+4])
+		size := binary.LittleEndian.Uint32(data[pos+4:])
+		body := data[pos+8 : pos+8+int(min(uint64(size), uint64(len(data)-pos-8)))]
+		switch id {
+		case "fmt ":
+			fmtChunk = body
+		case dataChunkID:
+			pcm, foundData = body, true
+		}
+		pos += 8 + len(body) + int(size&1) // chunks are padded to an even size
+	}
+	if fmtChunk == nil {
+		return nil, fmt.Errorf("fmt chunk not found")
+	}
+	if !foundData {
+		return nil, fmt.Errorf("data chunk not found")
+	}
+	if len(fmtChunk) < 16 {
+		return nil, fmt.Errorf("fmt chunk too short (%d bytes)", len(fmtChunk))
+	}
+
+	tag := binary.LittleEndian.Uint16(fmtChunk[0:])
+	if tag == extensibleFormat {
+		if len(fmtChunk) < 26 {
+			return nil, fmt.Errorf("extensible fmt chunk too short (%d bytes)", len(fmtChunk))
+		}
+		tag = binary.LittleEndian.Uint16(fmtChunk[24:]) // first bytes of the subformat GUID
+	}
+	w := &WAV{
+		Channels:      int(binary.LittleEndian.Uint16(fmtChunk[2:])),
+		SampleRate:    int(binary.LittleEndian.Uint32(fmtChunk[4:])),
+		BitsPerSample: int(binary.LittleEndian.Uint16(fmtChunk[14:])),
+		Float:         tag == floatFormat,
+	}
+	if w.Channels == 0 || w.SampleRate == 0 {
+		return nil, fmt.Errorf("invalid WAV header values")
+	}
+
+	var decode func([]byte) float32
+	switch {
+	case tag == pcmFormat && w.BitsPerSample == 8:
+		decode = func(b []byte) float32 { return float32(int(b[0])-128) / (1 << 7) }
+	case tag == pcmFormat && w.BitsPerSample == 16:
+		decode = func(b []byte) float32 { return float32(int16(binary.LittleEndian.Uint16(b))) / (1 << 15) }
+	case tag == pcmFormat && w.BitsPerSample == 24:
+		decode = func(b []byte) float32 {
+			return float32(int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24)>>8) / (1 << 23)
+		}
+	case tag == pcmFormat && w.BitsPerSample == 32:
+		decode = func(b []byte) float32 { return float32(int32(binary.LittleEndian.Uint32(b))) / (1 << 31) }
+	case tag == floatFormat && w.BitsPerSample == 32:
+		decode = func(b []byte) float32 { return math.Float32frombits(binary.LittleEndian.Uint32(b)) }
+	case tag == floatFormat && w.BitsPerSample == 64:
+		decode = func(b []byte) float32 { return float32(math.Float64frombits(binary.LittleEndian.Uint64(b))) }
+	case tag == pcmFormat || tag == floatFormat:
+		return nil, fmt.Errorf("unsupported %d-bit %s WAV", w.BitsPerSample, w.encoding())
+	default:
+		return nil, fmt.Errorf("unsupported WAV format tag 0x%04x (want PCM or IEEE float)", tag)
+	}
+
+	width := w.BitsPerSample / 8
+	frameSize := width * w.Channels
+	pcm = pcm[:len(pcm)/frameSize*frameSize] // drop a truncated last frame
+	w.Samples = make([]float32, len(pcm)/width)
+	for i := range w.Samples {
+		w.Samples[i] = decode(pcm[i*width:])
+	}
+	return w, nil
+}
+
+// Frames returns the number of samples per channel.
+func (w *WAV) Frames() int {
+	return len(w.Samples) / w.Channels
+}
+
+// Duration returns the length of the audio in seconds.
+func (w *WAV) Duration() float64 {
+	return float64(w.Frames()) / float64(w.SampleRate)
+}
+
+// String describes the format, e.g. "44100 Hz, 2 ch, 24-bit PCM".
+func (w *WAV) String() string {
+	return fmt.Sprintf("%d Hz, %d ch, %d-bit %s", w.SampleRate, w.Channels, w.BitsPerSample, w.encoding())
+}
+
+func (w *WAV) encoding() string {
+	if w.Float {
+		return "float"
+	}
+	return "PCM"
+}
+
+// Mono returns the channels mixed down to one by averaging them.
+func (w *WAV) Mono() []float32 {
+	if w.Channels == 1 {
+		return w.Samples
+	}
+	mono := make([]float32, w.Frames())
+	for i := range mono {
+		var sum float32
+		for _, s := range w.Samples[i*w.Channels : (i+1)*w.Channels] {
+			sum += s
+		}
+		mono[i] = sum / float32(w.Channels)
+	}
+	return mono
+}
+
+// PCM16 returns the audio as 16-bit mono PCM at sampleRate, the format the
+// recorder captures and EncodeWAV and EncodeFLAC take. 16-bit mono input at
+// the same rate is returned unchanged.
+func (w *WAV) PCM16(sampleRate int) []int16 {
+	return ToInt16(Resample(w.Mono(), w.SampleRate, sampleRate))
 }
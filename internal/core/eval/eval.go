@@ -0,0 +1,214 @@
+// Package eval measures transcription quality against a corpus of recordings
+// with expected text, so prompt and model changes can be checked for
+// regressions.
+package eval
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/noricha-vr/voicecode/internal/core/audio"
+	"github.com/noricha-vr/voicecode/internal/core/prompt"
+	"github.com/noricha-vr/voicecode/internal/core/transcriber"
+)
+
+// SampleRate is the rate corpus recordings are converted to before upload,
+// the rate the app records at.
+const SampleRate = 16000
+
+// Case is a recording and the text it should transcribe to.
+type Case struct {
+	Name     string // path relative to the corpus directory, without extension
+	WAVPath  string
+	Expected string
+}
+
+// LoadCorpus finds every .wav file under dir that has a .txt file with the
+// same name next to it, in name order. WAV files without expected text are
+// logged and skipped.
+func LoadCorpus(dir string) ([]Case, error) {
+	var cases []Case
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.EqualFold(filepath.Ext(path), ".wav") {
+			return nil
+		}
+		base := strings.TrimSuffix(path, filepath.Ext(path))
+		expected, err := os.ReadFile(base + ".txt")
+		if os.IsNotExist(err) {
+			log.Printf("[Eval] %s has no expected text (%s.txt), skipping", path, filepath.Base(base))
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading expected text: %w", err)
+		}
+		name, err := filepath.Rel(dir, base)
+		if err != nil {
+			name = base
+		}
+		cases = append(cases, Case{
+			Name:     filepath.ToSlash(name),
+			WAVPath:  path,
+			Expected: strings.TrimSpace(string(expected)),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(cases) == 0 {
+		return nil, fmt.Errorf("no .wav files with expected .txt text in %s", dir)
+	}
+	slices.SortFunc(cases, func(a, b Case) int { return strings.Compare(a.Name, b.Name) })
+	return cases, nil
+}
+
+// Normalize removes whitespace, which Japanese text does not use and models
+// insert inconsistently around English words, so it does not count as an
+// error.
+func Normalize(s string) string {
+	return strings.Join(strings.Fields(s), "")
+}
+
+// EditDistance returns the Levenshtein distance between a and b in runes.
+func EditDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+// CER returns the character error rate of got against expected: the edit
+// distance of the normalized texts divided by the expected length. An empty
+// expectation scores 0 when got is empty too and 1 otherwise.
+func CER(expected, got string) float64 {
+	expected, got = Normalize(expected), Normalize(got)
+	n := len([]rune(expected))
+	if n == 0 {
+		if got == "" {
+			return 0
+		}
+		return 1
+	}
+	return float64(EditDistance(expected, got)) / float64(n)
+}
+
+// Variant is one configuration under evaluation.
+type Variant struct {
+	Label string
+	Model string // pins Options.Model; empty uses the backend's model and fallback
+	Mode  string // settings.Mode name; empty uses the default prompts
+}
+
+// Result is the outcome of one case.
+type Result struct {
+	Name     string  `json:"name"`
+	Expected string  `json:"expected"`
+	Got      string  `json:"got"`
+	CER      float64 `json:"cer"`
+	Edits    int     `json:"edits"`
+	Chars    int     `json:"chars"` // normalized expected length
+	Exact    bool    `json:"exact"`
+	Elapsed  float64 `json:"elapsed_sec"`
+	Error    string  `json:"error,omitempty"`
+}
+
+// Report is the outcome of a Variant over a corpus.
+type Report struct {
+	Variant    string   `json:"variant"`
+	Backend    string   `json:"backend"`
+	Model      string   `json:"model"`
+	Mode       string   `json:"mode,omitempty"`
+	Cases      []Result `json:"cases"`
+	CER        float64  `json:"cer"` // total edits over total expected characters
+	ExactMatch float64  `json:"exact_match"`
+	Exact      int      `json:"exact"`
+	Failed     int      `json:"failed"`
+}
+
+// Run transcribes every case with b under v, applies replacer (the local
+// dictionary pass; nil skips it) like the app does before pasting, and
+// scores the results. A failed request counts as if nothing was transcribed.
+func Run(ctx context.Context, b transcriber.Backend, replacer *prompt.Replacer, cases []Case, v Variant) Report {
+	r := Report{Variant: v.Label, Backend: b.Name(), Model: v.Model, Mode: v.Mode}
+	if r.Model == "" {
+		r.Model = b.ModelName()
+	}
+	totalEdits, totalChars := 0, 0
+	for _, c := range cases {
+		res := Result{Name: c.Name, Expected: c.Expected}
+		got, elapsed, err := transcribe(ctx, b, c.WAVPath, v)
+		res.Elapsed = elapsed
+		if err != nil {
+			res.Error = err.Error()
+			r.Failed++
+		} else {
+			if replacer != nil {
+				got, _ = replacer.Apply(got)
+			}
+			res.Got = strings.TrimSpace(got)
+		}
+		want, have := Normalize(res.Expected), Normalize(res.Got)
+		res.Chars = len([]rune(want))
+		res.Edits = EditDistance(want, have)
+		res.CER = CER(res.Expected, res.Got)
+		res.Exact = err == nil && want == have
+		if res.Exact {
+			r.Exact++
+		}
+		totalEdits += res.Edits
+		totalChars += res.Chars
+		r.Cases = append(r.Cases, res)
+	}
+	if totalChars > 0 {
+		r.CER = float64(totalEdits) / float64(totalChars)
+	}
+	if len(cases) > 0 {
+		r.ExactMatch = float64(r.Exact) / float64(len(cases))
+	}
+	return r
+}
+
+// transcribe sends the recording at path as 16 kHz mono 16-bit WAV, like the
+// app's recordings and the transcribe command, whatever the corpus file's
+// rate, channels and sample format.
+func transcribe(ctx context.Context, b transcriber.Backend, path string, v Variant) (string, float64, error) {
+	w, err := audio.ReadWAV(path)
+	if err != nil {
+		return "", 0, fmt.Errorf("read audio file: %w", err)
+	}
+	data, err := audio.EncodeWAV(w.PCM16(SampleRate), SampleRate)
+	if err != nil {
+		return "", 0, fmt.Errorf("encode audio: %w", err)
+	}
+	start := time.Now()
+	text, err := b.Transcribe(ctx, data, transcriber.Options{
+		MIMEType: audio.FormatWAV.MIMEType(),
+		Model:    v.Model,
+		Mode:     v.Mode,
+	})
+	return text, time.Since(start).Seconds(), err
+}
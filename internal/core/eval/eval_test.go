@@ -0,0 +1,280 @@
+package eval
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/noricha-vr/voicecode/internal/core/audio"
+	"github.com/noricha-vr/voicecode/internal/core/prompt"
+	"github.com/noricha-vr/voicecode/internal/core/transcriber"
+)
+
+func TestCER(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+		got      string
+		want     float64
+	}{
+		{"exact", "Dockerでデプロイ", "Dockerでデプロイ", 0},
+		{"whitespace ignored", "Docker でデプロイ", "Dockerで デプロイ", 0},
+		{"substitution", "Dockerでデプロイ", "ドッカーでデプロイ", 6.0 / 11},
+		{"deletion", "abcd", "abc", 0.25},
+		{"empty both", "", "", 0},
+		{"empty expected", "", "x", 1},
+		{"nothing transcribed", "abcd", "", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CER(tt.expected, tt.got); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("CER(%q, %q) = %v, want %v", tt.expected, tt.got, got, tt.want)
+			}
+		})
+	}
+}
+
+// nameSamples spells name in 16-bit samples, one byte per sample.
+func nameSamples(name string) []int16 {
+	samples := make([]int16, len(name))
+	for i := range len(name) {
+		samples[i] = int16(name[i]) << 8
+	}
+	return samples
+}
+
+// sampleName reverses nameSamples on the WAV a backend received.
+func sampleName(data []byte) (string, error) {
+	w, err := audio.DecodeWAV(data)
+	if err != nil {
+		return "", err
+	}
+	var name []byte
+	for _, s := range w.PCM16(w.SampleRate) {
+		name = append(name, byte((int(s)+128)>>8))
+	}
+	return string(name), nil
+}
+
+// writeCorpus creates name.wav (whose samples spell the name, see
+// nameSamples) and name.txt files.
+func writeCorpus(t *testing.T, expected map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, text := range expected {
+		base := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(base), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := audio.WriteWAV(base+".wav", nameSamples(name), SampleRate); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(base+".txt", []byte(text+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadCorpus(t *testing.T) {
+	dir := writeCorpus(t, map[string]string{"b": "二", "a": "一", "sub/c": "三"})
+	if err := os.WriteFile(filepath.Join(dir, "orphan.wav"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cases, err := LoadCorpus(dir)
+	if err != nil {
+		t.Fatalf("LoadCorpus() error: %v", err)
+	}
+	var names []string
+	for _, c := range cases {
+		names = append(names, c.Name+"="+c.Expected)
+	}
+	if got := strings.Join(names, ","); got != "a=一,b=二,sub/c=三" {
+		t.Errorf("cases = %s, want sorted cases with trimmed text and no orphan", got)
+	}
+
+	if _, err := LoadCorpus(t.TempDir()); err == nil {
+		t.Error("LoadCorpus(empty dir) should fail")
+	}
+}
+
+// mapBackend transcribes audio whose samples spell a case name to
+// texts[name].
+type mapBackend struct {
+	texts map[string]string
+	calls []transcriber.Options
+	audio [][]byte
+}
+
+func (b *mapBackend) Transcribe(_ context.Context, data []byte, opts transcriber.Options) (string, error) {
+	b.calls = append(b.calls, opts)
+	b.audio = append(b.audio, data)
+	name, err := sampleName(data)
+	if err != nil {
+		return "", err
+	}
+	text, ok := b.texts[name]
+	if !ok {
+		return "", errors.New("boom")
+	}
+	return text, nil
+}
+func (b *mapBackend) Name() string      { return "map" }
+func (b *mapBackend) ModelName() string { return "map-1" }
+func (b *mapBackend) Close() error      { return nil }
+
+func TestRun(t *testing.T) {
+	dir := writeCorpus(t, map[string]string{"exact": "Dockerでデプロイ", "dict": "Claude Codeで直す", "fail": "abcd"})
+	cases, err := LoadCorpus(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &mapBackend{texts: map[string]string{"exact": "Docker でデプロイ", "dict": "クロードコードでなおす"}}
+	replacer := prompt.NewReplacer([]prompt.Entry{{Reading: "クロードコード", Notation: "Claude Code", Context: prompt.DefaultContext}})
+
+	r := Run(context.Background(), b, replacer, cases, Variant{Label: "A", Mode: "commit"})
+
+	if r.Backend != "map" || r.Model != "map-1" || r.Mode != "commit" {
+		t.Errorf("report header = %s/%s/%s", r.Backend, r.Model, r.Mode)
+	}
+	if b.calls[0].Mode != "commit" || b.calls[0].Model != "" {
+		t.Errorf("request options = %+v, want the variant's mode and no pinned model", b.calls[0])
+	}
+	byName := make(map[string]Result)
+	for _, c := range r.Cases {
+		byName[c.Name] = c
+	}
+	if c := byName["dict"]; c.Got != "Claude Codeでなおす" || c.Edits != 2 {
+		t.Errorf("dict case = %+v, want the dictionary applied and 2 edits", c)
+	}
+	if c := byName["exact"]; !c.Exact || c.CER != 0 {
+		t.Errorf("exact case = %+v", c)
+	}
+	if c := byName["fail"]; c.Error == "" || c.CER != 1 || c.Exact {
+		t.Errorf("failed case = %+v, want an error scored as CER 1", c)
+	}
+	// 2 + 0 + 4 edits over 13 + 11 + 4 characters.
+	if r.Exact != 1 || r.Failed != 1 || math.Abs(r.CER-6.0/28) > 1e-9 || math.Abs(r.ExactMatch-1.0/3) > 1e-9 {
+		t.Errorf("summary = CER %v, exact %d (%v), failed %d", r.CER, r.Exact, r.ExactMatch, r.Failed)
+	}
+}
+
+func TestRunNormalizesAudio(t *testing.T) {
+	dir := t.TempDir()
+	// One second of 48 kHz stereo, 3 bytes per sample.
+	w := &audio.WAV{SampleRate: 48000, Channels: 2, BitsPerSample: 24, Samples: make([]float32, 2*48000)}
+	if err := os.WriteFile(filepath.Join(dir, "stereo.wav"), encode24(w), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "stereo.txt"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cases, err := LoadCorpus(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := &mapBackend{}
+	Run(context.Background(), b, nil, cases, Variant{})
+	got, err := audio.DecodeWAV(b.audio[0])
+	if err != nil {
+		t.Fatalf("backend received undecodable audio: %v", err)
+	}
+	if got.SampleRate != SampleRate || got.Channels != 1 || got.BitsPerSample != 16 || len(got.Samples) != SampleRate {
+		t.Errorf("uploaded WAV = %d Hz, %d ch, %d bit, %d samples; want 16 kHz mono 16-bit, 1s", got.SampleRate, got.Channels, got.BitsPerSample, len(got.Samples))
+	}
+	if b.calls[0].MIMEType != "audio/wav" {
+		t.Errorf("MIMEType = %q, want audio/wav", b.calls[0].MIMEType)
+	}
+}
+
+// encode24 writes w as a 24-bit PCM WAV file.
+func encode24(w *audio.WAV) []byte {
+	var pcm bytes.Buffer
+	for _, s := range w.Samples {
+		v := int32(s * (1 << 23))
+		pcm.Write([]byte{byte(v), byte(v >> 8), byte(v >> 16)})
+	}
+	var buf bytes.Buffer
+	le := func(v any) { binary.Write(&buf, binary.LittleEndian, v) }
+	buf.WriteString("RIFF")
+	le(uint32(36 + pcm.Len()))
+	buf.WriteString("WAVEfmt ")
+	le(uint32(16))
+	le(uint16(1))
+	le(uint16(w.Channels))
+	le(uint32(w.SampleRate))
+	le(uint32(w.SampleRate * w.Channels * 3))
+	le(uint16(w.Channels * 3))
+	le(uint16(24))
+	buf.WriteString("data")
+	le(uint32(pcm.Len()))
+	buf.Write(pcm.Bytes())
+	return buf.Bytes()
+}
+
+func TestRecordAndReplayFixtures(t *testing.T) {
+	dir := writeCorpus(t, map[string]string{"a": "一", "b": "二"})
+	cases, err := LoadCorpus(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	live := &mapBackend{texts: map[string]string{"a": "一", "b": "ニ"}}
+	rec := NewRecorder(live, nil)
+	want := Run(context.Background(), rec, nil, cases, Variant{Label: "live", Model: "m1"})
+	path := filepath.Join(t.TempDir(), "fixtures.json")
+	if err := rec.Save(path); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	fixtures, err := LoadFixtures(path)
+	if err != nil {
+		t.Fatalf("LoadFixtures() error: %v", err)
+	}
+	if len(fixtures) != 2 {
+		t.Fatalf("fixtures = %+v, want 2", fixtures)
+	}
+	replay := NewFixtureBackend(fixtures)
+	got := Run(context.Background(), replay, nil, cases, Variant{Label: "replay", Model: "m1"})
+	if got.CER != want.CER || got.Exact != want.Exact || got.Failed != 0 {
+		t.Errorf("replayed report = %+v, want the recorded results %+v", got, want)
+	}
+
+	other := Run(context.Background(), replay, nil, cases, Variant{Label: "other", Model: "m2"})
+	if other.Failed != 2 || !strings.Contains(other.Cases[0].Error, ErrNoFixture.Error()) {
+		t.Errorf("unrecorded model = %+v, want ErrNoFixture for every case", other.Cases)
+	}
+}
+
+func TestWriteTextComparison(t *testing.T) {
+	a := Report{Variant: "A", Backend: "gemini", Model: "m1", CER: 0.1, ExactMatch: 0.5, Exact: 1, Cases: []Result{
+		{Name: "one", Expected: "getState", Got: "get state", Exact: true},
+		{Name: "two", Expected: "abcd", Got: "abxd", CER: 0.25},
+	}}
+	b := a
+	b.Variant, b.CER, b.ExactMatch, b.Exact = "B", 0, 1, 2
+	b.Cases = []Result{a.Cases[0], {Name: "two", Expected: "abcd", Got: "abcd", Exact: true}}
+
+	var buf bytes.Buffer
+	if err := WriteText(&buf, []Report{a, b}); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"== A (gemini, model m1)",
+		`"abcd" -> "abxd"`,
+		"2 cases: CER 10.0%, exact match 50.0% (1/2), 0 failed",
+		"0.0% (-25.0)",
+		"100.0% (+50.0)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package eval
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/noricha-vr/voicecode/internal/core/transcriber"
+)
+
+// ErrNoFixture is returned by a FixtureBackend for a request it has no
+// recording of.
+var ErrNoFixture = errors.New("no recorded transcription")
+
+// Fixture is a recorded transcription, keyed by the audio and the request's
+// model and mode.
+type Fixture struct {
+	AudioSHA256 string `json:"audio_sha256"`
+	Model       string `json:"model,omitempty"`
+	Mode        string `json:"mode,omitempty"`
+	Text        string `json:"text"`
+}
+
+type fixtureKey struct{ audio, model, mode string }
+
+func keyOf(audio []byte, opts transcriber.Options) fixtureKey {
+	sum := sha256.Sum256(audio)
+	return fixtureKey{hex.EncodeToString(sum[:]), opts.Model, opts.Mode}
+}
+
+type fixtureFile struct {
+	Fixtures []Fixture `json:"fixtures"`
+}
+
+// LoadFixtures reads a fixture file written by Recorder.Save.
+func LoadFixtures(path string) ([]Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading fixtures: %w", err)
+	}
+	var f fixtureFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parsing fixtures %s: %w", path, err)
+	}
+	return f.Fixtures, nil
+}
+
+// FixtureBackend replays recorded transcriptions without network access, so
+// an eval corpus can run offline and in CI.
+type FixtureBackend struct {
+	texts map[fixtureKey]string
+}
+
+// NewFixtureBackend returns a backend that answers from fixtures.
+func NewFixtureBackend(fixtures []Fixture) *FixtureBackend {
+	b := &FixtureBackend{texts: make(map[fixtureKey]string, len(fixtures))}
+	for _, f := range fixtures {
+		b.texts[fixtureKey{f.AudioSHA256, f.Model, f.Mode}] = f.Text
+	}
+	return b
+}
+
+// Transcribe returns the recorded text for the audio, model and mode, or
+// ErrNoFixture.
+func (b *FixtureBackend) Transcribe(_ context.Context, audio []byte, opts transcriber.Options) (string, error) {
+	k := keyOf(audio, opts)
+	text, ok := b.texts[k]
+	if !ok {
+		return "", fmt.Errorf("%w for audio %.12s (model %q, mode %q)", ErrNoFixture, k.audio, k.model, k.mode)
+	}
+	return text, nil
+}
+
+func (b *FixtureBackend) Name() string      { return "fixture" }
+func (b *FixtureBackend) ModelName() string { return "fixture" }
+func (b *FixtureBackend) Close() error      { return nil }
+
+// Recorder wraps a backend and records its successful transcriptions as
+// fixtures.
+type Recorder struct {
+	transcriber.Backend
+
+	mu       sync.Mutex
+	fixtures []Fixture
+	index    map[fixtureKey]int
+}
+
+// NewRecorder returns a Recorder that starts from existing fixtures (which
+// may be nil); recordings replace existing fixtures with the same key.
+func NewRecorder(b transcriber.Backend, existing []Fixture) *Recorder {
+	r := &Recorder{Backend: b, index: make(map[fixtureKey]int)}
+	for _, f := range existing {
+		r.add(fixtureKey{f.AudioSHA256, f.Model, f.Mode}, f.Text)
+	}
+	return r
+}
+
+// Transcribe delegates to the wrapped backend and records the result.
+func (r *Recorder) Transcribe(ctx context.Context, audio []byte, opts transcriber.Options) (string, error) {
+	text, err := r.Backend.Transcribe(ctx, audio, opts)
+	if err != nil {
+		return "", err
+	}
+	r.mu.Lock()
+	r.add(keyOf(audio, opts), text)
+	r.mu.Unlock()
+	return text, nil
+}
+
+func (r *Recorder) add(k fixtureKey, text string) {
+	if i, ok := r.index[k]; ok {
+		r.fixtures[i].Text = text
+		return
+	}
+	r.index[k] = len(r.fixtures)
+	r.fixtures = append(r.fixtures, Fixture{AudioSHA256: k.audio, Model: k.model, Mode: k.mode, Text: text})
+}
+
+// Save writes the fixtures to path.
+func (r *Recorder) Save(path string) error {
+	r.mu.Lock()
+	data, err := json.MarshalIndent(fixtureFile{Fixtures: r.fixtures}, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("writing fixtures: %w", err)
+	}
+	return nil
+}
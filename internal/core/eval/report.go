@@ -0,0 +1,100 @@
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/noricha-vr/voicecode/internal/core/prompt"
+)
+
+// WriteText writes each report's per-case results, with the edits of cases
+// that did not match exactly, and its summary. With more than one report it
+// ends with a side-by-side comparison against the first.
+func WriteText(w io.Writer, reports []Report) error {
+	for i, r := range reports {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "== %s (%s, model %s", r.Variant, r.Backend, r.Model)
+		if r.Mode != "" {
+			fmt.Fprintf(w, ", mode %s", r.Mode)
+		}
+		fmt.Fprintln(w, ")")
+		for _, c := range r.Cases {
+			mark := "ok  "
+			if !c.Exact {
+				mark = "FAIL"
+			}
+			fmt.Fprintf(w, "%s %s  CER %s\n", mark, c.Name, percent(c.CER))
+			if c.Exact {
+				continue
+			}
+			if c.Error != "" {
+				fmt.Fprintf(w, "     error: %s\n", c.Error)
+				continue
+			}
+			for _, e := range prompt.DiffEdits(Normalize(c.Expected), Normalize(c.Got)) {
+				fmt.Fprintf(w, "     %q -> %q\n", e.From, e.To)
+			}
+		}
+		fmt.Fprintf(w, "%d cases: CER %s, exact match %s (%d/%d), %d failed\n",
+			len(r.Cases), percent(r.CER), percent(r.ExactMatch), r.Exact, len(r.Cases), r.Failed)
+	}
+	if len(reports) < 2 {
+		return nil
+	}
+
+	fmt.Fprintln(w)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	header := []string{"case"}
+	for _, r := range reports {
+		header = append(header, r.Variant)
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	base := reports[0]
+	for i, c := range base.Cases {
+		row := []string{c.Name, percent(c.CER)}
+		for _, r := range reports[1:] {
+			if i >= len(r.Cases) || r.Cases[i].Name != c.Name {
+				row = append(row, "-")
+				continue
+			}
+			row = append(row, percent(r.Cases[i].CER)+delta(r.Cases[i].CER-c.CER))
+		}
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	total := []string{"CER", percent(base.CER)}
+	exact := []string{"exact match", percent(base.ExactMatch)}
+	for _, r := range reports[1:] {
+		total = append(total, percent(r.CER)+delta(r.CER-base.CER))
+		exact = append(exact, percent(r.ExactMatch)+delta(r.ExactMatch-base.ExactMatch))
+	}
+	fmt.Fprintln(tw, strings.Join(total, "\t"))
+	fmt.Fprintln(tw, strings.Join(exact, "\t"))
+	return tw.Flush()
+}
+
+// WriteJSON writes the reports as {"reports": [...]}.
+func WriteJSON(w io.Writer, reports []Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(struct {
+		Reports []Report `json:"reports"`
+	}{reports})
+}
+
+func percent(f float64) string {
+	return fmt.Sprintf("%.1f%%", f*100)
+}
+
+// delta formats a change in percentage points, or "" when there is none.
+func delta(d float64) string {
+	if s := fmt.Sprintf("%+.1f", d*100); s != "+0.0" && s != "-0.0" {
+		return " (" + s + ")"
+	}
+	return ""
+}
@@ -2,20 +2,49 @@ package history
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 )
 
 // Entry represents a single transcription history record.
 type Entry struct {
+	// ID is the base filename shared by the JSON and audio files. It is not
+	// stored in the JSON.
+	ID               string  `json:"-"`
 	Timestamp        string  `json:"timestamp"`
 	RawTranscription string  `json:"raw_transcription"`
 	ProcessedText    string  `json:"processed_text"`
 	AudioFile        string  `json:"audio_file"`
 	DurationSec      float64 `json:"duration_sec"`
+	// Replacements lists the dictionary conversions applied locally to
+	// RawTranscription to produce ProcessedText.
+	Replacements []Replacement `json:"replacements,omitempty"`
+	// Mode is the transcription mode (settings.Mode) used; empty for the
+	// default prompts.
+	Mode string `json:"mode,omitempty"`
+	// Language is the configured spoken language (ja, en or auto).
+	Language string `json:"language,omitempty"`
+	// TranslatedText is ProcessedText translated into TranslatedTo; it is
+	// what was pasted when set.
+	TranslatedText string `json:"translated_text,omitempty"`
+	TranslatedTo   string `json:"translated_to,omitempty"`
+	// CorrectedText is the text as the user fixed it after pasting, recorded
+	// with Correct. Empty when the transcription was not corrected.
+	CorrectedText string `json:"corrected_text,omitempty"`
+	CorrectedAt   string `json:"corrected_at,omitempty"`
+}
+
+// Replacement is a dictionary conversion that fired on a transcription.
+type Replacement struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Count int    `json:"count"`
 }
 
 // historyDirFunc is overridable for testing.
@@ -37,26 +66,34 @@ func HistoryDir() string {
 // Save writes WAV data and JSON metadata to the history directory.
 // Returns the base filename (without extension).
 func Save(wavData []byte, raw, processed string, durationSec float64) (string, error) {
+	return SaveEntry(wavData, ".wav", Entry{
+		RawTranscription: raw,
+		ProcessedText:    processed,
+		DurationSec:      durationSec,
+	})
+}
+
+// SaveEntry writes audio data, stored with the file extension ext (e.g.
+// ".wav" or ".flac"), and the entry's JSON metadata to the history directory.
+// Timestamp and AudioFile are filled in; DurationSec is rounded to 0.1s.
+// Returns the base filename (without extension).
+func SaveEntry(audioData []byte, ext string, entry Entry) (string, error) {
 	dir := historyDirFunc()
 	if err := os.MkdirAll(dir, 0o755); err != nil {
 		return "", fmt.Errorf("creating history directory: %w", err)
 	}
 
 	baseName := time.Now().Format("2006-01-02_150405")
-	wavPath := filepath.Join(dir, baseName+".wav")
+	audioPath := filepath.Join(dir, baseName+ext)
 	jsonPath := filepath.Join(dir, baseName+".json")
 
-	if err := os.WriteFile(wavPath, wavData, 0o644); err != nil {
-		return "", fmt.Errorf("writing WAV file: %w", err)
+	if err := os.WriteFile(audioPath, audioData, 0o644); err != nil {
+		return "", fmt.Errorf("writing audio file: %w", err)
 	}
 
-	entry := Entry{
-		Timestamp:        time.Now().Format(time.RFC3339),
-		RawTranscription: raw,
-		ProcessedText:    processed,
-		AudioFile:        baseName + ".wav",
-		DurationSec:      math.Round(durationSec*10) / 10,
-	}
+	entry.Timestamp = time.Now().Format(time.RFC3339)
+	entry.AudioFile = baseName + ext
+	entry.DurationSec = math.Round(entry.DurationSec*10) / 10
 
 	data, err := json.MarshalIndent(entry, "", "  ")
 	if err != nil {
@@ -69,3 +106,109 @@ func Save(wavData []byte, raw, processed string, durationSec float64) (string, e
 
 	return baseName, nil
 }
+
+// Load reads the history entry with the given ID.
+func Load(id string) (Entry, error) {
+	if id == "" || id != filepath.Base(id) || strings.HasPrefix(id, ".") {
+		return Entry{}, fmt.Errorf("invalid history id %q", id)
+	}
+	data, err := os.ReadFile(filepath.Join(historyDirFunc(), id+".json"))
+	if err != nil {
+		return Entry{}, fmt.Errorf("reading history entry: %w", err)
+	}
+	var e Entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return Entry{}, fmt.Errorf("parsing history entry %s: %w", id, err)
+	}
+	e.ID = id
+	return e, nil
+}
+
+// List returns all history entries, oldest first. Unreadable files are
+// skipped. A missing history directory yields no entries.
+func List() ([]Entry, error) {
+	names, err := filepath.Glob(filepath.Join(historyDirFunc(), "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	var entries []Entry
+	for _, name := range names {
+		e, err := Load(strings.TrimSuffix(filepath.Base(name), ".json"))
+		if err != nil {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// Latest returns the most recent history entry.
+func Latest() (Entry, error) {
+	entries, err := List()
+	if err != nil {
+		return Entry{}, err
+	}
+	if len(entries) == 0 {
+		return Entry{}, errors.New("no history entries")
+	}
+	return entries[len(entries)-1], nil
+}
+
+// DictionaryCorrection returns the pasted text of e and the user's
+// correction of it, for learning dictionary entries. ok is false when e has
+// no correction, or when a translation was pasted: the correction then edits
+// the translation, while the dictionary applies to the source-language text
+// before translating.
+func (e Entry) DictionaryCorrection() (original, corrected string, ok bool) {
+	if e.CorrectedText == "" || e.TranslatedText != "" {
+		return "", "", false
+	}
+	// The processed text is what was pasted, so conversions the dictionary
+	// already applied are not suggested again.
+	original = e.ProcessedText
+	if original == "" {
+		original = e.RawTranscription
+	}
+	return original, e.CorrectedText, true
+}
+
+// Correct records the user-corrected text of entry id. An empty text removes
+// the correction. Only the correction fields of the JSON file are rewritten,
+// so fields written by other versions are kept.
+func Correct(id, text string) (Entry, error) {
+	e, err := Load(id)
+	if err != nil {
+		return Entry{}, err
+	}
+	e.CorrectedText = text
+	e.CorrectedAt = ""
+	if text != "" {
+		e.CorrectedAt = time.Now().Format(time.RFC3339)
+	}
+
+	path := filepath.Join(historyDirFunc(), id+".json")
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return Entry{}, fmt.Errorf("reading history entry: %w", err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return Entry{}, fmt.Errorf("parsing history entry %s: %w", id, err)
+	}
+	delete(fields, "corrected_text")
+	delete(fields, "corrected_at")
+	if text != "" {
+		fields["corrected_text"], _ = json.Marshal(e.CorrectedText)
+		fields["corrected_at"], _ = json.Marshal(e.CorrectedAt)
+	}
+
+	data, err := json.MarshalIndent(fields, "", "  ")
+	if err != nil {
+		return Entry{}, fmt.Errorf("marshaling entry: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return Entry{}, fmt.Errorf("writing JSON file: %w", err)
+	}
+	return e, nil
+}
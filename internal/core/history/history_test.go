@@ -146,3 +146,168 @@ func TestHistoryDir(t *testing.T) {
 		t.Errorf("HistoryDir() = %q, expected to contain .voicecoding", dir)
 	}
 }
+
+func TestSaveRecordsReplacements(t *testing.T) {
+	dir := withTempHistoryDir(t)
+
+	replacements := []Replacement{{From: "クバネティス", To: "Kubernetes", Count: 2}}
+	baseName, err := SaveEntry([]byte("test"), ".wav", Entry{RawTranscription: "クバネティスとクバネティス", ProcessedText: "KubernetesとKubernetes", DurationSec: 1.0, Replacements: replacements})
+	if err != nil {
+		t.Fatalf("SaveEntry() error: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, baseName+".json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		t.Fatalf("JSON parse error: %v", err)
+	}
+	if len(entry.Replacements) != 1 || entry.Replacements[0] != replacements[0] {
+		t.Errorf("Replacements = %+v, want %+v", entry.Replacements, replacements)
+	}
+
+	// Entries without replacements omit the field.
+	baseName, err = SaveEntry([]byte("test"), ".wav", Entry{RawTranscription: "raw", ProcessedText: "raw", DurationSec: 1.0})
+	if err != nil {
+		t.Fatalf("SaveEntry() error: %v", err)
+	}
+	data, _ = os.ReadFile(filepath.Join(dir, baseName+".json"))
+	if strings.Contains(string(data), "replacements") {
+		t.Errorf("JSON = %s, want no replacements field", data)
+	}
+}
+
+func TestSaveEntryRecordsOptionalFields(t *testing.T) {
+	withTempHistoryDir(t)
+
+	id, err := SaveEntry([]byte("fLaC"), ".flac", Entry{
+		RawTranscription: "raw",
+		ProcessedText:    "fix: typo",
+		DurationSec:      1.26,
+		Mode:             "commit",
+		TranslatedText:   "fix: typo",
+		TranslatedTo:     "en",
+	})
+	if err != nil {
+		t.Fatalf("SaveEntry() error: %v", err)
+	}
+	e, err := Load(id)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if e.Mode != "commit" || e.TranslatedTo != "en" || e.DurationSec != 1.3 || e.AudioFile != id+".flac" || e.Timestamp == "" {
+		t.Errorf("entry = %+v, want mode, translation, rounded duration, audio file and timestamp", e)
+	}
+	if data, err := os.ReadFile(filepath.Join(HistoryDir(), e.AudioFile)); err != nil || string(data) != "fLaC" {
+		t.Errorf("audio file = %q, %v; want the saved audio", data, err)
+	}
+}
+
+func TestDictionaryCorrection(t *testing.T) {
+	tests := []struct {
+		name         string
+		entry        Entry
+		wantOriginal string
+		wantOK       bool
+	}{
+		{"not corrected", Entry{ProcessedText: "a"}, "", false},
+		{"processed", Entry{RawTranscription: "raw", ProcessedText: "processed", CorrectedText: "fixed"}, "processed", true},
+		{"raw only", Entry{RawTranscription: "raw", CorrectedText: "fixed"}, "raw", true},
+		{
+			"translation pasted",
+			Entry{ProcessedText: "パーサーのテスト", TranslatedText: "Parser tests", TranslatedTo: "en", CorrectedText: "Parser unit tests"},
+			"", false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original, corrected, ok := tt.entry.DictionaryCorrection()
+			if ok != tt.wantOK || original != tt.wantOriginal {
+				t.Errorf("DictionaryCorrection() = %q, %q, %v; want %q, %v", original, corrected, ok, tt.wantOriginal, tt.wantOK)
+			}
+			if ok && corrected != tt.entry.CorrectedText {
+				t.Errorf("corrected = %q, want %q", corrected, tt.entry.CorrectedText)
+			}
+		})
+	}
+}
+
+func writeEntry(t *testing.T, dir, id string, e Entry) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(e)
+	if err := os.WriteFile(filepath.Join(dir, id+".json"), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestListAndLatest(t *testing.T) {
+	dir := withTempHistoryDir(t)
+	if entries, err := List(); err != nil || len(entries) != 0 {
+		t.Fatalf("List() = %v, %v; want none without a history directory", entries, err)
+	}
+
+	writeEntry(t, dir, "2026-01-02_100000", Entry{ProcessedText: "second"})
+	writeEntry(t, dir, "2026-01-01_100000", Entry{ProcessedText: "first"})
+	if err := os.WriteFile(filepath.Join(dir, "2026-01-03_100000.json"), []byte("{broken"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := List()
+	if err != nil {
+		t.Fatalf("List() error: %v", err)
+	}
+	if len(entries) != 2 || entries[0].ID != "2026-01-01_100000" || entries[1].ProcessedText != "second" {
+		t.Errorf("List() = %+v, want first and second in order", entries)
+	}
+	latest, err := Latest()
+	if err != nil || latest.ID != "2026-01-02_100000" {
+		t.Errorf("Latest() = %+v, %v", latest, err)
+	}
+}
+
+func TestCorrect(t *testing.T) {
+	dir := withTempHistoryDir(t)
+	writeEntry(t, dir, "2026-01-01_100000", Entry{RawTranscription: "クバネティス", ProcessedText: "クバネティス"})
+
+	if _, err := Correct("2026-01-01_100000", "Kubernetes"); err != nil {
+		t.Fatalf("Correct() error: %v", err)
+	}
+	e, err := Load("2026-01-01_100000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.CorrectedText != "Kubernetes" || e.CorrectedAt == "" || e.RawTranscription != "クバネティス" {
+		t.Errorf("entry = %+v, want correction recorded and raw text kept", e)
+	}
+
+	if _, err := Correct("2026-01-01_100000", ""); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "2026-01-01_100000.json"))
+	if strings.Contains(string(data), "corrected") || strings.Contains(string(data), `"ID"`) {
+		t.Errorf("JSON = %s, want correction removed and no ID field", data)
+	}
+
+	// Fields this version does not know about survive a correction.
+	if err := os.WriteFile(filepath.Join(dir, "2026-01-02_100000.json"), []byte(`{"processed_text": "a", "speaker": "me"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Correct("2026-01-02_100000", "b"); err != nil {
+		t.Fatal(err)
+	}
+	data, _ = os.ReadFile(filepath.Join(dir, "2026-01-02_100000.json"))
+	if !strings.Contains(string(data), `"speaker": "me"`) || !strings.Contains(string(data), `"corrected_text": "b"`) {
+		t.Errorf("JSON = %s, want the unknown field kept next to the correction", data)
+	}
+
+	for _, id := range []string{"missing", "../settings", ""} {
+		if _, err := Correct(id, "x"); err == nil {
+			t.Errorf("Correct(%q) should fail", id)
+		}
+	}
+}
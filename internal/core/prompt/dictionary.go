@@ -2,11 +2,17 @@ package prompt
 
 import (
 	"bufio"
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"html"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 )
 
@@ -19,50 +25,206 @@ func DictionaryPath() string {
 	return filepath.Join(home, ".voicecoding", "dictionary.txt")
 }
 
-// ParseDictionary reads the dictionary file and returns conversion XML and hint XML.
-// Format:
-//   - Lines with TAB: conversion entries (japanese\tenglish)
-//   - Lines without TAB: hint words
+// DefaultContext is the context of entries that apply regardless of topic.
+const DefaultContext = "always"
+
+// maxIncludeDepth bounds nested #include directives.
+const maxIncludeDepth = 8
+
+// Entry is a single dictionary entry.
+type Entry struct {
+	// Reading is the spoken form (or, for regex entries, the pattern source).
+	// For hint words it is the word itself.
+	Reading string
+	// Notation is the written form; empty for hint words.
+	Notation string
+	// Pattern is set for regex entries, written as /pattern/ in the file.
+	Pattern *regexp.Regexp
+	// Context limits a conversion to a topic such as "programming";
+	// DefaultContext applies everywhere.
+	Context  string
+	Priority int
+	Note     string
+	// Ignored lists extra columns that are not known options. They are
+	// skipped so older dictionaries keep loading; dict lint warns about them.
+	Ignored []string
+	// Source is "path:line" of the entry.
+	Source string
+}
+
+// IsHint reports whether e is a hint word rather than a conversion.
+func (e Entry) IsHint() bool {
+	return e.Notation == ""
+}
+
+// LineError is a malformed dictionary line. The rest of the file is still used.
+type LineError struct {
+	Path string
+	Line int
+	Err  error
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("%s:%d: %v", e.Path, e.Line, e.Err)
+}
+
+func (e *LineError) Unwrap() error { return e.Err }
+
+// ParseDictionary reads the dictionary file and returns its entries.
+// Format (one entry per line, columns separated by TAB):
+//   - reading<TAB>notation: conversion entry
+//   - /regex/<TAB>notation: conversion of anything matching regex
+//   - word: hint word (also word<TAB><TAB>options)
+//   - further columns are key=value options: context=programming,
+//     priority=10, note=free text; other columns are ignored
+//     (Entry.Ignored)
+//   - #include path: entries of another file (relative to this file)
 //   - Lines starting with #: comments (skipped)
 //   - Empty lines: skipped
 //
-// If file doesn't exist, returns empty strings (no error).
-func ParseDictionary(path string) (conversionXML, hintXML string, err error) {
-	f, err := os.Open(path)
+// If file doesn't exist, returns no entries (no error). Malformed lines are
+// skipped and reported as *LineError values joined into the returned error,
+// alongside the valid entries.
+func ParseDictionary(path string) ([]Entry, error) {
+	p, err := parseDictionary(path)
+	if err != nil {
+		return nil, err
+	}
+	return p.entries, errors.Join(p.problems...)
+}
+
+func parseDictionary(path string) (*dictionaryParser, error) {
+	p := &dictionaryParser{visiting: make(map[string]bool)}
+	if err := p.parseFile(path, 0); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Dictionary is a user dictionary rendered for the system prompt.
+type Dictionary struct {
+	ConversionXML string
+	HintXML       string
+	Conversions   int // number of conversion entries
+	Hints         int // number of hint words
+	Entries       []Entry
+	// Warnings lists malformed lines that were skipped.
+	Warnings []error
+	// Files lists the dictionary files that were read, including #include
+	// targets, so callers can watch them for changes.
+	Files []string
+	// Profile is the overlay profile merged into the base dictionary, if any.
+	Profile string
+}
+
+// LoadDictionary reads the dictionary file at path.
+// If file doesn't exist, returns an empty Dictionary (no error). Malformed
+// lines do not fail the load; they are reported in Dictionary.Warnings.
+func LoadDictionary(path string) (Dictionary, error) {
+	p, err := parseDictionary(path)
 	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			return "", "", nil
+		return Dictionary{}, err
+	}
+	d := newDictionary(p.entries)
+	d.Warnings = p.problems
+	d.Files = p.files
+	return d, nil
+}
+
+// newDictionary renders entries and counts them.
+func newDictionary(entries []Entry) Dictionary {
+	d := Dictionary{Entries: entries}
+	d.ConversionXML, d.HintXML = dictionaryXML(entries)
+	for _, e := range entries {
+		if e.IsHint() {
+			d.Hints++
+		} else {
+			d.Conversions++
 		}
-		return "", "", fmt.Errorf("opening dictionary: %w", err)
 	}
-	defer f.Close()
+	return d
+}
 
-	var conversions []string
-	var hints []string
+// Replacer returns a Replacer for the conversion entries.
+func (d Dictionary) Replacer() *Replacer {
+	return NewReplacer(d.Entries)
+}
 
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
+// Terms returns the total number of dictionary entries.
+func (d Dictionary) Terms() int {
+	return d.Conversions + d.Hints
+}
+
+// Hash returns a short stable fingerprint of the rendered dictionary.
+// It is "none" for an empty dictionary.
+func (d Dictionary) Hash() string {
+	if d.ConversionXML == "" && d.HintXML == "" {
+		return "none"
+	}
+	sum := sha256.Sum256([]byte(d.ConversionXML + "\x00" + d.HintXML))
+	return hex.EncodeToString(sum[:4])
+}
+
+// ComposeSystemPrompt returns SystemPrompt with the user dictionary inserted
+// before the final guard. An empty dictionary returns SystemPrompt unchanged.
+func ComposeSystemPrompt(d Dictionary) string {
+	return ComposePrompt(SystemPrompt, d)
+}
+
+// ComposePrompt inserts the user dictionary into a system prompt: before its
+// <final_guard>, else before its closing </instructions>, else at the end.
+// Custom mode prompts use the same dictionary block as SystemPrompt.
+func ComposePrompt(base string, d Dictionary) string {
+	var parts []string
+	for _, xml := range []string{d.ConversionXML, d.HintXML} {
+		if xml != "" {
+			parts = append(parts, xml)
 		}
+	}
+	if len(parts) == 0 {
+		return base
+	}
+
+	block := fmt.Sprintf(
+		"<user_dictionary>\n<note>ユーザーが登録した用語です。context=\"always\" の変換エントリは文脈に関係なく常に english の表記で出力してください。それ以外の context（例: programming）のエントリは、その文脈と判断できる場合のみ変換してください。pattern 属性のエントリは正規表現にマッチする表現を english の表記にします。priority が高いエントリほど優先し、note は補足説明です。</note>\n%s\n</user_dictionary>\n\n",
+		strings.Join(parts, "\n"),
+	)
+	const anchor = "<final_guard>"
+	if i := strings.Index(base, anchor); i >= 0 {
+		return base[:i] + block + base[i:]
+	}
+	const closing = "</instructions>"
+	if i := strings.LastIndex(base, closing); i >= 0 {
+		return base[:i] + block + base[i:]
+	}
+	return strings.TrimRight(base, "\n") + "\n\n" + strings.TrimRight(block, "\n")
+}
+
+func dictionaryXML(entries []Entry) (conversionXML, hintXML string) {
+	sorted := slices.Clone(entries)
+	slices.SortStableFunc(sorted, func(a, b Entry) int { return cmp.Compare(b.Priority, a.Priority) })
 
-		if parts := strings.SplitN(line, "\t", 2); len(parts) == 2 {
-			japanese := strings.TrimSpace(parts[0])
-			english := strings.TrimSpace(parts[1])
-			if japanese != "" && english != "" {
-				conversions = append(conversions, fmt.Sprintf(
-					`<term japanese="%s" english="%s" context="always"/>`,
-					html.EscapeString(japanese),
-					html.EscapeString(english),
-				))
+	var conversions, hints, hintNotes []string
+	for _, e := range sorted {
+		if e.IsHint() {
+			hints = append(hints, e.Reading)
+			if e.Note != "" {
+				hintNotes = append(hintNotes, fmt.Sprintf(`<term hint="%s" note="%s"/>`, html.EscapeString(e.Reading), html.EscapeString(e.Note)))
 			}
-		} else {
-			hints = append(hints, line)
+			continue
 		}
-	}
-	if err := scanner.Err(); err != nil {
-		return "", "", fmt.Errorf("reading dictionary: %w", err)
+		source := fmt.Sprintf(`japanese="%s"`, html.EscapeString(e.Reading))
+		if e.Pattern != nil {
+			source = fmt.Sprintf(`pattern="%s"`, html.EscapeString(e.Pattern.String()))
+		}
+		attrs := fmt.Sprintf(`%s english="%s" context="%s"`, source, html.EscapeString(e.Notation), html.EscapeString(e.Context))
+		if e.Priority != 0 {
+			attrs += fmt.Sprintf(` priority="%d"`, e.Priority)
+		}
+		if e.Note != "" {
+			attrs += fmt.Sprintf(` note="%s"`, html.EscapeString(e.Note))
+		}
+		conversions = append(conversions, "<term "+attrs+"/>")
 	}
 
 	if len(conversions) > 0 {
@@ -73,11 +235,229 @@ func ParseDictionary(path string) (conversionXML, hintXML string, err error) {
 	}
 
 	if len(hints) > 0 {
+		notes := ""
+		if len(hintNotes) > 0 {
+			notes = "\n" + strings.Join(hintNotes, "\n")
+		}
 		hintXML = fmt.Sprintf(
-			"<category name=\"ユーザー辞書（ヒント）\" type=\"hint\">\n<hint>%s</hint>\n<note>これらの単語はプログラミング文脈で頻繁に使用されます。音声認識結果にこれらの単語が含まれる可能性が高い場合、優先的に採用してください。</note>\n</category>",
+			"<category name=\"ユーザー辞書（ヒント）\" type=\"hint\">\n<hint>%s</hint>%s\n<note>これらの単語はプログラミング文脈で頻繁に使用されます。音声認識結果にこれらの単語が含まれる可能性が高い場合、優先的に採用してください。</note>\n</category>",
 			html.EscapeString(strings.Join(hints, ", ")),
+			notes,
 		)
 	}
+	return conversionXML, hintXML
+}
+
+// Vocabulary reads the dictionary file and returns the English forms of
+// conversion entries followed by hint words, de-duplicated and comma-separated.
+// It is meant for Whisper-style "prompt" fields that only accept plain text.
+//
+// If file doesn't exist, returns an empty string (no error).
+func Vocabulary(path string) (string, error) {
+	d, err := LoadDictionary(path)
+	if err != nil {
+		return "", err
+	}
+	return d.Vocabulary(), nil
+}
+
+// Vocabulary returns the English forms of conversion entries followed by
+// hint words, de-duplicated and comma-separated.
+func (d Dictionary) Vocabulary() string {
+	words, _ := d.vocabularyWords()
+	return strings.Join(words, ", ")
+}
+
+// VocabularyWithin is Vocabulary cut down to at most maxTokens as estimated by
+// EstimateTokens. Words of higher-priority entries are kept first, and the
+// kept words stay in Vocabulary order. dropped is the number of words left
+// out.
+func (d Dictionary) VocabularyWithin(maxTokens int) (vocabulary string, dropped int) {
+	words, priorities := d.vocabularyWords()
+	if all := strings.Join(words, ", "); EstimateTokens(all) <= maxTokens {
+		return all, 0
+	}
 
-	return conversionXML, hintXML, nil
+	order := make([]int, len(words))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int { return cmp.Compare(priorities[b], priorities[a]) })
+
+	keep := make([]bool, len(words))
+	used := 0
+	for _, i := range order {
+		cost := EstimateTokens(words[i] + ", ")
+		if used+cost > maxTokens {
+			break
+		}
+		used += cost
+		keep[i] = true
+	}
+	var kept []string
+	for i, w := range words {
+		if keep[i] {
+			kept = append(kept, w)
+		}
+	}
+	return strings.Join(kept, ", "), len(words) - len(kept)
+}
+
+// vocabularyWords returns the words of Vocabulary with the highest priority
+// of the entries that produced each.
+func (d Dictionary) vocabularyWords() (words []string, priorities []int) {
+	index := make(map[string]int)
+	add := func(w string, priority int) {
+		if w == "" {
+			return
+		}
+		if i, ok := index[w]; ok {
+			priorities[i] = max(priorities[i], priority)
+			return
+		}
+		index[w] = len(words)
+		words = append(words, w)
+		priorities = append(priorities, priority)
+	}
+	for _, e := range d.Entries {
+		add(e.Notation, e.Priority)
+	}
+	for _, e := range d.Entries {
+		if e.IsHint() {
+			add(e.Reading, e.Priority)
+		}
+	}
+	return words, priorities
+}
+
+type dictionaryParser struct {
+	entries  []Entry
+	problems []error
+	files    []string
+	visiting map[string]bool // files on the current #include chain
+}
+
+func (p *dictionaryParser) parseFile(path string, depth int) error {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) && depth == 0 {
+			return nil
+		}
+		return fmt.Errorf("opening dictionary: %w", err)
+	}
+	defer f.Close()
+	p.files = append(p.files, path)
+
+	abs, _ := filepath.Abs(path)
+	p.visiting[abs] = true
+	defer delete(p.visiting, abs)
+
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if target, ok := strings.CutPrefix(line, "#include"); ok && (target == "" || target[0] == ' ' || target[0] == '\t') {
+			if err := p.include(path, strings.Trim(strings.TrimSpace(target), `"`), depth); err != nil {
+				p.problems = append(p.problems, &LineError{Path: path, Line: lineNo, Err: err})
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		e, err := parseEntry(line)
+		if err != nil {
+			p.problems = append(p.problems, &LineError{Path: path, Line: lineNo, Err: err})
+			continue
+		}
+		e.Source = fmt.Sprintf("%s:%d", path, lineNo)
+		p.entries = append(p.entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("reading dictionary: %w", err)
+	}
+	return nil
+}
+
+func (p *dictionaryParser) include(from, target string, depth int) error {
+	if target == "" {
+		return errors.New("#include needs a path")
+	}
+	if rest, ok := strings.CutPrefix(target, "~/"); ok {
+		home, _ := os.UserHomeDir()
+		target = filepath.Join(home, rest)
+	} else if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(from), target)
+	}
+	abs, _ := filepath.Abs(target)
+	switch {
+	case p.visiting[abs]:
+		return fmt.Errorf("#include cycle: %s", target)
+	case depth+1 > maxIncludeDepth:
+		return fmt.Errorf("#include nested deeper than %d", maxIncludeDepth)
+	}
+	if _, err := os.Stat(target); err != nil {
+		return fmt.Errorf("#include %s: %w", target, err)
+	}
+	return p.parseFile(target, depth+1)
+}
+
+// parseEntry parses one non-comment line.
+func parseEntry(line string) (Entry, error) {
+	cols := strings.Split(line, "\t")
+	for i := range cols {
+		cols[i] = strings.TrimSpace(cols[i])
+	}
+	e := Entry{Reading: cols[0], Context: DefaultContext}
+	if len(cols) > 1 {
+		e.Notation = cols[1]
+	}
+	if e.Reading == "" {
+		return Entry{}, errors.New("empty reading")
+	}
+
+	for _, opt := range cols[min(2, len(cols)):] {
+		if opt == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(opt, "=")
+		if !ok {
+			e.Ignored = append(e.Ignored, opt)
+			continue
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		switch key {
+		case "context":
+			if value == "" || strings.ContainsAny(value, " \"<>&") {
+				return Entry{}, fmt.Errorf("invalid context %q", value)
+			}
+			e.Context = value
+		case "priority":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return Entry{}, fmt.Errorf("invalid priority %q", value)
+			}
+			e.Priority = n
+		case "note":
+			e.Note = value
+		default:
+			e.Ignored = append(e.Ignored, opt)
+		}
+	}
+
+	// A single column like /api/ is a hint word, not a regex.
+	if !e.IsHint() && len(e.Reading) >= 2 && strings.HasPrefix(e.Reading, "/") && strings.HasSuffix(e.Reading, "/") {
+		re, err := regexp.Compile(e.Reading[1 : len(e.Reading)-1])
+		if err != nil {
+			return Entry{}, fmt.Errorf("invalid regex: %w", err)
+		}
+		e.Reading = re.String()
+		e.Pattern = re
+	}
+	return e, nil
 }
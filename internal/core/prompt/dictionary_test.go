@@ -1,18 +1,196 @@
 package prompt
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 )
 
+// loadXML loads path and returns the rendered conversion and hint XML.
+func loadXML(path string) (conversionXML, hintXML string, err error) {
+	d, err := LoadDictionary(path)
+	return d.ConversionXML, d.HintXML, err
+}
+
+func writeDict(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+	return path
+}
+
+func TestParseDictionaryEntries(t *testing.T) {
+	dir := t.TempDir()
+	path := writeDict(t, dir, "dict.txt", strings.Join([]string{
+		"リアクト\tReact",
+		"ノード\tNode.js\tcontext=programming\tpriority=3\tnote=JavaScript ランタイム",
+		"/(?i)next\\s*js/\tNext.js",
+		"supabase",
+		"vercel\t\tnote=ホスティング",
+	}, "\n"))
+
+	entries, err := ParseDictionary(path)
+	if err != nil {
+		t.Fatalf("ParseDictionary() error: %v", err)
+	}
+	if len(entries) != 5 {
+		t.Fatalf("entries = %d, want 5", len(entries))
+	}
+
+	react := entries[0]
+	if react.Reading != "リアクト" || react.Notation != "React" || react.Context != DefaultContext || react.IsHint() {
+		t.Errorf("entry 0 = %+v", react)
+	}
+	if react.Source != path+":1" {
+		t.Errorf("Source = %q, want %q", react.Source, path+":1")
+	}
+	node := entries[1]
+	if node.Context != "programming" || node.Priority != 3 || node.Note != "JavaScript ランタイム" {
+		t.Errorf("entry 1 = %+v", node)
+	}
+	next := entries[2]
+	if next.Pattern == nil || !next.Pattern.MatchString("Next JS") || next.Reading != `(?i)next\s*js` {
+		t.Errorf("entry 2 = %+v, want a regex entry", next)
+	}
+	if !entries[3].IsHint() || entries[3].Reading != "supabase" {
+		t.Errorf("entry 3 = %+v, want hint supabase", entries[3])
+	}
+	if !entries[4].IsHint() || entries[4].Note != "ホスティング" {
+		t.Errorf("entry 4 = %+v, want hint vercel with a note", entries[4])
+	}
+}
+
+func TestParseDictionaryMalformedLines(t *testing.T) {
+	path := writeDict(t, t.TempDir(), "dict.txt", strings.Join([]string{
+		"リアクト\tReact",
+		"ノード\tNode.js\tpriority=high",
+		"/[/\tbroken",
+		"スベルト\tSvelte",
+	}, "\n"))
+
+	entries, err := ParseDictionary(path)
+	if len(entries) != 2 || entries[1].Notation != "Svelte" {
+		t.Errorf("entries = %+v, want the two valid lines", entries)
+	}
+	var lineErrs []int
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		t.Fatalf("err = %v, want joined line errors", err)
+	}
+	for _, e := range joined.Unwrap() {
+		var le *LineError
+		if !errors.As(e, &le) {
+			t.Fatalf("error %v is not a *LineError", e)
+		}
+		lineErrs = append(lineErrs, le.Line)
+	}
+	if fmt.Sprint(lineErrs) != "[2 3]" {
+		t.Errorf("error lines = %v, want [2 3]", lineErrs)
+	}
+
+	d, err := LoadDictionary(path)
+	if err != nil {
+		t.Fatalf("LoadDictionary() error: %v, want warnings only", err)
+	}
+	if d.Conversions != 2 || len(d.Warnings) != 2 {
+		t.Errorf("Conversions = %d Warnings = %d, want 2 and 2", d.Conversions, len(d.Warnings))
+	}
+}
+
+func TestParseDictionaryLegacyLines(t *testing.T) {
+	path := writeDict(t, t.TempDir(), "dict.txt", strings.Join([]string{
+		"a\tb\tc",
+		"ビュー\tVue\tcolor=green",
+		"/usr/",
+		"/api/\t\tnote=REST",
+	}, "\n"))
+
+	entries, err := ParseDictionary(path)
+	if err != nil {
+		t.Fatalf("ParseDictionary() error: %v, want every line to load", err)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("entries = %+v, want 4", entries)
+	}
+	if e := entries[0]; e.Notation != "b" || fmt.Sprint(e.Ignored) != "[c]" {
+		t.Errorf("entry 0 = %+v, want notation b with c ignored", e)
+	}
+	if e := entries[1]; e.Notation != "Vue" || fmt.Sprint(e.Ignored) != "[color=green]" {
+		t.Errorf("entry 1 = %+v, want notation Vue with color=green ignored", e)
+	}
+	for _, e := range entries[2:] {
+		if !e.IsHint() || e.Pattern != nil {
+			t.Errorf("entry %+v, want a hint word, not a regex", e)
+		}
+	}
+
+	d, err := LoadDictionary(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, i := range Lint(d) {
+		got = append(got, fmt.Sprintf("%s %s", strings.TrimPrefix(i.Source, path), i.Severity))
+	}
+	if want := ":1 warning, :2 warning"; strings.Join(got, ", ") != want {
+		t.Errorf("Lint() = %v, want %s", got, want)
+	}
+}
+
+func TestParseDictionaryInclude(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "shared"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeDict(t, dir, "shared/go.txt", "ゴルーチン\tgoroutine\n#include ../dict.txt\n")
+	path := writeDict(t, dir, "dict.txt", "#include shared/go.txt\n#include \"missing.txt\"\nリアクト\tReact\n")
+
+	entries, err := ParseDictionary(path)
+	if len(entries) != 2 || entries[0].Notation != "goroutine" || entries[1].Notation != "React" {
+		t.Errorf("entries = %+v, want the included entry first", entries)
+	}
+	if !strings.HasSuffix(entries[0].Source, filepath.Join("shared", "go.txt")+":1") {
+		t.Errorf("Source = %q, want the included file", entries[0].Source)
+	}
+	// The cycle back to dict.txt and the missing file are reported, not fatal.
+	if err == nil || !strings.Contains(err.Error(), "cycle") || !strings.Contains(err.Error(), "missing.txt") {
+		t.Errorf("err = %v, want cycle and missing include errors", err)
+	}
+}
+
+func TestDictionaryXMLExtendedAttributes(t *testing.T) {
+	path := writeDict(t, t.TempDir(), "dict.txt", strings.Join([]string{
+		"リアクト\tReact",
+		"ノード\tNode.js\tcontext=programming\tpriority=3\tnote=<runtime>",
+		"/next\\s*js/\tNext.js",
+	}, "\n"))
+	conv, _, err := loadXML(path)
+	if err != nil {
+		t.Fatalf("LoadDictionary() error: %v", err)
+	}
+	node := `<term japanese="ノード" english="Node.js" context="programming" priority="3" note="&lt;runtime&gt;"/>`
+	if !strings.Contains(conv, node) {
+		t.Errorf("conversion XML missing %s:\n%s", node, conv)
+	}
+	if strings.Index(conv, node) > strings.Index(conv, "リアクト") {
+		t.Error("higher priority entries should come first")
+	}
+	if !strings.Contains(conv, `<term pattern="next\s*js" english="Next.js" context="always"/>`) {
+		t.Errorf("conversion XML missing the regex entry:\n%s", conv)
+	}
+}
+
 func TestParseDictionaryBothConversionAndHint(t *testing.T) {
 	path := filepath.Join("testdata", "dictionary_test.txt")
 
-	conv, hint, err := ParseDictionary(path)
+	conv, hint, err := loadXML(path)
 	if err != nil {
-		t.Fatalf("ParseDictionary() error: %v", err)
+		t.Fatalf("LoadDictionary() error: %v", err)
 	}
 
 	// Check conversion XML
@@ -55,9 +233,9 @@ func TestParseDictionaryConversionsOnly(t *testing.T) {
 		t.Fatalf("WriteFile error: %v", err)
 	}
 
-	conv, hint, err := ParseDictionary(path)
+	conv, hint, err := loadXML(path)
 	if err != nil {
-		t.Fatalf("ParseDictionary() error: %v", err)
+		t.Fatalf("LoadDictionary() error: %v", err)
 	}
 
 	if conv == "" {
@@ -76,9 +254,9 @@ func TestParseDictionaryHintsOnly(t *testing.T) {
 		t.Fatalf("WriteFile error: %v", err)
 	}
 
-	conv, hint, err := ParseDictionary(path)
+	conv, hint, err := loadXML(path)
 	if err != nil {
-		t.Fatalf("ParseDictionary() error: %v", err)
+		t.Fatalf("LoadDictionary() error: %v", err)
 	}
 
 	if conv != "" {
@@ -100,9 +278,9 @@ func TestParseDictionaryCommentsAndEmptyLines(t *testing.T) {
 		t.Fatalf("WriteFile error: %v", err)
 	}
 
-	conv, hint, err := ParseDictionary(path)
+	conv, hint, err := loadXML(path)
 	if err != nil {
-		t.Fatalf("ParseDictionary() error: %v", err)
+		t.Fatalf("LoadDictionary() error: %v", err)
 	}
 
 	if !strings.Contains(conv, `japanese="リアクト"`) {
@@ -117,9 +295,9 @@ func TestParseDictionaryCommentsAndEmptyLines(t *testing.T) {
 }
 
 func TestParseDictionaryFileNotFound(t *testing.T) {
-	conv, hint, err := ParseDictionary("/nonexistent/path/dict.txt")
+	conv, hint, err := loadXML("/nonexistent/path/dict.txt")
 	if err != nil {
-		t.Fatalf("ParseDictionary() should not error on missing file, got: %v", err)
+		t.Fatalf("LoadDictionary() should not error on missing file, got: %v", err)
 	}
 	if conv != "" {
 		t.Errorf("expected empty conversion XML, got %q", conv)
@@ -137,9 +315,9 @@ func TestParseDictionaryHTMLEscape(t *testing.T) {
 		t.Fatalf("WriteFile error: %v", err)
 	}
 
-	conv, hint, err := ParseDictionary(path)
+	conv, hint, err := loadXML(path)
 	if err != nil {
-		t.Fatalf("ParseDictionary() error: %v", err)
+		t.Fatalf("LoadDictionary() error: %v", err)
 	}
 
 	if !strings.Contains(conv, "テスト&amp;アンド") {
@@ -153,3 +331,147 @@ func TestParseDictionaryHTMLEscape(t *testing.T) {
 		t.Errorf("hint should have < and > escaped, got %q", hint)
 	}
 }
+
+func TestVocabulary(t *testing.T) {
+	path := filepath.Join("testdata", "dictionary_test.txt")
+
+	got, err := Vocabulary(path)
+	if err != nil {
+		t.Fatalf("Vocabulary() error: %v", err)
+	}
+	want := "Claude Code, React, Next.js, haiku, gemini, typescript"
+	if got != want {
+		t.Errorf("Vocabulary() = %q, want %q", got, want)
+	}
+}
+
+func TestVocabularyWithin(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "dict.txt")
+	content := "リアクト\tReact\nネクスト\tNext.js\tpriority=2\nタイプスクリプト\tTypeScript\tpriority=1\nsupabase\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+	d, err := LoadDictionary(path)
+	if err != nil {
+		t.Fatalf("LoadDictionary() error: %v", err)
+	}
+
+	tests := []struct {
+		maxTokens   int
+		want        string
+		wantDropped int
+	}{
+		{100, "React, Next.js, TypeScript, supabase", 0},
+		{7, "Next.js, TypeScript", 2},
+		{3, "Next.js", 3},
+		{0, "", 4},
+	}
+	for _, tt := range tests {
+		got, dropped := d.VocabularyWithin(tt.maxTokens)
+		if got != tt.want || dropped != tt.wantDropped {
+			t.Errorf("VocabularyWithin(%d) = %q, %d; want %q, %d", tt.maxTokens, got, dropped, tt.want, tt.wantDropped)
+		}
+	}
+}
+
+func TestVocabularyDeduplicates(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "dict.txt")
+	content := "リアクト\tReact\nりあくと\tReact\nReact\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+
+	got, err := Vocabulary(path)
+	if err != nil {
+		t.Fatalf("Vocabulary() error: %v", err)
+	}
+	if got != "React" {
+		t.Errorf("Vocabulary() = %q, want %q", got, "React")
+	}
+}
+
+func TestVocabularyFileNotFound(t *testing.T) {
+	got, err := Vocabulary("/nonexistent/path/dict.txt")
+	if err != nil {
+		t.Fatalf("Vocabulary() should not error on missing file, got: %v", err)
+	}
+	if got != "" {
+		t.Errorf("expected empty vocabulary, got %q", got)
+	}
+}
+
+func TestLoadDictionaryAndComposeSystemPrompt(t *testing.T) {
+	d, err := LoadDictionary(filepath.Join("testdata", "dictionary_test.txt"))
+	if err != nil {
+		t.Fatalf("LoadDictionary() error: %v", err)
+	}
+	if d.Conversions != 3 || d.Hints != 3 || d.Terms() != 6 {
+		t.Errorf("counts = %d conversions, %d hints, want 3 and 3", d.Conversions, d.Hints)
+	}
+
+	composed := ComposeSystemPrompt(d)
+	dict := strings.Index(composed, "<user_dictionary>")
+	guard := strings.Index(composed, "<final_guard>")
+	if dict < 0 || guard < 0 || dict > guard {
+		t.Fatalf("user dictionary should be inserted before <final_guard> (dict=%d guard=%d)", dict, guard)
+	}
+	if !strings.Contains(composed, `english="Claude Code"`) || !strings.Contains(composed, "typescript") {
+		t.Error("composed prompt missing dictionary entries")
+	}
+	if !strings.HasSuffix(composed, "</instructions>") {
+		t.Error("composed prompt should still end with </instructions>")
+	}
+}
+
+func TestComposeSystemPromptEmptyDictionary(t *testing.T) {
+	d, err := LoadDictionary(filepath.Join(t.TempDir(), "missing.txt"))
+	if err != nil {
+		t.Fatalf("LoadDictionary() error: %v", err)
+	}
+	if got := ComposeSystemPrompt(d); got != SystemPrompt {
+		t.Error("empty dictionary should leave SystemPrompt unchanged")
+	}
+	if d.Hash() != "none" {
+		t.Errorf("Hash() = %q, want none", d.Hash())
+	}
+}
+
+func TestComposePromptCustomBase(t *testing.T) {
+	d := Dictionary{ConversionXML: `<entry reading="クロード" english="Claude"/>`}
+	tests := []struct {
+		name string
+		base string
+		want string
+	}{
+		{"final guard", "<instructions>a<final_guard>b</final_guard></instructions>", "a<user_dictionary>"},
+		{"closing tag", "<instructions>a</instructions>", "a<user_dictionary>"},
+		{"plain text", "Write a commit message.\n", "Write a commit message.\n\n<user_dictionary>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ComposePrompt(tt.base, d)
+			if !strings.Contains(got, tt.want) || !strings.Contains(got, `english="Claude"`) {
+				t.Errorf("ComposePrompt() = %q, want it to contain %q", got, tt.want)
+			}
+		})
+	}
+	if got := ComposePrompt("plain", Dictionary{}); got != "plain" {
+		t.Errorf("ComposePrompt() with an empty dictionary = %q, want the base unchanged", got)
+	}
+}
+
+func TestDictionaryHash(t *testing.T) {
+	a := Dictionary{ConversionXML: "<a/>"}
+	b := Dictionary{ConversionXML: "<b/>"}
+	if a.Hash() != a.Hash() {
+		t.Error("Hash() should be stable")
+	}
+	if a.Hash() == b.Hash() {
+		t.Error("different dictionaries should hash differently")
+	}
+	if len(a.Hash()) != 8 {
+		t.Errorf("Hash() = %q, want 8 hex chars", a.Hash())
+	}
+}
@@ -0,0 +1,236 @@
+package prompt
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// IMEFormat is the export format of an IME user dictionary.
+type IMEFormat string
+
+const (
+	// FormatGoogleIME is a Google Japanese Input export:
+	// reading<TAB>word<TAB>part of speech[<TAB>comment], UTF-8.
+	FormatGoogleIME IMEFormat = "google-ime"
+	// FormatMSIME is a Microsoft IME text export: the same columns after
+	// "!"-prefixed header lines, usually UTF-16 with a BOM.
+	FormatMSIME IMEFormat = "ms-ime"
+)
+
+// ParseIMEFormat validates a format name.
+func ParseIMEFormat(name string) (IMEFormat, error) {
+	switch f := IMEFormat(name); f {
+	case FormatGoogleIME, FormatMSIME:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown IME dictionary format %q (want %s or %s)", name, FormatGoogleIME, FormatMSIME)
+}
+
+// suppressedPOS marks IME entries that hide a conversion instead of adding one.
+const suppressedPOS = "抑制単語"
+
+// IMEEntry is a reading→surface pair from an IME user dictionary.
+type IMEEntry struct {
+	Reading string // as exported (usually hiragana)
+	Surface string
+	Line    int
+}
+
+// ParseIMEDictionary reads an IME user dictionary export. Comment and header
+// lines, suppression entries and entries whose surface equals the reading are
+// skipped; lines without a surface are reported as *LineError values joined
+// into the returned error, alongside the valid entries.
+func ParseIMEDictionary(path string, format IMEFormat) ([]IMEEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading IME dictionary: %w", err)
+	}
+	text, err := decodeIMEText(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []IMEEntry
+	var problems []error
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") ||
+			format == FormatMSIME && strings.HasPrefix(line, "!") {
+			continue
+		}
+		cols := strings.Split(line, "\t")
+		if len(cols) < 2 || strings.TrimSpace(cols[0]) == "" || strings.TrimSpace(cols[1]) == "" {
+			problems = append(problems, &LineError{Path: path, Line: lineNo, Err: errors.New("expected reading<TAB>word")})
+			continue
+		}
+		if len(cols) > 2 && strings.TrimSpace(cols[2]) == suppressedPOS {
+			continue
+		}
+		e := IMEEntry{Reading: strings.TrimSpace(cols[0]), Surface: strings.TrimSpace(cols[1]), Line: lineNo}
+		if e.Reading == e.Surface {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading IME dictionary: %w", err)
+	}
+	return entries, errors.Join(problems...)
+}
+
+// decodeIMEText returns data as a string, decoding UTF-16 when it starts with
+// a byte order mark and dropping a UTF-8 BOM.
+func decodeIMEText(data []byte) (string, error) {
+	var order func([]byte) uint16
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		order = func(b []byte) uint16 { return uint16(b[0]) | uint16(b[1])<<8 }
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		order = func(b []byte) uint16 { return uint16(b[1]) | uint16(b[0])<<8 }
+	default:
+		data = bytes.TrimPrefix(data, []byte{0xEF, 0xBB, 0xBF})
+		if !utf8.Valid(data) {
+			return "", errors.New("IME dictionary is neither UTF-8 nor UTF-16; export it as Unicode")
+		}
+		return string(data), nil
+	}
+	data = data[2:]
+	units := make([]uint16, 0, len(data)/2)
+	for i := 0; i+1 < len(data); i += 2 {
+		units = append(units, order(data[i:]))
+	}
+	return string(utf16.Decode(units)), nil
+}
+
+// toKatakana converts hiragana to katakana. IME readings are typed in
+// hiragana, but transcriptions spell unfamiliar loanwords in katakana, which
+// is what the dictionary needs to match.
+func toKatakana(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'ぁ' && r <= 'ゖ' || r == 'ゝ' || r == 'ゞ' {
+			return r + ('ァ' - 'ぁ')
+		}
+		return r
+	}, s)
+}
+
+// ImportConflict is an imported reading that already maps to another surface.
+type ImportConflict struct {
+	Reading  string
+	Existing string // notation kept
+	Imported string // notation skipped
+	Source   string // where Existing is defined: "path:line" or "import line N"
+}
+
+// ImportPlan is the outcome of merging IME entries into a dictionary.
+type ImportPlan struct {
+	Added      []Entry
+	Duplicates int // already present with the same notation
+	Conflicts  []ImportConflict
+	Invalid    []IMEEntry // rejected by ValidateEntry
+}
+
+// ValidateEntry reports whether e can be written as a dictionary line and
+// read back as the same conversion: neither column may contain a tab or a
+// line break, and neither may start with "#" or "/", which would turn the
+// line into a comment or a regex (or the notation into one).
+func ValidateEntry(e Entry) error {
+	for _, col := range []struct{ name, value string }{{"reading", e.Reading}, {"notation", e.Notation}} {
+		switch {
+		case strings.ContainsAny(col.value, "\t\r\n"):
+			return fmt.Errorf("%s %q contains a tab or line break", col.name, col.value)
+		case strings.HasPrefix(col.value, "#"), strings.HasPrefix(col.value, "/"):
+			return fmt.Errorf("%s %q starts with %q", col.name, col.value, col.value[:1])
+		}
+	}
+	return nil
+}
+
+// PlanImport converts IME entries to conversion entries (readings in
+// katakana) and drops those already covered by existing. A reading that
+// already maps to a different notation, in the dictionary or earlier in the
+// import, is a conflict: the earlier notation wins. Entries that
+// ValidateEntry rejects are returned in Invalid.
+func PlanImport(existing []Entry, imported []IMEEntry) ImportPlan {
+	type known struct{ notation, source string }
+	byReading := make(map[string]known)
+	for _, e := range existing {
+		if e.IsHint() || e.Pattern != nil {
+			continue
+		}
+		key := toKatakana(e.Reading)
+		if _, ok := byReading[key]; !ok {
+			byReading[key] = known{e.Notation, e.Source}
+		}
+	}
+
+	var plan ImportPlan
+	for _, ie := range imported {
+		reading := strings.Join(strings.Fields(toKatakana(ie.Reading)), "")
+		if reading == "" || ValidateEntry(Entry{Reading: reading, Notation: ie.Surface}) != nil {
+			plan.Invalid = append(plan.Invalid, ie)
+			continue
+		}
+		k, ok := byReading[reading]
+		switch {
+		case !ok:
+			byReading[reading] = known{ie.Surface, fmt.Sprintf("import line %d", ie.Line)}
+			plan.Added = append(plan.Added, Entry{Reading: reading, Notation: ie.Surface, Context: DefaultContext})
+		case k.notation == ie.Surface:
+			plan.Duplicates++
+		default:
+			plan.Conflicts = append(plan.Conflicts, ImportConflict{Reading: reading, Existing: k.notation, Imported: ie.Surface, Source: k.source})
+		}
+	}
+	return plan
+}
+
+// AppendEntries appends conversion entries to the dictionary at path under a
+// comment header, creating the file if needed. Nothing is written if any
+// entry fails ValidateEntry or the header spans lines.
+func AppendEntries(path, header string, entries []Entry) error {
+	if strings.ContainsAny(header, "\r\n") {
+		return fmt.Errorf("header %q contains a line break", header)
+	}
+	for _, e := range entries {
+		if err := ValidateEntry(e); err != nil {
+			return fmt.Errorf("invalid entry: %w", err)
+		}
+	}
+	var b strings.Builder
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("reading dictionary: %w", err)
+	}
+	if len(data) > 0 {
+		if !bytes.HasSuffix(data, []byte("\n")) {
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+	}
+	if header != "" {
+		b.WriteString("# " + header + "\n")
+	}
+	for _, e := range entries {
+		b.WriteString(e.Reading + "\t" + e.Notation + "\n")
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("opening dictionary: %w", err)
+	}
+	if _, err := f.WriteString(b.String()); err != nil {
+		f.Close()
+		return fmt.Errorf("writing dictionary: %w", err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,186 @@
+package prompt
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"unicode/utf16"
+)
+
+func utf16LE(s string) []byte {
+	out := []byte{0xFF, 0xFE}
+	for _, u := range utf16.Encode([]rune(s)) {
+		out = append(out, byte(u), byte(u>>8))
+	}
+	return out
+}
+
+func TestParseIMEDictionary(t *testing.T) {
+	tests := []struct {
+		name   string
+		format IMEFormat
+		data   []byte
+	}{
+		{
+			name:   "google-ime",
+			format: FormatGoogleIME,
+			data: []byte("\xEF\xBB\xBF# Google 日本語入力\n" +
+				"くばねてぃす\tKubernetes\t名詞\t\n" +
+				"どっかー\tDocker\t名詞\tコンテナ\n" +
+				"ぐーぐる\tgoogle\t抑制単語\t\n" +
+				"てすと\tてすと\t名詞\n" +
+				"壊れた行\n"),
+		},
+		{
+			name:   "ms-ime utf-16",
+			format: FormatMSIME,
+			data: utf16LE("!Microsoft IME Dictionary Tool\r\n!Version:\r\n\r\n" +
+				"くばねてぃす\tKubernetes\t名詞\r\n" +
+				"どっかー\tDocker\t名詞\r\n" +
+				"ぐーぐる\tgoogle\t抑制単語\r\n" +
+				"てすと\tてすと\t名詞\r\n" +
+				"壊れた行\r\n"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "export.txt")
+			if err := os.WriteFile(path, tt.data, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			entries, err := ParseIMEDictionary(path, tt.format)
+
+			var lineErr *LineError
+			if !errors.As(err, &lineErr) {
+				t.Fatalf("error = %v, want a *LineError for the malformed line", err)
+			}
+			var got [][2]string
+			for _, e := range entries {
+				got = append(got, [2]string{e.Reading, e.Surface})
+			}
+			want := [][2]string{{"くばねてぃす", "Kubernetes"}, {"どっかー", "Docker"}}
+			if !slices.Equal(got, want) {
+				t.Errorf("entries = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestParseIMEDictionaryRejectsShiftJIS(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "export.txt")
+	if err := os.WriteFile(path, []byte{0x82, 0xA0, '\t', 'A', '\n'}, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseIMEDictionary(path, FormatMSIME); err == nil {
+		t.Error("ParseIMEDictionary() should reject non-Unicode input")
+	}
+}
+
+func TestPlanImport(t *testing.T) {
+	existing := []Entry{
+		{Reading: "クバネティス", Notation: "Kubernetes", Source: "dict.txt:1"},
+		{Reading: "ゴー", Notation: "Go", Source: "dict.txt:2"},
+		{Reading: "Docker"},
+	}
+	imported := []IMEEntry{
+		{Reading: "くばねてぃす", Surface: "Kubernetes", Line: 1},
+		{Reading: "ごー", Surface: "Golang", Line: 2},
+		{Reading: "どっかー", Surface: "Docker", Line: 3},
+		{Reading: "どっかー", Surface: "docker", Line: 4},
+		{Reading: "#たぐ", Surface: "tag", Line: 5},
+		{Reading: "はっしゅ", Surface: "#hash", Line: 6},
+		{Reading: "すらっしゅ", Surface: "/usr", Line: 7},
+		{Reading: "たぶ", Surface: "a\rb", Line: 8},
+	}
+
+	plan := PlanImport(existing, imported)
+
+	if want := conversions([2]string{"ドッカー", "Docker"}); !slices.EqualFunc(plan.Added, want, func(a, b Entry) bool {
+		return a.Reading == b.Reading && a.Notation == b.Notation && a.Context == b.Context
+	}) {
+		t.Errorf("Added = %+v, want %+v", plan.Added, want)
+	}
+	if plan.Duplicates != 1 {
+		t.Errorf("Duplicates = %d, want 1", plan.Duplicates)
+	}
+	want := []ImportConflict{
+		{Reading: "ゴー", Existing: "Go", Imported: "Golang", Source: "dict.txt:2"},
+		{Reading: "ドッカー", Existing: "Docker", Imported: "docker", Source: "import line 3"},
+	}
+	if !slices.Equal(plan.Conflicts, want) {
+		t.Errorf("Conflicts = %+v, want %+v", plan.Conflicts, want)
+	}
+	var invalid []int
+	for _, e := range plan.Invalid {
+		invalid = append(invalid, e.Line)
+	}
+	if want := []int{5, 6, 7, 8}; !slices.Equal(invalid, want) {
+		t.Errorf("Invalid lines = %v, want %v", invalid, want)
+	}
+}
+
+func TestValidateEntry(t *testing.T) {
+	tests := []struct {
+		reading, notation string
+		wantErr           bool
+	}{
+		{"クバネティス", "Kubernetes", false},
+		{"シーシャープ", "C#", false},
+		{"エーピーアイ", "api/v1", false},
+		{"タブ", "a\tb", true},
+		{"カイギョウ", "a\nb", true},
+		{"フッキ", "a\r", true},
+		{"ハッシュ", "#include", true},
+		{"スラッシュ", "/api/", true},
+		{"#コメント", "comment", true},
+		{"/ア/", "a", true},
+		{"ア\tイ", "a", true},
+	}
+	for _, tt := range tests {
+		err := ValidateEntry(Entry{Reading: tt.reading, Notation: tt.notation})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidateEntry(%q, %q) error = %v, wantErr %v", tt.reading, tt.notation, err, tt.wantErr)
+		}
+	}
+}
+
+func TestAppendEntries(t *testing.T) {
+	dir := t.TempDir()
+	path := writeDict(t, dir, "dict.txt", "クバネティス\tKubernetes")
+
+	if err := AppendEntries(path, "Imported from ime.txt", conversions([2]string{"ドッカー", "Docker"})); err != nil {
+		t.Fatalf("AppendEntries() error: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if want := "クバネティス\tKubernetes\n\n# Imported from ime.txt\nドッカー\tDocker\n"; string(data) != want {
+		t.Errorf("dictionary = %q, want %q", data, want)
+	}
+
+	entries, err := ParseDictionary(path)
+	if err != nil || len(entries) != 2 {
+		t.Errorf("ParseDictionary() = %d entries, %v; want 2", len(entries), err)
+	}
+
+	before, _ := os.ReadFile(path)
+	for _, bad := range [][2]string{{"タブ", "a\tb"}, {"カイギョウ", "a\nb"}, {"ハッシュ", "#x"}, {"スラッシュ", "/x/"}} {
+		if err := AppendEntries(path, "", conversions([2]string{"ゴー", "Go"}, bad)); err == nil {
+			t.Errorf("AppendEntries(%q) succeeded, want error", bad)
+		}
+	}
+	if err := AppendEntries(path, "two\nlines", conversions([2]string{"ゴー", "Go"})); err == nil {
+		t.Error("AppendEntries(multi-line header) succeeded, want error")
+	}
+	if after, _ := os.ReadFile(path); string(after) != string(before) {
+		t.Errorf("rejected AppendEntries wrote %q", after[len(before):])
+	}
+
+	fresh := filepath.Join(dir, "new.txt")
+	if err := AppendEntries(fresh, "", conversions([2]string{"ゴー", "Go"})); err != nil {
+		t.Fatalf("AppendEntries(new file) error: %v", err)
+	}
+	if data, _ := os.ReadFile(fresh); string(data) != "ゴー\tGo\n" {
+		t.Errorf("new dictionary = %q", data)
+	}
+}
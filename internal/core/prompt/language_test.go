@@ -0,0 +1,32 @@
+package prompt
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTranscribePromptFor(t *testing.T) {
+	tests := []struct {
+		language string
+		want     string
+	}{
+		{"", TranscribePrompt},
+		{"ja", TranscribePrompt},
+		{"en", "英語で"},
+		{"EN", "英語で"},
+		{"auto", "話された言語のまま"},
+		{"pt", "ptで"},
+	}
+	for _, tt := range tests {
+		if got := TranscribePromptFor(tt.language); !strings.Contains(got, tt.want) {
+			t.Errorf("TranscribePromptFor(%q) = %q, want it to contain %q", tt.language, got, tt.want)
+		}
+	}
+}
+
+func TestTranslateSystemPrompt(t *testing.T) {
+	p := TranslateSystemPrompt("en")
+	if !strings.Contains(p, "英語に翻訳します") || !strings.Contains(p, "すでに英語の場合") {
+		t.Errorf("TranslateSystemPrompt(en) should name the target language:\n%s", p)
+	}
+}
@@ -0,0 +1,163 @@
+package prompt
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Severity ranks lint findings. Errors change transcription results in ways
+// the author likely did not intend; warnings only waste prompt tokens.
+type Severity int
+
+const (
+	SeverityWarning Severity = iota
+	SeverityError
+)
+
+func (s Severity) String() string {
+	if s == SeverityError {
+		return "error"
+	}
+	return "warning"
+}
+
+// Issue is a single dictionary lint finding.
+type Issue struct {
+	Severity Severity
+	Source   string // "path:line" of the offending entry
+	Message  string
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%s: %s: %s", i.Source, i.Severity, i.Message)
+}
+
+// Lint checks the entries of d for duplicates, conflicting conversions, hint
+// words that are also conversion sources and characters that cannot match a
+// transcription. Malformed lines skipped while loading are reported as
+// errors. Issues are returned in dictionary order.
+func Lint(d Dictionary) []Issue {
+	var issues []Issue
+	for _, w := range d.Warnings {
+		issues = append(issues, Issue{Severity: SeverityError, Source: lineSource(w), Message: lineMessage(w)})
+	}
+
+	type conversionKey struct{ reading, context string }
+	conversions := make(map[conversionKey]Entry)
+	conversionSources := make(map[string]Entry)
+	hints := make(map[string]Entry)
+	for _, e := range d.Entries {
+		for _, field := range []string{e.Reading, e.Notation} {
+			if msg := invalidChars(field); msg != "" {
+				issues = append(issues, Issue{Severity: SeverityError, Source: e.Source, Message: fmt.Sprintf("%q %s", field, msg)})
+			}
+		}
+
+		for _, col := range e.Ignored {
+			issues = append(issues, Issue{Severity: SeverityWarning, Source: e.Source, Message: fmt.Sprintf("ignored column %q (options are context=, priority= and note=)", col)})
+		}
+
+		if e.IsHint() {
+			if prev, ok := hints[e.Reading]; ok {
+				issues = append(issues, Issue{Severity: SeverityWarning, Source: e.Source, Message: fmt.Sprintf("duplicate hint word %q (first at %s)", e.Reading, prev.Source)})
+				continue
+			}
+			hints[e.Reading] = e
+			if c, ok := conversionSources[e.Reading]; ok {
+				issues = append(issues, hintConversionIssue(e, c))
+			}
+			continue
+		}
+		if e.Pattern == nil && isHalfWidthKatakana(e.Reading) {
+			issues = append(issues, Issue{Severity: SeverityError, Source: e.Source, Message: fmt.Sprintf("reading %q uses half-width katakana, which transcriptions never contain", e.Reading)})
+		}
+
+		key := conversionKey{e.Reading, e.Context}
+		if prev, ok := conversions[key]; ok {
+			if prev.Notation == e.Notation {
+				issues = append(issues, Issue{Severity: SeverityWarning, Source: e.Source, Message: fmt.Sprintf("duplicate conversion %q -> %q (first at %s)", e.Reading, e.Notation, prev.Source)})
+			} else {
+				issues = append(issues, Issue{Severity: SeverityError, Source: e.Source, Message: fmt.Sprintf("conflicting conversion %q -> %q; %s maps it to %q", e.Reading, e.Notation, prev.Source, prev.Notation)})
+			}
+			continue
+		}
+		conversions[key] = e
+		if e.Pattern == nil {
+			if _, ok := conversionSources[e.Reading]; !ok {
+				conversionSources[e.Reading] = e
+			}
+			if h, ok := hints[e.Reading]; ok {
+				issues = append(issues, hintConversionIssue(h, e))
+			}
+		}
+	}
+	return issues
+}
+
+func hintConversionIssue(hint, conversion Entry) Issue {
+	return Issue{
+		Severity: SeverityError,
+		Source:   hint.Source,
+		Message:  fmt.Sprintf("hint word %q is also converted to %q at %s", hint.Reading, conversion.Notation, conversion.Source),
+	}
+}
+
+// invalidChars describes characters in s that never appear in transcriptions
+// and break matching: control and zero-width format characters, the Unicode
+// replacement character left by a wrong encoding, and surrounding
+// full-width spaces. It returns "" when s is clean.
+func invalidChars(s string) string {
+	for _, r := range s {
+		switch {
+		case r == unicode.ReplacementChar:
+			return "contains U+FFFD (file saved in a non-UTF-8 encoding?)"
+		case unicode.IsControl(r):
+			return fmt.Sprintf("contains control character %U", r)
+		case unicode.Is(unicode.Cf, r):
+			return fmt.Sprintf("contains invisible character %U", r)
+		}
+	}
+	if strings.Trim(s, "　") != s {
+		return "has a leading or trailing full-width space"
+	}
+	return ""
+}
+
+func isHalfWidthKatakana(s string) bool {
+	return strings.ContainsFunc(s, func(r rune) bool { return r >= 'ｦ' && r <= 'ﾟ' })
+}
+
+// lineSource and lineMessage split a *LineError into location and text.
+func lineSource(err error) string {
+	var le *LineError
+	if errors.As(err, &le) {
+		return fmt.Sprintf("%s:%d", le.Path, le.Line)
+	}
+	return "dictionary"
+}
+
+func lineMessage(err error) string {
+	var le *LineError
+	if errors.As(err, &le) {
+		return le.Err.Error()
+	}
+	return err.Error()
+}
+
+// EstimateTokens approximates the Gemini token count of s without calling
+// the API: about four ASCII characters per token, and one token per
+// character for Japanese and other non-ASCII text. It errs on the high side
+// for Japanese, which is what a budget check wants.
+func EstimateTokens(s string) int {
+	ascii, other := 0, 0
+	for _, r := range s {
+		if r < 0x80 {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return (ascii+3)/4 + other
+}
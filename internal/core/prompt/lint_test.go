@@ -0,0 +1,75 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	dir := t.TempDir()
+	path := writeDict(t, dir, "dict.txt", strings.Join([]string{
+		"クバネティス\tKubernetes",                // 1
+		"クバネティス\tKubernetes",                // 2 duplicate
+		"ドッカー\tDocker",                      // 3
+		"ドッカー\tdocker",                      // 4 conflict
+		"ドッカー\tDocker\tcontext=programming", // 5 other context: fine
+		"React",                // 6
+		"React",                // 7 duplicate hint
+		"ゴー",                   // 8 hint that is converted below
+		"ゴー\tGo",               // 9
+		"ﾘｱｸﾄ\tReact",          // 10 half-width
+		"ジェミニ\u200b\tGemini",   // 11 zero-width space
+		"壊れた\t\tpriority=high", // 12 malformed
+	}, "\n"))
+	d, err := LoadDictionary(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, i := range Lint(d) {
+		got = append(got, fmt.Sprintf("%s %s", strings.TrimPrefix(i.Source, path), i.Severity))
+	}
+	want := []string{
+		":12 error",  // malformed lines come first
+		":2 warning", // duplicate conversion
+		":4 error",   // conflicting conversion
+		":7 warning", // duplicate hint
+		":8 error",   // hint also converted
+		":10 error",  // half-width katakana
+		":11 error",  // invisible character
+	}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("Lint() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestLintCleanDictionary(t *testing.T) {
+	path := writeDict(t, t.TempDir(), "dict.txt", "クバネティス\tKubernetes\nReact\n/ゴー(ラング)?/\tGo\n")
+	d, err := LoadDictionary(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issues := Lint(d); len(issues) != 0 {
+		t.Errorf("Lint() = %v, want no issues", issues)
+	}
+}
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+	}{
+		{"", 0},
+		{"abcd", 1},
+		{"abcde", 2},
+		{"日本語", 3},
+		{"Go言語", 3},
+	}
+	for _, tt := range tests {
+		if got := EstimateTokens(tt.in); got != tt.want {
+			t.Errorf("EstimateTokens(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
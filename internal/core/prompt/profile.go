@@ -0,0 +1,92 @@
+package prompt
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ProfileDir returns the directory of dictionary profiles for the base
+// dictionary at basePath (~/.voicecoding/dictionaries by default).
+func ProfileDir(basePath string) string {
+	return filepath.Join(filepath.Dir(basePath), "dictionaries")
+}
+
+// ProfilePath returns the file of the named profile.
+func ProfilePath(basePath, name string) (string, error) {
+	if name == "" || name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		return "", fmt.Errorf("invalid dictionary profile name %q", name)
+	}
+	return filepath.Join(ProfileDir(basePath), name+".txt"), nil
+}
+
+// ListProfiles returns the names of the available profiles, sorted.
+// A missing profile directory yields no profiles.
+func ListProfiles(basePath string) ([]string, error) {
+	entries, err := os.ReadDir(ProfileDir(basePath))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading profile directory: %w", err)
+	}
+	var names []string
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), ".txt")
+		if ok && !e.IsDir() && !strings.HasPrefix(name, ".") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// LoadProfile loads the base dictionary at basePath with the named profile
+// overlaid. Profile entries take precedence: a profile entry replaces any base
+// entry with the same reading and is listed first, so it also wins priority
+// ties. An empty profile loads the base dictionary only; a missing profile
+// falls back to the base dictionary with a warning.
+func LoadProfile(basePath, profile string) (Dictionary, error) {
+	base, err := LoadDictionary(basePath)
+	if err != nil || profile == "" {
+		return base, err
+	}
+
+	path, err := ProfilePath(basePath, profile)
+	if err != nil {
+		return Dictionary{}, err
+	}
+	if _, err := os.Stat(path); err != nil {
+		base.Warnings = append(base.Warnings, fmt.Errorf("dictionary profile %q: %w", profile, err))
+		base.Files = append(base.Files, path)
+		return base, nil
+	}
+	overlay, err := LoadDictionary(path)
+	if err != nil {
+		return Dictionary{}, fmt.Errorf("dictionary profile %q: %w", profile, err)
+	}
+
+	d := newDictionary(mergeEntries(base.Entries, overlay.Entries))
+	d.Warnings = append(base.Warnings, overlay.Warnings...)
+	d.Files = append(base.Files, overlay.Files...)
+	d.Profile = profile
+	return d, nil
+}
+
+func mergeEntries(base, overlay []Entry) []Entry {
+	overridden := make(map[string]bool, len(overlay))
+	merged := make([]Entry, 0, len(base)+len(overlay))
+	for _, e := range overlay {
+		overridden[e.Reading] = true
+		merged = append(merged, e)
+	}
+	for _, e := range base {
+		if !overridden[e.Reading] {
+			merged = append(merged, e)
+		}
+	}
+	return merged
+}
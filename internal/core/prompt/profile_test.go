@@ -0,0 +1,97 @@
+package prompt
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestListProfiles(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "dictionary.txt")
+	if got, err := ListProfiles(base); err != nil || got != nil {
+		t.Fatalf("ListProfiles() = %v, %v; want none without a profile directory", got, err)
+	}
+
+	profiles := ProfileDir(base)
+	if err := os.MkdirAll(filepath.Join(profiles, "archive.txt"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"react.txt", "go-backend.txt", ".hidden.txt", "notes.md"} {
+		writeDict(t, profiles, name, "")
+	}
+	got, err := ListProfiles(base)
+	if err != nil {
+		t.Fatalf("ListProfiles() error: %v", err)
+	}
+	if want := []string{"go-backend", "react"}; !slices.Equal(got, want) {
+		t.Errorf("ListProfiles() = %v, want %v", got, want)
+	}
+}
+
+func TestProfilePathRejectsPaths(t *testing.T) {
+	for _, name := range []string{"", "../secrets", "a/b", ".hidden"} {
+		if _, err := ProfilePath("/home/u/.voicecoding/dictionary.txt", name); err == nil {
+			t.Errorf("ProfilePath(%q) should fail", name)
+		}
+	}
+	got, err := ProfilePath("/home/u/.voicecoding/dictionary.txt", "react")
+	if err != nil || got != filepath.Join("/home/u/.voicecoding/dictionaries", "react.txt") {
+		t.Errorf("ProfilePath(react) = %q, %v", got, err)
+	}
+}
+
+func TestLoadProfileOverlaysBase(t *testing.T) {
+	dir := t.TempDir()
+	base := writeDict(t, dir, "dictionary.txt", strings.Join([]string{
+		"ノード\tnode",
+		"リアクト\tReact",
+		"supabase",
+	}, "\n"))
+	if err := os.MkdirAll(ProfileDir(base), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeDict(t, ProfileDir(base), "frontend.txt", strings.Join([]string{
+		"ノード\tNode.js",
+		"ビート\tVite",
+		"tailwind",
+	}, "\n"))
+
+	d, err := LoadProfile(base, "frontend")
+	if err != nil {
+		t.Fatalf("LoadProfile() error: %v", err)
+	}
+	if d.Profile != "frontend" || d.Conversions != 3 || d.Hints != 2 {
+		t.Errorf("Profile=%q Conversions=%d Hints=%d, want frontend 3 2", d.Profile, d.Conversions, d.Hints)
+	}
+	if got, _ := d.Replacer().Apply("ノードとリアクトとビート"); got != "Node.jsとReactとVite" {
+		t.Errorf("Apply() = %q, profile entries should override the base", got)
+	}
+	if len(d.Files) != 2 {
+		t.Errorf("Files = %v, want base and profile", d.Files)
+	}
+
+	baseOnly, err := LoadProfile(base, "")
+	if err != nil {
+		t.Fatalf("LoadProfile(base) error: %v", err)
+	}
+	if baseOnly.Hash() == d.Hash() || baseOnly.Profile != "" {
+		t.Error("switching profiles should change the dictionary hash")
+	}
+}
+
+func TestLoadProfileMissingFallsBackToBase(t *testing.T) {
+	base := writeDict(t, t.TempDir(), "dictionary.txt", "リアクト\tReact\n")
+	d, err := LoadProfile(base, "missing")
+	if err != nil {
+		t.Fatalf("LoadProfile() error: %v", err)
+	}
+	if d.Conversions != 1 || len(d.Warnings) != 1 || d.Profile != "" {
+		t.Errorf("Conversions=%d Warnings=%v Profile=%q, want the base with a warning", d.Conversions, d.Warnings, d.Profile)
+	}
+	if _, err := LoadProfile(base, "../x"); err == nil {
+		t.Error("invalid profile names should fail")
+	}
+}
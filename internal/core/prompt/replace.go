@@ -0,0 +1,213 @@
+package prompt
+
+import (
+	"cmp"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Replacement records a conversion entry applied by a Replacer. From is the
+// replaced text, which for regex entries is what the pattern matched.
+type Replacement struct {
+	From  string
+	To    string
+	Count int
+}
+
+// regexLookahead is how many runes a streamed regex match may span before
+// the text is committed. Longer matches are only found by Apply.
+const regexLookahead = 32
+
+// Replacer applies dictionary conversion entries to transcribed text
+// deterministically. Only entries with DefaultContext are applied, since the
+// topic of an utterance is not known locally. Higher priority wins, then
+// longer readings over shorter ones, then literal readings over regex
+// entries. A match must sit on a word boundary: an ASCII edge must not touch
+// another ASCII letter or digit, and a katakana edge must not touch more
+// katakana, so "ドッカー" does not match inside "ドッカーファイル". Hiragana
+// and kanji have no reliable word boundary and match anywhere.
+type Replacer struct {
+	rules  []replaceRule // longest first
+	maxLen int           // longest reading in runes
+}
+
+type replaceRule struct {
+	from, to string
+	pattern  *regexp.Regexp // anchored; nil for literal readings
+	runes    int            // rune length of a literal reading
+	priority int
+}
+
+// NewReplacer builds a Replacer from the conversion entries. Hints, entries
+// limited to a context and entries whose reading equals the notation are
+// dropped; for duplicate readings the highest priority (then the first) wins.
+func NewReplacer(entries []Entry) *Replacer {
+	r := &Replacer{}
+	seen := make(map[string]bool)
+	byPriority := slices.Clone(entries)
+	slices.SortStableFunc(byPriority, func(a, b Entry) int { return cmp.Compare(b.Priority, a.Priority) })
+	for _, e := range byPriority {
+		if e.IsHint() || e.Context != DefaultContext || e.Reading == e.Notation || seen[e.Reading] {
+			continue
+		}
+		seen[e.Reading] = true
+		rule := replaceRule{from: e.Reading, to: e.Notation, priority: e.Priority}
+		if e.Pattern != nil {
+			rule.pattern = regexp.MustCompile(`^(?:` + e.Pattern.String() + `)`)
+			r.maxLen = max(r.maxLen, regexLookahead)
+		} else {
+			rule.runes = utf8.RuneCountInString(e.Reading)
+			r.maxLen = max(r.maxLen, rule.runes)
+		}
+		r.rules = append(r.rules, rule)
+	}
+	sort.SliceStable(r.rules, func(i, j int) bool {
+		a, b := r.rules[i], r.rules[j]
+		if a.priority != b.priority {
+			return a.priority > b.priority
+		}
+		return a.runes > b.runes
+	})
+	return r
+}
+
+// Len returns the number of conversion rules.
+func (r *Replacer) Len() int {
+	if r == nil {
+		return 0
+	}
+	return len(r.rules)
+}
+
+// Apply replaces every reading in text and reports which rules fired, in the
+// order they first fired. A nil Replacer returns text unchanged.
+func (r *Replacer) Apply(text string) (string, []Replacement) {
+	s := r.Stream()
+	out := s.Write(text) + s.Flush()
+	return out, s.Replacements()
+}
+
+// Stream returns a StreamReplacer that applies r to text arriving in pieces.
+func (r *Replacer) Stream() *StreamReplacer {
+	return &StreamReplacer{r: r, fired: make(map[string]int)}
+}
+
+// StreamReplacer applies a Replacer to streamed deltas. It holds back the
+// tail that could still grow into a match, so the concatenated output is
+// identical to Apply on the whole text.
+type StreamReplacer struct {
+	r       *Replacer
+	pending string
+	prev    rune // last rune consumed from the input, for the start boundary
+	order   []Replacement
+	fired   map[string]int // index into order by replaced text
+}
+
+// Write consumes delta and returns the output that is final.
+func (s *StreamReplacer) Write(delta string) string {
+	s.pending += delta
+	return s.scan(false)
+}
+
+// Flush returns the held-back tail once the input has ended.
+func (s *StreamReplacer) Flush() string {
+	return s.scan(true)
+}
+
+// Replacements returns the rules that fired so far.
+func (s *StreamReplacer) Replacements() []Replacement {
+	return s.order
+}
+
+func (s *StreamReplacer) scan(final bool) string {
+	if s.r.Len() == 0 {
+		out := s.pending
+		s.pending = ""
+		return out
+	}
+
+	var out strings.Builder
+	text := s.pending
+	remaining := utf8.RuneCountInString(text)
+	i := 0
+	// Without the final flag a position is only decided when more than
+	// maxLen runes follow it, which also covers the end-boundary rune.
+	for i < len(text) && (final || remaining > s.r.maxLen) {
+		if rule, matched, ok := s.match(text, i); ok {
+			out.WriteString(rule.to)
+			s.record(matched, rule.to)
+			last, _ := utf8.DecodeLastRuneInString(matched)
+			s.prev = last
+			i += len(matched)
+			remaining -= utf8.RuneCountInString(matched)
+			continue
+		}
+		c, size := utf8.DecodeRuneInString(text[i:])
+		out.WriteRune(c)
+		s.prev = c
+		i += size
+		remaining--
+	}
+	s.pending = text[i:]
+	return out.String()
+}
+
+// match returns the first rule matching at text[i:] and the matched text.
+func (s *StreamReplacer) match(text string, i int) (replaceRule, string, bool) {
+	for _, rule := range s.r.rules {
+		matched := rule.from
+		if rule.pattern != nil {
+			loc := rule.pattern.FindStringIndex(text[i:])
+			if loc == nil || loc[1] == 0 {
+				continue
+			}
+			matched = text[i : i+loc[1]]
+		} else if !strings.HasPrefix(text[i:], rule.from) {
+			continue
+		}
+		first, _ := utf8.DecodeRuneInString(matched)
+		if s.prev != 0 && joins(s.prev, first) {
+			continue
+		}
+		last, _ := utf8.DecodeLastRuneInString(matched)
+		if next, _ := utf8.DecodeRuneInString(text[i+len(matched):]); next != utf8.RuneError && joins(last, next) {
+			continue
+		}
+		return rule, matched, true
+	}
+	return replaceRule{}, "", false
+}
+
+// record counts a replacement by the text it replaced, so every spelling a
+// regex entry caught is listed separately.
+func (s *StreamReplacer) record(from, to string) {
+	if idx, ok := s.fired[from]; ok {
+		s.order[idx].Count++
+		return
+	}
+	s.fired[from] = len(s.order)
+	s.order = append(s.order, Replacement{From: from, To: to, Count: 1})
+}
+
+// joins reports whether a and b would form one word if adjacent.
+func joins(a, b rune) bool {
+	switch {
+	case isASCIIWord(a):
+		return isASCIIWord(b)
+	case isKatakana(a):
+		return isKatakana(b)
+	}
+	return false
+}
+
+func isASCIIWord(r rune) bool {
+	return r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_')
+}
+
+func isKatakana(r rune) bool {
+	return unicode.Is(unicode.Katakana, r) || r == 'ー'
+}
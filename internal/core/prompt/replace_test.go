@@ -0,0 +1,160 @@
+package prompt
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// conversions returns always-context entries for reading -> notation pairs.
+func conversions(pairs ...[2]string) []Entry {
+	entries := make([]Entry, len(pairs))
+	for i, p := range pairs {
+		entries[i] = Entry{Reading: p[0], Notation: p[1], Context: DefaultContext}
+	}
+	return entries
+}
+
+func TestReplacerApply(t *testing.T) {
+	r := NewReplacer(conversions(
+		[2]string{"クバネティス", "Kubernetes"},
+		[2]string{"ドッカー", "Docker"},
+		[2]string{"ドッカーコンポーズ", "Docker Compose"},
+		[2]string{"react", "React"},
+		[2]string{"型", "型"},         // identical entries are ignored
+		[2]string{"ドッカー", "docker"}, // duplicate reading: first wins
+		[2]string{"でぷろい", "デプロイ"},
+	))
+
+	tests := []struct {
+		name string
+		text string
+		want string
+		hits []Replacement
+	}{
+		{"katakana reading", "クバネティスにデプロイ", "Kubernetesにデプロイ",
+			[]Replacement{{"クバネティス", "Kubernetes", 1}}},
+		{"longest match first", "ドッカーコンポーズとドッカー", "Docker ComposeとDocker",
+			[]Replacement{{"ドッカーコンポーズ", "Docker Compose", 1}, {"ドッカー", "Docker", 1}}},
+		{"katakana boundary", "ドッカーファイルを書く", "ドッカーファイルを書く", nil},
+		{"ascii boundary", "reactive と react を比較", "reactive と React を比較",
+			[]Replacement{{"react", "React", 1}}},
+		{"ascii next to japanese", "reactのフック", "Reactのフック",
+			[]Replacement{{"react", "React", 1}}},
+		{"hiragana matches anywhere", "本番にでぷろいする", "本番にデプロイする",
+			[]Replacement{{"でぷろい", "デプロイ", 1}}},
+		{"counts repeated hits", "react, react", "React, React",
+			[]Replacement{{"react", "React", 2}}},
+		{"output is not rescanned", "ドッカー", "Docker",
+			[]Replacement{{"ドッカー", "Docker", 1}}},
+		{"no match", "今日は晴れ", "今日は晴れ", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, hits := r.Apply(tt.text)
+			if got != tt.want {
+				t.Errorf("Apply() = %q, want %q", got, tt.want)
+			}
+			if !reflect.DeepEqual(hits, tt.hits) {
+				t.Errorf("replacements = %+v, want %+v", hits, tt.hits)
+			}
+
+			// Streaming rune by rune yields the same text.
+			s := r.Stream()
+			var out strings.Builder
+			for _, c := range tt.text {
+				out.WriteString(s.Write(string(c)))
+			}
+			out.WriteString(s.Flush())
+			if out.String() != tt.want {
+				t.Errorf("streamed = %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestStreamReplacerHoldsBackPossibleMatch(t *testing.T) {
+	r := NewReplacer(conversions([2]string{"ドッカー", "Docker"}))
+	s := r.Stream()
+	// Only runes followed by more than the longest reading are final.
+	if got := s.Write("今日はドッ"); got != "今" {
+		t.Errorf("Write() = %q, want %q", got, "今")
+	}
+	if got := s.Write("カーを使う"); got != "日はDocker" {
+		t.Errorf("Write() = %q, want %q", got, "日はDocker")
+	}
+	if got := s.Flush(); got != "を使う" {
+		t.Errorf("Flush() = %q, want %q", got, "を使う")
+	}
+}
+
+func TestNilReplacer(t *testing.T) {
+	var r *Replacer
+	if got, hits := r.Apply("ドッカー"); got != "ドッカー" || hits != nil {
+		t.Errorf("Apply() = %q %v, want the text unchanged", got, hits)
+	}
+}
+
+func TestDictionaryReplacer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dictionary.txt")
+	if err := os.WriteFile(path, []byte("クバネティス\tKubernetes\nsupabase\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	d, err := LoadDictionary(path)
+	if err != nil {
+		t.Fatalf("LoadDictionary() error: %v", err)
+	}
+	r := d.Replacer()
+	if r.Len() != 1 {
+		t.Fatalf("Len() = %d, want 1 (hints are not replaced)", r.Len())
+	}
+	if got, _ := r.Apply("クバネティスとsupabase"); got != "Kubernetesとsupabase" {
+		t.Errorf("Apply() = %q", got)
+	}
+}
+
+func TestReplacerExtendedEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dictionary.txt")
+	content := strings.Join([]string{
+		"/(?i)next\\s*js/\tNext.js",
+		"ノード\tNode.js\tcontext=programming",
+		"リアクト\tReact",
+		"リアクトネイティブ\tReact Native",
+		"リアクトネイティブ\tRN\tpriority=5",
+		"型推論\ttype inference",
+		"型\tkata\tpriority=1",
+	}, "\n")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	d, err := LoadDictionary(path)
+	if err != nil {
+		t.Fatalf("LoadDictionary() error: %v", err)
+	}
+
+	tests := []struct {
+		name, text, want string
+	}{
+		{"regex", "nextjs と Next JS", "Next.js と Next.js"},
+		{"context entries are left to the model", "ノードを追加", "ノードを追加"},
+		{"priority beats length", "型推論", "kata推論"},
+		{"duplicate reading resolved by priority", "リアクトネイティブ", "RN"},
+		{"katakana boundary still applies", "リアクトネイティブとリアクト", "RNとReact"},
+	}
+	r := d.Replacer()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, _ := r.Apply(tt.text); got != tt.want {
+				t.Errorf("Apply(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+
+	_, hits := r.Apply("nextjs, Next JS, nextjs")
+	want := []Replacement{{"nextjs", "Next.js", 2}, {"Next JS", "Next.js", 1}}
+	if !reflect.DeepEqual(hits, want) {
+		t.Errorf("replacements = %+v, want %+v", hits, want)
+	}
+}
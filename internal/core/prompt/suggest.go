@@ -0,0 +1,218 @@
+package prompt
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+)
+
+// Edit is a span of a transcription and the text the user replaced it with.
+type Edit struct {
+	From string
+	To   string
+}
+
+// maxDiffCells bounds the LCS table; longer texts are compared as a whole.
+const maxDiffCells = 4_000_000
+
+// Suggestions only come from short edits: longer ones are rewrites rather
+// than misrecognized terms.
+const (
+	maxSuggestFrom = 20
+	maxSuggestTo   = 40
+)
+
+// DiffEdits returns the replaced spans between original and corrected, in
+// order. Spans are widened to whole words where they touch an ASCII word or
+// katakana run (so "get state" -> "getState" is one edit, not " s" -> "S"),
+// and spans that end up adjacent are merged. Pure insertions and deletions
+// are included with an empty From or To.
+func DiffEdits(original, corrected string) []Edit {
+	a, b := []rune(original), []rune(corrected)
+
+	// Trim the common prefix and suffix to keep the table small.
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+
+	type hunk struct{ a0, a1, b0, b1 int }
+	var hunks []hunk
+	for _, h := range diffHunks(a[pre:len(a)-suf], b[pre:len(b)-suf]) {
+		hunks = append(hunks, hunk{h[0] + pre, h[1] + pre, h[2] + pre, h[3] + pre})
+	}
+
+	var merged []hunk
+	for _, h := range hunks {
+		// Widen over equal runes that would join the edited text into one word.
+		for h.a0 > 0 && h.b0 > 0 && (joinsBefore(a, h.a0, h.a1) || joinsBefore(b, h.b0, h.b1)) {
+			h.a0--
+			h.b0--
+		}
+		for h.a1 < len(a) && h.b1 < len(b) && (joinsAfter(a, h.a0, h.a1) || joinsAfter(b, h.b0, h.b1)) {
+			h.a1++
+			h.b1++
+		}
+		if n := len(merged); n > 0 && h.a0 <= merged[n-1].a1 {
+			merged[n-1].a1 = max(merged[n-1].a1, h.a1)
+			merged[n-1].b1 = max(merged[n-1].b1, h.b1)
+			continue
+		}
+		merged = append(merged, h)
+	}
+
+	edits := make([]Edit, 0, len(merged))
+	for _, h := range merged {
+		edits = append(edits, Edit{From: string(a[h.a0:h.a1]), To: string(b[h.b0:h.b1])})
+	}
+	return edits
+}
+
+// joinsBefore reports whether the rune before x[x0:x1] joins the edited text
+// into one word. For an empty span the edit joined it to x[x1].
+func joinsBefore(x []rune, x0, x1 int) bool {
+	if x0 < x1 {
+		return joins(x[x0-1], x[x0])
+	}
+	return x1 < len(x) && joins(x[x0-1], x[x1])
+}
+
+// joinsAfter is joinsBefore for the rune after x[x0:x1].
+func joinsAfter(x []rune, x0, x1 int) bool {
+	if x0 < x1 {
+		return joins(x[x1-1], x[x1])
+	}
+	return x0 > 0 && joins(x[x0-1], x[x1])
+}
+
+// diffHunks returns the [aStart, aEnd, bStart, bEnd) ranges where a and b
+// differ, from a longest common subsequence.
+func diffHunks(a, b []rune) [][4]int {
+	if len(a) == 0 && len(b) == 0 {
+		return nil
+	}
+	if len(a)*len(b) > maxDiffCells {
+		return [][4]int{{0, len(a), 0, len(b)}}
+	}
+
+	// lcs[i][j] is the LCS length of a[i:] and b[j:].
+	w := len(b) + 1
+	lcs := make([]int, (len(a)+1)*w)
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i*w+j] = lcs[(i+1)*w+j+1] + 1
+			} else {
+				lcs[i*w+j] = max(lcs[(i+1)*w+j], lcs[i*w+j+1])
+			}
+		}
+	}
+
+	var hunks [][4]int
+	open := false
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		if i < len(a) && j < len(b) && a[i] == b[j] {
+			open = false
+			i++
+			j++
+			continue
+		}
+		if !open {
+			hunks = append(hunks, [4]int{i, i, j, j})
+			open = true
+		}
+		if j == len(b) || i < len(a) && lcs[(i+1)*w+j] >= lcs[i*w+j+1] {
+			i++
+		} else {
+			j++
+		}
+		hunks[len(hunks)-1][1], hunks[len(hunks)-1][3] = i, j
+	}
+	return hunks
+}
+
+// Correction is a transcription and its user-corrected text.
+type Correction struct {
+	Original  string
+	Corrected string
+}
+
+// Suggestion is a proposed conversion entry learned from corrections.
+type Suggestion struct {
+	From    string
+	To      string
+	Count   int // times the edit was made
+	Entries int // corrections containing the edit
+	// Existing is the notation the dictionary already maps From to, if it
+	// differs from To.
+	Existing string
+}
+
+// Suggest collects replacement edits across corrections and proposes them as
+// conversion entries, most frequent first. Insertions, deletions, long
+// rewrites and edits the dictionary already contains are dropped; edits whose
+// reading the dictionary maps elsewhere are kept with Existing set.
+func Suggest(corrections []Correction, existing []Entry) []Suggestion {
+	known := make(map[string]string)
+	for _, e := range existing {
+		if !e.IsHint() && e.Pattern == nil {
+			if _, ok := known[e.Reading]; !ok {
+				known[e.Reading] = e.Notation
+			}
+		}
+	}
+
+	type key struct{ from, to string }
+	byEdit := make(map[key]*Suggestion)
+	var order []key
+	for _, c := range corrections {
+		seen := make(map[key]bool)
+		for _, e := range DiffEdits(c.Original, c.Corrected) {
+			from, to := strings.TrimSpace(e.From), strings.TrimSpace(e.To)
+			if !suggestible(from, to) || known[from] == to {
+				continue
+			}
+			k := key{from, to}
+			s, ok := byEdit[k]
+			if !ok {
+				s = &Suggestion{From: from, To: to, Existing: known[from]}
+				byEdit[k] = s
+				order = append(order, k)
+			}
+			s.Count++
+			if !seen[k] {
+				seen[k] = true
+				s.Entries++
+			}
+		}
+	}
+
+	out := make([]Suggestion, 0, len(order))
+	for _, k := range order {
+		out = append(out, *byEdit[k])
+	}
+	slices.SortStableFunc(out, func(a, b Suggestion) int {
+		if c := cmp.Compare(b.Count, a.Count); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.Entries, a.Entries)
+	})
+	return out
+}
+
+func suggestible(from, to string) bool {
+	if from == "" || to == "" || from == to {
+		return false
+	}
+	if len([]rune(from)) > maxSuggestFrom || len([]rune(to)) > maxSuggestTo {
+		return false
+	}
+	// Readings that would parse as a comment or regex cannot be written back.
+	return !strings.ContainsAny(from, "\t\n") && !strings.ContainsAny(to, "\t\n") &&
+		!strings.HasPrefix(from, "#") && !strings.HasPrefix(from, "/")
+}
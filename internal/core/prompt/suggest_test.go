@@ -0,0 +1,58 @@
+package prompt
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDiffEdits(t *testing.T) {
+	tests := []struct {
+		name      string
+		original  string
+		corrected string
+		want      []Edit
+	}{
+		{"identical", "同じです", "同じです", []Edit{}},
+		{"katakana to english", "クバネティスにデプロイ", "Kubernetesにデプロイ", []Edit{{"クバネティス", "Kubernetes"}}},
+		{"two edits", "ドッカーとクバネティス", "DockerとKubernetes", []Edit{{"ドッカー", "Docker"}, {"クバネティス", "Kubernetes"}}},
+		{"widened to the ascii word", "get state を呼ぶ", "getState を呼ぶ", []Edit{{"get state", "getState"}}},
+		{"joined words", "Type Script で書く", "TypeScript で書く", []Edit{{"Type Script", "TypeScript"}}},
+		{"case only", "github に push", "GitHub に push", []Edit{{"github", "GitHub"}}},
+		{"partial katakana", "リアクトフックス", "React Hooks", []Edit{{"リアクトフックス", "React Hooks"}}},
+		{"deletion", "えーと、ビルドする", "ビルドする", []Edit{{"えーと、", ""}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DiffEdits(tt.original, tt.corrected)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("DiffEdits(%q, %q) = %q, want %q", tt.original, tt.corrected, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	corrections := []Correction{
+		{"クバネティスとクバネティス", "KubernetesとKubernetes"},
+		{"クバネティスにドッカー", "KubernetesにDocker"},
+		{"ゴーで書く", "Golangで書く"},
+		{"ドッカーを使う", "Dockerを使う"},
+		{"ジェミニ", "Gemini"},
+		{"えーと、ビルド", "ビルド"},
+		{"全然違う長い文章をまるごと書き直した結果です", "Completely rewritten sentence that has nothing in common"},
+	}
+	existing := []Entry{
+		{Reading: "ジェミニ", Notation: "Gemini"},
+		{Reading: "ゴー", Notation: "Go"},
+	}
+
+	got := Suggest(corrections, existing)
+	want := []Suggestion{
+		{From: "クバネティス", To: "Kubernetes", Count: 3, Entries: 2},
+		{From: "ドッカー", To: "Docker", Count: 2, Entries: 2},
+		{From: "ゴー", To: "Golang", Count: 1, Entries: 1, Existing: "Go"},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Suggest() =\n%+v\nwant\n%+v", got, want)
+	}
+}
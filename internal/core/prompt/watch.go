@@ -0,0 +1,52 @@
+package prompt
+
+import (
+	"context"
+	"os"
+	"slices"
+	"time"
+)
+
+// WatchFiles polls paths every interval and calls onChange when the size or
+// modification time of any of them changes, including when one is created or
+// removed. It blocks until ctx is done. Polling avoids a file-notification
+// dependency and works with editors that replace the file on save.
+func WatchFiles(ctx context.Context, paths []string, interval time.Duration, onChange func()) {
+	last := statFiles(paths)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		cur := statFiles(paths)
+		if !slices.Equal(cur, last) {
+			last = cur
+			onChange()
+		}
+	}
+}
+
+type fileStamp struct {
+	exists  bool
+	size    int64
+	modTime time.Time
+}
+
+func statFiles(paths []string) []fileStamp {
+	stamps := make([]fileStamp, len(paths))
+	for i, p := range paths {
+		stamps[i] = statFile(p)
+	}
+	return stamps
+}
+
+func statFile(path string) fileStamp {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{exists: true, size: info.Size(), modTime: info.ModTime()}
+}
@@ -0,0 +1,56 @@
+package prompt
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "dictionary.txt")
+	profile := filepath.Join(dir, "react.txt")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes := make(chan struct{}, 10)
+	go WatchFiles(ctx, []string{path, profile}, 10*time.Millisecond, func() { changes <- struct{}{} })
+
+	wait := func(what string) {
+		t.Helper()
+		select {
+		case <-changes:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("no change reported after %s", what)
+		}
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if err := os.WriteFile(path, []byte("supabase\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	wait("create")
+
+	if err := os.WriteFile(path, []byte("supabase\nvercel\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	wait("edit")
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	wait("remove")
+
+	if err := os.WriteFile(profile, []byte("vite\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	wait("second file")
+
+	select {
+	case <-changes:
+		t.Error("unexpected change without an edit")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -7,23 +7,143 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 const (
-	DefaultHotkey               = "f15"
-	DefaultRestoreClipboard     = true
-	DefaultMaxRecordingDuration = 120
-	DefaultPushToTalk           = false
-	MinRecordingDuration        = 10
-	MaxRecordingDuration        = 300
+	DefaultHotkey                = "f15"
+	DefaultRestoreClipboard      = true
+	DefaultMaxRecordingDuration  = 120
+	DefaultPushToTalk            = false
+	DefaultBackend               = "gemini"
+	DefaultOpenAIBaseURL         = "https://api.openai.com/v1"
+	DefaultOpenAIModel           = "whisper-1"
+	DefaultTranscribeTimeoutSec  = 10.0
+	DefaultBreakerThreshold      = 3
+	DefaultBreakerCooldownSec    = 60.0
+	DefaultChunkSec              = 30.0
+	DefaultChunkConcurrency      = 3
+	DefaultChunkRetries          = 1
+	DefaultPromptTokenBudget     = 6000
+	DefaultLanguage              = "ja"
+	LanguageAuto                 = "auto"
+	DefaultContextMaxChars       = 2000
+	DefaultHandsFreeSilenceSec   = 1.5
+	DefaultHandsFreeMinSpeechSec = 0.5
+	DefaultAudioFormat           = "wav"
+	MinRecordingDuration         = 10
+	MaxRecordingDuration         = 300
 )
 
 // Settings holds user-configurable application settings.
 type Settings struct {
-	Hotkey               string `json:"hotkey"`
-	RestoreClipboard     bool   `json:"restore_clipboard"`
-	MaxRecordingDuration int    `json:"max_recording_duration"`
-	PushToTalk           bool   `json:"push_to_talk"`
+	Hotkey               string         `json:"hotkey"`
+	RestoreClipboard     bool           `json:"restore_clipboard"`
+	MaxRecordingDuration int            `json:"max_recording_duration"`
+	PushToTalk           bool           `json:"push_to_talk"`
+	Streaming            bool           `json:"streaming"` // paste partial text while transcribing
+	Backend              string         `json:"backend"`
+	OpenAI               OpenAISettings `json:"openai"`
+	Gemini               GeminiSettings `json:"gemini"`
+	TranscribeTimeoutSec float64        `json:"transcribe_timeout_sec"`
+	FallbackChain        []FallbackStep `json:"fallback_chain,omitempty"`
+	Chunking             ChunkSettings  `json:"chunking"`
+	HandsFree            HandsFree      `json:"hands_free"`
+	DictionaryProfile    string         `json:"dictionary_profile,omitempty"` // overlay from ~/.voicecoding/dictionaries/; empty = base only
+	PromptTokenBudget    int            `json:"prompt_token_budget"`          // dict lint fails above this estimated system prompt size
+	Language             string         `json:"language"`                     // spoken language: ja, en or auto
+	TranslateTo          string         `json:"translate_to,omitempty"`       // language code to translate into before pasting; empty = off
+	Modes                []Mode         `json:"modes,omitempty"`
+	Mode                 string         `json:"mode,omitempty"`           // mode of Hotkey and the transcribe command; empty = built-in prompts
+	ContextSource        string         `json:"context_source,omitempty"` // reference context sent with the audio: clipboard or selection; empty = off
+	ContextMaxChars      int            `json:"context_max_chars"`        // reference context is truncated to this many characters
+	UploadFormat         string         `json:"upload_format"`            // audio encoding sent to the backend: wav or flac
+	HistoryFormat        string         `json:"history_format"`           // audio encoding of history recordings: wav or flac
+}
+
+// Mode is a named set of prompts and generation parameters selectable per
+// hotkey or from the tray menu, e.g. verbatim dictation or commit messages.
+// Empty fields keep the built-in defaults. The user dictionary is merged into
+// the mode's system prompt like the default one.
+type Mode struct {
+	Name             string   `json:"name"`
+	Hotkey           string   `json:"hotkey,omitempty"`             // starts recording in this mode
+	SystemPrompt     string   `json:"system_prompt,omitempty"`      // replaces prompt.SystemPrompt
+	SystemPromptFile string   `json:"system_prompt_file,omitempty"` // used when SystemPrompt is empty; relative to ~/.voicecoding
+	TranscribePrompt string   `json:"transcribe_prompt,omitempty"`  // replaces prompt.TranscribePrompt
+	Temperature      *float64 `json:"temperature,omitempty"`        // default 0
+	ThinkingLevel    string   `json:"thinking_level,omitempty"`     // minimal/low/medium/high
+}
+
+// FindMode returns the mode called name.
+func (s *Settings) FindMode(name string) (Mode, bool) {
+	for _, m := range s.Modes {
+		if m.Name == name {
+			return m, true
+		}
+	}
+	return Mode{}, false
+}
+
+// LoadSystemPrompt returns the mode's system prompt, reading
+// SystemPromptFile if needed. It returns "" when the mode keeps the default.
+func (m Mode) LoadSystemPrompt() (string, error) {
+	if m.SystemPrompt != "" || m.SystemPromptFile == "" {
+		return m.SystemPrompt, nil
+	}
+	path := m.SystemPromptFile
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(settingsPathFunc()), path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("reading system prompt of mode %s: %w", m.Name, err)
+	}
+	return string(data), nil
+}
+
+// ChunkSettings controls how long recordings are split into parallel requests.
+type ChunkSettings struct {
+	MaxChunkSec float64 `json:"max_chunk_sec"` // 0 disables chunking
+	Concurrency int     `json:"concurrency"`
+	Retries     int     `json:"retries"`
+}
+
+// HandsFree stops a toggle-mode recording automatically once the speaker
+// has been silent for SilenceSec after at least MinSpeechSec of speech. It is
+// ignored in push-to-talk mode.
+type HandsFree struct {
+	Enabled      bool    `json:"enabled"`
+	SilenceSec   float64 `json:"silence_sec"`
+	MinSpeechSec float64 `json:"min_speech_sec"`
+}
+
+// FallbackStep is one entry of the ordered transcription fallback chain.
+// Steps are tried in order; each attempt gets at most TimeoutSec, capped by
+// the remaining TranscribeTimeoutSec budget shared by the whole chain.
+type FallbackStep struct {
+	Backend       string  `json:"backend,omitempty"` // empty uses Settings.Backend
+	Model         string  `json:"model,omitempty"`   // empty uses the backend's model
+	TimeoutSec    float64 `json:"timeout_sec,omitempty"`
+	Retries       int     `json:"retries,omitempty"`
+	ThinkingLevel string  `json:"thinking_level,omitempty"`
+}
+
+// OpenAISettings configures the OpenAI-compatible /v1/audio/transcriptions backend.
+type OpenAISettings struct {
+	BaseURL  string `json:"base_url"`
+	Model    string `json:"model"`
+	APIKey   string `json:"api_key,omitempty"`
+	Language string `json:"language,omitempty"`
+}
+
+// GeminiSettings tunes the Gemini backend's hedged requests and per-model
+// circuit breakers. The VOICECODE_HEDGE_DELAY, VOICECODE_BREAKER_THRESHOLD
+// and VOICECODE_BREAKER_COOLDOWN environment variables override them.
+type GeminiSettings struct {
+	HedgeDelaySec      float64 `json:"hedge_delay_sec"` // 0 disables hedged requests
+	BreakerThreshold   int     `json:"breaker_threshold"`
+	BreakerCooldownSec float64 `json:"breaker_cooldown_sec"`
 }
 
 // Default returns a Settings with default values.
@@ -33,7 +153,54 @@ func Default() *Settings {
 		RestoreClipboard:     DefaultRestoreClipboard,
 		MaxRecordingDuration: DefaultMaxRecordingDuration,
 		PushToTalk:           DefaultPushToTalk,
+		Backend:              DefaultBackend,
+		OpenAI: OpenAISettings{
+			BaseURL: DefaultOpenAIBaseURL,
+			Model:   DefaultOpenAIModel,
+		},
+		Gemini: GeminiSettings{
+			BreakerThreshold:   DefaultBreakerThreshold,
+			BreakerCooldownSec: DefaultBreakerCooldownSec,
+		},
+		TranscribeTimeoutSec: DefaultTranscribeTimeoutSec,
+		Chunking: ChunkSettings{
+			MaxChunkSec: DefaultChunkSec,
+			Concurrency: DefaultChunkConcurrency,
+			Retries:     DefaultChunkRetries,
+		},
+		HandsFree: HandsFree{
+			SilenceSec:   DefaultHandsFreeSilenceSec,
+			MinSpeechSec: DefaultHandsFreeMinSpeechSec,
+		},
+		PromptTokenBudget: DefaultPromptTokenBudget,
+		Language:          DefaultLanguage,
+		ContextMaxChars:   DefaultContextMaxChars,
+		UploadFormat:      DefaultAudioFormat,
+		HistoryFormat:     DefaultAudioFormat,
+	}
+}
+
+// languages are the accepted values of Settings.Language.
+var languages = map[string]bool{"ja": true, "en": true, LanguageAuto: true}
+
+// audioFormats are the accepted values of Settings.UploadFormat and
+// Settings.HistoryFormat.
+var audioFormats = map[string]bool{"wav": true, "flac": true}
+
+// Reference context sources accepted in Settings.ContextSource.
+const (
+	ContextClipboard = "clipboard"
+	ContextSelection = "selection" // Linux primary selection
+)
+
+// TranslationTarget returns the language to translate transcriptions into,
+// or "" when no translation is needed because the spoken language already
+// is the target.
+func (s *Settings) TranslationTarget() string {
+	if s.TranslateTo == "" || s.TranslateTo == s.Language {
+		return ""
 	}
+	return s.TranslateTo
 }
 
 // settingsPathFunc is overridable for testing.
@@ -64,7 +231,9 @@ func Load() (*Settings, error) {
 		return s, nil
 	}
 
-	var s Settings
+	// Chunking keys missing from the file keep their defaults, while an
+	// explicit "max_chunk_sec": 0 or "retries": 0 still decodes as 0.
+	s := Settings{Chunking: ChunkSettings{MaxChunkSec: DefaultChunkSec, Retries: DefaultChunkRetries}}
 	if err := json.Unmarshal(data, &s); err != nil {
 		return nil, fmt.Errorf("parsing settings: %w", err)
 	}
@@ -92,8 +261,85 @@ func (s *Settings) Save() error {
 }
 
 // clampDefaults adjusts out-of-range values to the nearest valid boundary
-// and logs warnings for each clamped field.
+// and logs warnings for each clamped field. Missing fields added in later
+// versions are filled with their defaults.
 func (s *Settings) clampDefaults() {
+	if s.Backend == "" {
+		s.Backend = DefaultBackend
+	}
+	if s.OpenAI.BaseURL == "" {
+		s.OpenAI.BaseURL = DefaultOpenAIBaseURL
+	}
+	if s.OpenAI.Model == "" {
+		s.OpenAI.Model = DefaultOpenAIModel
+	}
+	if s.TranscribeTimeoutSec <= 0 {
+		s.TranscribeTimeoutSec = DefaultTranscribeTimeoutSec
+	}
+	if s.Gemini.HedgeDelaySec < 0 {
+		log.Printf("[Settings] gemini.hedge_delay_sec %g is negative, disabling hedged requests", s.Gemini.HedgeDelaySec)
+		s.Gemini.HedgeDelaySec = 0
+	}
+	if s.Gemini.BreakerThreshold <= 0 {
+		s.Gemini.BreakerThreshold = DefaultBreakerThreshold
+	}
+	if s.Gemini.BreakerCooldownSec <= 0 {
+		s.Gemini.BreakerCooldownSec = DefaultBreakerCooldownSec
+	}
+	if s.Chunking.MaxChunkSec < 0 {
+		log.Printf("[Settings] chunking.max_chunk_sec %g is negative, disabling chunking", s.Chunking.MaxChunkSec)
+		s.Chunking.MaxChunkSec = 0
+	}
+	if s.Chunking.Concurrency <= 0 {
+		s.Chunking.Concurrency = DefaultChunkConcurrency
+	}
+	if s.HandsFree.SilenceSec <= 0 {
+		s.HandsFree.SilenceSec = DefaultHandsFreeSilenceSec
+	}
+	if s.HandsFree.MinSpeechSec <= 0 {
+		s.HandsFree.MinSpeechSec = DefaultHandsFreeMinSpeechSec
+	}
+	if s.PromptTokenBudget <= 0 {
+		s.PromptTokenBudget = DefaultPromptTokenBudget
+	}
+	s.Language = strings.ToLower(strings.TrimSpace(s.Language))
+	if s.Language == "" {
+		s.Language = DefaultLanguage
+	} else if !languages[s.Language] {
+		log.Printf("[Settings] language %q is not one of ja, en, auto, using %s", s.Language, DefaultLanguage)
+		s.Language = DefaultLanguage
+	}
+	s.TranslateTo = strings.ToLower(strings.TrimSpace(s.TranslateTo))
+	if s.TranslateTo == LanguageAuto {
+		log.Printf("[Settings] translate_to cannot be auto, disabling translation")
+		s.TranslateTo = ""
+	}
+	s.ContextSource = strings.ToLower(strings.TrimSpace(s.ContextSource))
+	if s.ContextSource != "" && s.ContextSource != ContextClipboard && s.ContextSource != ContextSelection {
+		log.Printf("[Settings] context_source %q is not one of clipboard, selection, disabling reference context", s.ContextSource)
+		s.ContextSource = ""
+	}
+	if s.ContextMaxChars <= 0 {
+		s.ContextMaxChars = DefaultContextMaxChars
+	}
+	s.UploadFormat = clampAudioFormat("upload_format", s.UploadFormat)
+	s.HistoryFormat = clampAudioFormat("history_format", s.HistoryFormat)
+	if s.Chunking.Retries < 0 {
+		log.Printf("[Settings] chunking.retries %d is negative, clamping to 0", s.Chunking.Retries)
+		s.Chunking.Retries = 0
+	}
+	for i := range s.FallbackChain {
+		step := &s.FallbackChain[i]
+		if step.Retries < 0 {
+			log.Printf("[Settings] fallback_chain[%d].retries %d is negative, clamping to 0", i, step.Retries)
+			step.Retries = 0
+		}
+		if step.TimeoutSec < 0 {
+			log.Printf("[Settings] fallback_chain[%d].timeout_sec %.1f is negative, clamping to 0", i, step.TimeoutSec)
+			step.TimeoutSec = 0
+		}
+	}
+	s.clampModes()
 	if s.MaxRecordingDuration < MinRecordingDuration {
 		log.Printf("[Settings] max_recording_duration %d is below minimum %d, clamping", s.MaxRecordingDuration, MinRecordingDuration)
 		s.MaxRecordingDuration = MinRecordingDuration
@@ -104,6 +350,35 @@ func (s *Settings) clampDefaults() {
 	}
 }
 
+// clampModes drops modes that cannot be selected by name and out-of-range
+// temperatures, and resets Mode when it names no defined mode.
+func (s *Settings) clampModes() {
+	seen := make(map[string]bool)
+	modes := s.Modes[:0]
+	for i, m := range s.Modes {
+		m.Name = strings.TrimSpace(m.Name)
+		switch {
+		case m.Name == "":
+			log.Printf("[Settings] modes[%d] has no name, ignoring", i)
+			continue
+		case seen[m.Name]:
+			log.Printf("[Settings] modes[%d] duplicates mode %q, ignoring", i, m.Name)
+			continue
+		}
+		seen[m.Name] = true
+		if m.Temperature != nil && (*m.Temperature < 0 || *m.Temperature > 2) {
+			log.Printf("[Settings] modes[%d].temperature %.2f is outside 0-2, using the default", i, *m.Temperature)
+			m.Temperature = nil
+		}
+		modes = append(modes, m)
+	}
+	s.Modes = modes
+	if s.Mode != "" && !seen[s.Mode] {
+		log.Printf("[Settings] mode %q is not defined in modes, using the default", s.Mode)
+		s.Mode = ""
+	}
+}
+
 // Validate checks that settings values are within acceptable ranges.
 func (s *Settings) Validate() error {
 	if s.MaxRecordingDuration < MinRecordingDuration || s.MaxRecordingDuration > MaxRecordingDuration {
@@ -114,3 +389,17 @@ func (s *Settings) Validate() error {
 	}
 	return nil
 }
+
+// clampAudioFormat normalizes an audio format setting, falling back to
+// DefaultAudioFormat when it is empty or unknown.
+func clampAudioFormat(key, format string) string {
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format == "" {
+		return DefaultAudioFormat
+	}
+	if !audioFormats[format] {
+		log.Printf("[Settings] %s %q is not one of wav, flac, using %s", key, format, DefaultAudioFormat)
+		return DefaultAudioFormat
+	}
+	return format
+}
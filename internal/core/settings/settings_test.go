@@ -169,3 +169,342 @@ func TestValidateOutOfRange(t *testing.T) {
 		})
 	}
 }
+
+func TestLoadFallbackChain(t *testing.T) {
+	path := withTempSettingsPath(t)
+	os.MkdirAll(filepath.Dir(path), 0o755)
+	data := `{
+  "hotkey": "f15",
+  "max_recording_duration": 60,
+  "fallback_chain": [
+    {"model": "gemini-3-flash-preview", "timeout_sec": 4, "retries": 1, "thinking_level": "minimal"},
+    {"backend": "openai", "timeout_sec": -1, "retries": -2}
+  ]
+}`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+
+	s, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if s.TranscribeTimeoutSec != DefaultTranscribeTimeoutSec {
+		t.Errorf("TranscribeTimeoutSec = %v, want default %v", s.TranscribeTimeoutSec, DefaultTranscribeTimeoutSec)
+	}
+	if len(s.FallbackChain) != 2 {
+		t.Fatalf("FallbackChain len = %d, want 2", len(s.FallbackChain))
+	}
+	first := s.FallbackChain[0]
+	if first.Model != "gemini-3-flash-preview" || first.TimeoutSec != 4 || first.Retries != 1 || first.ThinkingLevel != "minimal" {
+		t.Errorf("first step = %+v", first)
+	}
+	second := s.FallbackChain[1]
+	if second.Backend != "openai" || second.TimeoutSec != 0 || second.Retries != 0 {
+		t.Errorf("negative values should be clamped, got %+v", second)
+	}
+}
+
+func TestLoadChunkingDefaults(t *testing.T) {
+	path := withTempSettingsPath(t)
+	os.MkdirAll(filepath.Dir(path), 0o755)
+	if err := os.WriteFile(path, []byte(`{"hotkey": "f15", "max_recording_duration": 60, "chunking": {"retries": -1}}`), 0o644); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+
+	s, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if s.Chunking.MaxChunkSec != DefaultChunkSec || s.Chunking.Concurrency != DefaultChunkConcurrency {
+		t.Errorf("Chunking = %+v, want defaults for missing fields", s.Chunking)
+	}
+	if s.Chunking.Retries != 0 {
+		t.Errorf("Chunking.Retries = %d, want negative clamped to 0", s.Chunking.Retries)
+	}
+	if d := Default(); d.Chunking.MaxChunkSec != DefaultChunkSec {
+		t.Errorf("Default().Chunking.MaxChunkSec = %v, want %v", d.Chunking.MaxChunkSec, DefaultChunkSec)
+	}
+}
+
+func TestLoadWithoutChunkingKey(t *testing.T) {
+	path := withTempSettingsPath(t)
+	os.MkdirAll(filepath.Dir(path), 0o755)
+	// A settings.json saved before chunking existed.
+	if err := os.WriteFile(path, []byte(`{"hotkey": "f15", "max_recording_duration": 60}`), 0o644); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+
+	s, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	want := ChunkSettings{MaxChunkSec: DefaultChunkSec, Concurrency: DefaultChunkConcurrency, Retries: DefaultChunkRetries}
+	if s.Chunking != want {
+		t.Errorf("Chunking = %+v, want %+v", s.Chunking, want)
+	}
+}
+
+func TestLoadChunkingMaxChunkSec(t *testing.T) {
+	tests := []struct {
+		json string
+		want float64
+	}{
+		{`{"chunking": {"max_chunk_sec": 0}}`, 0},
+		{`{"chunking": {"max_chunk_sec": 45}}`, 45},
+		{`{"chunking": {"max_chunk_sec": -5}}`, 0},
+		{`{"chunking": {"retries": 2}}`, DefaultChunkSec},
+	}
+	for _, tt := range tests {
+		path := withTempSettingsPath(t)
+		os.MkdirAll(filepath.Dir(path), 0o755)
+		if err := os.WriteFile(path, []byte(tt.json), 0o644); err != nil {
+			t.Fatalf("WriteFile error: %v", err)
+		}
+		s, err := Load()
+		if err != nil {
+			t.Fatalf("Load() error: %v", err)
+		}
+		if s.Chunking.MaxChunkSec != tt.want {
+			t.Errorf("%s: MaxChunkSec = %v, want %v", tt.json, s.Chunking.MaxChunkSec, tt.want)
+		}
+	}
+}
+
+func TestLoadHandsFreeDefaults(t *testing.T) {
+	path := withTempSettingsPath(t)
+	os.MkdirAll(filepath.Dir(path), 0o755)
+	if err := os.WriteFile(path, []byte(`{"hands_free": {"enabled": true, "silence_sec": 2.5, "min_speech_sec": -1}}`), 0o644); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+
+	s, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	want := HandsFree{Enabled: true, SilenceSec: 2.5, MinSpeechSec: DefaultHandsFreeMinSpeechSec}
+	if s.HandsFree != want {
+		t.Errorf("HandsFree = %+v, want %+v", s.HandsFree, want)
+	}
+}
+
+func TestLoadGemini(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want GeminiSettings
+	}{
+		{
+			name: "missing key keeps the defaults",
+			json: `{"hotkey": "f15"}`,
+			want: GeminiSettings{BreakerThreshold: DefaultBreakerThreshold, BreakerCooldownSec: DefaultBreakerCooldownSec},
+		},
+		{
+			name: "configured",
+			json: `{"gemini": {"hedge_delay_sec": 1.5, "breaker_threshold": 5, "breaker_cooldown_sec": 120}}`,
+			want: GeminiSettings{HedgeDelaySec: 1.5, BreakerThreshold: 5, BreakerCooldownSec: 120},
+		},
+		{
+			name: "out of range values are clamped",
+			json: `{"gemini": {"hedge_delay_sec": -1, "breaker_threshold": 0, "breaker_cooldown_sec": -5}}`,
+			want: GeminiSettings{BreakerThreshold: DefaultBreakerThreshold, BreakerCooldownSec: DefaultBreakerCooldownSec},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := withTempSettingsPath(t)
+			os.MkdirAll(filepath.Dir(path), 0o755)
+			if err := os.WriteFile(path, []byte(tt.json), 0o644); err != nil {
+				t.Fatalf("WriteFile error: %v", err)
+			}
+
+			s, err := Load()
+			if err != nil {
+				t.Fatalf("Load() error: %v", err)
+			}
+			if s.Gemini != tt.want {
+				t.Errorf("Gemini = %+v, want %+v", s.Gemini, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadPromptTokenBudgetDefault(t *testing.T) {
+	path := withTempSettingsPath(t)
+	os.MkdirAll(filepath.Dir(path), 0o755)
+	if err := os.WriteFile(path, []byte(`{"hotkey": "f15", "max_recording_duration": 60}`), 0o644); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+
+	s, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if s.PromptTokenBudget != DefaultPromptTokenBudget {
+		t.Errorf("PromptTokenBudget = %d, want default %d", s.PromptTokenBudget, DefaultPromptTokenBudget)
+	}
+}
+
+func TestLoadModes(t *testing.T) {
+	path := withTempSettingsPath(t)
+	os.MkdirAll(filepath.Dir(path), 0o755)
+	data := `{
+  "hotkey": "f15",
+  "max_recording_duration": 60,
+  "mode": "commit",
+  "modes": [
+    {"name": "verbatim", "system_prompt": "verbatim", "temperature": 0.2},
+    {"name": "commit", "hotkey": "f16", "system_prompt_file": "prompts/commit.md", "temperature": 3},
+    {"name": ""},
+    {"name": "verbatim"}
+  ]
+}`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+	os.MkdirAll(filepath.Join(filepath.Dir(path), "prompts"), 0o755)
+	if err := os.WriteFile(filepath.Join(filepath.Dir(path), "prompts", "commit.md"), []byte("commit prompt"), 0o644); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+
+	s, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if len(s.Modes) != 2 {
+		t.Fatalf("Modes = %+v, want unnamed and duplicate modes dropped", s.Modes)
+	}
+	if s.Mode != "commit" {
+		t.Errorf("Mode = %q, want commit", s.Mode)
+	}
+	verbatim, ok := s.FindMode("verbatim")
+	if !ok || verbatim.Temperature == nil || *verbatim.Temperature != 0.2 {
+		t.Errorf("FindMode(verbatim) = %+v, %v", verbatim, ok)
+	}
+	commit, _ := s.FindMode("commit")
+	if commit.Temperature != nil {
+		t.Errorf("commit.Temperature = %v, want out-of-range value dropped", *commit.Temperature)
+	}
+	if p, err := commit.LoadSystemPrompt(); err != nil || p != "commit prompt" {
+		t.Errorf("LoadSystemPrompt() = %q, %v; want the file relative to the settings directory", p, err)
+	}
+	if p, err := verbatim.LoadSystemPrompt(); err != nil || p != "verbatim" {
+		t.Errorf("LoadSystemPrompt() = %q, %v", p, err)
+	}
+	if _, ok := s.FindMode("missing"); ok {
+		t.Error("FindMode(missing) should fail")
+	}
+}
+
+func TestLoadResetsUnknownMode(t *testing.T) {
+	path := withTempSettingsPath(t)
+	os.MkdirAll(filepath.Dir(path), 0o755)
+	if err := os.WriteFile(path, []byte(`{"hotkey": "f15", "max_recording_duration": 60, "mode": "gone"}`), 0o644); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+
+	s, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if s.Mode != "" {
+		t.Errorf("Mode = %q, want reset to the default", s.Mode)
+	}
+}
+
+func TestLoadLanguage(t *testing.T) {
+	tests := []struct {
+		name       string
+		json       string
+		language   string
+		translate  string
+		wantTarget string
+	}{
+		{"default", `{}`, "ja", "", ""},
+		{"translate", `{"language": "ja", "translate_to": "EN"}`, "ja", "en", "en"},
+		{"same language", `{"language": "en", "translate_to": "en"}`, "en", "en", ""},
+		{"auto", `{"language": "auto", "translate_to": "en"}`, "auto", "en", "en"},
+		{"unknown language", `{"language": "klingon"}`, "ja", "", ""},
+		{"auto target", `{"translate_to": "auto"}`, "ja", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := withTempSettingsPath(t)
+			os.MkdirAll(filepath.Dir(path), 0o755)
+			if err := os.WriteFile(path, []byte(tt.json), 0o644); err != nil {
+				t.Fatalf("WriteFile error: %v", err)
+			}
+			s, err := Load()
+			if err != nil {
+				t.Fatalf("Load() error: %v", err)
+			}
+			if s.Language != tt.language || s.TranslateTo != tt.translate {
+				t.Errorf("Language, TranslateTo = %q, %q; want %q, %q", s.Language, s.TranslateTo, tt.language, tt.translate)
+			}
+			if got := s.TranslationTarget(); got != tt.wantTarget {
+				t.Errorf("TranslationTarget() = %q, want %q", got, tt.wantTarget)
+			}
+		})
+	}
+}
+
+func TestLoadAudioFormats(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		upload  string
+		history string
+	}{
+		{"default", `{}`, "wav", "wav"},
+		{"flac", `{"upload_format": "FLAC", "history_format": "flac"}`, "flac", "flac"},
+		{"mixed", `{"upload_format": "flac"}`, "flac", "wav"},
+		{"unknown", `{"upload_format": "mp3", "history_format": "ogg"}`, "wav", "wav"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := withTempSettingsPath(t)
+			os.MkdirAll(filepath.Dir(path), 0o755)
+			if err := os.WriteFile(path, []byte(tt.json), 0o644); err != nil {
+				t.Fatalf("WriteFile error: %v", err)
+			}
+			s, err := Load()
+			if err != nil {
+				t.Fatalf("Load() error: %v", err)
+			}
+			if s.UploadFormat != tt.upload || s.HistoryFormat != tt.history {
+				t.Errorf("UploadFormat, HistoryFormat = %q, %q; want %q, %q", s.UploadFormat, s.HistoryFormat, tt.upload, tt.history)
+			}
+		})
+	}
+}
+
+func TestLoadContextSource(t *testing.T) {
+	tests := []struct {
+		name     string
+		json     string
+		source   string
+		maxChars int
+	}{
+		{"default", `{}`, "", DefaultContextMaxChars},
+		{"clipboard", `{"context_source": "Clipboard", "context_max_chars": 500}`, "clipboard", 500},
+		{"selection", `{"context_source": "selection"}`, "selection", DefaultContextMaxChars},
+		{"unknown source", `{"context_source": "screen"}`, "", DefaultContextMaxChars},
+		{"negative max", `{"context_source": "clipboard", "context_max_chars": -1}`, "clipboard", DefaultContextMaxChars},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := withTempSettingsPath(t)
+			os.MkdirAll(filepath.Dir(path), 0o755)
+			if err := os.WriteFile(path, []byte(tt.json), 0o644); err != nil {
+				t.Fatalf("WriteFile error: %v", err)
+			}
+			s, err := Load()
+			if err != nil {
+				t.Fatalf("Load() error: %v", err)
+			}
+			if s.ContextSource != tt.source || s.ContextMaxChars != tt.maxChars {
+				t.Errorf("ContextSource, ContextMaxChars = %q, %d; want %q, %d", s.ContextSource, s.ContextMaxChars, tt.source, tt.maxChars)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package transcriber
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/noricha-vr/voicecode/internal/core/audio"
+	"github.com/noricha-vr/voicecode/internal/core/settings"
+	"github.com/noricha-vr/voicecode/internal/core/trace"
+)
+
+// DefaultMIMEType is the MIME type of audio produced by audio.WriteWAV.
+const DefaultMIMEType = "audio/wav"
+
+// Options controls a single transcription request.
+type Options struct {
+	// MIMEType of the audio payload. Empty means DefaultMIMEType.
+	MIMEType string
+	// Model pins the request to a specific model when the backend supports it.
+	// A pinned request is a single attempt: the backend skips its own
+	// retries and model fallback so callers such as Chain own that policy.
+	Model string
+	// ThinkingLevel overrides the backend's thinking level (minimal/low/medium/high)
+	// when supported. Empty keeps the backend default.
+	ThinkingLevel string
+	// Mode selects a settings.Mode by name when the backend supports modes.
+	// Empty, or a name the backend does not know, uses the default prompts.
+	Mode string
+	// Context is reference text the user is looking at (e.g. the clipboard),
+	// already redacted and truncated. Backends that support it show it to
+	// the model to spell identifiers; it is never transcribed.
+	Context string
+}
+
+type modeKey struct{}
+
+// WithMode returns a context whose file and sample helpers (TranscribeFile,
+// TranscribeFileStream, TranscribeSamples) request the named mode.
+func WithMode(ctx context.Context, mode string) context.Context {
+	return context.WithValue(ctx, modeKey{}, mode)
+}
+
+// ModeFromContext returns the mode set by WithMode, or "".
+func ModeFromContext(ctx context.Context) string {
+	mode, _ := ctx.Value(modeKey{}).(string)
+	return mode
+}
+
+type referenceContextKey struct{}
+
+// WithReferenceContext returns a context whose file and sample helpers send
+// text as Options.Context.
+func WithReferenceContext(ctx context.Context, text string) context.Context {
+	return context.WithValue(ctx, referenceContextKey{}, text)
+}
+
+// ReferenceContextFromContext returns the text set by WithReferenceContext,
+// or "".
+func ReferenceContextFromContext(ctx context.Context) string {
+	text, _ := ctx.Value(referenceContextKey{}).(string)
+	return text
+}
+
+// requestOptions returns the Options the helpers send for ctx.
+func requestOptions(ctx context.Context) Options {
+	return Options{
+		MIMEType: DefaultMIMEType,
+		Mode:     ModeFromContext(ctx),
+		Context:  ReferenceContextFromContext(ctx),
+	}
+}
+
+// Backend transcribes audio with a specific speech-to-text engine.
+type Backend interface {
+	// Transcribe converts the audio payload into text.
+	Transcribe(ctx context.Context, audio []byte, opts Options) (string, error)
+	// Name returns the registry name of the backend (e.g. "gemini").
+	Name() string
+	// ModelName returns the model currently used by the backend.
+	ModelName() string
+	// Close releases resources held by the backend.
+	Close() error
+}
+
+// Factory creates a Backend from user settings.
+type Factory func(ctx context.Context, cfg *settings.Settings) (Backend, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Factory)
+)
+
+// Register makes a backend factory available under name.
+// It panics if name is empty or already registered.
+func Register(name string, factory Factory) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || factory == nil {
+		panic("transcriber: Register requires a name and a factory")
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, dup := registry[name]; dup {
+		panic("transcriber: Register called twice for backend " + name)
+	}
+	registry[name] = factory
+}
+
+// Backends returns the sorted names of registered backends.
+func Backends() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewBackend creates the backend selected by cfg.Backend.
+// An empty name selects settings.DefaultBackend. A non-empty
+// cfg.FallbackChain wraps the configured steps in a Chain.
+func NewBackend(ctx context.Context, cfg *settings.Settings) (Backend, error) {
+	if cfg == nil {
+		cfg = settings.Default()
+	}
+	if len(cfg.FallbackChain) > 0 {
+		return NewChain(ctx, cfg)
+	}
+
+	name := strings.ToLower(strings.TrimSpace(cfg.Backend))
+	if name == "" {
+		name = settings.DefaultBackend
+	}
+	factory, err := lookupFactory(name)
+	if err != nil {
+		return nil, err
+	}
+	return factory(ctx, cfg)
+}
+
+func lookupFactory(name string) (Factory, error) {
+	registryMu.RLock()
+	factory, ok := registry[name]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown transcription backend %q (available: %s)", name, strings.Join(Backends(), ", "))
+	}
+	return factory, nil
+}
+
+// TranscribeFile reads an audio file and transcribes it with b.
+// It returns the text and the elapsed seconds spent in the backend.
+func TranscribeFile(ctx context.Context, b Backend, path string) (string, float64, error) {
+	stepper := trace.FromContext(ctx)
+
+	readDone := stepper.Step("os.ReadFile(audio)")
+	audioData, err := os.ReadFile(path)
+	readDone(err)
+	if err != nil {
+		return "", 0, fmt.Errorf("read audio file: %w", err)
+	}
+
+	start := time.Now()
+	text, err := b.Transcribe(ctx, audioData, fileOptions(ctx, path))
+	return text, time.Since(start).Seconds(), err
+}
+
+// fileOptions returns the request options for the audio file at path, with
+// the MIME type taken from its extension.
+func fileOptions(ctx context.Context, path string) Options {
+	opts := requestOptions(ctx)
+	if f, ok := audio.FormatOf(path); ok {
+		opts.MIMEType = f.MIMEType()
+	}
+	return opts
+}
+
+func (o Options) mimeType() string {
+	if o.MIMEType == "" {
+		return DefaultMIMEType
+	}
+	return o.MIMEType
+}
@@ -0,0 +1,166 @@
+package transcriber
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/noricha-vr/voicecode/internal/core/settings"
+)
+
+type stubBackend struct {
+	text     string
+	gotAudio []byte
+	gotOpts  Options
+}
+
+func (s *stubBackend) Transcribe(ctx context.Context, audio []byte, opts Options) (string, error) {
+	s.gotAudio = audio
+	s.gotOpts = opts
+	return s.text, nil
+}
+func (s *stubBackend) Name() string      { return "stub" }
+func (s *stubBackend) ModelName() string { return "stub-model" }
+func (s *stubBackend) Close() error      { return nil }
+
+// unregister removes a backend registered by a test so the package's tests
+// can run more than once in one process (go test -count=2).
+func unregister(name string) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	delete(registry, strings.ToLower(strings.TrimSpace(name)))
+}
+
+func TestNewBackendSelectsRegisteredFactory(t *testing.T) {
+	stub := &stubBackend{text: "hello"}
+	Register("test-select", func(ctx context.Context, cfg *settings.Settings) (Backend, error) {
+		return stub, nil
+	})
+	t.Cleanup(func() { unregister("test-select") })
+
+	cfg := settings.Default()
+	cfg.Backend = " Test-Select "
+	b, err := NewBackend(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("NewBackend() error: %v", err)
+	}
+	if b != stub {
+		t.Errorf("NewBackend() returned %v, want registered stub", b)
+	}
+}
+
+func TestNewBackendUnknownName(t *testing.T) {
+	cfg := settings.Default()
+	cfg.Backend = "does-not-exist"
+	_, err := NewBackend(context.Background(), cfg)
+	if err == nil {
+		t.Fatal("NewBackend() should fail for unknown backend")
+	}
+	if !strings.Contains(err.Error(), settings.DefaultBackend) {
+		t.Errorf("error should list available backends, got %q", err)
+	}
+}
+
+func TestRegisterDuplicatePanics(t *testing.T) {
+	factory := func(ctx context.Context, cfg *settings.Settings) (Backend, error) { return nil, nil }
+	Register("test-duplicate", factory)
+	t.Cleanup(func() { unregister("test-duplicate") })
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Register() should panic on duplicate name")
+		}
+	}()
+	Register("test-duplicate", factory)
+}
+
+func TestBackendsIncludesGemini(t *testing.T) {
+	found := false
+	for _, name := range Backends() {
+		if name == settings.DefaultBackend {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Backends() = %v, want to include %q", Backends(), settings.DefaultBackend)
+	}
+}
+
+func TestTranscribeFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audio.wav")
+	if err := os.WriteFile(path, []byte("RIFF"), 0o644); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+
+	stub := &stubBackend{text: "こんにちは"}
+	text, elapsed, err := TranscribeFile(context.Background(), stub, path)
+	if err != nil {
+		t.Fatalf("TranscribeFile() error: %v", err)
+	}
+	if text != "こんにちは" {
+		t.Errorf("text = %q, want %q", text, "こんにちは")
+	}
+	if elapsed < 0 {
+		t.Errorf("elapsed = %v, want >= 0", elapsed)
+	}
+	if string(stub.gotAudio) != "RIFF" {
+		t.Errorf("backend received %q, want file contents", stub.gotAudio)
+	}
+	if stub.gotOpts.MIMEType != DefaultMIMEType {
+		t.Errorf("MIMEType = %q, want %q", stub.gotOpts.MIMEType, DefaultMIMEType)
+	}
+}
+
+func TestTranscribeFileMIMEType(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"audio.wav", "audio/wav"},
+		{"audio.flac", "audio/flac"},
+		{"audio.FLAC", "audio/flac"},
+		{"audio.bin", DefaultMIMEType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.name)
+			if err := os.WriteFile(path, testAudio, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			b := &stubBackend{}
+			if _, _, err := TranscribeFile(context.Background(), b, path); err != nil {
+				t.Fatalf("TranscribeFile() error: %v", err)
+			}
+			if b.gotOpts.MIMEType != tt.want {
+				t.Errorf("MIMEType = %q, want %q", b.gotOpts.MIMEType, tt.want)
+			}
+		})
+	}
+}
+
+func TestTranscribeFileMissing(t *testing.T) {
+	_, _, err := TranscribeFile(context.Background(), &stubBackend{}, "/nonexistent/audio.wav")
+	if err == nil {
+		t.Error("TranscribeFile() should fail for missing file")
+	}
+}
+
+func TestTranscribeFileUsesContextMode(t *testing.T) {
+	b := &stubBackend{}
+	path := filepath.Join(t.TempDir(), "audio.wav")
+	if err := os.WriteFile(path, testAudio, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := WithReferenceContext(WithMode(context.Background(), "commit"), "func parseConfig()")
+	if _, _, err := TranscribeFile(ctx, b, path); err != nil {
+		t.Fatalf("TranscribeFile() error: %v", err)
+	}
+	if b.gotOpts.Mode != "commit" {
+		t.Errorf("Options.Mode = %q, want the mode from the context", b.gotOpts.Mode)
+	}
+	if b.gotOpts.Context != "func parseConfig()" {
+		t.Errorf("Options.Context = %q, want the reference context from the context", b.gotOpts.Context)
+	}
+}
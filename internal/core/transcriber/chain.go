@@ -0,0 +1,311 @@
+package transcriber
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/noricha-vr/voicecode/internal/core/prompt"
+	"github.com/noricha-vr/voicecode/internal/core/settings"
+	"github.com/noricha-vr/voicecode/internal/core/trace"
+)
+
+// ChainBackendName is the name reported by a Chain.
+const ChainBackendName = "chain"
+
+// ErrBudgetExhausted is returned when the chain deadline expires before any step succeeds.
+var ErrBudgetExhausted = errors.New("transcription deadline budget exhausted")
+
+type chainStep struct {
+	backend       Backend
+	model         string
+	timeout       time.Duration
+	retries       int
+	thinkingLevel string
+}
+
+func (s chainStep) label(i int) string {
+	return fmt.Sprintf("chain[%d] %s/%s", i, s.backend.Name(), s.modelName())
+}
+
+func (s chainStep) modelName() string {
+	if s.model != "" {
+		return s.model
+	}
+	return s.backend.ModelName()
+}
+
+// Chain walks an ordered list of backend/model steps deterministically.
+// All steps share one deadline budget; each attempt is logged to the trace timeline.
+type Chain struct {
+	steps    []chainStep
+	budget   time.Duration
+	backends []Backend
+}
+
+var (
+	_ StreamingBackend = (*Chain)(nil)
+	_ HealthReporter   = (*Chain)(nil)
+)
+
+// NewChain builds a Chain from cfg.FallbackChain, creating each distinct
+// backend once through the registry.
+func NewChain(ctx context.Context, cfg *settings.Settings) (*Chain, error) {
+	if len(cfg.FallbackChain) == 0 {
+		return nil, fmt.Errorf("fallback_chain is empty")
+	}
+
+	budget := time.Duration(cfg.TranscribeTimeoutSec * float64(time.Second))
+	if budget <= 0 {
+		budget = time.Duration(settings.DefaultTranscribeTimeoutSec * float64(time.Second))
+	}
+	c := &Chain{budget: budget}
+
+	byName := make(map[string]Backend)
+	for i, step := range cfg.FallbackChain {
+		name := strings.ToLower(strings.TrimSpace(step.Backend))
+		if name == "" {
+			name = strings.ToLower(strings.TrimSpace(cfg.Backend))
+		}
+		if name == "" {
+			name = settings.DefaultBackend
+		}
+
+		b, ok := byName[name]
+		if !ok {
+			factory, err := lookupFactory(name)
+			if err != nil {
+				c.Close()
+				return nil, fmt.Errorf("fallback_chain[%d]: %w", i, err)
+			}
+			b, err = factory(ctx, cfg)
+			if err != nil {
+				c.Close()
+				return nil, fmt.Errorf("fallback_chain[%d]: create %s backend: %w", i, name, err)
+			}
+			byName[name] = b
+			c.backends = append(c.backends, b)
+		}
+
+		model := strings.TrimSpace(step.Model)
+		if gem, ok := b.(*Transcriber); ok && model != "" {
+			model = normalizeModelName(model)
+			gem.ensurePromptCaches(ctx, model)
+		}
+
+		c.steps = append(c.steps, chainStep{
+			backend:       b,
+			model:         model,
+			timeout:       time.Duration(step.TimeoutSec * float64(time.Second)),
+			retries:       step.Retries,
+			thinkingLevel: step.ThinkingLevel,
+		})
+		log.Printf("[Chain] step %d: backend=%s model=%s timeout=%.1fs retries=%d thinking=%s",
+			i, name, c.steps[i].modelName(), step.TimeoutSec, step.Retries, step.ThinkingLevel)
+	}
+	return c, nil
+}
+
+// Transcribe tries each step in order until one succeeds or the budget runs out.
+// Transient errors and step timeouts are retried within a step after a
+// backoff (or the server's RetryAfter); other errors move to the next step.
+// A step without a model leaves the choice to its backend, so the Gemini
+// breaker and hedged requests still apply.
+func (c *Chain) Transcribe(ctx context.Context, audioData []byte, opts Options) (string, error) {
+	return c.walk(ctx, audioData, opts, nil)
+}
+
+// TranscribeStream walks the steps like Transcribe. Steps whose backend
+// streams deliver partial text to emit; other steps emit their whole answer
+// at once. Once text has been emitted a failure is returned instead of
+// moving on, since the next attempt would paste it again.
+func (c *Chain) TranscribeStream(ctx context.Context, audioData []byte, opts Options, emit func(delta string)) (string, error) {
+	return c.walk(ctx, audioData, opts, emit)
+}
+
+// walk runs the steps; emit is nil for Transcribe.
+func (c *Chain) walk(ctx context.Context, audioData []byte, opts Options, emit func(delta string)) (string, error) {
+	tl := trace.FromContext(ctx)
+	emitted := false
+	onDelta := func(delta string) {
+		if delta != "" {
+			emitted = true
+			emit(delta)
+		}
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.budget)
+	defer cancel()
+	deadline, _ := ctx.Deadline()
+
+	var lastErr error
+	for i, step := range c.steps {
+		for attempt := 0; attempt <= step.retries; attempt++ {
+			if attempt > 0 {
+				wait := retryWait(lastErr, attempt)
+				if time.Until(deadline) <= wait {
+					// No budget left to wait for a retry; try the next step.
+					break
+				}
+				tl.Eventf("chain.backoff step=%d wait=%s", i, wait)
+				select {
+				case <-ctx.Done():
+					return "", budgetError(lastErr)
+				case <-time.After(wait):
+				}
+			}
+			remaining := time.Until(deadline)
+			if remaining <= 0 {
+				tl.Eventf("chain.budget_exhausted step=%d budget=%s", i, c.budget)
+				return "", budgetError(lastErr)
+			}
+			timeout := remaining
+			if step.timeout > 0 && step.timeout < timeout {
+				timeout = step.timeout
+			}
+
+			stepOpts := opts
+			if step.model != "" {
+				stepOpts.Model = step.model
+			}
+			if step.thinkingLevel != "" {
+				stepOpts.ThinkingLevel = step.thinkingLevel
+			}
+
+			attemptCtx, attemptCancel := context.WithTimeout(ctx, timeout)
+			done := tl.Step(fmt.Sprintf("%s attempt=%d/%d timeout=%s", step.label(i), attempt+1, step.retries+1, timeout.Truncate(time.Millisecond)))
+			var text string
+			var err error
+			if sb, ok := step.backend.(StreamingBackend); ok && emit != nil {
+				text, err = sb.TranscribeStream(attemptCtx, audioData, stepOpts, onDelta)
+			} else {
+				text, err = step.backend.Transcribe(attemptCtx, audioData, stepOpts)
+				if err == nil && emit != nil {
+					onDelta(text)
+				}
+			}
+			attemptCancel()
+			done(err)
+			if err == nil {
+				return text, nil
+			}
+			lastErr = err
+			if emitted {
+				log.Printf("[Chain] %s が出力の途中で失敗しました: %v", step.label(i), err)
+				return "", err
+			}
+
+			if ctx.Err() != nil {
+				tl.Eventf("chain.budget_exhausted step=%d budget=%s", i, c.budget)
+				return "", budgetError(lastErr)
+			}
+			// A step timeout is worth retrying like any other transient failure.
+			if !IsTransient(err) && !errors.Is(err, context.DeadlineExceeded) {
+				break
+			}
+		}
+		if i+1 < len(c.steps) {
+			log.Printf("[Chain] %s が失敗したため次のステップへ進みます: %v", step.label(i), lastErr)
+		}
+	}
+	return "", lastErr
+}
+
+// Name returns ChainBackendName.
+func (c *Chain) Name() string {
+	return ChainBackendName
+}
+
+// ModelName returns the model of the first step.
+func (c *Chain) ModelName() string {
+	if len(c.steps) == 0 {
+		return ""
+	}
+	return c.steps[0].modelName()
+}
+
+// Health merges the model health of every backend that reports it.
+func (c *Chain) Health() []ModelHealth {
+	var out []ModelHealth
+	for _, b := range c.backends {
+		if hr, ok := b.(HealthReporter); ok {
+			out = append(out, hr.Health()...)
+		}
+	}
+	return out
+}
+
+// ReloadDictionary reloads the dictionary of every backend that supports it.
+func (c *Chain) ReloadDictionary(ctx context.Context) (prompt.Dictionary, bool, error) {
+	return c.eachReloader(func(r DictionaryReloader) (prompt.Dictionary, bool, error) {
+		return r.ReloadDictionary(ctx)
+	})
+}
+
+// SetDictionaryProfile switches the profile of every backend that supports it.
+func (c *Chain) SetDictionaryProfile(ctx context.Context, profile string) (prompt.Dictionary, bool, error) {
+	return c.eachReloader(func(r DictionaryReloader) (prompt.Dictionary, bool, error) {
+		return r.SetDictionaryProfile(ctx, profile)
+	})
+}
+
+// SetDictionary installs d in every backend that supports it.
+func (c *Chain) SetDictionary(ctx context.Context, profile string, d prompt.Dictionary) (bool, error) {
+	_, changed, err := c.eachReloader(func(r DictionaryReloader) (prompt.Dictionary, bool, error) {
+		changed, err := r.SetDictionary(ctx, profile, d)
+		return d, changed, err
+	})
+	return changed, err
+}
+
+func (c *Chain) eachReloader(fn func(DictionaryReloader) (prompt.Dictionary, bool, error)) (prompt.Dictionary, bool, error) {
+	var (
+		dict    prompt.Dictionary
+		changed bool
+		errs    []error
+	)
+	for _, b := range c.backends {
+		r, ok := b.(DictionaryReloader)
+		if !ok {
+			continue
+		}
+		d, ok, err := fn(r)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		dict, changed = d, changed || ok
+	}
+	return dict, changed, errors.Join(errs...)
+}
+
+// Close closes every backend created for the chain.
+func (c *Chain) Close() error {
+	var errs []error
+	for _, b := range c.backends {
+		if err := b.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// retryWait returns the pause before retry number attempt (1-based) of a
+// step: the same linear backoff as Gemini's transient retries, or the
+// server's RetryAfter when that is longer.
+func retryWait(err error, attempt int) time.Duration {
+	wait := time.Duration(float64(time.Second) * RetryBackoffSeconds * float64(attempt))
+	if e := Classify(err); e != nil && e.RetryAfter > wait {
+		wait = e.RetryAfter
+	}
+	return wait
+}
+
+func budgetError(lastErr error) error {
+	if lastErr == nil {
+		return ErrBudgetExhausted
+	}
+	return fmt.Errorf("%w: %w", ErrBudgetExhausted, lastErr)
+}
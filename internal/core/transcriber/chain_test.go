@@ -0,0 +1,328 @@
+package transcriber
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/noricha-vr/voicecode/internal/core/settings"
+	"github.com/noricha-vr/voicecode/internal/core/trace"
+	"github.com/noricha-vr/voicecode/internal/core/transcriber/geminitest"
+)
+
+func newFakeChain(t *testing.T, srv *geminitest.Server, budgetSec float64, steps ...settings.FallbackStep) *Chain {
+	t.Helper()
+	t.Setenv("GOOGLE_API_KEY", "test-key")
+	t.Setenv(BaseURLEnvVar, srv.URL)
+	t.Setenv(ModelEnvVar, "")
+	t.Setenv(ThinkingLevelEnvVar, "")
+	t.Setenv(HedgeDelayEnvVar, "")
+	t.Setenv(EnablePromptCacheEnvVar, "false")
+
+	cfg := settings.Default()
+	cfg.FallbackChain = steps
+	if budgetSec > 0 {
+		cfg.TranscribeTimeoutSec = budgetSec
+	}
+	b, err := NewBackend(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("NewBackend() error: %v", err)
+	}
+	c, ok := b.(*Chain)
+	if !ok {
+		t.Fatalf("NewBackend() = %T, want *Chain", b)
+	}
+	return c
+}
+
+func generateModels(srv *geminitest.Server) []string {
+	var models []string
+	for _, c := range srv.GenerateCalls() {
+		models = append(models, c.Model)
+	}
+	return models
+}
+
+func TestChainWalksStepsInOrder(t *testing.T) {
+	srv := geminitest.NewServer("model-a", "model-b")
+	defer srv.Close()
+	srv.FailGenerate("model-a", geminitest.Unavailable(), geminitest.Unavailable())
+	srv.SetText("model-b", "fallback answer")
+
+	c := newFakeChain(t, srv, 0,
+		settings.FallbackStep{Model: "model-a", Retries: 1},
+		settings.FallbackStep{Model: "models/model-b", ThinkingLevel: "high"},
+	)
+
+	var buf bytes.Buffer
+	tl := trace.NewWith(log.New(&buf, "", 0), nil, true, "test", time.Now())
+	text, err := c.Transcribe(trace.WithTimeline(context.Background(), tl), testAudio, Options{})
+	if err != nil {
+		t.Fatalf("Transcribe() error: %v", err)
+	}
+	if text != "fallback answer" {
+		t.Errorf("text = %q", text)
+	}
+
+	if got := strings.Join(generateModels(srv), ","); got != "model-a,model-a,model-b" {
+		t.Errorf("call order = %s, want model-a,model-a,model-b", got)
+	}
+	calls := srv.GenerateCalls()
+	if calls[2].ThinkingLevel != "HIGH" {
+		t.Errorf("step thinking level = %q, want HIGH", calls[2].ThinkingLevel)
+	}
+	out := buf.String()
+	for _, want := range []string{"chain[0] gemini/model-a attempt=1/2", "chain[0] gemini/model-a attempt=2/2", "chain[1] gemini/model-b attempt=1/1"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("timeline missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestChainNonTransientErrorSkipsRetries(t *testing.T) {
+	srv := geminitest.NewServer("model-a", "model-b")
+	defer srv.Close()
+
+	c := newFakeChain(t, srv, 0,
+		settings.FallbackStep{Model: "gone-model", Retries: 3},
+		settings.FallbackStep{Model: "model-b"},
+	)
+	if _, err := c.Transcribe(context.Background(), testAudio, Options{}); err != nil {
+		t.Fatalf("Transcribe() error: %v", err)
+	}
+	if got := strings.Join(generateModels(srv), ","); got != "gone-model,model-b" {
+		t.Errorf("call order = %s, want gone-model,model-b", got)
+	}
+}
+
+func TestChainStepTimeout(t *testing.T) {
+	srv := geminitest.NewServer("slow-model", "fast-model")
+	defer srv.Close()
+	srv.SetDelay("slow-model", 2*time.Second)
+
+	c := newFakeChain(t, srv, 5,
+		settings.FallbackStep{Model: "slow-model", TimeoutSec: 0.05},
+		settings.FallbackStep{Model: "fast-model"},
+	)
+
+	start := time.Now()
+	if _, err := c.Transcribe(context.Background(), testAudio, Options{}); err != nil {
+		t.Fatalf("Transcribe() error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Transcribe took %s, step timeout should cut the slow step", elapsed)
+	}
+}
+
+func TestChainSharedBudgetExhausted(t *testing.T) {
+	srv := geminitest.NewServer("slow-a", "slow-b")
+	defer srv.Close()
+	srv.SetDelay("slow-a", 2*time.Second)
+	srv.SetDelay("slow-b", 2*time.Second)
+
+	c := newFakeChain(t, srv, 0.1,
+		settings.FallbackStep{Model: "slow-a", Retries: 2},
+		settings.FallbackStep{Model: "slow-b"},
+	)
+
+	start := time.Now()
+	_, err := c.Transcribe(context.Background(), testAudio, Options{})
+	if !errors.Is(err, ErrBudgetExhausted) {
+		t.Fatalf("Transcribe() error = %v, want ErrBudgetExhausted", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Transcribe took %s, want the 100ms budget to be honoured", elapsed)
+	}
+}
+
+func TestChainBacksOffBeforeRetrying(t *testing.T) {
+	slowDown := geminitest.RateLimited()
+	slowDown.Details[0]["retryDelay"] = "0.5s"
+	tests := []struct {
+		name    string
+		fault   geminitest.Fault
+		minWait time.Duration
+	}{
+		{"backoff", geminitest.Unavailable(), 300 * time.Millisecond},
+		{"retry after", slowDown, 500 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := geminitest.NewServer("model-a")
+			defer srv.Close()
+			srv.FailGenerate("model-a", tt.fault)
+
+			c := newFakeChain(t, srv, 0, settings.FallbackStep{Model: "model-a", Retries: 1})
+			start := time.Now()
+			if _, err := c.Transcribe(context.Background(), testAudio, Options{}); err != nil {
+				t.Fatalf("Transcribe() error: %v", err)
+			}
+			if n := len(srv.GenerateCalls()); n != 2 {
+				t.Fatalf("generateContent calls = %d, want 2", n)
+			}
+			if elapsed := time.Since(start); elapsed < tt.minWait {
+				t.Errorf("retry came after %s, want at least %s", elapsed, tt.minWait)
+			}
+		})
+	}
+}
+
+func TestChainStepWithoutModelUsesGeminiFallback(t *testing.T) {
+	srv := geminitest.NewServer(PreferredModels...)
+	defer srv.Close()
+	primary, fallback := PreferredModels[0], PreferredModels[1]
+	srv.FailGenerate(primary, geminitest.Unavailable(), geminitest.Unavailable())
+
+	c := newFakeChain(t, srv, 0, settings.FallbackStep{})
+	if _, err := c.Transcribe(context.Background(), testAudio, Options{}); err != nil {
+		t.Fatalf("Transcribe() error: %v", err)
+	}
+	// Unpinned, the Gemini backend retries and falls back on its own.
+	if got := strings.Join(generateModels(srv), ","); got != primary+","+primary+","+fallback {
+		t.Errorf("call order = %s, want the primary twice then %s", got, fallback)
+	}
+}
+
+type scriptedBackend struct {
+	name  string
+	errs  []error
+	calls []Options
+}
+
+func (s *scriptedBackend) Transcribe(ctx context.Context, audio []byte, opts Options) (string, error) {
+	s.calls = append(s.calls, opts)
+	if len(s.errs) > 0 {
+		err := s.errs[0]
+		s.errs = s.errs[1:]
+		return "", err
+	}
+	return s.name + " ok", nil
+}
+func (s *scriptedBackend) Name() string      { return s.name }
+func (s *scriptedBackend) ModelName() string { return s.name + "-default" }
+func (s *scriptedBackend) Close() error      { return nil }
+
+func TestChainMixesBackends(t *testing.T) {
+	local := &scriptedBackend{name: "test-chain-local", errs: []error{errors.New("connection refused")}}
+	remote := &scriptedBackend{name: "test-chain-remote"}
+	Register(local.name, func(ctx context.Context, cfg *settings.Settings) (Backend, error) { return local, nil })
+	Register(remote.name, func(ctx context.Context, cfg *settings.Settings) (Backend, error) { return remote, nil })
+	t.Cleanup(func() {
+		unregister(local.name)
+		unregister(remote.name)
+	})
+
+	cfg := settings.Default()
+	cfg.FallbackChain = []settings.FallbackStep{
+		{Backend: local.name, Retries: 2},
+		{Backend: remote.name, Model: "large-v3"},
+	}
+	c, err := NewChain(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("NewChain() error: %v", err)
+	}
+
+	text, err := c.Transcribe(context.Background(), testAudio, Options{})
+	if err != nil {
+		t.Fatalf("Transcribe() error: %v", err)
+	}
+	if text != "test-chain-remote ok" {
+		t.Errorf("text = %q", text)
+	}
+	if len(local.calls) != 1 {
+		t.Errorf("local calls = %d, want 1 (non-transient error is not retried)", len(local.calls))
+	}
+	if local.calls[0].Model != "" || remote.calls[0].Model != "large-v3" {
+		t.Errorf("pinned models = %q/%q, want only the step that names a model pinned", local.calls[0].Model, remote.calls[0].Model)
+	}
+	if c.ModelName() != "test-chain-local-default" {
+		t.Errorf("ModelName() = %q", c.ModelName())
+	}
+}
+
+func TestNewChainUnknownBackend(t *testing.T) {
+	cfg := settings.Default()
+	cfg.FallbackChain = []settings.FallbackStep{{Backend: "nope"}}
+	if _, err := NewChain(context.Background(), cfg); err == nil {
+		t.Fatal("NewChain() should fail for unknown backend")
+	}
+}
+
+func TestChainTranscribeStream(t *testing.T) {
+	srv := geminitest.NewServer("model-a", "model-b")
+	defer srv.Close()
+	srv.SetText("", "ReactのuseStateを使う")
+	srv.SetStreamChunkSize(3)
+	srv.FailGenerate("model-a", geminitest.InvalidAPIKey())
+
+	c := newFakeChain(t, srv, 0,
+		settings.FallbackStep{Model: "model-a"},
+		settings.FallbackStep{Model: "model-b"},
+	)
+	var deltas []string
+	text, err := c.TranscribeStream(context.Background(), testAudio, Options{}, func(d string) {
+		deltas = append(deltas, d)
+	})
+	if err != nil {
+		t.Fatalf("TranscribeStream() error: %v", err)
+	}
+	if text != "ReactのuseStateを使う" || strings.Join(deltas, "") != text {
+		t.Errorf("text = %q, deltas = %q; want the deltas to add up to the text", text, deltas)
+	}
+	if len(deltas) < 2 {
+		t.Errorf("deltas = %q, want the fallback step streamed in pieces", deltas)
+	}
+	calls := srv.Calls()
+	if last := calls[len(calls)-1]; last.Method != "streamGenerateContent" || last.Model != "model-b" {
+		t.Errorf("last call = %s %s, want a stream from model-b", last.Method, last.Model)
+	}
+}
+
+func TestChainTranscribeStreamStopsAfterPartialOutput(t *testing.T) {
+	srv := geminitest.NewServer("model-a", "model-b")
+	defer srv.Close()
+	srv.SetText("", "ReactのuseStateを使う")
+	srv.SetStreamChunkSize(3)
+	srv.FailStreamAfter(2)
+
+	c := newFakeChain(t, srv, 0,
+		settings.FallbackStep{Model: "model-a", Retries: 1},
+		settings.FallbackStep{Model: "model-b"},
+	)
+	var emitted strings.Builder
+	if _, err := c.TranscribeStream(context.Background(), testAudio, Options{}, func(d string) {
+		emitted.WriteString(d)
+	}); err == nil {
+		t.Fatal("TranscribeStream() should fail after a broken stream")
+	}
+	if emitted.Len() == 0 {
+		t.Fatal("expected partial output before the failure")
+	}
+	if got := strings.Join(generateModels(srv), ","); got != "model-a" {
+		t.Errorf("calls = %s, want no retry or next step after text was pasted", got)
+	}
+}
+
+func TestChainTranscribeStreamNonStreamingStep(t *testing.T) {
+	remote := &scriptedBackend{name: "test-chain-stream-remote"}
+	Register(remote.name, func(ctx context.Context, cfg *settings.Settings) (Backend, error) { return remote, nil })
+	t.Cleanup(func() { unregister(remote.name) })
+
+	cfg := settings.Default()
+	cfg.FallbackChain = []settings.FallbackStep{{Backend: remote.name}}
+	c, err := NewChain(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("NewChain() error: %v", err)
+	}
+	var deltas []string
+	text, err := c.TranscribeStream(context.Background(), testAudio, Options{}, func(d string) {
+		deltas = append(deltas, d)
+	})
+	if err != nil || text != "test-chain-stream-remote ok" || len(deltas) != 1 || deltas[0] != text {
+		t.Errorf("TranscribeStream() = %q, %v with deltas %q; want the whole answer emitted once", text, err, deltas)
+	}
+}
@@ -0,0 +1,160 @@
+package transcriber
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/noricha-vr/voicecode/internal/core/audio"
+	"github.com/noricha-vr/voicecode/internal/core/trace"
+)
+
+// ChunkConfig controls chunked transcription of long recordings.
+type ChunkConfig struct {
+	// MaxChunkSec is the longest chunk sent in one request.
+	MaxChunkSec float64
+	// Concurrency bounds the number of chunks transcribed at the same time.
+	Concurrency int
+	// Retries is the number of extra attempts for a failed chunk.
+	Retries int
+	// Format is the encoding of each chunk's upload. Empty means WAV.
+	Format audio.Format
+}
+
+// TranscribeSamples transcribes 16-bit mono PCM. Recordings longer than
+// cfg.MaxChunkSec are split at quiet points, transcribed in parallel and
+// stitched in order; only failed chunks are retried. emit, when non-nil,
+// receives the text of each chunk as soon as all earlier chunks are done.
+// Once a chunk has failed for good the remaining requests are cancelled,
+// since the result can no longer be complete.
+func TranscribeSamples(ctx context.Context, b Backend, samples []int16, sampleRate int, cfg ChunkConfig, emit func(delta string)) (string, error) {
+	tl := trace.FromContext(ctx)
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	chunks := audio.SplitAtSilence(samples, sampleRate, cfg.MaxChunkSec)
+	if len(chunks) == 0 {
+		return "", nil
+	}
+	concurrency := cfg.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	tl.Eventf("chunked.split chunks=%d concurrency=%d", len(chunks), concurrency)
+
+	results := make([]string, len(chunks))
+	errs := make([]error, len(chunks))
+	done := make([]bool, len(chunks))
+
+	var (
+		mu       sync.Mutex
+		next     int // first chunk not yet emitted
+		stitched strings.Builder
+	)
+	// flush emits finished chunks in order and stops at the first failed
+	// chunk, so text after a gap is never pasted. Called with mu held.
+	flush := func() {
+		for next < len(chunks) && done[next] && errs[next] == nil {
+			if results[next] != "" {
+				delta := joinDelta(stitched.String(), results[next])
+				stitched.WriteString(delta)
+				if emit != nil {
+					emit(delta)
+				}
+			}
+			next++
+		}
+	}
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, c := range chunks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				mu.Lock()
+				errs[i], done[i] = ctx.Err(), true
+				mu.Unlock()
+				return
+			}
+			defer func() { <-sem }()
+
+			text, err := transcribeChunk(ctx, b, samples[c.Start:c.End], sampleRate, cfg.Format, i, cfg.Retries)
+			if err != nil && ctx.Err() == nil {
+				tl.Eventf("chunked.cancel failed=%d", i)
+				cancel()
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			results[i], errs[i], done[i] = text, err, true
+			flush()
+		}()
+	}
+	wg.Wait()
+
+	var failed []error
+	for i, err := range errs {
+		// Chunks we cancelled after another one failed add nothing.
+		if err != nil && (parent.Err() != nil || !errors.Is(err, context.Canceled)) {
+			failed = append(failed, fmt.Errorf("chunk %d/%d: %w", i+1, len(chunks), err))
+		}
+	}
+	if len(failed) > 0 {
+		return stitched.String(), errors.Join(failed...)
+	}
+	return stitched.String(), nil
+}
+
+func transcribeChunk(ctx context.Context, b Backend, samples []int16, sampleRate int, format audio.Format, index, retries int) (string, error) {
+	tl := trace.FromContext(ctx)
+	data, err := audio.Encode(format, samples, sampleRate)
+	if err != nil {
+		return "", err
+	}
+	opts := requestOptions(ctx)
+	opts.MIMEType = format.MIMEType()
+
+	var lastErr error
+	for attempt := 0; attempt <= retries; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		stepDone := tl.Step(fmt.Sprintf("chunk[%d] attempt=%d/%d dur=%s", index, attempt+1, retries+1,
+			time.Duration(float64(len(samples))/float64(sampleRate)*float64(time.Second)).Truncate(time.Millisecond)))
+		text, err := b.Transcribe(ctx, data, opts)
+		stepDone(err)
+		if err == nil {
+			return text, nil
+		}
+		lastErr = err
+		log.Printf("[Chunk] chunk %d の文字起こしに失敗しました (attempt %d/%d): %v", index, attempt+1, retries+1, err)
+	}
+	return "", lastErr
+}
+
+// joinDelta returns next prefixed with a space when both sides of the seam
+// are Latin letters or digits; Japanese text is joined without a separator.
+func joinDelta(prev, next string) string {
+	if prev == "" {
+		return next
+	}
+	last, _ := utf8.DecodeLastRuneInString(prev)
+	first, _ := utf8.DecodeRuneInString(next)
+	if (isLatinWord(last) || last == '.' || last == ',') && isLatinWord(first) {
+		return " " + next
+	}
+	return next
+}
+
+func isLatinWord(r rune) bool {
+	return r <= unicode.MaxLatin1 && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
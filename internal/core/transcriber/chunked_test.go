@@ -0,0 +1,226 @@
+package transcriber
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/noricha-vr/voicecode/internal/core/audio"
+)
+
+// chunkBackend identifies a chunk by its peak amplitude (1000 * (index+1))
+// and answers with texts[index]. failures[index] errors are returned first.
+type chunkBackend struct {
+	texts    []string
+	mu       sync.Mutex
+	failures map[int]int
+	calls    map[int]int
+	inFlight atomic.Int32
+	maxSeen  atomic.Int32
+}
+
+func (b *chunkBackend) Transcribe(ctx context.Context, data []byte, opts Options) (string, error) {
+	n := b.inFlight.Add(1)
+	defer b.inFlight.Add(-1)
+	for {
+		m := b.maxSeen.Load()
+		if n <= m || b.maxSeen.CompareAndSwap(m, n) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	var peak int16
+	for i := 44; i+1 < len(data); i += 2 {
+		if v := int16(binary.LittleEndian.Uint16(data[i:])); v > peak {
+			peak = v
+		}
+	}
+	index := int(peak)/1000 - 1
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.calls[index]++
+	if b.failures[index] > 0 {
+		b.failures[index]--
+		return "", errors.New("503 unavailable")
+	}
+	return b.texts[index], nil
+}
+func (b *chunkBackend) Name() string      { return "chunk" }
+func (b *chunkBackend) ModelName() string { return "chunk-model" }
+func (b *chunkBackend) Close() error      { return nil }
+
+// chunkedSamples returns len(texts) segments of 2s speech separated by 1s pauses.
+func chunkedSamples(n int) []int16 {
+	const sr = 1000
+	var samples []int16
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			samples = append(samples, make([]int16, sr)...)
+		}
+		for j := 0; j < 2*sr; j++ {
+			samples = append(samples, int16(1000*(i+1)))
+		}
+	}
+	return samples
+}
+
+func TestTranscribeSamplesChunked(t *testing.T) {
+	tests := []struct {
+		name       string
+		texts      []string
+		failures   map[int]int
+		retries    int
+		want       string
+		wantDeltas []string
+		wantErr    bool
+	}{
+		{
+			name:       "stitches in order",
+			texts:      []string{"今日は", "React", "useState", "を使う"},
+			want:       "今日はReact useStateを使う",
+			wantDeltas: []string{"今日は", "React", " useState", "を使う"},
+		},
+		{
+			name:       "retries only the failed chunk",
+			texts:      []string{"一", "二", "三"},
+			failures:   map[int]int{1: 1},
+			retries:    1,
+			want:       "一二三",
+			wantDeltas: []string{"一", "二", "三"},
+		},
+		{
+			name:       "persistent failure stops emission at the gap",
+			texts:      []string{"一", "二", "三"},
+			failures:   map[int]int{1: 5},
+			retries:    1,
+			want:       "一",
+			wantDeltas: []string{"一"},
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &chunkBackend{texts: tt.texts, failures: map[int]int{}, calls: map[int]int{}}
+			for i, n := range tt.failures {
+				b.failures[i] = n
+			}
+			var deltas []string
+			cfg := ChunkConfig{MaxChunkSec: 3.5, Concurrency: 2, Retries: tt.retries}
+			got, err := TranscribeSamples(context.Background(), b, chunkedSamples(len(tt.texts)), 1000, cfg, func(d string) {
+				deltas = append(deltas, d)
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("text = %q, want %q", got, tt.want)
+			}
+			if strings.Join(deltas, "|") != strings.Join(tt.wantDeltas, "|") {
+				t.Errorf("deltas = %q, want %q", deltas, tt.wantDeltas)
+			}
+			if m := b.maxSeen.Load(); m > int32(cfg.Concurrency) {
+				t.Errorf("max concurrent requests = %d, want <= %d", m, cfg.Concurrency)
+			}
+			for i := range tt.texts {
+				if tt.wantErr && i > len(tt.wantDeltas) {
+					break // cancelled once the gap failed for good
+				}
+				want := 1 + min(tt.failures[i], tt.retries)
+				if b.calls[i] != want {
+					t.Errorf("chunk %d calls = %d, want %d", i, b.calls[i], want)
+				}
+			}
+		})
+	}
+}
+
+// blockingBackend fails the chunk with peak 1000 and blocks every other
+// chunk until its context is cancelled.
+type blockingBackend struct {
+	answered atomic.Int32
+}
+
+func (b *blockingBackend) Transcribe(ctx context.Context, data []byte, opts Options) (string, error) {
+	var peak int16
+	for i := 44; i+1 < len(data); i += 2 {
+		peak = max(peak, int16(binary.LittleEndian.Uint16(data[i:])))
+	}
+	if peak == 1000 {
+		return "", errors.New("400 invalid argument")
+	}
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case <-time.After(5 * time.Second):
+		b.answered.Add(1)
+		return "late", nil
+	}
+}
+func (b *blockingBackend) Name() string      { return "blocking" }
+func (b *blockingBackend) ModelName() string { return "blocking-model" }
+func (b *blockingBackend) Close() error      { return nil }
+
+func TestTranscribeSamplesCancelsAfterTerminalFailure(t *testing.T) {
+	b := &blockingBackend{}
+	cfg := ChunkConfig{MaxChunkSec: 3.5, Concurrency: 3}
+	start := time.Now()
+	_, err := TranscribeSamples(context.Background(), b, chunkedSamples(3), 1000, cfg, nil)
+	if err == nil || !strings.Contains(err.Error(), "invalid argument") {
+		t.Fatalf("err = %v, want the failed chunk's error", err)
+	}
+	if errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, should not include the chunks cancelled because of it", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("TranscribeSamples took %s, want the other chunks cancelled", elapsed)
+	}
+	if n := b.answered.Load(); n != 0 {
+		t.Errorf("answered requests = %d, want the others cancelled", n)
+	}
+}
+
+func TestTranscribeSamplesFLAC(t *testing.T) {
+	b := &stubBackend{text: "テスト"}
+	cfg := ChunkConfig{MaxChunkSec: 60, Concurrency: 1, Format: audio.FormatFLAC}
+	got, err := TranscribeSamples(context.Background(), b, chunkedSamples(1), 1000, cfg, func(string) {})
+	if err != nil {
+		t.Fatalf("TranscribeSamples() error: %v", err)
+	}
+	if got != "テスト" {
+		t.Errorf("text = %q, want %q", got, "テスト")
+	}
+	if b.gotOpts.MIMEType != "audio/flac" {
+		t.Errorf("MIMEType = %q, want audio/flac", b.gotOpts.MIMEType)
+	}
+	if !bytes.HasPrefix(b.gotAudio, []byte("fLaC")) {
+		t.Errorf("uploaded audio starts with %q, want a FLAC stream", b.gotAudio[:min(4, len(b.gotAudio))])
+	}
+}
+
+func TestJoinDelta(t *testing.T) {
+	tests := []struct {
+		prev, next, want string
+	}{
+		{"", "hello", "hello"},
+		{"hello", "world", " world"},
+		{"end.", "Next", " Next"},
+		{"日本語", "です", "です"},
+		{"React", "を使う", "を使う"},
+		{"使う", "React", "React"},
+		{"foo", ", bar", ", bar"},
+	}
+	for _, tt := range tests {
+		if got := joinDelta(tt.prev, tt.next); got != tt.want {
+			t.Errorf("joinDelta(%q, %q) = %q, want %q", tt.prev, tt.next, got, tt.want)
+		}
+	}
+}
@@ -1,45 +1,287 @@
 package transcriber
 
 import (
-	"regexp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
 	"strings"
+	"syscall"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+// ErrorKind classifies transcription failures by what the user can do about them.
+type ErrorKind int
+
+const (
+	// KindUnknown is an error that could not be classified.
+	KindUnknown ErrorKind = iota
+	// KindAuth means the API key is missing, invalid or lacks permission.
+	KindAuth
+	// KindQuota means a billing or daily quota is used up; retrying soon will not help.
+	KindQuota
+	// KindRateLimit means too many requests; retry after APIError.RetryAfter.
+	KindRateLimit
+	// KindInvalidArgument means the request itself was rejected.
+	KindInvalidArgument
+	// KindModelNotFound means the requested model does not exist or is retired.
+	KindModelNotFound
+	// KindTransient means a server or network hiccup that may succeed on retry.
+	KindTransient
+	// KindCancelled means the caller cancelled the request.
+	KindCancelled
 )
 
-var httpErrorCodePattern = regexp.MustCompile(`\b(429|500|502|503|504)\b`)
+func (k ErrorKind) String() string {
+	switch k {
+	case KindAuth:
+		return "auth"
+	case KindQuota:
+		return "quota"
+	case KindRateLimit:
+		return "rate_limit"
+	case KindInvalidArgument:
+		return "invalid_argument"
+	case KindModelNotFound:
+		return "model_not_found"
+	case KindTransient:
+		return "transient"
+	case KindCancelled:
+		return "cancelled"
+	default:
+		return "unknown"
+	}
+}
+
+// APIError is a classified transcription error. It wraps the original error
+// returned by the SDK or HTTP client.
+type APIError struct {
+	Kind ErrorKind
+	// Code is the HTTP status code, or 0 when the request never got a response.
+	Code int
+	// Status is the canonical status (e.g. "INVALID_ARGUMENT") when known.
+	Status string
+	// Reason is the structured error reason (e.g. "API_KEY_INVALID") when known.
+	Reason string
+	// Message is the server-provided message.
+	Message string
+	// RetryAfter is the server-suggested wait before retrying; 0 if unknown.
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *APIError) Error() string {
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%s: HTTP %d %s: %s", e.Kind, e.Code, e.Status, e.Message)
+}
+
+func (e *APIError) Unwrap() error { return e.Err }
+
+// Classify returns err as a classified *APIError. It returns nil for nil,
+// the existing *APIError when err already carries one, and otherwise derives
+// the kind from genai.APIError, context errors and network errors.
+func Classify(err error) *APIError {
+	if err == nil {
+		return nil
+	}
+	var classified *APIError
+	if errors.As(err, &classified) {
+		return classified
+	}
+
+	var gerr genai.APIError
+	if errors.As(err, &gerr) {
+		e := &APIError{Code: gerr.Code, Status: gerr.Status, Message: gerr.Message, Err: err}
+		quotaExhausted := false
+		for _, d := range gerr.Details {
+			typ, _ := d["@type"].(string)
+			switch {
+			case strings.HasSuffix(typ, "google.rpc.ErrorInfo"):
+				e.Reason, _ = d["reason"].(string)
+			case strings.HasSuffix(typ, "google.rpc.RetryInfo"):
+				if s, ok := d["retryDelay"].(string); ok {
+					e.RetryAfter, _ = time.ParseDuration(s)
+				}
+			case strings.HasSuffix(typ, "google.rpc.QuotaFailure"):
+				quotaExhausted = quotaExhausted || hasDailyQuotaViolation(d)
+			}
+		}
+		e.Kind = kindForStatus(gerr.Code, gerr.Status, e.Reason, gerr.Message)
+		if e.Kind == KindRateLimit && quotaExhausted {
+			e.Kind = KindQuota
+		}
+		return e
+	}
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return &APIError{Kind: KindCancelled, Err: err}
+	case errors.Is(err, context.DeadlineExceeded):
+		return &APIError{Kind: KindTransient, Err: err}
+	}
+	if isNetworkError(err) {
+		return &APIError{Kind: KindTransient, Err: err}
+	}
+	return &APIError{Kind: KindUnknown, Err: err}
+}
+
+// isNetworkError reports whether err is a timeout, or a failure to reach the
+// server or to read its response: refused or reset connections, DNS failures
+// and responses cut short. Other net.Error values are not matched by type
+// alone because *url.Error is one for any failed request, including a
+// malformed URL. A bare io.EOF is not matched either; it also ends reads
+// that have nothing to do with the network.
+func isNetworkError(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		// Only a dial or read that was refused or reset; a *net.OpError also
+		// reports e.g. TLS handshake and write failures.
+		return (opErr.Op == "dial" || opErr.Op == "read") &&
+			(errors.Is(opErr, syscall.ECONNREFUSED) || errors.Is(opErr, syscall.ECONNRESET))
+	}
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.ECONNRESET):
+		return true
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return true
+	}
+	return false
+}
+
+// KindOf returns the ErrorKind of err; KindUnknown for nil.
+func KindOf(err error) ErrorKind {
+	if e := Classify(err); e != nil {
+		return e.Kind
+	}
+	return KindUnknown
+}
+
+// kindForStatus maps an HTTP status and canonical gRPC status to an ErrorKind.
+func kindForStatus(code int, status, reason, message string) ErrorKind {
+	switch reason {
+	case "API_KEY_INVALID", "API_KEY_SERVICE_BLOCKED", "API_KEY_HTTP_REFERRER_BLOCKED":
+		return KindAuth
+	}
+	switch status {
+	case "UNAUTHENTICATED":
+		return KindAuth
+	case "PERMISSION_DENIED":
+		if isCachedContentMessage(message) {
+			return KindInvalidArgument
+		}
+		return KindAuth
+	case "RESOURCE_EXHAUSTED":
+		return KindRateLimit
+	case "NOT_FOUND":
+		if isCachedContentMessage(message) {
+			return KindInvalidArgument
+		}
+		return KindModelNotFound
+	case "INVALID_ARGUMENT", "FAILED_PRECONDITION", "OUT_OF_RANGE":
+		return KindInvalidArgument
+	case "UNAVAILABLE", "INTERNAL", "DEADLINE_EXCEEDED", "ABORTED":
+		return KindTransient
+	case "CANCELLED":
+		return KindCancelled
+	}
+
+	switch {
+	case code == http.StatusUnauthorized || code == http.StatusForbidden:
+		return KindAuth
+	case code == http.StatusTooManyRequests:
+		return KindRateLimit
+	case code == http.StatusNotFound:
+		return KindModelNotFound
+	case code == 499:
+		return KindCancelled
+	case code == http.StatusRequestTimeout || code >= 500:
+		return KindTransient
+	case code >= 400:
+		return KindInvalidArgument
+	}
+	return KindUnknown
+}
+
+func hasDailyQuotaViolation(detail map[string]any) bool {
+	violations, _ := detail["violations"].([]any)
+	for _, v := range violations {
+		m, _ := v.(map[string]any)
+		id, _ := m["quotaId"].(string)
+		if strings.Contains(id, "PerDay") {
+			return true
+		}
+	}
+	return false
+}
+
+// parseRetryAfter parses an HTTP Retry-After header given in seconds or as an HTTP date.
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0
+	}
+	if sec, err := strconv.ParseFloat(v, 64); err == nil && sec > 0 {
+		return time.Duration(sec * float64(time.Second))
+	}
+	if t, err := http.ParseTime(v); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
 
 // IsModelNotFound returns true if the error indicates the model was not found.
 func IsModelNotFound(err error) bool {
-	msg := strings.ToLower(err.Error())
-	return strings.Contains(msg, "is not found for api version") ||
-		(strings.Contains(msg, "404") && strings.Contains(msg, "models/") && strings.Contains(msg, "not found"))
+	return KindOf(err) == KindModelNotFound
 }
 
 // IsTransient returns true if the error is a transient API error that may succeed on retry.
+// Rate limiting counts as transient; an exhausted quota does not.
 func IsTransient(err error) bool {
-	msg := strings.ToLower(err.Error())
-	patterns := []string{
-		"deadline expired",
-		"timed out",
-		"timeout",
-		"temporarily unavailable",
-		"service unavailable",
-	}
-	for _, p := range patterns {
-		if strings.Contains(msg, p) {
-			return true
-		}
+	switch KindOf(err) {
+	case KindTransient, KindRateLimit:
+		return true
 	}
-	return httpErrorCodePattern.MatchString(msg) || strings.Contains(msg, "too many requests")
+	return false
 }
 
 // IsThinkingUnsupported returns true if the error indicates thinking level is not supported.
+// The API reports it as INVALID_ARGUMENT without a dedicated reason, so the
+// message is only inspected for invalid-argument errors.
 func IsThinkingUnsupported(err error) bool {
-	return strings.Contains(strings.ToLower(err.Error()), "thinking level is not supported")
+	e := Classify(err)
+	return e != nil && e.Kind == KindInvalidArgument &&
+		strings.Contains(strings.ToLower(e.Message), "thinking level is not supported")
 }
 
 // IsCachedContentError returns true if the error indicates a cached content problem.
 func IsCachedContentError(err error) bool {
-	msg := strings.ToLower(err.Error())
-	return strings.Contains(msg, "cachedcontent not found") ||
-		(strings.Contains(msg, "permission_denied") && strings.Contains(msg, "cached"))
+	e := Classify(err)
+	if e == nil || e.Kind != KindInvalidArgument {
+		return false
+	}
+	switch e.Status {
+	case "PERMISSION_DENIED", "NOT_FOUND", "INVALID_ARGUMENT":
+		return isCachedContentMessage(e.Message)
+	}
+	return false
+}
+
+func isCachedContentMessage(message string) bool {
+	msg := strings.ToLower(message)
+	return strings.Contains(msg, "cachedcontent") || strings.Contains(msg, "cached content")
 }
@@ -1,84 +1,156 @@
 package transcriber
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"syscall"
 	"testing"
+	"time"
+
+	"google.golang.org/genai"
+
+	"github.com/noricha-vr/voicecode/internal/core/settings"
+	"github.com/noricha-vr/voicecode/internal/core/transcriber/geminitest"
 )
 
-func TestIsModelNotFound(t *testing.T) {
-	tests := []struct {
-		name string
-		err  error
-		want bool
-	}{
-		{"api version error", errors.New("models/gemini-x is not found for api version v1beta"), true},
-		{"404 not found", errors.New("404 models/gemini-x not found"), true},
-		{"unrelated error", errors.New("connection refused"), false},
-		{"partial match", errors.New("404 something else"), false},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			if got := IsModelNotFound(tt.err); got != tt.want {
-				t.Errorf("IsModelNotFound(%q) = %v, want %v", tt.err, got, tt.want)
-			}
-		})
-	}
+func apiErr(f geminitest.Fault) error {
+	return fmt.Errorf("generate content: %w", genai.APIError{Code: f.Code, Status: f.Status, Message: f.Message, Details: f.Details})
 }
 
-func TestIsTransient(t *testing.T) {
+func TestClassify(t *testing.T) {
 	tests := []struct {
-		name string
-		err  error
-		want bool
+		name           string
+		err            error
+		wantKind       ErrorKind
+		wantRetryAfter time.Duration
 	}{
-		{"timeout", errors.New("request timed out"), true},
-		{"deadline", errors.New("deadline expired"), true},
-		{"429", errors.New("HTTP 429 Too Many Requests"), true},
-		{"503", errors.New("503 service unavailable"), true},
-		{"too many requests", errors.New("too many requests"), true},
-		{"normal error", errors.New("invalid argument"), false},
+		{"invalid api key", apiErr(geminitest.InvalidAPIKey()), KindAuth, 0},
+		{"unauthenticated", genai.APIError{Code: 401, Status: "UNAUTHENTICATED"}, KindAuth, 0},
+		{"permission denied", genai.APIError{Code: 403, Status: "PERMISSION_DENIED", Message: "Method doesn't allow unregistered callers"}, KindAuth, 0},
+		{"rate limited", apiErr(geminitest.RateLimited()), KindRateLimit, 200 * time.Millisecond},
+		{"daily quota", genai.APIError{Code: 429, Status: "RESOURCE_EXHAUSTED", Details: []map[string]any{{
+			"@type":      "type.googleapis.com/google.rpc.QuotaFailure",
+			"violations": []any{map[string]any{"quotaId": "GenerateRequestsPerDayPerProjectPerModel-FreeTier"}},
+		}}}, KindQuota, 0},
+		{"model not found", apiErr(geminitest.ModelNotFound("gemini-x")), KindModelNotFound, 0},
+		{"cache not found", apiErr(geminitest.CacheNotFound()), KindInvalidArgument, 0},
+		{"thinking unsupported", apiErr(geminitest.ThinkingUnsupported()), KindInvalidArgument, 0},
+		{"unavailable", apiErr(geminitest.Unavailable()), KindTransient, 0},
+		{"http 500 without status", genai.APIError{Code: 500}, KindTransient, 0},
+		{"deadline", fmt.Errorf("post: %w", context.DeadlineExceeded), KindTransient, 0},
+		{"cancelled", fmt.Errorf("post: %w", context.Canceled), KindCancelled, 0},
+		{"connection refused", &url.Error{Op: "Post", URL: "https://example.com", Err: &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}}, KindTransient, 0},
+		{"connection reset", fmt.Errorf("read body: %w", syscall.ECONNRESET), KindTransient, 0},
+		{"dns failure", &url.Error{Op: "Post", URL: "https://example.com", Err: &net.DNSError{Err: "no such host", Name: "example.com", IsNotFound: true}}, KindTransient, 0},
+		{"unexpected eof", fmt.Errorf("read response: %w", io.ErrUnexpectedEOF), KindTransient, 0},
+		{"read timeout", &url.Error{Op: "Post", URL: "https://example.com", Err: &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ETIMEDOUT)}}, KindTransient, 0},
+		{"read reset", &url.Error{Op: "Post", URL: "https://example.com", Err: &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}}, KindTransient, 0},
+		{"tls handshake", &url.Error{Op: "Post", URL: "https://example.com", Err: &net.OpError{Op: "remote error", Err: errors.New("tls: bad certificate")}}, KindUnknown, 0},
+		{"write broken pipe", &url.Error{Op: "Post", URL: "https://example.com", Err: &net.OpError{Op: "write", Net: "tcp", Err: os.NewSyscallError("write", syscall.EPIPE)}}, KindUnknown, 0},
+		{"bare eof", &url.Error{Op: "Post", URL: "https://example.com", Err: io.EOF}, KindUnknown, 0},
+		{"malformed url", &url.Error{Op: "Post", URL: "example.com", Err: errors.New("unsupported protocol scheme \"\"")}, KindUnknown, 0},
+		{"plain error", errors.New("503 service unavailable"), KindUnknown, 0},
+		{"already classified", &APIError{Kind: KindQuota, Err: errors.New("quota")}, KindQuota, 0},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := IsTransient(tt.err); got != tt.want {
-				t.Errorf("IsTransient(%q) = %v, want %v", tt.err, got, tt.want)
+			e := Classify(tt.err)
+			if e.Kind != tt.wantKind {
+				t.Errorf("Kind = %s, want %s", e.Kind, tt.wantKind)
+			}
+			if e.RetryAfter != tt.wantRetryAfter {
+				t.Errorf("RetryAfter = %s, want %s", e.RetryAfter, tt.wantRetryAfter)
+			}
+			if e.Error() != tt.err.Error() {
+				t.Errorf("Error() = %q, want the original message %q", e.Error(), tt.err.Error())
 			}
 		})
 	}
+
+	if Classify(nil) != nil || KindOf(nil) != KindUnknown {
+		t.Error("Classify(nil) should be nil")
+	}
 }
 
-func TestIsThinkingUnsupported(t *testing.T) {
+func TestErrorPredicates(t *testing.T) {
 	tests := []struct {
-		name string
-		err  error
-		want bool
+		name                                         string
+		err                                          error
+		modelNotFound, transient, thinking, cacheErr bool
 	}{
-		{"unsupported", errors.New("thinking level is not supported for this model"), true},
-		{"other", errors.New("some other error"), false},
+		{"model not found", apiErr(geminitest.ModelNotFound("gemini-x")), true, false, false, false},
+		{"rate limited", apiErr(geminitest.RateLimited()), false, true, false, false},
+		{"unavailable", apiErr(geminitest.Unavailable()), false, true, false, false},
+		{"deadline", context.DeadlineExceeded, false, true, false, false},
+		{"thinking unsupported", apiErr(geminitest.ThinkingUnsupported()), false, false, true, false},
+		{"cache not found", apiErr(geminitest.CacheNotFound()), false, false, false, true},
+		{"cached content invalid argument", genai.APIError{Code: 400, Status: "INVALID_ARGUMENT", Message: "Cached content is too large"}, false, false, false, true},
+		{"invalid api key", apiErr(geminitest.InvalidAPIKey()), false, false, false, false},
+		// Wording alone no longer classifies an error.
+		{"untyped message", errors.New("404 models/gemini-x not found: thinking level is not supported"), false, false, false, false},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := IsThinkingUnsupported(tt.err); got != tt.want {
-				t.Errorf("IsThinkingUnsupported(%q) = %v, want %v", tt.err, got, tt.want)
+			if got := IsModelNotFound(tt.err); got != tt.modelNotFound {
+				t.Errorf("IsModelNotFound = %v, want %v", got, tt.modelNotFound)
+			}
+			if got := IsTransient(tt.err); got != tt.transient {
+				t.Errorf("IsTransient = %v, want %v", got, tt.transient)
+			}
+			if got := IsThinkingUnsupported(tt.err); got != tt.thinking {
+				t.Errorf("IsThinkingUnsupported = %v, want %v", got, tt.thinking)
+			}
+			if got := IsCachedContentError(tt.err); got != tt.cacheErr {
+				t.Errorf("IsCachedContentError = %v, want %v", got, tt.cacheErr)
 			}
 		})
 	}
 }
 
-func TestIsCachedContentError(t *testing.T) {
+func TestOpenAIErrorKinds(t *testing.T) {
 	tests := []struct {
-		name string
-		err  error
-		want bool
+		name           string
+		status         int
+		body           string
+		retryAfter     string
+		wantKind       ErrorKind
+		wantRetryAfter time.Duration
 	}{
-		{"not found", errors.New("CachedContent not found"), true},
-		{"permission", errors.New("PERMISSION_DENIED: cached content access denied"), true},
-		{"other", errors.New("invalid request"), false},
+		{"invalid key", 401, `{"error":{"message":"Incorrect API key","code":"invalid_api_key"}}`, "", KindAuth, 0},
+		{"rate limit", 429, `{"error":{"message":"Rate limit reached","code":"rate_limit_exceeded"}}`, "3", KindRateLimit, 3 * time.Second},
+		{"insufficient quota", 429, `{"error":{"message":"You exceeded your current quota","code":"insufficient_quota"}}`, "", KindQuota, 0},
+		{"bad audio", 400, `{"error":{"message":"Invalid file format"}}`, "", KindInvalidArgument, 0},
+		{"server error", 502, `bad gateway`, "", KindTransient, 0},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := IsCachedContentError(tt.err); got != tt.want {
-				t.Errorf("IsCachedContentError(%q) = %v, want %v", tt.err, got, tt.want)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.retryAfter != "" {
+					w.Header().Set("Retry-After", tt.retryAfter)
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			b, err := NewOpenAI(settings.OpenAISettings{BaseURL: srv.URL})
+			if err != nil {
+				t.Fatalf("NewOpenAI() error: %v", err)
+			}
+			_, err = b.Transcribe(context.Background(), []byte("RIFF"), Options{})
+			e := Classify(err)
+			if e.Kind != tt.wantKind || e.Code != tt.status {
+				t.Errorf("Kind = %s Code = %d, want %s %d", e.Kind, e.Code, tt.wantKind, tt.status)
+			}
+			if e.RetryAfter != tt.wantRetryAfter {
+				t.Errorf("RetryAfter = %s, want %s", e.RetryAfter, tt.wantRetryAfter)
 			}
 		})
 	}
@@ -0,0 +1,579 @@
+// Package geminitest provides an in-process fake of the Gemini REST API
+// (models.list, generateContent, streamGenerateContent and cachedContents)
+// for hermetic tests.
+//
+// Point transcriber.Config.BaseURL at Server.URL and script failures with
+// the Fail* methods to exercise retry, fallback and prompt cache paths offline.
+package geminitest
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultText is returned by generateContent when no text is configured.
+const DefaultText = "テスト音声です。"
+
+// Fault is a scripted API error response.
+type Fault struct {
+	Code    int
+	Status  string
+	Message string
+	Details []map[string]any
+}
+
+// ModelNotFound mimics the 404 returned for unknown or retired models.
+func ModelNotFound(model string) Fault {
+	return Fault{
+		Code:    http.StatusNotFound,
+		Status:  "NOT_FOUND",
+		Message: fmt.Sprintf("models/%s is not found for API version v1beta, or is not supported for generateContent.", model),
+	}
+}
+
+// RateLimited mimics a 429 quota/rate-limit response.
+func RateLimited() Fault {
+	return Fault{
+		Code:    http.StatusTooManyRequests,
+		Status:  "RESOURCE_EXHAUSTED",
+		Message: "Resource has been exhausted (e.g. check quota).",
+		Details: []map[string]any{{
+			"@type":      "type.googleapis.com/google.rpc.RetryInfo",
+			"retryDelay": "0.2s",
+		}},
+	}
+}
+
+// Unavailable mimics a 503 overloaded response.
+func Unavailable() Fault {
+	return Fault{
+		Code:    http.StatusServiceUnavailable,
+		Status:  "UNAVAILABLE",
+		Message: "The model is overloaded. Please try again later.",
+	}
+}
+
+// ThinkingUnsupported mimics the 400 returned when thinking_level is sent to an older model.
+func ThinkingUnsupported() Fault {
+	return Fault{
+		Code:    http.StatusBadRequest,
+		Status:  "INVALID_ARGUMENT",
+		Message: "Thinking level is not supported for this model.",
+	}
+}
+
+// CacheNotFound mimics the 403 returned for expired or deleted cached contents.
+func CacheNotFound() Fault {
+	return Fault{
+		Code:    http.StatusForbidden,
+		Status:  "PERMISSION_DENIED",
+		Message: "CachedContent not found (or permission denied)",
+	}
+}
+
+// InvalidAPIKey mimics the 400 returned for a missing or wrong API key.
+func InvalidAPIKey() Fault {
+	return Fault{
+		Code:    http.StatusBadRequest,
+		Status:  "INVALID_ARGUMENT",
+		Message: "API key not valid. Please pass a valid API key.",
+		Details: []map[string]any{{
+			"@type":  "type.googleapis.com/google.rpc.ErrorInfo",
+			"reason": "API_KEY_INVALID",
+			"domain": "googleapis.com",
+		}},
+	}
+}
+
+// Call records a single request received by the fake.
+type Call struct {
+	Method         string // "models.list", "generateContent", "streamGenerateContent", "cachedContents.create", "cachedContents.delete"
+	Model          string
+	CachedContent  string
+	DisplayName    string // cachedContents.create only
+	SystemPrompt   string
+	ThinkingLevel  string
+	ThinkingBudget *int
+	Temperature    *float64
+	Texts          []string
+	AudioMIMEType  string
+	AudioBytes     int
+	Status         int
+}
+
+// Server is a fake Gemini API server.
+type Server struct {
+	URL string
+
+	srv *httptest.Server
+
+	mu              sync.Mutex
+	models          []string
+	texts           map[string]string
+	generateFaults  map[string][]Fault
+	listFaults      []Fault
+	cacheFaults     []Fault
+	noThinkingLevel map[string]bool
+	caches          map[string]string // cache name -> model
+	delays          map[string]time.Duration
+	chunkRunes      int
+	streamFaultAt   int // fail the stream after this many chunks; 0 = never
+	cacheSeq        int
+	calls           []Call
+}
+
+// NewServer starts a fake server advertising models for generateContent.
+// Call Close when done.
+func NewServer(models ...string) *Server {
+	s := &Server{
+		models:          models,
+		texts:           make(map[string]string),
+		generateFaults:  make(map[string][]Fault),
+		noThinkingLevel: make(map[string]bool),
+		caches:          make(map[string]string),
+		delays:          make(map[string]time.Duration),
+	}
+	s.srv = httptest.NewServer(http.HandlerFunc(s.handle))
+	s.URL = s.srv.URL
+	return s
+}
+
+// Close shuts down the server.
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// SetText sets the transcription returned for model. An empty model sets the default.
+func (s *Server) SetText(model, text string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.texts[model] = text
+}
+
+// FailGenerate queues faults returned by the next generateContent calls for model.
+// An empty model matches any model once its own queue is empty.
+func (s *Server) FailGenerate(model string, faults ...Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.generateFaults[model] = append(s.generateFaults[model], faults...)
+}
+
+// FailList queues faults returned by the next models.list calls.
+func (s *Server) FailList(faults ...Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listFaults = append(s.listFaults, faults...)
+}
+
+// FailCacheCreate queues faults returned by the next cachedContents.create calls.
+func (s *Server) FailCacheCreate(faults ...Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cacheFaults = append(s.cacheFaults, faults...)
+}
+
+// SetDelay makes generateContent for model wait d before answering.
+// The wait is aborted when the client cancels the request.
+func (s *Server) SetDelay(model string, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.delays[model] = d
+}
+
+// SetStreamChunkSize splits streamed text into chunks of n runes (default 4).
+func (s *Server) SetStreamChunkSize(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.chunkRunes = n
+}
+
+// FailStreamAfter makes the next streams emit n chunks and then an Unavailable error event.
+// Zero disables the mid-stream failure.
+func (s *Server) FailStreamAfter(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.streamFaultAt = n
+}
+
+// DisableThinkingLevel makes model reject requests carrying thinking_level,
+// like models that only understand thinking_budget.
+func (s *Server) DisableThinkingLevel(model string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.noThinkingLevel[model] = true
+}
+
+// ExpireCaches drops every cached content, as if their TTL had elapsed.
+func (s *Server) ExpireCaches() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.caches = make(map[string]string)
+}
+
+// Caches returns the names of live cached contents.
+func (s *Server) Caches() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, 0, len(s.caches))
+	for name := range s.caches {
+		names = append(names, name)
+	}
+	return names
+}
+
+// Calls returns a copy of every request received so far.
+func (s *Server) Calls() []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Call(nil), s.calls...)
+}
+
+// GenerateCalls returns the generateContent and streamGenerateContent requests received so far.
+func (s *Server) GenerateCalls() []Call {
+	var out []Call
+	for _, c := range s.Calls() {
+		if c.Method == "generateContent" || c.Method == "streamGenerateContent" {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("x-goog-api-key") == "" {
+		writeFault(w, InvalidAPIKey())
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/v1beta/")
+	switch {
+	case r.Method == http.MethodGet && path == "models":
+		s.handleList(w)
+	case r.Method == http.MethodPost && strings.HasPrefix(path, "models/") && strings.HasSuffix(path, ":generateContent"):
+		model := strings.TrimSuffix(strings.TrimPrefix(path, "models/"), ":generateContent")
+		s.handleGenerate(w, r, model, false)
+	case r.Method == http.MethodPost && strings.HasPrefix(path, "models/") && strings.HasSuffix(path, ":streamGenerateContent"):
+		model := strings.TrimSuffix(strings.TrimPrefix(path, "models/"), ":streamGenerateContent")
+		s.handleGenerate(w, r, model, true)
+	case r.Method == http.MethodPost && path == "cachedContents":
+		s.handleCacheCreate(w, r)
+	case r.Method == http.MethodDelete && strings.HasPrefix(path, "cachedContents/"):
+		s.handleCacheDelete(w, path)
+	default:
+		writeFault(w, Fault{Code: http.StatusNotFound, Status: "NOT_FOUND", Message: "unknown path " + r.URL.Path})
+	}
+}
+
+func (s *Server) handleList(w http.ResponseWriter) {
+	s.mu.Lock()
+	call := Call{Method: "models.list"}
+	if f, ok := popFault(&s.listFaults); ok {
+		call.Status = f.Code
+		s.calls = append(s.calls, call)
+		s.mu.Unlock()
+		writeFault(w, f)
+		return
+	}
+	type model struct {
+		Name                       string   `json:"name"`
+		SupportedGenerationMethods []string `json:"supportedGenerationMethods"`
+	}
+	resp := struct {
+		Models []model `json:"models"`
+	}{}
+	for _, m := range s.models {
+		resp.Models = append(resp.Models, model{Name: "models/" + m, SupportedGenerationMethods: []string{"generateContent", "countTokens"}})
+	}
+	call.Status = http.StatusOK
+	s.calls = append(s.calls, call)
+	s.mu.Unlock()
+	writeJSON(w, resp)
+}
+
+type part struct {
+	Text       string `json:"text,omitempty"`
+	InlineData *struct {
+		MIMEType string `json:"mimeType"`
+		Data     string `json:"data"`
+	} `json:"inlineData,omitempty"`
+}
+
+type content struct {
+	Parts []part `json:"parts"`
+}
+
+type generateRequest struct {
+	CachedContent     string    `json:"cachedContent"`
+	Contents          []content `json:"contents"`
+	SystemInstruction *content  `json:"systemInstruction"`
+	GenerationConfig  struct {
+		Temperature    *float64 `json:"temperature"`
+		ThinkingConfig *struct {
+			ThinkingLevel  string `json:"thinkingLevel"`
+			ThinkingBudget *int   `json:"thinkingBudget"`
+		} `json:"thinkingConfig"`
+	} `json:"generationConfig"`
+}
+
+func (s *Server) handleGenerate(w http.ResponseWriter, r *http.Request, model string, stream bool) {
+	var req generateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeFault(w, Fault{Code: http.StatusBadRequest, Status: "INVALID_ARGUMENT", Message: err.Error()})
+		return
+	}
+
+	call := Call{Method: "generateContent", Model: model, CachedContent: req.CachedContent}
+	if stream {
+		call.Method = "streamGenerateContent"
+	}
+	if req.SystemInstruction != nil {
+		call.SystemPrompt = joinTexts(req.SystemInstruction.Parts)
+	}
+	call.Temperature = req.GenerationConfig.Temperature
+	if tc := req.GenerationConfig.ThinkingConfig; tc != nil {
+		call.ThinkingLevel = tc.ThinkingLevel
+		call.ThinkingBudget = tc.ThinkingBudget
+	}
+	for _, c := range req.Contents {
+		for _, p := range c.Parts {
+			if p.Text != "" {
+				call.Texts = append(call.Texts, p.Text)
+			}
+			if p.InlineData != nil {
+				call.AudioMIMEType = p.InlineData.MIMEType
+				if data, err := base64.StdEncoding.DecodeString(p.InlineData.Data); err == nil {
+					call.AudioBytes = len(data)
+				}
+			}
+		}
+	}
+
+	s.mu.Lock()
+	delay := s.delays[model]
+	s.mu.Unlock()
+	if delay > 0 {
+		select {
+		case <-r.Context().Done():
+			s.mu.Lock()
+			call.Status = 499 // client closed request
+			s.calls = append(s.calls, call)
+			s.mu.Unlock()
+			return
+		case <-time.After(delay):
+		}
+	}
+
+	s.mu.Lock()
+	fault, failed := s.nextGenerateFault(model, req, call)
+	text, ok := s.texts[model]
+	if !ok {
+		text, ok = s.texts[""]
+	}
+	if !ok {
+		text = DefaultText
+	}
+	if failed {
+		call.Status = fault.Code
+	} else {
+		call.Status = http.StatusOK
+	}
+	s.calls = append(s.calls, call)
+	chunkRunes, faultAt := s.chunkRunes, s.streamFaultAt
+	s.mu.Unlock()
+
+	if failed {
+		writeFault(w, fault)
+		return
+	}
+	if !stream {
+		writeJSON(w, textResponse(model, text, "STOP"))
+		return
+	}
+
+	if chunkRunes <= 0 {
+		chunkRunes = 4
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	flusher, _ := w.(http.Flusher)
+	chunks := splitRunes(text, chunkRunes)
+	for i, chunk := range chunks {
+		if faultAt > 0 && i == faultAt {
+			f := Unavailable()
+			data, _ := json.Marshal(map[string]any{"error": map[string]any{"code": f.Code, "message": f.Message, "status": f.Status}})
+			// Mid-stream errors arrive as a bare JSON line, not a data event.
+			fmt.Fprintf(w, "%s\n\n", data)
+			return
+		}
+		finish := ""
+		if i == len(chunks)-1 {
+			finish = "STOP"
+		}
+		data, _ := json.Marshal(textResponse(model, chunk, finish))
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
+
+func textResponse(model, text, finishReason string) map[string]any {
+	candidate := map[string]any{
+		"content": map[string]any{"role": "model", "parts": []map[string]any{{"text": text}}},
+	}
+	if finishReason != "" {
+		candidate["finishReason"] = finishReason
+	}
+	return map[string]any{
+		"candidates":   []map[string]any{candidate},
+		"modelVersion": model,
+	}
+}
+
+func splitRunes(text string, n int) []string {
+	runes := []rune(text)
+	if len(runes) == 0 {
+		return []string{""}
+	}
+	var chunks []string
+	for len(runes) > 0 {
+		k := n
+		if k > len(runes) {
+			k = len(runes)
+		}
+		chunks = append(chunks, string(runes[:k]))
+		runes = runes[k:]
+	}
+	return chunks
+}
+
+// nextGenerateFault decides whether a generateContent call fails. Caller holds s.mu.
+func (s *Server) nextGenerateFault(model string, req generateRequest, call Call) (Fault, bool) {
+	if q := s.generateFaults[model]; len(q) > 0 {
+		f, _ := popFault(&q)
+		s.generateFaults[model] = q
+		return f, true
+	}
+	if q := s.generateFaults[""]; len(q) > 0 {
+		f, _ := popFault(&q)
+		s.generateFaults[""] = q
+		return f, true
+	}
+	if !s.hasModel(model) {
+		return ModelNotFound(model), true
+	}
+	if req.CachedContent != "" {
+		if cached, ok := s.caches[req.CachedContent]; !ok || cached != model {
+			return CacheNotFound(), true
+		}
+	}
+	if s.noThinkingLevel[model] && call.ThinkingLevel != "" {
+		return ThinkingUnsupported(), true
+	}
+	return Fault{}, false
+}
+
+func (s *Server) hasModel(model string) bool {
+	for _, m := range s.models {
+		if m == model {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) handleCacheCreate(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Model             string   `json:"model"`
+		DisplayName       string   `json:"displayName"`
+		TTL               string   `json:"ttl"`
+		SystemInstruction *content `json:"systemInstruction"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeFault(w, Fault{Code: http.StatusBadRequest, Status: "INVALID_ARGUMENT", Message: err.Error()})
+		return
+	}
+	model := strings.TrimPrefix(req.Model, "models/")
+	call := Call{Method: "cachedContents.create", Model: model, DisplayName: req.DisplayName}
+	if req.SystemInstruction != nil {
+		call.SystemPrompt = joinTexts(req.SystemInstruction.Parts)
+	}
+
+	s.mu.Lock()
+	if f, ok := popFault(&s.cacheFaults); ok {
+		call.Status = f.Code
+		s.calls = append(s.calls, call)
+		s.mu.Unlock()
+		writeFault(w, f)
+		return
+	}
+	s.cacheSeq++
+	name := fmt.Sprintf("cachedContents/fake-%d", s.cacheSeq)
+	s.caches[name] = model
+	call.Status = http.StatusOK
+	s.calls = append(s.calls, call)
+	s.mu.Unlock()
+
+	writeJSON(w, map[string]any{
+		"name":        name,
+		"model":       req.Model,
+		"displayName": req.DisplayName,
+	})
+}
+
+func (s *Server) handleCacheDelete(w http.ResponseWriter, name string) {
+	s.mu.Lock()
+	_, ok := s.caches[name]
+	delete(s.caches, name)
+	call := Call{Method: "cachedContents.delete", CachedContent: name, Status: http.StatusOK}
+	if !ok {
+		call.Status = http.StatusForbidden
+	}
+	s.calls = append(s.calls, call)
+	s.mu.Unlock()
+
+	if !ok {
+		writeFault(w, CacheNotFound())
+		return
+	}
+	writeJSON(w, map[string]any{})
+}
+
+func popFault(q *[]Fault) (Fault, bool) {
+	if len(*q) == 0 {
+		return Fault{}, false
+	}
+	f := (*q)[0]
+	*q = (*q)[1:]
+	return f, true
+}
+
+func joinTexts(parts []part) string {
+	var texts []string
+	for _, p := range parts {
+		if p.Text != "" {
+			texts = append(texts, p.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+func writeFault(w http.ResponseWriter, f Fault) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(f.Code)
+	body := map[string]any{"code": f.Code, "message": f.Message, "status": f.Status}
+	if len(f.Details) > 0 {
+		body["details"] = f.Details
+	}
+	json.NewEncoder(w).Encode(map[string]any{"error": body})
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
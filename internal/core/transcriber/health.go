@@ -0,0 +1,326 @@
+package transcriber
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/noricha-vr/voicecode/internal/core/trace"
+)
+
+const (
+	BreakerThresholdEnvVar = "VOICECODE_BREAKER_THRESHOLD"
+	BreakerCooldownEnvVar  = "VOICECODE_BREAKER_COOLDOWN"
+
+	// DefaultBreakerThreshold is 3 so that a single timeout or 503, which
+	// the regular fallback already absorbs, does not bench the preferred
+	// model for a whole cool-down; a model that keeps failing still opens
+	// within a few requests. Model-not-found opens the breaker at once.
+	DefaultBreakerThreshold = 3
+	DefaultBreakerCooldown  = 60 * time.Second
+	// MaxBreakerCooldown caps the cool-down, which doubles after each failed probe.
+	MaxBreakerCooldown = 10 * time.Minute
+)
+
+// BreakerState is the circuit breaker state of a model.
+type BreakerState int
+
+const (
+	// BreakerClosed: the model is healthy and receives traffic.
+	BreakerClosed BreakerState = iota
+	// BreakerOpen: the model failed recently and is skipped until the cool-down ends.
+	BreakerOpen
+	// BreakerHalfOpen: the cool-down ended and a single probe request is in flight.
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// ModelHealth is a snapshot of a model's circuit breaker.
+type ModelHealth struct {
+	Model               string
+	State               BreakerState
+	ConsecutiveFailures int
+	Successes           int
+	Failures            int
+	// OpenUntil is when an open breaker allows the next probe.
+	OpenUntil time.Time
+	LastError string
+}
+
+func (h ModelHealth) String() string {
+	s := fmt.Sprintf("%s state=%s ok=%d fail=%d consecutive=%d", h.Model, h.State, h.Successes, h.Failures, h.ConsecutiveFailures)
+	if h.State == BreakerOpen {
+		s += fmt.Sprintf(" retry_in=%s", time.Until(h.OpenUntil).Truncate(time.Second))
+	}
+	return s
+}
+
+// HealthReporter is implemented by backends that track per-model health.
+type HealthReporter interface {
+	Health() []ModelHealth
+}
+
+// healthTracker keeps a circuit breaker per model. A model opens after
+// threshold consecutive failures, stays open for the cool-down, then lets a
+// single half-open probe through: success closes it, failure reopens it with
+// a doubled cool-down.
+type healthTracker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu     sync.Mutex
+	models map[string]*breaker
+}
+
+type breaker struct {
+	ModelHealth
+	cooldown time.Duration // current cool-down; doubles on failed probes
+}
+
+func newHealthTracker(threshold int, cooldown time.Duration) *healthTracker {
+	return &healthTracker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+		models:    make(map[string]*breaker),
+	}
+}
+
+func (h *healthTracker) get(model string) *breaker {
+	b, ok := h.models[model]
+	if !ok {
+		b = &breaker{ModelHealth: ModelHealth{Model: model}, cooldown: h.cooldown}
+		h.models[model] = b
+	}
+	return b
+}
+
+// Allow reports whether a request may be sent to model. An open breaker whose
+// cool-down has elapsed turns half-open and admits exactly one probe.
+func (h *healthTracker) Allow(model string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	b := h.get(model)
+	switch b.State {
+	case BreakerOpen:
+		if h.now().Before(b.OpenUntil) {
+			return false
+		}
+		b.State = BreakerHalfOpen
+		log.Printf("[Gemini] %s の cool-down が終了したため probe リクエストを送ります", model)
+		return true
+	case BreakerHalfOpen:
+		return false // a probe is already in flight
+	default:
+		return true
+	}
+}
+
+// Available reports whether model is usable without consuming a probe.
+func (h *healthTracker) Available(model string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	b := h.get(model)
+	return b.State == BreakerClosed
+}
+
+// Success records a successful request and closes the breaker.
+func (h *healthTracker) Success(model string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	b := h.get(model)
+	if b.State != BreakerClosed {
+		log.Printf("[Gemini] %s が回復しました (breaker closed)", model)
+	}
+	b.State = BreakerClosed
+	b.ConsecutiveFailures = 0
+	b.Successes++
+	b.cooldown = h.cooldown
+}
+
+// Failure records a failed request. hard failures (e.g. model not found)
+// open the breaker immediately with the maximum cool-down.
+func (h *healthTracker) Failure(model string, err error, hard bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	b := h.get(model)
+	b.Failures++
+	b.ConsecutiveFailures++
+	if err != nil {
+		b.LastError = err.Error()
+	}
+
+	switch {
+	case hard:
+		b.cooldown = MaxBreakerCooldown
+	case b.State == BreakerHalfOpen:
+		b.cooldown = min(b.cooldown*2, MaxBreakerCooldown)
+	case b.ConsecutiveFailures < h.threshold:
+		return
+	}
+	b.State = BreakerOpen
+	b.OpenUntil = h.now().Add(b.cooldown)
+	log.Printf("[Gemini] %s を %s 休止します (連続失敗 %d 回)", model, b.cooldown, b.ConsecutiveFailures)
+}
+
+// Release returns a half-open probe that ended without a verdict (e.g. the
+// request was cancelled) so the next request can probe again.
+func (h *healthTracker) Release(model string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if b := h.get(model); b.State == BreakerHalfOpen {
+		b.State = BreakerOpen
+	}
+}
+
+// Snapshot returns the health of every model seen so far, sorted by name.
+func (h *healthTracker) Snapshot() []ModelHealth {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	out := make([]ModelHealth, 0, len(h.models))
+	for _, b := range h.models {
+		out = append(out, b.ModelHealth)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Model < out[j].Model })
+	return out
+}
+
+// resolveBreakerThreshold returns VOICECODE_BREAKER_THRESHOLD when set, else
+// configured, else DefaultBreakerThreshold.
+func resolveBreakerThreshold(configured int) int {
+	if configured < 1 {
+		configured = DefaultBreakerThreshold
+	}
+	v := strings.TrimSpace(os.Getenv(BreakerThresholdEnvVar))
+	if v == "" {
+		return configured
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		log.Printf("[Gemini] %s=%q は無効です。%d を使用します", BreakerThresholdEnvVar, v, configured)
+		return configured
+	}
+	return n
+}
+
+// resolveBreakerCooldown returns VOICECODE_BREAKER_COOLDOWN when set, else
+// configured, else DefaultBreakerCooldown.
+func resolveBreakerCooldown(configured time.Duration) time.Duration {
+	if configured <= 0 {
+		configured = DefaultBreakerCooldown
+	}
+	v := strings.TrimSpace(os.Getenv(BreakerCooldownEnvVar))
+	if v == "" {
+		return configured
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		log.Printf("[Gemini] %s=%q は無効です。%s を使用します", BreakerCooldownEnvVar, v, configured)
+		return configured
+	}
+	return d
+}
+
+var _ HealthReporter = (*Transcriber)(nil)
+
+// Health returns the circuit breaker state of every model used so far.
+func (t *Transcriber) Health() []ModelHealth {
+	return t.health.Snapshot()
+}
+
+// selectModel returns the model for the next request: the preferred model
+// unless its breaker is open, otherwise the current fallback.
+func (t *Transcriber) selectModel(ctx context.Context) string {
+	t.mu.Lock()
+	preferred, active := t.preferredModel, t.modelName
+	t.mu.Unlock()
+
+	if t.health.Allow(preferred) {
+		if active != preferred {
+			trace.FromContext(ctx).Eventf("gemini.health.try_preferred model=%s", preferred)
+		}
+		return preferred
+	}
+	if active != preferred && t.health.Allow(active) {
+		return active
+	}
+
+	// Every known model is cooling down: try one we have not used yet, or
+	// keep the active model when nothing else is available. The candidates
+	// come from the list New resolved, so an open breaker costs no
+	// models.list round trip, and the pick stays active until a breaker
+	// half-opens or it fails in turn.
+	if m := firstModel(t.models, t.unavailableModels("")); m != "" {
+		t.mu.Lock()
+		t.modelName = m
+		t.mu.Unlock()
+		return m
+	}
+	return active
+}
+
+// recordHealth updates the breaker of model after a request that was
+// answered by answeredBy (the hedge model may win over the primary; then
+// generateHedged has already recorded the primary's outcome).
+func (t *Transcriber) recordHealth(model, answeredBy string, err error) {
+	switch {
+	case err == nil:
+		t.health.Success(answeredBy)
+		t.mu.Lock()
+		if answeredBy == t.preferredModel && t.modelName != answeredBy {
+			log.Printf("[Gemini] 優先モデルに戻します: %s -> %s", t.modelName, answeredBy)
+			t.modelName = answeredBy
+		}
+		t.mu.Unlock()
+	case IsModelNotFound(err):
+		t.health.Failure(model, err, true)
+	case IsTransient(err):
+		t.health.Failure(model, err, false)
+	default:
+		// Auth, invalid requests and cancellations say nothing about the model.
+		t.health.Release(model)
+	}
+}
+
+// unavailableModels returns the models whose breaker is not closed, plus extra.
+func (t *Transcriber) unavailableModels(extra string) map[string]bool {
+	exclude := make(map[string]bool)
+	if extra != "" {
+		exclude[extra] = true
+	}
+	for _, h := range t.health.Snapshot() {
+		if h.State != BreakerClosed {
+			exclude[h.Model] = true
+		}
+	}
+	return exclude
+}
+
+// logHealth writes a one-line breaker summary to the timing log.
+func (t *Transcriber) logHealth(tl *trace.Timeline) {
+	if !tl.Enabled() {
+		return
+	}
+	var parts []string
+	for _, h := range t.health.Snapshot() {
+		parts = append(parts, fmt.Sprintf("%s=%s/%d", h.Model, h.State, h.ConsecutiveFailures))
+	}
+	tl.Eventf("gemini.health %s", strings.Join(parts, " "))
+}
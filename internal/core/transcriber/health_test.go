@@ -0,0 +1,207 @@
+package transcriber
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/noricha-vr/voicecode/internal/core/transcriber/geminitest"
+)
+
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func TestHealthTrackerBreaker(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	h := newHealthTracker(2, time.Minute)
+	h.now = clock.now
+	errBusy := errors.New("503")
+
+	state := func() BreakerState { return h.Snapshot()[0].State }
+
+	if !h.Allow("m") {
+		t.Fatal("new model should be allowed")
+	}
+	h.Failure("m", errBusy, false)
+	if state() != BreakerClosed {
+		t.Fatalf("state = %s after 1 failure, want closed (threshold 2)", state())
+	}
+	h.Failure("m", errBusy, false)
+	if state() != BreakerOpen || h.Allow("m") {
+		t.Fatalf("state = %s, want open and not allowed", state())
+	}
+
+	clock.advance(time.Minute)
+	if !h.Allow("m") || state() != BreakerHalfOpen {
+		t.Fatalf("state = %s, want a half-open probe after cool-down", state())
+	}
+	if h.Allow("m") {
+		t.Fatal("only one probe should be admitted while half-open")
+	}
+
+	h.Failure("m", errBusy, false)
+	clock.advance(time.Minute)
+	if h.Allow("m") {
+		t.Fatal("failed probe should double the cool-down")
+	}
+	clock.advance(time.Minute)
+	if !h.Allow("m") {
+		t.Fatal("probe should be allowed after the doubled cool-down")
+	}
+
+	h.Success("m")
+	got := h.Snapshot()[0]
+	if got.State != BreakerClosed || got.ConsecutiveFailures != 0 || got.Successes != 1 || got.Failures != 3 {
+		t.Errorf("after success = %+v", got)
+	}
+
+	h.Failure("m", errBusy, true)
+	if got := h.Snapshot()[0]; got.State != BreakerOpen || got.OpenUntil.Sub(clock.now()) != MaxBreakerCooldown {
+		t.Errorf("hard failure should open for %s, got %+v", MaxBreakerCooldown, got)
+	}
+}
+
+func TestTranscribeReturnsToPreferredModelAfterCooldown(t *testing.T) {
+	srv := geminitest.NewServer(PreferredModels...)
+	defer srv.Close()
+	t.Setenv(BreakerCooldownEnvVar, "30s")
+	t.Setenv(BreakerThresholdEnvVar, "1")
+
+	tr := newFakeTranscriber(t, srv, false)
+	clock := &fakeClock{t: time.Now()}
+	tr.health.now = clock.now
+	primary, fallback := PreferredModels[0], PreferredModels[1]
+
+	srv.FailGenerate(primary, geminitest.Unavailable(), geminitest.Unavailable())
+	if _, err := tr.Transcribe(context.Background(), testAudio, Options{}); err != nil {
+		t.Fatalf("Transcribe() error: %v", err)
+	}
+	if tr.ModelName() != fallback {
+		t.Fatalf("ModelName() = %q, want fallback %q", tr.ModelName(), fallback)
+	}
+
+	// While the breaker is open the primary is not contacted at all.
+	before := len(srv.GenerateCalls())
+	if _, err := tr.Transcribe(context.Background(), testAudio, Options{}); err != nil {
+		t.Fatalf("Transcribe() error: %v", err)
+	}
+	calls := srv.GenerateCalls()[before:]
+	if len(calls) != 1 || calls[0].Model != fallback {
+		t.Fatalf("calls during cool-down = %+v, want one call to %s", calls, fallback)
+	}
+
+	// After the cool-down a probe goes to the primary and restores it.
+	clock.advance(30 * time.Second)
+	before = len(srv.GenerateCalls())
+	if _, err := tr.Transcribe(context.Background(), testAudio, Options{}); err != nil {
+		t.Fatalf("Transcribe() error: %v", err)
+	}
+	calls = srv.GenerateCalls()[before:]
+	if len(calls) != 1 || calls[0].Model != primary {
+		t.Fatalf("calls after cool-down = %+v, want a probe to %s", calls, primary)
+	}
+	if tr.ModelName() != primary {
+		t.Errorf("ModelName() = %q, want preferred %q restored", tr.ModelName(), primary)
+	}
+
+	health := map[string]ModelHealth{}
+	for _, h := range tr.Health() {
+		health[h.Model] = h
+	}
+	if h := health[primary]; h.State != BreakerClosed || h.Failures != 1 || h.Successes != 1 {
+		t.Errorf("primary health = %+v", h)
+	}
+	if h := health[fallback]; h.Successes != 2 {
+		t.Errorf("fallback health = %+v", h)
+	}
+}
+
+func TestTranscribeDefaultThresholdKeepsPreferredModel(t *testing.T) {
+	srv := geminitest.NewServer(PreferredModels...)
+	defer srv.Close()
+	t.Setenv(BreakerThresholdEnvVar, "")
+
+	tr := newFakeTranscriber(t, srv, false)
+	primary := PreferredModels[0]
+
+	// A single 503 falls back for that request only.
+	srv.FailGenerate(primary, geminitest.Unavailable(), geminitest.Unavailable())
+	if _, err := tr.Transcribe(context.Background(), testAudio, Options{}); err != nil {
+		t.Fatalf("Transcribe() error: %v", err)
+	}
+	before := len(srv.GenerateCalls())
+	if _, err := tr.Transcribe(context.Background(), testAudio, Options{}); err != nil {
+		t.Fatalf("Transcribe() error: %v", err)
+	}
+	calls := srv.GenerateCalls()[before:]
+	if len(calls) != 1 || calls[0].Model != primary {
+		t.Fatalf("calls after one failure = %+v, want the preferred %s again", calls, primary)
+	}
+	for _, h := range tr.Health() {
+		if h.Model == primary && (h.State != BreakerClosed || h.Failures != 1) {
+			t.Errorf("primary health = %+v, want closed after one failure", h)
+		}
+	}
+}
+
+func TestSelectModelDoesNotListModelsWhileBreakersAreOpen(t *testing.T) {
+	srv := geminitest.NewServer(PreferredModels...)
+	defer srv.Close()
+	t.Setenv(BreakerThresholdEnvVar, "1")
+
+	tr := newFakeTranscriber(t, srv, false)
+	primary, fallback, third := PreferredModels[0], PreferredModels[1], PreferredModels[2]
+
+	srv.FailGenerate(primary, geminitest.ModelNotFound(primary))
+	srv.FailGenerate(fallback, geminitest.ModelNotFound(fallback))
+	if _, err := tr.Transcribe(context.Background(), testAudio, Options{}); err == nil {
+		t.Fatal("Transcribe() should fail when the primary and the fallback are gone")
+	}
+
+	before := len(srv.Calls())
+	for i := 0; i < 3; i++ {
+		if _, err := tr.Transcribe(context.Background(), testAudio, Options{}); err != nil {
+			t.Fatalf("Transcribe() #%d error: %v", i, err)
+		}
+	}
+	for _, c := range srv.Calls()[before:] {
+		if c.Method == "models.list" {
+			t.Fatalf("calls while the breakers are open = %+v, want no models.list", srv.Calls()[before:])
+		}
+		if c.Method == "generateContent" && c.Model != third {
+			t.Errorf("generateContent went to %s, want %s", c.Model, third)
+		}
+	}
+}
+
+func TestResolveBreakerSettings(t *testing.T) {
+	tests := []struct {
+		name          string
+		thresholdEnv  string
+		cooldownEnv   string
+		threshold     int
+		cooldown      time.Duration
+		wantThreshold int
+		wantCooldown  time.Duration
+	}{
+		{"defaults", "", "", 0, 0, DefaultBreakerThreshold, DefaultBreakerCooldown},
+		{"settings", "", "", 5, 2 * time.Minute, 5, 2 * time.Minute},
+		{"env overrides settings", "2", "10s", 5, 2 * time.Minute, 2, 10 * time.Second},
+		{"invalid env keeps settings", "zero", "-1s", 5, 2 * time.Minute, 5, 2 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(BreakerThresholdEnvVar, tt.thresholdEnv)
+			t.Setenv(BreakerCooldownEnvVar, tt.cooldownEnv)
+			if got := resolveBreakerThreshold(tt.threshold); got != tt.wantThreshold {
+				t.Errorf("resolveBreakerThreshold(%d) = %d, want %d", tt.threshold, got, tt.wantThreshold)
+			}
+			if got := resolveBreakerCooldown(tt.cooldown); got != tt.wantCooldown {
+				t.Errorf("resolveBreakerCooldown(%s) = %s, want %s", tt.cooldown, got, tt.wantCooldown)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package transcriber
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/noricha-vr/voicecode/internal/core/trace"
+	"google.golang.org/genai"
+)
+
+const (
+	HedgeDelayEnvVar = "VOICECODE_HEDGE_DELAY"
+	HedgeModelEnvVar = "VOICECODE_HEDGE_MODEL"
+)
+
+type hedgeResult struct {
+	model string
+	resp  *genai.GenerateContentResponse
+	err   error
+}
+
+// generateHedged sends the request to model and, if it has not answered within
+// the hedge delay, races a second request to the hedge model. The first
+// non-empty answer wins and the other request is cancelled.
+//
+// If the primary fails before the hedge fires, its error is returned as-is so
+// Transcribe can apply the regular fallback.
+// It returns the response and the model that produced it. When the hedge
+// wins, the primary's own outcome is recorded here: its error, or a transient
+// failure when it had not answered yet, so a primary that keeps hanging
+// opens its breaker instead of costing every request the hedge delay. A
+// failed hedge request is recorded under the hedge model.
+func (t *Transcriber) generateHedged(ctx context.Context, model string, audioData []byte, opts Options) (*genai.GenerateContentResponse, string, error) {
+	t.mu.Lock()
+	hedgeModel := t.hedgeModel
+	t.mu.Unlock()
+	if t.hedgeDelay <= 0 || hedgeModel == "" || hedgeModel == model || !t.health.Available(hedgeModel) {
+		resp, err := t.generateContentWithRetry(ctx, model, audioData, opts)
+		return resp, model, err
+	}
+
+	tl := trace.FromContext(ctx)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Buffered so the losing goroutine never blocks after we return.
+	results := make(chan hedgeResult, 2)
+	launch := func(m string) {
+		go func() {
+			resp, err := t.generateContentWithRetry(ctx, m, audioData, opts)
+			results <- hedgeResult{model: m, resp: resp, err: err}
+		}()
+	}
+
+	start := time.Now()
+	launch(model)
+	inflight := 1
+	hedged := false
+	timer := time.NewTimer(t.hedgeDelay)
+	defer timer.Stop()
+
+	var empty *hedgeResult
+	var primaryErr, lastErr error
+	// settle records the primary's outcome when winner answered instead.
+	settle := func(winner string) {
+		if winner == model {
+			return
+		}
+		outcome := primaryErr
+		switch {
+		case outcome != nil:
+		case empty != nil && empty.model == model:
+			// The primary answered, just with empty text.
+		default:
+			outcome = &APIError{Kind: KindTransient, Err: fmt.Errorf("%s did not answer within %s; %s answered first", model, time.Since(start).Truncate(time.Millisecond), winner)}
+		}
+		t.recordHealth(model, model, outcome)
+	}
+	for inflight > 0 {
+		select {
+		case <-timer.C:
+			hedged = true
+			launch(hedgeModel)
+			inflight++
+			tl.Eventf("gemini.hedge.fire model=%s after=%s", hedgeModel, t.hedgeDelay)
+
+		case r := <-results:
+			inflight--
+			if r.err != nil {
+				if r.model == model {
+					primaryErr = r.err
+				}
+				lastErr = r.err
+				if !hedged {
+					return nil, model, r.err
+				}
+				tl.Eventf("gemini.hedge.failed model=%s err=%v", r.model, r.err)
+				if r.model != model {
+					// A failing hedge model must stop being raced.
+					t.recordHealth(r.model, r.model, r.err)
+				}
+				continue
+			}
+			if strings.TrimSpace(r.resp.Text()) == "" && inflight > 0 {
+				// An empty answer may be a legitimate silence result; keep it
+				// as a fallback but give the other model a chance.
+				res := r
+				empty = &res
+				continue
+			}
+			if hedged {
+				tl.Eventf("gemini.hedge.winner model=%s elapsed=%s", r.model, time.Since(start).Truncate(time.Millisecond))
+				if r.model != model {
+					log.Printf("[Gemini] Hedged request が先に応答しました: %s (primary=%s)", r.model, model)
+				}
+			}
+			settle(r.model)
+			return r.resp, r.model, nil
+		}
+	}
+
+	if empty != nil {
+		tl.Eventf("gemini.hedge.winner model=%s elapsed=%s empty=true", empty.model, time.Since(start).Truncate(time.Millisecond))
+		settle(empty.model)
+		return empty.resp, empty.model, nil
+	}
+	if primaryErr != nil {
+		return nil, model, primaryErr
+	}
+	return nil, model, lastErr
+}
+
+// resolveHedgeDelay returns VOICECODE_HEDGE_DELAY when set, else configured.
+// Zero disables hedged requests.
+func resolveHedgeDelay(configured time.Duration) time.Duration {
+	configured = max(configured, 0)
+	raw := strings.TrimSpace(os.Getenv(HedgeDelayEnvVar))
+	if raw == "" {
+		return configured
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d < 0 {
+		log.Printf("[Gemini] %s=%q は無効です。%s を使用します", HedgeDelayEnvVar, raw, configured)
+		return configured
+	}
+	return d
+}
+
+// resolveHedgeModel picks the model raced against primary: the configured
+// VOICECODE_HEDGE_MODEL, or else the next model in models.
+func resolveHedgeModel(models []string, primary string) string {
+	if configured := strings.TrimSpace(os.Getenv(HedgeModelEnvVar)); configured != "" {
+		if m := normalizeModelName(configured); m != primary {
+			return m
+		}
+		return ""
+	}
+	return firstModel(models, map[string]bool{primary: true})
+}
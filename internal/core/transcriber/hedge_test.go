@@ -0,0 +1,158 @@
+package transcriber
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/noricha-vr/voicecode/internal/core/trace"
+	"github.com/noricha-vr/voicecode/internal/core/transcriber/geminitest"
+)
+
+func newHedgedTranscriber(t *testing.T, srv *geminitest.Server, delay string) *Transcriber {
+	t.Helper()
+	t.Setenv(HedgeDelayEnvVar, delay)
+	t.Setenv(HedgeModelEnvVar, "")
+	return newFakeTranscriber(t, srv, false)
+}
+
+func TestHedgedRequestSlowPrimaryLoses(t *testing.T) {
+	srv := geminitest.NewServer(PreferredModels...)
+	defer srv.Close()
+
+	tr := newHedgedTranscriber(t, srv, "50ms")
+	primary, hedge := PreferredModels[0], PreferredModels[1]
+	if tr.hedgeModel != hedge {
+		t.Fatalf("hedgeModel = %q, want %q", tr.hedgeModel, hedge)
+	}
+	srv.SetDelay(primary, 2*time.Second)
+	srv.SetText(hedge, "hedge answer")
+
+	var buf bytes.Buffer
+	tl := trace.NewWith(log.New(&buf, "", 0), nil, true, "test", time.Now())
+	ctx := trace.WithTimeline(context.Background(), tl)
+
+	start := time.Now()
+	text, err := tr.Transcribe(ctx, testAudio, Options{})
+	if err != nil {
+		t.Fatalf("Transcribe() error: %v", err)
+	}
+	if text != "hedge answer" {
+		t.Errorf("text = %q, want hedge answer", text)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Transcribe took %s, hedge should have short-circuited the slow primary", elapsed)
+	}
+	if tr.ModelName() != primary {
+		t.Errorf("ModelName() = %q, hedging must not switch the primary model", tr.ModelName())
+	}
+	if out := buf.String(); !strings.Contains(out, "gemini.hedge.winner model="+hedge) {
+		t.Errorf("timeline should record the winner, got %q", out)
+	}
+	// The primary lost without answering: that counts against it.
+	for _, h := range tr.Health() {
+		if h.Model == primary && (h.Failures != 1 || h.Successes != 0) {
+			t.Errorf("primary health = %+v, want the lost race recorded as a failure", h)
+		}
+	}
+}
+
+func TestHedgedRequestRecordsHedgeFailure(t *testing.T) {
+	srv := geminitest.NewServer(PreferredModels...)
+	defer srv.Close()
+
+	tr := newHedgedTranscriber(t, srv, "20ms")
+	primary, hedge := PreferredModels[0], PreferredModels[1]
+	// The hedge retries once after RetryBackoffSeconds, so the primary must
+	// outlast both of its attempts.
+	srv.SetDelay(primary, time.Second)
+	srv.FailGenerate(hedge, geminitest.Unavailable(), geminitest.Unavailable())
+
+	if _, err := tr.Transcribe(context.Background(), testAudio, Options{}); err != nil {
+		t.Fatalf("Transcribe() error: %v", err)
+	}
+	for _, h := range tr.Health() {
+		if h.Model == hedge && h.Failures != 1 {
+			t.Errorf("hedge health = %+v, want its failure recorded", h)
+		}
+	}
+}
+
+func TestHedgedRequestFastPrimaryDoesNotFire(t *testing.T) {
+	srv := geminitest.NewServer(PreferredModels...)
+	defer srv.Close()
+
+	tr := newHedgedTranscriber(t, srv, "500ms")
+	if _, err := tr.Transcribe(context.Background(), testAudio, Options{}); err != nil {
+		t.Fatalf("Transcribe() error: %v", err)
+	}
+	calls := srv.GenerateCalls()
+	if len(calls) != 1 || calls[0].Model != PreferredModels[0] {
+		t.Errorf("expected a single primary call, got %+v", calls)
+	}
+}
+
+func TestHedgedRequestEmptyAnswerWaitsForOther(t *testing.T) {
+	srv := geminitest.NewServer(PreferredModels...)
+	defer srv.Close()
+
+	tr := newHedgedTranscriber(t, srv, "20ms")
+	primary, hedge := PreferredModels[0], PreferredModels[1]
+	srv.SetDelay(primary, 100*time.Millisecond)
+	srv.SetText(primary, "")
+	srv.SetDelay(hedge, 200*time.Millisecond)
+	srv.SetText(hedge, "聞き取れた内容")
+
+	text, err := tr.Transcribe(context.Background(), testAudio, Options{})
+	if err != nil {
+		t.Fatalf("Transcribe() error: %v", err)
+	}
+	if text != "聞き取れた内容" {
+		t.Errorf("text = %q, want the non-empty hedge answer", text)
+	}
+	// The primary did answer, so it is not blamed.
+	for _, h := range tr.Health() {
+		if h.Model == primary && (h.Failures != 0 || h.Successes != 1) {
+			t.Errorf("primary health = %+v, want its empty answer recorded as a success", h)
+		}
+	}
+}
+
+func TestHedgedRequestDisabledByDefault(t *testing.T) {
+	srv := geminitest.NewServer(PreferredModels...)
+	defer srv.Close()
+
+	tr := newHedgedTranscriber(t, srv, "")
+	if tr.hedgeModel != "" || tr.hedgeDelay != 0 {
+		t.Errorf("hedging should be disabled, got model=%q delay=%s", tr.hedgeModel, tr.hedgeDelay)
+	}
+}
+
+func TestResolveHedgeDelay(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        string
+		configured time.Duration
+		want       time.Duration
+	}{
+		{"unset", "", 0, 0},
+		{"env", "300ms", 0, 300 * time.Millisecond},
+		{"invalid env", "invalid", 0, 0},
+		{"negative env", "-1s", 0, 0},
+		{"settings", "", 1500 * time.Millisecond, 1500 * time.Millisecond},
+		{"env overrides settings", "300ms", 1500 * time.Millisecond, 300 * time.Millisecond},
+		{"env disables settings", "0s", 1500 * time.Millisecond, 0},
+		{"invalid env keeps settings", "invalid", 1500 * time.Millisecond, 1500 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(HedgeDelayEnvVar, tt.env)
+			if got := resolveHedgeDelay(tt.configured); got != tt.want {
+				t.Errorf("resolveHedgeDelay() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -83,10 +83,15 @@ func listAvailableModels(ctx context.Context, client *genai.Client) ([]string, e
 
 // ResolveModel determines the best model to use.
 func ResolveModel(ctx context.Context, client *genai.Client, exclude map[string]bool) string {
-	if exclude == nil {
-		exclude = make(map[string]bool)
-	}
-	candidates := buildModelCandidates()
+	return firstModel(ResolveModels(ctx, client), exclude)
+}
+
+// ResolveModels returns the usable models in priority order: the candidates
+// (VOICECODE_GEMINI_MODEL, then PreferredModels) the API lists, followed by
+// any other listed model. When the list cannot be fetched it falls back to
+// the configured model and DefaultModel. Transcriber resolves it once and
+// picks fallbacks from it without listing the models again.
+func ResolveModels(ctx context.Context, client *genai.Client) []string {
 	configured := strings.TrimSpace(os.Getenv(ModelEnvVar))
 	if configured != "" {
 		configured = normalizeModelName(configured)
@@ -94,34 +99,44 @@ func ResolveModel(ctx context.Context, client *genai.Client, exclude map[string]
 
 	available, err := listAvailableModels(ctx, client)
 	if err != nil {
-		if configured != "" && !exclude[configured] {
-			log.Printf("[Gemini] モデル一覧取得に失敗したため環境変数モデルを使用します: %s (%v)", configured, err)
-			return configured
+		log.Printf("[Gemini] モデル一覧取得に失敗したため環境変数モデルと既定モデルを使用します (%v)", err)
+		var models []string
+		if configured != "" {
+			models = append(models, configured)
 		}
-		if !exclude[DefaultModel] {
-			log.Printf("[Gemini] モデル一覧取得に失敗したため既定モデルを使用します: %s (%v)", DefaultModel, err)
-			return DefaultModel
+		if configured != DefaultModel {
+			models = append(models, DefaultModel)
 		}
-		return ""
+		return models
 	}
 
 	availSet := make(map[string]bool, len(available))
 	for _, m := range available {
 		availSet[m] = true
 	}
-
-	for _, c := range candidates {
-		if availSet[c] && !exclude[c] {
-			return c
+	var models []string
+	seen := make(map[string]bool)
+	for _, c := range buildModelCandidates() {
+		if availSet[c] && !seen[c] {
+			models = append(models, c)
+			seen[c] = true
 		}
 	}
-
 	for _, m := range available {
+		if !seen[m] {
+			models = append(models, m)
+			seen[m] = true
+		}
+	}
+	return models
+}
+
+// firstModel returns the first model in models that is not excluded, or "".
+func firstModel(models []string, exclude map[string]bool) string {
+	for _, m := range models {
 		if !exclude[m] {
-			log.Printf("[Gemini] 候補モデルが見つからないため利用可能モデルにフォールバックします: %s", m)
 			return m
 		}
 	}
-
 	return ""
 }
@@ -0,0 +1,319 @@
+package transcriber
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/noricha-vr/voicecode/internal/core/prompt"
+	"github.com/noricha-vr/voicecode/internal/core/settings"
+	"github.com/noricha-vr/voicecode/internal/core/trace"
+)
+
+const (
+	OpenAIBackendName  = "openai"
+	OpenAIAPIKeyEnvVar = "OPENAI_API_KEY"
+	OpenAITimeout      = 30 * time.Second
+	maxErrorBodyBytes  = 1024
+	// WhisperPromptMaxTokens is the prompt length Whisper considers; it
+	// ignores everything before the last 224 tokens.
+	WhisperPromptMaxTokens = 224
+)
+
+func init() {
+	Register(OpenAIBackendName, func(ctx context.Context, cfg *settings.Settings) (Backend, error) {
+		oc := cfg.OpenAI
+		if oc.Language == "" && cfg.Language != settings.LanguageAuto {
+			oc.Language = cfg.Language
+		}
+		b, err := NewOpenAI(oc)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.DictionaryProfile != "" {
+			if _, _, err := b.SetDictionaryProfile(ctx, cfg.DictionaryProfile); err != nil {
+				b.Close()
+				return nil, err
+			}
+		}
+		return b, nil
+	})
+}
+
+// OpenAIBackend transcribes audio via an OpenAI-compatible
+// multipart /v1/audio/transcriptions endpoint (OpenAI, whisper.cpp server, faster-whisper, etc.).
+type OpenAIBackend struct {
+	httpClient *http.Client
+	endpoint   string
+	model      string
+	apiKey     string
+	language   string
+
+	mu                sync.Mutex // guards the dictionary fields below
+	dictionaryPath    string
+	dictionaryProfile string
+	prompt            string
+}
+
+var (
+	_ Backend            = (*OpenAIBackend)(nil)
+	_ DictionaryReloader = (*OpenAIBackend)(nil)
+)
+
+// NewOpenAI creates an OpenAI-compatible backend.
+// The API key falls back to OPENAI_API_KEY and may be empty for local servers.
+// The user dictionary is loaded once and sent as the "prompt" field.
+func NewOpenAI(cfg settings.OpenAISettings) (*OpenAIBackend, error) {
+	baseURL := strings.TrimRight(strings.TrimSpace(cfg.BaseURL), "/")
+	if baseURL == "" {
+		baseURL = settings.DefaultOpenAIBaseURL
+	}
+	model := strings.TrimSpace(cfg.Model)
+	if model == "" {
+		model = settings.DefaultOpenAIModel
+	}
+	apiKey := cfg.APIKey
+	if apiKey == "" {
+		apiKey = os.Getenv(OpenAIAPIKeyEnvVar)
+	}
+
+	dictPath := prompt.DictionaryPath()
+	dict, err := prompt.LoadDictionary(dictPath)
+	if err != nil {
+		log.Printf("[OpenAI] ユーザー辞書を読み込めないため prompt なしで続行します: %v", err)
+	}
+	vocabulary := whisperVocabulary(dict)
+
+	b := &OpenAIBackend{
+		httpClient:     &http.Client{Timeout: OpenAITimeout},
+		endpoint:       baseURL + "/audio/transcriptions",
+		model:          model,
+		apiKey:         apiKey,
+		language:       strings.TrimSpace(cfg.Language),
+		dictionaryPath: dictPath,
+		prompt:         vocabulary,
+	}
+	log.Printf("[OpenAI] endpoint=%s model=%s", b.endpoint, b.model)
+	return b, nil
+}
+
+// Transcribe uploads the audio as multipart form data and returns the text.
+// opts.Mode and opts.Context are ignored: the endpoint has no system prompt
+// to replace and no room for reference text.
+func (b *OpenAIBackend) Transcribe(ctx context.Context, audioData []byte, opts Options) (string, error) {
+	stepper := trace.FromContext(ctx)
+	start := time.Now()
+
+	model := b.model
+	if opts.Model != "" {
+		model = opts.Model
+	}
+
+	buildDone := stepper.Step("openai.buildMultipart")
+	body, contentType, err := b.buildMultipart(model, audioData, opts)
+	buildDone(err)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.endpoint, body)
+	if err != nil {
+		return "", fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	if b.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+b.apiKey)
+	}
+
+	postDone := stepper.Step("openai.audioTranscriptions")
+	text, err := b.do(req)
+	postDone(err)
+	elapsed := time.Since(start).Seconds()
+	if err != nil {
+		log.Printf("[OpenAI %.2fs] API呼び出しに失敗しました(model=%s): %v", elapsed, model, err)
+		return "", err
+	}
+
+	log.Printf("[OpenAI %.2fs] %s (model=%s)", elapsed, text, model)
+	return text, nil
+}
+
+// Name returns the registry name of the backend.
+func (b *OpenAIBackend) Name() string {
+	return OpenAIBackendName
+}
+
+// ModelName returns the configured model.
+func (b *OpenAIBackend) ModelName() string {
+	return b.model
+}
+
+// Close releases idle HTTP connections.
+func (b *OpenAIBackend) Close() error {
+	b.httpClient.CloseIdleConnections()
+	return nil
+}
+
+func (b *OpenAIBackend) buildMultipart(model string, audioData []byte, opts Options) (io.Reader, string, error) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+
+	fields := [][2]string{
+		{"model", model},
+		{"response_format", "json"},
+		{"temperature", "0"},
+	}
+	if b.language != "" {
+		fields = append(fields, [2]string{"language", b.language})
+	}
+	b.mu.Lock()
+	vocabulary := b.prompt
+	b.mu.Unlock()
+	if vocabulary != "" {
+		fields = append(fields, [2]string{"prompt", vocabulary})
+	}
+	for _, f := range fields {
+		if err := w.WriteField(f[0], f[1]); err != nil {
+			return nil, "", fmt.Errorf("write field %s: %w", f[0], err)
+		}
+	}
+
+	fw, err := w.CreateFormFile("file", "audio"+fileExtension(opts.mimeType()))
+	if err != nil {
+		return nil, "", fmt.Errorf("create form file: %w", err)
+	}
+	if _, err := fw.Write(audioData); err != nil {
+		return nil, "", fmt.Errorf("write audio: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, "", fmt.Errorf("close multipart writer: %w", err)
+	}
+	return &buf, w.FormDataContentType(), nil
+}
+
+func (b *OpenAIBackend) do(req *http.Request) (string, error) {
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("post audio transcription: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg := strings.TrimSpace(string(data))
+		if len(msg) > maxErrorBodyBytes {
+			msg = msg[:maxErrorBodyBytes]
+		}
+		return "", openAIError(resp, data, msg)
+	}
+
+	var parsed struct {
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return "", fmt.Errorf("parse response: %w", err)
+	}
+	return strings.TrimSpace(parsed.Text), nil
+}
+
+// openAIError classifies a non-2xx response. OpenAI reports an exhausted
+// billing quota as HTTP 429 with error.code "insufficient_quota".
+func openAIError(resp *http.Response, body []byte, msg string) *APIError {
+	var parsed struct {
+		Error struct {
+			Message string `json:"message"`
+			Code    string `json:"code"`
+		} `json:"error"`
+	}
+	_ = json.Unmarshal(body, &parsed)
+
+	e := &APIError{
+		Kind:       kindForStatus(resp.StatusCode, "", "", ""),
+		Code:       resp.StatusCode,
+		Reason:     parsed.Error.Code,
+		Message:    parsed.Error.Message,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		Err:        fmt.Errorf("audio transcription failed: HTTP %d: %s", resp.StatusCode, msg),
+	}
+	switch parsed.Error.Code {
+	case "insufficient_quota":
+		e.Kind = KindQuota
+	case "invalid_api_key":
+		e.Kind = KindAuth
+	}
+	return e
+}
+
+// fileExtension returns the upload filename extension for an audio MIME type.
+// Whisper-compatible servers sniff the format from the filename.
+func fileExtension(mimeType string) string {
+	switch mimeType {
+	case "audio/flac":
+		return ".flac"
+	case "audio/mpeg", "audio/mp3":
+		return ".mp3"
+	case "audio/ogg":
+		return ".ogg"
+	default:
+		return ".wav"
+	}
+}
+
+// ReloadDictionary re-reads the dictionary and updates the prompt vocabulary.
+func (b *OpenAIBackend) ReloadDictionary(ctx context.Context) (prompt.Dictionary, bool, error) {
+	b.mu.Lock()
+	profile := b.dictionaryProfile
+	b.mu.Unlock()
+
+	dict, err := prompt.LoadProfile(b.dictionaryPath, profile)
+	if err != nil {
+		return prompt.Dictionary{}, false, fmt.Errorf("load dictionary: %w", err)
+	}
+	changed, err := b.SetDictionary(ctx, profile, dict)
+	return dict, changed, err
+}
+
+// SetDictionary installs an already loaded dictionary as the prompt vocabulary.
+func (b *OpenAIBackend) SetDictionary(ctx context.Context, profile string, dict prompt.Dictionary) (bool, error) {
+	vocabulary := whisperVocabulary(dict)
+
+	b.mu.Lock()
+	b.dictionaryProfile = profile
+	changed := vocabulary != b.prompt
+	b.prompt = vocabulary
+	b.mu.Unlock()
+	if changed {
+		log.Printf("[OpenAI] ユーザー辞書を再読み込みしました (profile=%s, %d 語)", profileLabel(dict.Profile), dict.Terms())
+	}
+	return changed, nil
+}
+
+// SetDictionaryProfile switches the dictionary profile and reloads it.
+func (b *OpenAIBackend) SetDictionaryProfile(ctx context.Context, profile string) (prompt.Dictionary, bool, error) {
+	b.mu.Lock()
+	b.dictionaryProfile = profile
+	b.mu.Unlock()
+	return b.ReloadDictionary(ctx)
+}
+
+// whisperVocabulary returns the dictionary vocabulary that fits in
+// WhisperPromptMaxTokens, dropping the lowest-priority words first.
+func whisperVocabulary(d prompt.Dictionary) string {
+	vocabulary, dropped := d.VocabularyWithin(WhisperPromptMaxTokens)
+	if dropped > 0 {
+		log.Printf("[OpenAI] prompt が約 %d トークンを超えるため、優先度の低い %d 語を省略します", WhisperPromptMaxTokens, dropped)
+	}
+	return vocabulary
+}
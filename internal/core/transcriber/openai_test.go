@@ -0,0 +1,224 @@
+package transcriber
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/noricha-vr/voicecode/internal/core/prompt"
+	"github.com/noricha-vr/voicecode/internal/core/settings"
+)
+
+func TestOpenAIBackendTranscribe(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	dictDir := filepath.Join(home, ".voicecoding")
+	if err := os.MkdirAll(dictDir, 0o755); err != nil {
+		t.Fatalf("MkdirAll error: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dictDir, "dictionary.txt"), []byte("リアクト\tReact\nuseState\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+
+	var gotFields map[string]string
+	var gotFile []byte
+	var gotFilename, gotAuth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/audio/transcriptions" {
+			http.NotFound(w, r)
+			return
+		}
+		gotAuth = r.Header.Get("Authorization")
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("ParseMultipartForm error: %v", err)
+		}
+		gotFields = map[string]string{}
+		for k, v := range r.MultipartForm.Value {
+			gotFields[k] = v[0]
+		}
+		f, hdr, err := r.FormFile("file")
+		if err != nil {
+			t.Errorf("FormFile error: %v", err)
+			return
+		}
+		gotFilename = hdr.Filename
+		gotFile, _ = io.ReadAll(f)
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"text":" ReactのuseStateを使う \n"}`)
+	}))
+	defer srv.Close()
+
+	b, err := NewOpenAI(settings.OpenAISettings{
+		BaseURL:  srv.URL + "/v1/",
+		Model:    "large-v3",
+		APIKey:   "sk-test",
+		Language: "ja",
+	})
+	if err != nil {
+		t.Fatalf("NewOpenAI() error: %v", err)
+	}
+
+	text, err := b.Transcribe(context.Background(), []byte("RIFFdata"), Options{})
+	if err != nil {
+		t.Fatalf("Transcribe() error: %v", err)
+	}
+	if text != "ReactのuseStateを使う" {
+		t.Errorf("text = %q", text)
+	}
+	if gotAuth != "Bearer sk-test" {
+		t.Errorf("Authorization = %q", gotAuth)
+	}
+	if gotFields["model"] != "large-v3" || gotFields["language"] != "ja" {
+		t.Errorf("unexpected fields: %v", gotFields)
+	}
+	if gotFields["prompt"] != "React, useState" {
+		t.Errorf("prompt = %q, want dictionary vocabulary", gotFields["prompt"])
+	}
+	if gotFilename != "audio.wav" || string(gotFile) != "RIFFdata" {
+		t.Errorf("file = %q (%q)", gotFilename, gotFile)
+	}
+	if b.Name() != OpenAIBackendName || b.ModelName() != "large-v3" {
+		t.Errorf("Name/ModelName = %q/%q", b.Name(), b.ModelName())
+	}
+}
+
+func TestOpenAIBackendHTTPError(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(OpenAIAPIKeyEnvVar, "")
+
+	var gotAuth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		http.Error(w, `{"error":"overloaded"}`, http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	b, err := NewOpenAI(settings.OpenAISettings{BaseURL: srv.URL})
+	if err != nil {
+		t.Fatalf("NewOpenAI() error: %v", err)
+	}
+
+	_, err = b.Transcribe(context.Background(), []byte("RIFF"), Options{})
+	if err == nil {
+		t.Fatal("Transcribe() should fail on HTTP 503")
+	}
+	if !IsTransient(err) {
+		t.Errorf("HTTP 503 should be transient, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "overloaded") {
+		t.Errorf("error should include response body, got %v", err)
+	}
+	if gotAuth != "" {
+		t.Errorf("Authorization should be omitted without API key, got %q", gotAuth)
+	}
+}
+
+func TestOpenAIBackendModelOverride(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	var gotModel string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotModel = r.FormValue("model")
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"text":"ok"}`)
+	}))
+	defer srv.Close()
+
+	b, err := NewOpenAI(settings.OpenAISettings{BaseURL: srv.URL, Model: "large-v3"})
+	if err != nil {
+		t.Fatalf("NewOpenAI() error: %v", err)
+	}
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	if _, err := b.Transcribe(context.Background(), []byte("RIFF"), Options{Model: "distil-large-v3"}); err != nil {
+		t.Fatalf("Transcribe() error: %v", err)
+	}
+	if gotModel != "distil-large-v3" {
+		t.Errorf("model field = %q, want the pinned model", gotModel)
+	}
+	if out := logs.String(); !strings.Contains(out, "(model=distil-large-v3)") {
+		t.Errorf("log should name the model that was sent, got %q", out)
+	}
+}
+
+func TestOpenAIBackendDictionaryProfile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	profiles := filepath.Join(home, ".voicecoding", "dictionaries")
+	if err := os.MkdirAll(profiles, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".voicecoding", "dictionary.txt"), []byte("useState\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(profiles, "go.txt"), []byte("ゴルーチン\tgoroutine\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := NewOpenAI(settings.OpenAISettings{BaseURL: "http://127.0.0.1:0"})
+	if err != nil {
+		t.Fatalf("NewOpenAI() error: %v", err)
+	}
+	if _, changed, err := b.SetDictionaryProfile(context.Background(), "go"); err != nil || !changed {
+		t.Fatalf("SetDictionaryProfile() = changed %v err %v", changed, err)
+	}
+	if b.prompt != "goroutine, useState" {
+		t.Errorf("prompt = %q, want the profile terms first", b.prompt)
+	}
+	if _, changed, _ := b.ReloadDictionary(context.Background()); changed {
+		t.Error("reloading an unchanged dictionary should report no change")
+	}
+}
+
+func TestOpenAIFactoryInvalidProfile(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	cfg := settings.Default()
+	cfg.Backend = OpenAIBackendName
+	cfg.DictionaryProfile = "../outside"
+
+	b, err := NewBackend(context.Background(), cfg)
+	if err == nil {
+		t.Fatal("NewBackend() should fail for an invalid dictionary profile")
+	}
+	if b != nil {
+		t.Errorf("NewBackend() = %v, want a nil backend with the error", b)
+	}
+}
+
+func TestOpenAIBackendPromptFitsWhisperLimit(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	var dict strings.Builder
+	for i := 0; i < 200; i++ {
+		fmt.Fprintf(&dict, "longterm%03d\n", i)
+	}
+	dict.WriteString("ゴルーチン\tgoroutine\tpriority=5\n")
+	if err := os.MkdirAll(filepath.Join(home, ".voicecoding"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".voicecoding", "dictionary.txt"), []byte(dict.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := NewOpenAI(settings.OpenAISettings{BaseURL: "http://127.0.0.1:0"})
+	if err != nil {
+		t.Fatalf("NewOpenAI() error: %v", err)
+	}
+	if n := prompt.EstimateTokens(b.prompt); n > WhisperPromptMaxTokens {
+		t.Errorf("prompt estimates %d tokens, want at most %d", n, WhisperPromptMaxTokens)
+	}
+	if !strings.HasPrefix(b.prompt, "goroutine, longterm000") {
+		t.Errorf("prompt = %.40q..., want the priority term kept", b.prompt)
+	}
+}
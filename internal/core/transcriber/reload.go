@@ -0,0 +1,157 @@
+package transcriber
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/noricha-vr/voicecode/internal/core/prompt"
+)
+
+// DictionaryReloader is implemented by backends that merge the user
+// dictionary into their prompt and can pick up edits without a restart.
+type DictionaryReloader interface {
+	// ReloadDictionary re-reads the dictionary. changed is false when its
+	// content is unchanged.
+	ReloadDictionary(ctx context.Context) (d prompt.Dictionary, changed bool, err error)
+	// SetDictionaryProfile switches the profile overlaid on the dictionary
+	// ("" for the base dictionary only) and reloads it.
+	SetDictionaryProfile(ctx context.Context, profile string) (d prompt.Dictionary, changed bool, err error)
+	// SetDictionary installs d, which the caller loaded with
+	// prompt.LoadProfile for profile, without reading the files again.
+	// Later reloads read profile.
+	SetDictionary(ctx context.Context, profile string, d prompt.Dictionary) (changed bool, err error)
+}
+
+var _ DictionaryReloader = (*Transcriber)(nil)
+
+// ReloadDictionary re-reads the user dictionary and rebuilds the system
+// prompt. Models that had a prompt cache get a new one; the superseded caches
+// are deleted once no in-flight request references them. On a read error the
+// current prompt is kept.
+func (t *Transcriber) ReloadDictionary(ctx context.Context) (prompt.Dictionary, bool, error) {
+	t.mu.Lock()
+	profile := t.dictionaryProfile
+	t.mu.Unlock()
+
+	dict, err := prompt.LoadProfile(t.dictionaryPath, profile)
+	if err != nil {
+		return prompt.Dictionary{}, false, fmt.Errorf("load dictionary: %w", err)
+	}
+	changed, err := t.SetDictionary(ctx, profile, dict)
+	return dict, changed, err
+}
+
+// SetDictionary installs an already loaded dictionary like ReloadDictionary.
+// It never fails.
+func (t *Transcriber) SetDictionary(ctx context.Context, profile string, dict prompt.Dictionary) (bool, error) {
+	hash := dict.Hash()
+	logDictionaryWarnings(dict)
+
+	t.mu.Lock()
+	t.dictionaryProfile = profile
+	if hash == t.dictionaryHash {
+		t.mu.Unlock()
+		return false, nil
+	}
+	t.systemPrompts = composeSystemPrompts(dict, t.modes)
+	t.dictionaryHash = hash
+	// Until the new caches exist, requests fall back to system_instruction
+	// with the new prompt rather than using a stale cache.
+	superseded := t.cacheNames
+	t.cacheNames = make(map[cacheKey]string)
+	t.mu.Unlock()
+
+	log.Printf("[Gemini] ユーザー辞書を再読み込みしました: 変換 %d 件, ヒント %d 件 (profile=%s, hash=%s)", dict.Conversions, dict.Hints, profileLabel(dict.Profile), hash)
+	for key, name := range superseded {
+		t.ensurePromptCache(ctx, key)
+		t.retireCache(ctx, name)
+	}
+	return true, nil
+}
+
+// SetDictionaryProfile switches the dictionary profile and reloads it. The
+// profile is kept even if loading fails, so a later reload can pick it up.
+func (t *Transcriber) SetDictionaryProfile(ctx context.Context, profile string) (prompt.Dictionary, bool, error) {
+	t.mu.Lock()
+	t.dictionaryProfile = profile
+	t.mu.Unlock()
+	return t.ReloadDictionary(ctx)
+}
+
+// acquireCache returns the cache for key, or the system prompt to send when
+// there is none. A returned cache is referenced until releaseCache.
+func (t *Transcriber) acquireCache(key cacheKey) (cacheName, systemPrompt string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	name := t.cacheNames[key]
+	if name != "" {
+		t.cacheRefs[name]++
+	}
+	return name, t.systemPrompts[key.mode]
+}
+
+func (t *Transcriber) releaseCache(name string) {
+	t.mu.Lock()
+	t.cacheRefs[name]--
+	remaining := t.cacheRefs[name]
+	if remaining > 0 {
+		t.mu.Unlock()
+		return
+	}
+	delete(t.cacheRefs, name)
+	retired := t.retiredCaches[name]
+	delete(t.retiredCaches, name)
+	t.mu.Unlock()
+
+	if retired {
+		t.deleteCache(context.Background(), name)
+	}
+}
+
+// retireCache deletes a superseded cache now, or after the last in-flight
+// request using it has finished.
+func (t *Transcriber) retireCache(ctx context.Context, name string) {
+	t.mu.Lock()
+	inUse := t.cacheRefs[name] > 0
+	if inUse {
+		t.retiredCaches[name] = true
+	}
+	t.mu.Unlock()
+
+	if inUse {
+		log.Printf("[Gemini] 使用中の prompt cache は完了後に削除します: %s", name)
+		return
+	}
+	t.deleteCache(ctx, name)
+}
+
+func (t *Transcriber) deleteCache(ctx context.Context, name string) {
+	if _, err := t.client.Caches.Delete(ctx, name, nil); err != nil {
+		// The cache expires with its TTL anyway.
+		log.Printf("[Gemini] Prompt cache の削除に失敗しました: %s: %v", name, err)
+		return
+	}
+	log.Printf("[Gemini] Prompt cache deleted: %s", name)
+}
+
+// logDictionaryWarnings reports dictionary lines that were skipped.
+func logDictionaryWarnings(d prompt.Dictionary) {
+	for _, w := range d.Warnings {
+		log.Printf("[Gemini] 辞書の行をスキップしました: %v", w)
+	}
+}
+
+func profileLabel(profile string) string {
+	if profile == "" {
+		return "base"
+	}
+	return profile
+}
+
+func modeLabel(mode string) string {
+	if mode == "" {
+		return "default"
+	}
+	return mode
+}
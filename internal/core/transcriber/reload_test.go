@@ -0,0 +1,235 @@
+package transcriber
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/noricha-vr/voicecode/internal/core/prompt"
+	"github.com/noricha-vr/voicecode/internal/core/transcriber/geminitest"
+)
+
+func callsOf(srv *geminitest.Server, method string) []geminitest.Call {
+	var out []geminitest.Call
+	for _, c := range srv.Calls() {
+		if c.Method == method {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func writeDictionary(t *testing.T, tr *Transcriber, content string) {
+	t.Helper()
+	if err := os.WriteFile(tr.dictionaryPath, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReloadDictionaryRotatesCache(t *testing.T) {
+	srv := geminitest.NewServer(PreferredModels...)
+	defer srv.Close()
+
+	tr := newFakeTranscriber(t, srv, true)
+	model := tr.ModelName()
+	oldCache := tr.cacheNames[cacheKey{model: model}]
+	if oldCache == "" {
+		t.Fatal("expected a prompt cache after New()")
+	}
+
+	// An unchanged dictionary is a no-op.
+	if _, changed, err := tr.ReloadDictionary(context.Background()); err != nil || changed {
+		t.Fatalf("ReloadDictionary() = changed %v, err %v; want no change", changed, err)
+	}
+	if n := len(callsOf(srv, "cachedContents.create")); n != 1 {
+		t.Fatalf("cachedContents.create calls = %d, want 1", n)
+	}
+
+	writeDictionary(t, tr, "クロードコード\tClaude Code\n")
+	dict, changed, err := tr.ReloadDictionary(context.Background())
+	if err != nil || !changed {
+		t.Fatalf("ReloadDictionary() = changed %v, err %v; want a change", changed, err)
+	}
+	if dict.Conversions != 1 {
+		t.Errorf("Conversions = %d, want 1", dict.Conversions)
+	}
+
+	creates := callsOf(srv, "cachedContents.create")
+	if len(creates) != 2 {
+		t.Fatalf("cachedContents.create calls = %d, want 2", len(creates))
+	}
+	if !strings.Contains(creates[1].SystemPrompt, `english="Claude Code"`) {
+		t.Error("new cache should contain the reloaded dictionary")
+	}
+	if creates[1].DisplayName != "vibescribe-system-prompt-cache-"+dict.Hash() {
+		t.Errorf("DisplayName = %q, want the new dictionary hash", creates[1].DisplayName)
+	}
+	deletes := callsOf(srv, "cachedContents.delete")
+	if len(deletes) != 1 || deletes[0].CachedContent != oldCache {
+		t.Fatalf("cachedContents.delete calls = %+v, want one for %s", deletes, oldCache)
+	}
+
+	newCache := tr.cacheNames[cacheKey{model: model}]
+	if newCache == "" || newCache == oldCache {
+		t.Fatalf("cache = %q, want a new cache", newCache)
+	}
+	if _, err := tr.Transcribe(context.Background(), testAudio, Options{}); err != nil {
+		t.Fatalf("Transcribe() error: %v", err)
+	}
+	calls := srv.GenerateCalls()
+	if last := calls[len(calls)-1]; last.CachedContent != newCache {
+		t.Errorf("CachedContent = %q, want %q", last.CachedContent, newCache)
+	}
+}
+
+func TestReloadDictionaryKeepsCacheOfInFlightRequest(t *testing.T) {
+	srv := geminitest.NewServer(PreferredModels...)
+	defer srv.Close()
+
+	tr := newFakeTranscriber(t, srv, true)
+	model := tr.ModelName()
+	// Simulate a request that picked the cache before the reload.
+	_, release := tr.buildGenerateConfig(model, Options{})
+	oldCache := tr.cacheNames[cacheKey{model: model}]
+
+	writeDictionary(t, tr, "supabase\n")
+	if _, changed, err := tr.ReloadDictionary(context.Background()); err != nil || !changed {
+		t.Fatalf("ReloadDictionary() = changed %v, err %v; want a change", changed, err)
+	}
+	if !slices.Contains(srv.Caches(), oldCache) {
+		t.Fatal("cache of the in-flight request was deleted")
+	}
+
+	release()
+	if slices.Contains(srv.Caches(), oldCache) {
+		t.Error("superseded cache should be deleted once the request finishes")
+	}
+	if got := srv.Caches(); len(got) != 1 || got[0] != tr.cacheNames[cacheKey{model: model}] {
+		t.Errorf("Caches() = %v, want only the new cache", got)
+	}
+}
+
+func TestReloadDictionaryWithoutCacheUsesNewPrompt(t *testing.T) {
+	srv := geminitest.NewServer(PreferredModels...)
+	defer srv.Close()
+
+	tr := newFakeTranscriber(t, srv, false)
+	writeDictionary(t, tr, "supabase\n")
+	if _, changed, err := tr.ReloadDictionary(context.Background()); err != nil || !changed {
+		t.Fatalf("ReloadDictionary() = changed %v, err %v; want a change", changed, err)
+	}
+	if _, err := tr.Transcribe(context.Background(), testAudio, Options{}); err != nil {
+		t.Fatalf("Transcribe() error: %v", err)
+	}
+	calls := srv.GenerateCalls()
+	if !strings.Contains(calls[len(calls)-1].SystemPrompt, "supabase") {
+		t.Error("system_instruction should contain the reloaded dictionary")
+	}
+}
+
+func TestSetDictionaryProfile(t *testing.T) {
+	srv := geminitest.NewServer(PreferredModels...)
+	defer srv.Close()
+
+	tr := newFakeTranscriber(t, srv, false)
+	writeDictionary(t, tr, "supabase\n")
+	profiles := prompt.ProfileDir(tr.dictionaryPath)
+	if err := os.MkdirAll(profiles, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(profiles, "frontend.txt"), []byte("ビート\tVite\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	lastPrompt := func() string {
+		t.Helper()
+		if _, err := tr.Transcribe(context.Background(), testAudio, Options{}); err != nil {
+			t.Fatalf("Transcribe() error: %v", err)
+		}
+		calls := srv.GenerateCalls()
+		return calls[len(calls)-1].SystemPrompt
+	}
+
+	dict, changed, err := tr.SetDictionaryProfile(context.Background(), "frontend")
+	if err != nil || !changed || dict.Profile != "frontend" {
+		t.Fatalf("SetDictionaryProfile(frontend) = %q changed %v err %v", dict.Profile, changed, err)
+	}
+	if p := lastPrompt(); !strings.Contains(p, `english="Vite"`) || !strings.Contains(p, "supabase") {
+		t.Error("prompt should contain the base dictionary and the profile")
+	}
+
+	if _, changed, err := tr.SetDictionaryProfile(context.Background(), ""); err != nil || !changed {
+		t.Fatalf("SetDictionaryProfile(\"\") = changed %v err %v", changed, err)
+	}
+	if p := lastPrompt(); strings.Contains(p, "Vite") {
+		t.Error("switching back to the base should drop the profile entries")
+	}
+}
+
+func TestEnsurePromptCacheCreatesOneCacheForConcurrentCalls(t *testing.T) {
+	srv := geminitest.NewServer(PreferredModels...)
+	defer srv.Close()
+
+	tr := newFakeTranscriber(t, srv, true)
+	key := cacheKey{model: tr.ModelName()}
+	tr.mu.Lock()
+	delete(tr.cacheNames, key)
+	tr.mu.Unlock()
+	before := len(callsOf(srv, "cachedContents.create"))
+
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			tr.ensurePromptCache(context.Background(), key)
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	if n := len(callsOf(srv, "cachedContents.create")) - before; n != 1 {
+		t.Errorf("cachedContents.create calls = %d, want 1", n)
+	}
+	if tr.cacheNames[key] == "" {
+		t.Error("expected the cache to be installed")
+	}
+}
+
+func TestSetDictionaryUsesTheGivenDictionary(t *testing.T) {
+	srv := geminitest.NewServer(PreferredModels...)
+	defer srv.Close()
+
+	tr := newFakeTranscriber(t, srv, false)
+	path := filepath.Join(t.TempDir(), "frontend.txt")
+	if err := os.WriteFile(path, []byte("ビート\tVite\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dict, err := prompt.LoadDictionary(path)
+	if err != nil {
+		t.Fatalf("LoadDictionary() error: %v", err)
+	}
+	changed, err := tr.SetDictionary(context.Background(), "frontend", dict)
+	if err != nil || !changed {
+		t.Fatalf("SetDictionary() = changed %v, err %v; want a change", changed, err)
+	}
+	if tr.dictionaryProfile != "frontend" {
+		t.Errorf("dictionaryProfile = %q, want frontend for later reloads", tr.dictionaryProfile)
+	}
+	if _, err := tr.Transcribe(context.Background(), testAudio, Options{}); err != nil {
+		t.Fatalf("Transcribe() error: %v", err)
+	}
+	calls := srv.GenerateCalls()
+	if !strings.Contains(calls[len(calls)-1].SystemPrompt, `english="Vite"`) {
+		t.Error("system_instruction should contain the given dictionary")
+	}
+	if changed, _ := tr.SetDictionary(context.Background(), "frontend", dict); changed {
+		t.Error("setting the same dictionary again should report no change")
+	}
+}
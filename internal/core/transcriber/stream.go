@@ -0,0 +1,198 @@
+package transcriber
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/noricha-vr/voicecode/internal/core/trace"
+)
+
+// StreamingBackend is implemented by backends that can deliver partial text
+// while the model is still generating.
+type StreamingBackend interface {
+	Backend
+	// TranscribeStream calls emit with each new piece of cleaned text in order
+	// and returns the complete cleaned text. The concatenation of all emitted
+	// pieces equals the returned text.
+	TranscribeStream(ctx context.Context, audio []byte, opts Options, emit func(delta string)) (string, error)
+}
+
+// TranscribeFileStream is like TranscribeFile but streams partial text to emit
+// when b implements StreamingBackend. Other backends emit the full text once.
+func TranscribeFileStream(ctx context.Context, b Backend, path string, emit func(delta string)) (string, float64, error) {
+	sb, ok := b.(StreamingBackend)
+	if !ok {
+		text, elapsed, err := TranscribeFile(ctx, b, path)
+		if err == nil && text != "" {
+			emit(text)
+		}
+		return text, elapsed, err
+	}
+
+	stepper := trace.FromContext(ctx)
+	readDone := stepper.Step("os.ReadFile(audio)")
+	audioData, err := os.ReadFile(path)
+	readDone(err)
+	if err != nil {
+		return "", 0, fmt.Errorf("read audio file: %w", err)
+	}
+
+	start := time.Now()
+	text, err := sb.TranscribeStream(ctx, audioData, fileOptions(ctx, path), emit)
+	return text, time.Since(start).Seconds(), err
+}
+
+// TranscribeStream streams partial text from Gemini. If the stream fails
+// with a transient or model-not-found error before any text was emitted, it
+// falls back to the regular Transcribe path (retries and model fallback)
+// within the same timeout; other failures are returned. The model is chosen
+// and its breaker updated like for Transcribe.
+func (t *Transcriber) TranscribeStream(ctx context.Context, audioData []byte, opts Options, emit func(delta string)) (string, error) {
+	tl := trace.FromContext(ctx)
+	t.warnUnknownMode(opts)
+
+	// The stream and the fallback request share one deadline.
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	var model string
+	if opts.Model != "" {
+		model = normalizeModelName(opts.Model)
+	} else {
+		model = t.selectModel(ctx)
+	}
+
+	start := time.Now()
+	var cleaner streamCleaner
+	var out strings.Builder
+	chunks := 0
+	emitClean := func(s string) {
+		if s == "" {
+			return
+		}
+		if out.Len() == 0 {
+			tl.Eventf("gemini.stream.first_text after=%s", time.Since(start).Truncate(time.Millisecond))
+		}
+		out.WriteString(s)
+		emit(s)
+	}
+
+	config, release := t.buildGenerateConfig(model, opts)
+	streamDone := tl.Step("gemini.generateContentStream")
+	var streamErr error
+	for resp, err := range t.client.Models.GenerateContentStream(ctx, model, t.buildContents(audioData, opts), config) {
+		if err != nil {
+			streamErr = err
+			break
+		}
+		chunks++
+		emitClean(cleaner.Write(resp.Text()))
+	}
+	release()
+	if streamErr == nil {
+		emitClean(cleaner.Flush())
+	}
+	streamDone(streamErr)
+	t.recordHealth(model, model, streamErr)
+
+	elapsed := time.Since(start).Seconds()
+	if streamErr != nil {
+		if out.Len() > 0 {
+			log.Printf("[Gemini %.2fs] ストリーミングが途中で失敗しました(model=%s): %v", elapsed, model, streamErr)
+			return out.String(), streamErr
+		}
+		if ctx.Err() != nil || !IsTransient(streamErr) && !IsModelNotFound(streamErr) {
+			log.Printf("[Gemini %.2fs] ストリーミングに失敗しました(model=%s): %v", elapsed, model, streamErr)
+			return "", streamErr
+		}
+		log.Printf("[Gemini] ストリーミングに失敗したため通常リクエストで再試行します (model=%s): %v", model, streamErr)
+		text, err := t.Transcribe(ctx, audioData, opts)
+		if err == nil && text != "" {
+			emit(text)
+		}
+		return text, err
+	}
+
+	tl.Eventf("gemini.stream.done chunks=%d", chunks)
+	log.Printf("[Gemini %.2fs] %s (model=%s, stream)", elapsed, out.String(), model)
+	return out.String(), nil
+}
+
+// streamCleaner applies cleanText incrementally: it removes <...> tags that
+// may be split across chunks and trims leading/trailing whitespace of the
+// whole stream. Concatenating every Write result and the final Flush equals
+// cleanText of the concatenated input.
+type streamCleaner struct {
+	tag     strings.Builder // pending "<..." not yet closed
+	inTag   bool
+	spaces  strings.Builder // whitespace held back until a non-space follows
+	started bool            // a non-space rune has been emitted
+}
+
+// Write consumes a chunk and returns the text that is safe to emit.
+func (c *streamCleaner) Write(chunk string) string {
+	var out strings.Builder
+	for _, r := range chunk {
+		if c.inTag {
+			if r == '>' {
+				if c.tag.Len() > 1 {
+					// "<x...>" is a tag: drop it.
+					c.tag.Reset()
+					c.inTag = false
+					continue
+				}
+				// "<>" is not a tag; emit it literally.
+				c.inTag = false
+				c.tag.Reset()
+				c.text(&out, '<')
+				c.text(&out, '>')
+				continue
+			}
+			c.tag.WriteRune(r)
+			continue
+		}
+		if r == '<' {
+			c.inTag = true
+			c.tag.WriteRune(r)
+			continue
+		}
+		c.text(&out, r)
+	}
+	return out.String()
+}
+
+// Flush returns any held-back text at end of stream. An unclosed "<..."
+// is literal text, and trailing whitespace is dropped.
+func (c *streamCleaner) Flush() string {
+	var out strings.Builder
+	if c.inTag {
+		pending := c.tag.String()
+		c.tag.Reset()
+		c.inTag = false
+		for _, r := range pending {
+			c.text(&out, r)
+		}
+	}
+	c.spaces.Reset()
+	return out.String()
+}
+
+func (c *streamCleaner) text(out *strings.Builder, r rune) {
+	if unicode.IsSpace(r) {
+		if c.started {
+			c.spaces.WriteRune(r)
+		}
+		return
+	}
+	if c.spaces.Len() > 0 {
+		out.WriteString(c.spaces.String())
+		c.spaces.Reset()
+	}
+	c.started = true
+	out.WriteRune(r)
+}
@@ -0,0 +1,213 @@
+package transcriber
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/noricha-vr/voicecode/internal/core/transcriber/geminitest"
+)
+
+func TestStreamCleanerMatchesCleanText(t *testing.T) {
+	tests := []struct {
+		name   string
+		chunks []string
+	}{
+		{"plain", []string{"Reactの", "useStateを", "使う"}},
+		{"tag split across chunks", []string{"<tex", "t>ReactのuseState", "を使う</te", "xt>\n"}},
+		{"leading and trailing space", []string{"  \n", "こんにちは", " 世界", "  \n"}},
+		{"empty angle brackets", []string{"a <", "> b"}},
+		{"unclosed tag is literal", []string{"x < y", " 以上"}},
+		{"inner spaces kept", []string{"foo", "  ", "bar"}},
+		{"only tags", []string{"<text>", "</text>"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var c streamCleaner
+			var got strings.Builder
+			for _, chunk := range tt.chunks {
+				got.WriteString(c.Write(chunk))
+			}
+			got.WriteString(c.Flush())
+
+			want := cleanText(strings.Join(tt.chunks, ""))
+			if got.String() != want {
+				t.Errorf("streamed = %q, cleanText = %q", got.String(), want)
+			}
+		})
+	}
+}
+
+func TestTranscribeStreamFakeServer(t *testing.T) {
+	srv := geminitest.NewServer(PreferredModels...)
+	defer srv.Close()
+	srv.SetText("", "<text>ReactのuseStateを使う</text>")
+	srv.SetStreamChunkSize(3)
+
+	tr := newFakeTranscriber(t, srv, false)
+	var deltas []string
+	text, err := tr.TranscribeStream(context.Background(), testAudio, Options{}, func(d string) {
+		deltas = append(deltas, d)
+	})
+	if err != nil {
+		t.Fatalf("TranscribeStream() error: %v", err)
+	}
+	if text != "ReactのuseStateを使う" {
+		t.Errorf("text = %q", text)
+	}
+	if len(deltas) < 2 {
+		t.Errorf("deltas = %q, want several partial pieces", deltas)
+	}
+	if strings.Join(deltas, "") != text {
+		t.Errorf("joined deltas = %q, want %q", strings.Join(deltas, ""), text)
+	}
+
+	calls := srv.GenerateCalls()
+	if len(calls) != 1 || calls[0].Method != "streamGenerateContent" {
+		t.Fatalf("calls = %+v, want one streamGenerateContent", calls)
+	}
+}
+
+func TestTranscribeStreamFallsBackBeforeFirstText(t *testing.T) {
+	srv := geminitest.NewServer(PreferredModels...)
+	defer srv.Close()
+	srv.SetText("", "こんにちは")
+
+	tr := newFakeTranscriber(t, srv, false)
+	srv.FailGenerate("", geminitest.Unavailable())
+
+	var deltas []string
+	text, err := tr.TranscribeStream(context.Background(), testAudio, Options{}, func(d string) {
+		deltas = append(deltas, d)
+	})
+	if err != nil {
+		t.Fatalf("TranscribeStream() error: %v", err)
+	}
+	if text != "こんにちは" || len(deltas) != 1 || deltas[0] != text {
+		t.Errorf("text = %q deltas = %q, want full text emitted once", text, deltas)
+	}
+
+	methods := []string{}
+	for _, c := range srv.GenerateCalls() {
+		methods = append(methods, c.Method)
+	}
+	if len(methods) < 2 || methods[0] != "streamGenerateContent" || methods[len(methods)-1] != "generateContent" {
+		t.Errorf("methods = %v, want stream then generateContent fallback", methods)
+	}
+}
+
+func TestTranscribeStreamDoesNotRetryPermanentErrors(t *testing.T) {
+	srv := geminitest.NewServer(PreferredModels...)
+	defer srv.Close()
+	srv.SetText("", "こんにちは")
+
+	tr := newFakeTranscriber(t, srv, false)
+	srv.FailGenerate("", geminitest.InvalidAPIKey())
+
+	var deltas []string
+	_, err := tr.TranscribeStream(context.Background(), testAudio, Options{}, func(d string) {
+		deltas = append(deltas, d)
+	})
+	if KindOf(err) != KindAuth {
+		t.Fatalf("err = %v, want the auth error from the stream", err)
+	}
+	if calls := srv.GenerateCalls(); len(calls) != 1 || len(deltas) != 0 {
+		t.Errorf("calls = %+v deltas = %q, want a single stream request and no retry", calls, deltas)
+	}
+}
+
+func TestTranscribeStreamUsesBreaker(t *testing.T) {
+	srv := geminitest.NewServer(PreferredModels...)
+	defer srv.Close()
+	srv.SetText("", "こんにちは")
+
+	tr := newFakeTranscriber(t, srv, false)
+	tr.health = newHealthTracker(1, time.Minute)
+	preferred := tr.ModelName()
+	srv.FailGenerate(preferred, geminitest.Unavailable())
+
+	if _, err := tr.TranscribeStream(context.Background(), testAudio, Options{}, func(string) {}); err != nil {
+		t.Fatalf("TranscribeStream() error: %v", err)
+	}
+	var failures int
+	for _, h := range tr.Health() {
+		if h.Model == preferred {
+			failures = h.Failures
+		}
+	}
+	if failures == 0 {
+		t.Errorf("Health() = %+v, want the stream failure recorded for %s", tr.Health(), preferred)
+	}
+
+	before := len(srv.GenerateCalls())
+	if _, err := tr.TranscribeStream(context.Background(), testAudio, Options{}, func(string) {}); err != nil {
+		t.Fatalf("TranscribeStream() error: %v", err)
+	}
+	if c := srv.GenerateCalls()[before]; c.Method != "streamGenerateContent" || c.Model == preferred {
+		t.Errorf("next stream went to %s (%s), want another model while %s is open", c.Model, c.Method, preferred)
+	}
+}
+
+func TestTranscribeStreamFailsAfterPartialText(t *testing.T) {
+	srv := geminitest.NewServer(PreferredModels...)
+	defer srv.Close()
+	srv.SetText("", "ReactのuseStateを使う")
+	srv.SetStreamChunkSize(4)
+	srv.FailStreamAfter(2)
+
+	tr := newFakeTranscriber(t, srv, false)
+	var deltas []string
+	text, err := tr.TranscribeStream(context.Background(), testAudio, Options{}, func(d string) {
+		deltas = append(deltas, d)
+	})
+	if err == nil {
+		t.Fatal("TranscribeStream() should fail after partial output")
+	}
+	if text != "Reactのus" || strings.Join(deltas, "") != text {
+		t.Errorf("text = %q deltas = %q, want partial text", text, deltas)
+	}
+	if n := len(srv.GenerateCalls()); n != 1 {
+		t.Errorf("generate calls = %d, want no retry after partial output", n)
+	}
+}
+
+func TestTranscribeFileStreamNonStreamingBackend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.wav")
+	if err := os.WriteFile(path, testAudio, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		backend    Backend
+		wantDeltas int
+		wantErr    bool
+	}{
+		{"emits full text once", &stubBackend{text: "hello"}, 1, false},
+		{"empty text emits nothing", &stubBackend{}, 0, false},
+		{"error emits nothing", &scriptedBackend{name: "stub", errs: []error{errors.New("boom")}}, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var deltas []string
+			text, _, err := TranscribeFileStream(context.Background(), tt.backend, path, func(d string) {
+				deltas = append(deltas, d)
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(deltas) != tt.wantDeltas {
+				t.Errorf("deltas = %q, want %d", deltas, tt.wantDeltas)
+			}
+			if tt.wantDeltas == 1 && deltas[0] != text {
+				t.Errorf("delta = %q, want %q", deltas[0], text)
+			}
+		})
+	}
+}
@@ -7,9 +7,11 @@ import (
 	"os"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/noricha-vr/voicecode/internal/core/prompt"
+	"github.com/noricha-vr/voicecode/internal/core/settings"
 	"github.com/noricha-vr/voicecode/internal/core/trace"
 	"google.golang.org/genai"
 )
@@ -18,15 +20,30 @@ const (
 	ThinkingLevelEnvVar     = "VOICECODE_THINKING_LEVEL"
 	EnablePromptCacheEnvVar = "VOICECODE_ENABLE_PROMPT_CACHE"
 	PromptCacheTTLEnvVar    = "VOICECODE_PROMPT_CACHE_TTL"
+	BaseURLEnvVar           = "VOICECODE_GEMINI_BASE_URL"
 	DefaultThinkingLevel    = "minimal"
 	DefaultPromptCacheTTL   = 3600 * time.Second
-	Timeout                 = 10 * time.Second
+	Timeout                 = 10 * time.Second // default Config.Timeout
 	MaxTransientRetries     = 1
 	RetryBackoffSeconds     = 0.3
 )
 
 var xmlTagPattern = regexp.MustCompile(`<[^>]+>`)
 
+func init() {
+	Register(settings.DefaultBackend, func(ctx context.Context, cfg *settings.Settings) (Backend, error) {
+		return New(ctx, Config{
+			DictionaryProfile: cfg.DictionaryProfile,
+			Modes:             cfg.Modes,
+			Language:          cfg.Language,
+			Timeout:           time.Duration(cfg.TranscribeTimeoutSec * float64(time.Second)),
+			HedgeDelay:        time.Duration(cfg.Gemini.HedgeDelaySec * float64(time.Second)),
+			BreakerThreshold:  cfg.Gemini.BreakerThreshold,
+			BreakerCooldown:   time.Duration(cfg.Gemini.BreakerCooldownSec * float64(time.Second)),
+		})
+	})
+}
+
 var thinkingLevelMap = map[string]genai.ThinkingLevel{
 	"minimal": genai.ThinkingLevelMinimal,
 	"low":     genai.ThinkingLevelLow,
@@ -35,19 +52,79 @@ var thinkingLevelMap = map[string]genai.ThinkingLevel{
 }
 
 // Transcriber handles audio transcription via Gemini API.
+// It is the default Backend.
 type Transcriber struct {
 	client            *genai.Client
-	systemPrompt      string
-	modelName         string
+	dictionaryPath    string
+	language          string
 	thinkingLevel     genai.ThinkingLevel
-	thinkingMode      string // "level" or "budget0"
 	enablePromptCache bool
 	promptCacheTTL    time.Duration
-	cacheNameByModel  map[string]string
+	hedgeDelay        time.Duration
+	timeout           time.Duration
+	health            *healthTracker
+	// models is the fallback order resolved once by New (see ResolveModels).
+	models []string
+
+	// mu guards the fields below; hedged requests touch them concurrently.
+	mu                  sync.Mutex
+	preferredModel      string // resolved at startup; retried once its breaker recovers
+	modelName           string // model currently serving requests
+	hedgeModel          string
+	thinkingModeByModel map[string]string // "level" (default) or "budget0"
+	cacheNames          map[cacheKey]string
+	cacheCreating       map[cacheKey]string // dictionary hash of a cache being created
+	modes               map[string]settings.Mode
+	systemPrompts       map[string]string // by mode name ("" is the default), composed with the user dictionary
+	dictionaryHash      string
+	dictionaryProfile   string
+	cacheRefs           map[string]int  // in-flight requests per cache name
+	retiredCaches       map[string]bool // superseded caches deleted once unreferenced
+}
+
+var _ StreamingBackend = (*Transcriber)(nil)
+
+// cacheKey identifies a prompt cache: each mode has its own system prompt.
+type cacheKey struct {
+	model string
+	mode  string
+}
+
+// Config configures a Gemini Transcriber.
+type Config struct {
+	// APIKey falls back to GOOGLE_API_KEY when empty.
+	APIKey string
+	// BaseURL overrides the Gemini API endpoint, e.g. a geminitest.Server.
+	// It falls back to VOICECODE_GEMINI_BASE_URL when empty.
+	BaseURL string
+	// DictionaryPath is the user dictionary merged into the system prompt.
+	// Empty uses prompt.DictionaryPath().
+	DictionaryPath string
+	// DictionaryProfile is overlaid on the dictionary (see prompt.LoadProfile).
+	DictionaryProfile string
+	// Modes are selectable per request with Options.Mode.
+	Modes []settings.Mode
+	// Language is the spoken language (see prompt.TranscribePromptFor).
+	// Empty means Japanese.
+	Language string
+	// Timeout bounds each Transcribe, TranscribeStream and Translate call,
+	// model fallback included (settings.TranscribeTimeoutSec). Zero means
+	// Timeout.
+	Timeout time.Duration
+	// HedgeDelay races the hedge model after this long without an answer
+	// (settings.GeminiSettings). Zero disables hedged requests.
+	// VOICECODE_HEDGE_DELAY overrides it.
+	HedgeDelay time.Duration
+	// BreakerThreshold and BreakerCooldown configure the per-model circuit
+	// breakers. Zero means DefaultBreakerThreshold and DefaultBreakerCooldown.
+	// VOICECODE_BREAKER_THRESHOLD and VOICECODE_BREAKER_COOLDOWN override them.
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
 }
 
 // New creates and initializes a Transcriber.
-func New(ctx context.Context, apiKey string) (*Transcriber, error) {
+func New(ctx context.Context, cfg Config) (*Transcriber, error) {
+	apiKey := cfg.APIKey
 	if apiKey == "" {
 		apiKey = os.Getenv("GOOGLE_API_KEY")
 	}
@@ -55,134 +132,251 @@ func New(ctx context.Context, apiKey string) (*Transcriber, error) {
 		return nil, fmt.Errorf("GOOGLE_API_KEY is not set")
 	}
 
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = strings.TrimSpace(os.Getenv(BaseURLEnvVar))
+	}
+
 	client, err := genai.NewClient(ctx, &genai.ClientConfig{
-		APIKey:  apiKey,
-		Backend: genai.BackendGeminiAPI,
+		APIKey:      apiKey,
+		Backend:     genai.BackendGeminiAPI,
+		HTTPOptions: genai.HTTPOptions{BaseURL: baseURL},
 	})
 	if err != nil {
 		return nil, fmt.Errorf("create genai client: %w", err)
 	}
 
+	dictPath := cfg.DictionaryPath
+	if dictPath == "" {
+		dictPath = prompt.DictionaryPath()
+	}
+	dict, err := prompt.LoadProfile(dictPath, cfg.DictionaryProfile)
+	if err != nil {
+		log.Printf("[Gemini] ユーザー辞書の読み込みに失敗したため辞書なしで続行します: %v", err)
+	}
+	if dict.Terms() > 0 {
+		log.Printf("[Gemini] ユーザー辞書: 変換 %d 件, ヒント %d 件 (%s, profile=%s, hash=%s)", dict.Conversions, dict.Hints, dictPath, profileLabel(dict.Profile), dict.Hash())
+	}
+	logDictionaryWarnings(dict)
+
+	modes := make(map[string]settings.Mode, len(cfg.Modes))
+	for _, m := range cfg.Modes {
+		modes[m.Name] = m
+	}
+
 	t := &Transcriber{
-		client:            client,
-		systemPrompt:      prompt.SystemPrompt,
-		thinkingLevel:     resolveThinkingLevel(),
-		thinkingMode:      "level",
-		enablePromptCache: resolvePromptCacheEnabled(),
-		promptCacheTTL:    resolvePromptCacheTTL(),
-		cacheNameByModel:  make(map[string]string),
+		client:              client,
+		dictionaryPath:      dictPath,
+		language:            cfg.Language,
+		dictionaryProfile:   cfg.DictionaryProfile,
+		thinkingLevel:       resolveThinkingLevel(),
+		enablePromptCache:   resolvePromptCacheEnabled(),
+		promptCacheTTL:      resolvePromptCacheTTL(),
+		hedgeDelay:          resolveHedgeDelay(cfg.HedgeDelay),
+		timeout:             cfg.Timeout,
+		health:              newHealthTracker(resolveBreakerThreshold(cfg.BreakerThreshold), resolveBreakerCooldown(cfg.BreakerCooldown)),
+		thinkingModeByModel: make(map[string]string),
+		cacheNames:          make(map[cacheKey]string),
+		cacheCreating:       make(map[cacheKey]string),
+		modes:               modes,
+		systemPrompts:       composeSystemPrompts(dict, modes),
+		dictionaryHash:      dict.Hash(),
+		cacheRefs:           make(map[string]int),
+		retiredCaches:       make(map[string]bool),
 	}
 
-	t.modelName = ResolveModel(ctx, client, nil)
+	if t.timeout <= 0 {
+		t.timeout = Timeout
+	}
+
+	t.models = ResolveModels(ctx, client)
+	t.modelName = firstModel(t.models, nil)
 	if t.modelName == "" {
 		return nil, fmt.Errorf("no available model found")
 	}
+	t.preferredModel = t.modelName
 
-	t.ensurePromptCache(ctx)
+	t.ensurePromptCaches(ctx, t.modelName)
 	log.Printf("[Gemini] 使用モデル: %s", t.modelName)
-	log.Printf("[Gemini] Thinking mode: %s (%s)", t.thinkingMode, t.thinkingLevel)
+	if len(modes) > 0 {
+		log.Printf("[Gemini] モード: %d 件", len(modes))
+	}
+	log.Printf("[Gemini] Thinking mode: %s (%s)", t.thinkingModeFor(t.modelName), t.thinkingLevel)
+
+	if t.hedgeDelay > 0 {
+		t.hedgeModel = resolveHedgeModel(t.models, t.modelName)
+		if t.hedgeModel != "" {
+			t.ensurePromptCaches(ctx, t.hedgeModel)
+			log.Printf("[Gemini] Hedged request: %s after %s", t.hedgeModel, t.hedgeDelay)
+		} else {
+			log.Printf("[Gemini] Hedge 用の代替モデルが見つからないため hedged request を無効化します")
+		}
+	}
 
 	return t, nil
 }
 
-// Transcribe transcribes a WAV file and returns the text.
-func (t *Transcriber) Transcribe(ctx context.Context, wavPath string) (string, float64, error) {
-	tl := trace.FromContext(ctx)
-	stepper := (*trace.Timeline)(nil)
-	if tl != nil {
-		stepper = tl
+// Transcribe transcribes audio data and returns the text. opts.Mode selects
+// the prompts and generation parameters of a configured mode.
+// When opts.Model is set the request is pinned to that model (see Options.Model).
+func (t *Transcriber) Transcribe(ctx context.Context, audioData []byte, opts Options) (string, error) {
+	t.warnUnknownMode(opts)
+	if opts.Model != "" {
+		return t.transcribePinned(ctx, normalizeModelName(opts.Model), audioData, opts)
 	}
 
-	readDone := stepper.Step("os.ReadFile(audio)")
-	audioData, err := os.ReadFile(wavPath)
-	readDone(err)
-	if err != nil {
-		return "", 0, fmt.Errorf("read audio file: %w", err)
-	}
+	stepper := trace.FromContext(ctx)
 
-	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
 	defer cancel()
 
 	start := time.Now()
 
+	model := t.selectModel(ctx)
 	genDone := stepper.Step("gemini.generateContentWithRetry(primary)")
-	response, err := t.generateContentWithRetry(ctx, audioData)
+	response, answeredBy, err := t.generateHedged(ctx, model, audioData, opts)
 	genDone(err)
+	t.recordHealth(model, answeredBy, err)
 	if err != nil {
-		if IsModelNotFound(err) || IsTransient(err) {
-			resolveDone := stepper.Step("gemini.ResolveModel(fallback)")
-			fallback := ResolveModel(ctx, t.client, map[string]bool{t.modelName: true})
-			resolveDone(nil)
-			if fallback != "" {
-				reason := "モデルが見つからないため"
-				if IsTransient(err) {
-					reason = "一時的なAPIエラーのため"
-				}
-				log.Printf("[Gemini] %sモデルを切替します: %s -> %s", reason, t.modelName, fallback)
-				t.modelName = fallback
-				cacheDone := stepper.Step("gemini.ensurePromptCache(fallback)")
-				t.ensurePromptCache(ctx)
-				cacheDone(nil)
-
-				genDone2 := stepper.Step("gemini.generateContentWithRetry(fallback)")
-				response, err = t.generateContentWithRetry(ctx, audioData)
-				genDone2(err)
-				if err != nil {
-					elapsed := time.Since(start).Seconds()
-					log.Printf("[Gemini %.2fs] API呼び出しに失敗しました(model=%s): %v", elapsed, t.modelName, err)
-					return "", elapsed, err
-				}
-			} else {
-				elapsed := time.Since(start).Seconds()
-				log.Printf("[Gemini %.2fs] API呼び出しに失敗しました(model=%s): %v", elapsed, t.modelName, err)
-				return "", elapsed, err
-			}
-		} else {
+		if !IsModelNotFound(err) && !IsTransient(err) {
+			elapsed := time.Since(start).Seconds()
+			log.Printf("[Gemini %.2fs] API呼び出しに失敗しました(model=%s): %v", elapsed, model, err)
+			t.logHealth(stepper)
+			return "", err
+		}
+
+		fallback := firstModel(t.models, t.unavailableModels(model))
+		if fallback == "" {
+			elapsed := time.Since(start).Seconds()
+			log.Printf("[Gemini %.2fs] API呼び出しに失敗しました(model=%s): %v", elapsed, model, err)
+			t.logHealth(stepper)
+			return "", err
+		}
+
+		reason := "モデルが見つからないため"
+		if IsTransient(err) {
+			reason = "一時的なAPIエラーのため"
+		}
+		log.Printf("[Gemini] %sモデルを切替します: %s -> %s", reason, model, fallback)
+		model = fallback
+		t.mu.Lock()
+		t.modelName = fallback
+		t.mu.Unlock()
+		cacheDone := stepper.Step("gemini.ensurePromptCache(fallback)")
+		t.ensurePromptCaches(ctx, model)
+		cacheDone(nil)
+
+		genDone2 := stepper.Step("gemini.generateContentWithRetry(fallback)")
+		response, err = t.generateContentWithRetry(ctx, model, audioData, opts)
+		answeredBy = model
+		genDone2(err)
+		t.recordHealth(model, answeredBy, err)
+		if err != nil {
 			elapsed := time.Since(start).Seconds()
-			log.Printf("[Gemini %.2fs] API呼び出しに失敗しました(model=%s): %v", elapsed, t.modelName, err)
-			return "", elapsed, err
+			log.Printf("[Gemini %.2fs] API呼び出しに失敗しました(model=%s): %v", elapsed, model, err)
+			t.logHealth(stepper)
+			return "", err
 		}
 	}
+	t.logHealth(stepper)
 
 	elapsed := time.Since(start).Seconds()
 	cleanDone := stepper.Step("transcriber.cleanText")
-	rawText := response.Text()
-	result := xmlTagPattern.ReplaceAllString(rawText, "")
-	result = strings.TrimSpace(result)
+	result := cleanText(response.Text())
 	cleanDone(nil)
 
-	log.Printf("[Gemini %.2fs] %s (model=%s)", elapsed, result, t.modelName)
-	return result, elapsed, nil
+	log.Printf("[Gemini %.2fs] %s (model=%s)", elapsed, result, answeredBy)
+	return result, nil
+}
+
+// transcribePinned makes a single attempt against model without transient
+// retries, hedging or model fallback. Cache invalidation and thinking-level
+// incompatibility are still handled since they are request-shape problems.
+func (t *Transcriber) transcribePinned(ctx context.Context, model string, audioData []byte, opts Options) (string, error) {
+	start := time.Now()
+
+	response, err := t.generateContent(ctx, model, audioData, opts)
+	if err != nil && IsCachedContentError(err) {
+		log.Printf("[Gemini] CachedContent が無効なためキャッシュなしで再試行します (model=%s)", model)
+		t.dropCache(model, opts)
+		response, err = t.generateContent(ctx, model, audioData, opts)
+	}
+	if err != nil && t.thinkingModeFor(model) == "level" && IsThinkingUnsupported(err) {
+		t.mu.Lock()
+		t.thinkingModeByModel[model] = "budget0"
+		t.mu.Unlock()
+		log.Printf("[Gemini] thinking_level 非対応モデルのため thinking_budget=0 に切替します (model=%s)", model)
+		response, err = t.generateContent(ctx, model, audioData, opts)
+	}
+	t.recordHealth(model, model, err)
+	elapsed := time.Since(start).Seconds()
+	if err != nil {
+		log.Printf("[Gemini %.2fs] API呼び出しに失敗しました(model=%s): %v", elapsed, model, err)
+		return "", err
+	}
+
+	result := cleanText(response.Text())
+	log.Printf("[Gemini %.2fs] %s (model=%s)", elapsed, result, model)
+	return result, nil
+}
+
+// cleanText strips XML tags the model sometimes echoes from the prompt.
+func cleanText(raw string) string {
+	return strings.TrimSpace(xmlTagPattern.ReplaceAllString(raw, ""))
+}
+
+// Name returns the registry name of the Gemini backend.
+func (t *Transcriber) Name() string {
+	return settings.DefaultBackend
+}
+
+// Close releases resources held by the Transcriber.
+// The genai client has no persistent connections to tear down.
+func (t *Transcriber) Close() error {
+	return nil
 }
 
 // ModelName returns the current model name.
 func (t *Transcriber) ModelName() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	return t.modelName
 }
 
-func (t *Transcriber) generateContentWithRetry(ctx context.Context, audioData []byte) (*genai.GenerateContentResponse, error) {
-	resp, err := t.retryLoop(ctx, audioData)
+func (t *Transcriber) thinkingModeFor(model string) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if mode, ok := t.thinkingModeByModel[model]; ok {
+		return mode
+	}
+	return "level"
+}
+
+func (t *Transcriber) generateContentWithRetry(ctx context.Context, model string, audioData []byte, opts Options) (*genai.GenerateContentResponse, error) {
+	resp, err := t.retryLoop(ctx, model, audioData, opts)
 	if err != nil && IsCachedContentError(err) {
-		log.Printf("[Gemini] CachedContent が無効なためキャッシュなしで再試行します (model=%s)", t.modelName)
-		delete(t.cacheNameByModel, t.modelName)
-		return t.retryLoop(ctx, audioData)
+		log.Printf("[Gemini] CachedContent が無効なためキャッシュなしで再試行します (model=%s)", model)
+		t.dropCache(model, opts)
+		return t.retryLoop(ctx, model, audioData, opts)
 	}
 	return resp, err
 }
 
-func (t *Transcriber) retryLoop(ctx context.Context, audioData []byte) (*genai.GenerateContentResponse, error) {
+func (t *Transcriber) retryLoop(ctx context.Context, model string, audioData []byte, opts Options) (*genai.GenerateContentResponse, error) {
 	var lastErr error
 	for attempt := 0; attempt <= MaxTransientRetries; attempt++ {
-		resp, err := t.generateContent(ctx, audioData)
+		resp, err := t.generateContent(ctx, model, audioData, opts)
 		if err == nil {
 			return resp, nil
 		}
 		lastErr = err
 
-		if t.thinkingMode == "level" && IsThinkingUnsupported(err) {
-			t.thinkingMode = "budget0"
-			log.Printf("[Gemini] thinking_level 非対応モデルのため thinking_budget=0 に切替します")
-			return t.generateContent(ctx, audioData)
+		if t.thinkingModeFor(model) == "level" && IsThinkingUnsupported(err) {
+			t.mu.Lock()
+			t.thinkingModeByModel[model] = "budget0"
+			t.mu.Unlock()
+			log.Printf("[Gemini] thinking_level 非対応モデルのため thinking_budget=0 に切替します (model=%s)", model)
+			return t.generateContent(ctx, model, audioData, opts)
 		}
 
 		if !IsTransient(err) || attempt >= MaxTransientRetries {
@@ -190,74 +384,196 @@ func (t *Transcriber) retryLoop(ctx context.Context, audioData []byte) (*genai.G
 		}
 
 		wait := time.Duration(float64(time.Second) * RetryBackoffSeconds * float64(attempt+1))
+		if e := Classify(err); e.RetryAfter > wait {
+			wait = e.RetryAfter
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			// The server asked for a longer pause than the remaining budget.
+			return nil, err
+		}
 		log.Printf("[Gemini] 一時的なAPIエラーのため再試行します(%d/%d): %v", attempt+1, MaxTransientRetries, err)
-		time.Sleep(wait)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
 	}
 	return nil, lastErr
 }
 
-func (t *Transcriber) generateContent(ctx context.Context, audioData []byte) (*genai.GenerateContentResponse, error) {
-	contents := []*genai.Content{
-		{
-			Parts: []*genai.Part{
-				{Text: prompt.TranscribePrompt},
-				{InlineData: &genai.Blob{Data: audioData, MIMEType: "audio/wav"}},
-			},
-		},
+func (t *Transcriber) generateContent(ctx context.Context, model string, audioData []byte, opts Options) (*genai.GenerateContentResponse, error) {
+	config, release := t.buildGenerateConfig(model, opts)
+	defer release()
+	return t.client.Models.GenerateContent(ctx, model, t.buildContents(audioData, opts), config)
+}
+
+func (t *Transcriber) buildContents(audioData []byte, opts Options) []*genai.Content {
+	transcribePrompt := prompt.TranscribePromptFor(t.language)
+	if m, ok := t.lookupMode(opts.Mode); ok && m.TranscribePrompt != "" {
+		transcribePrompt = m.TranscribePrompt
+	}
+	// Reference context goes in the request rather than the system prompt so
+	// the cached system prompt stays the same across requests.
+	var parts []*genai.Part
+	if opts.Context != "" {
+		parts = append(parts, &genai.Part{Text: prompt.ReferencePrompt(opts.Context)})
 	}
-	config := t.buildGenerateConfig()
-	return t.client.Models.GenerateContent(ctx, t.modelName, contents, config)
+	parts = append(parts,
+		&genai.Part{Text: transcribePrompt},
+		&genai.Part{InlineData: &genai.Blob{Data: audioData, MIMEType: opts.mimeType()}},
+	)
+	return []*genai.Content{{Parts: parts}}
 }
 
-func (t *Transcriber) buildGenerateConfig() *genai.GenerateContentConfig {
-	var tc *genai.ThinkingConfig
-	if t.thinkingMode == "level" {
-		tc = &genai.ThinkingConfig{ThinkingLevel: t.thinkingLevel}
-	} else {
-		tc = &genai.ThinkingConfig{ThinkingBudget: genai.Ptr[int32](0)}
+// buildGenerateConfig returns the request config for model. The caller must
+// call release once the request has finished so a cache superseded by a
+// dictionary reload is not deleted while it is still in use.
+//
+// Options.ThinkingLevel takes precedence over the mode's thinking level,
+// which takes precedence over the backend default.
+func (t *Transcriber) buildGenerateConfig(model string, opts Options) (*genai.GenerateContentConfig, func()) {
+	mode, _ := t.lookupMode(opts.Mode)
+	level := t.thinkingLevel
+	if l, ok := thinkingLevelMap[strings.ToLower(strings.TrimSpace(mode.ThinkingLevel))]; ok {
+		level = l
 	}
+	if l, ok := thinkingLevelMap[strings.ToLower(strings.TrimSpace(opts.ThinkingLevel))]; ok {
+		level = l
+	}
+	temperature := float32(0)
+	if mode.Temperature != nil {
+		temperature = float32(*mode.Temperature)
+	}
+
+	tc := t.thinkingConfig(model, level)
 
-	cachedName := t.cacheNameByModel[t.modelName]
+	cachedName, systemPrompt := t.acquireCache(cacheKey{model, mode.Name})
 	if cachedName != "" {
 		return &genai.GenerateContentConfig{
 			CachedContent:  cachedName,
 			ThinkingConfig: tc,
-			Temperature:    genai.Ptr[float32](0.0),
-		}
+			Temperature:    genai.Ptr(temperature),
+		}, func() { t.releaseCache(cachedName) }
 	}
 
 	return &genai.GenerateContentConfig{
 		SystemInstruction: &genai.Content{
-			Parts: []*genai.Part{{Text: t.systemPrompt}},
+			Parts: []*genai.Part{{Text: systemPrompt}},
 		},
 		ThinkingConfig: tc,
-		Temperature:    genai.Ptr[float32](0.0),
+		Temperature:    genai.Ptr(temperature),
+	}, func() {}
+}
+
+func (t *Transcriber) thinkingConfig(model string, level genai.ThinkingLevel) *genai.ThinkingConfig {
+	if t.thinkingModeFor(model) == "level" {
+		return &genai.ThinkingConfig{ThinkingLevel: level}
+	}
+	return &genai.ThinkingConfig{ThinkingBudget: genai.Ptr[int32](0)}
+}
+
+// lookupMode returns the mode called name. Unknown names fall back to the
+// default prompts; the zero Mode has the empty name of the default.
+func (t *Transcriber) lookupMode(name string) (settings.Mode, bool) {
+	m, ok := t.modes[name]
+	return m, ok
+}
+
+// warnUnknownMode logs a request for a mode that is not configured.
+func (t *Transcriber) warnUnknownMode(opts Options) {
+	if _, ok := t.lookupMode(opts.Mode); opts.Mode != "" && !ok {
+		log.Printf("[Gemini] 未定義のモードのためデフォルトのプロンプトを使用します: %s", opts.Mode)
+	}
+}
+
+// dropCache forgets the cache used by a request after the API rejected it.
+func (t *Transcriber) dropCache(model string, opts Options) {
+	mode, _ := t.lookupMode(opts.Mode)
+	t.mu.Lock()
+	delete(t.cacheNames, cacheKey{model, mode.Name})
+	t.mu.Unlock()
+}
+
+// composeSystemPrompts merges d into the default system prompt and into the
+// system prompt of each mode. A mode whose prompt file cannot be read uses
+// the default prompt.
+func composeSystemPrompts(d prompt.Dictionary, modes map[string]settings.Mode) map[string]string {
+	prompts := map[string]string{"": prompt.ComposeSystemPrompt(d)}
+	for name, m := range modes {
+		base, err := m.LoadSystemPrompt()
+		if err != nil {
+			log.Printf("[Gemini] モードのシステムプロンプトを読み込めないためデフォルトを使用します: %v", err)
+		}
+		if base == "" {
+			prompts[name] = prompts[""]
+			continue
+		}
+		prompts[name] = prompt.ComposePrompt(base, d)
+	}
+	return prompts
+}
+
+// ensurePromptCaches creates the prompt caches of model for the default
+// prompt and every mode.
+func (t *Transcriber) ensurePromptCaches(ctx context.Context, model string) {
+	t.ensurePromptCache(ctx, cacheKey{model: model})
+	for name := range t.modes {
+		t.ensurePromptCache(ctx, cacheKey{model, name})
 	}
 }
 
-func (t *Transcriber) ensurePromptCache(ctx context.Context) {
+func (t *Transcriber) ensurePromptCache(ctx context.Context, key cacheKey) {
 	if !t.enablePromptCache {
 		return
 	}
-	if _, ok := t.cacheNameByModel[t.modelName]; ok {
+	// A concurrent call for the same key and dictionary is already creating
+	// the cache; creating another would leak one of them.
+	t.mu.Lock()
+	existing, systemPrompt, hash := t.cacheNames[key], t.systemPrompts[key.mode], t.dictionaryHash
+	if existing != "" || t.cacheCreating[key] == hash {
+		t.mu.Unlock()
 		return
 	}
+	t.cacheCreating[key] = hash
+	t.mu.Unlock()
+	defer func() {
+		t.mu.Lock()
+		if t.cacheCreating[key] == hash {
+			delete(t.cacheCreating, key)
+		}
+		t.mu.Unlock()
+	}()
 
-	cache, err := t.client.Caches.Create(ctx, t.modelName, &genai.CreateCachedContentConfig{
+	displayName := "vibescribe-system-prompt-cache-" + hash
+	if key.mode != "" {
+		displayName += "-" + key.mode
+	}
+	cache, err := t.client.Caches.Create(ctx, key.model, &genai.CreateCachedContentConfig{
 		SystemInstruction: &genai.Content{
-			Parts: []*genai.Part{{Text: t.systemPrompt}},
+			Parts: []*genai.Part{{Text: systemPrompt}},
 		},
 		TTL:         t.promptCacheTTL,
-		DisplayName: "vibescribe-system-prompt-cache",
+		DisplayName: displayName,
 	})
 	if err != nil {
 		log.Printf("[Gemini] Prompt cache unavailable. system_instruction fallback を使用します: %v", err)
 		return
 	}
-	if cache.Name != "" {
-		t.cacheNameByModel[t.modelName] = cache.Name
-		log.Printf("[Gemini] Prompt cache created: %s (model=%s)", cache.Name, t.modelName)
+	if cache.Name == "" {
+		return
+	}
+	t.mu.Lock()
+	stale := t.dictionaryHash != hash
+	if !stale {
+		t.cacheNames[key] = cache.Name
+	}
+	t.mu.Unlock()
+	if stale {
+		// The dictionary was reloaded while the cache was being created.
+		t.deleteCache(ctx, cache.Name)
+		return
 	}
+	log.Printf("[Gemini] Prompt cache created: %s (model=%s, mode=%s)", cache.Name, key.model, modeLabel(key.mode))
 }
 
 func resolveThinkingLevel() genai.ThinkingLevel {
@@ -26,14 +26,14 @@ func TestTranscribeIntegration(t *testing.T) {
 	}
 
 	ctx := context.Background()
-	tr, err := New(ctx, apiKey)
+	tr, err := New(ctx, Config{APIKey: apiKey})
 	if err != nil {
 		t.Fatalf("New: %v", err)
 	}
 
 	wavPath := sampleWAVPath(t)
 
-	text, elapsed, err := tr.Transcribe(ctx, wavPath)
+	text, elapsed, err := TranscribeFile(ctx, tr, wavPath)
 	if err != nil {
 		t.Fatalf("Transcribe: %v", err)
 	}
@@ -54,14 +54,14 @@ func TestTranscribeMediumWAV(t *testing.T) {
 	}
 
 	ctx := context.Background()
-	tr, err := New(ctx, apiKey)
+	tr, err := New(ctx, Config{APIKey: apiKey})
 	if err != nil {
 		t.Fatalf("New: %v", err)
 	}
 
 	wavPath := sampleWAVPath(t)
 
-	text, elapsed, err := tr.Transcribe(ctx, wavPath)
+	text, elapsed, err := TranscribeFile(ctx, tr, wavPath)
 	if err != nil {
 		t.Fatalf("Transcribe: %v", err)
 	}
@@ -0,0 +1,400 @@
+package transcriber
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/noricha-vr/voicecode/internal/core/settings"
+	"github.com/noricha-vr/voicecode/internal/core/transcriber/geminitest"
+)
+
+var testAudio = []byte("RIFF\x00\x00\x00\x00WAVEfake")
+
+func newFakeTranscriber(t *testing.T, srv *geminitest.Server, cache bool) *Transcriber {
+	t.Helper()
+	t.Setenv(ModelEnvVar, "")
+	t.Setenv(ThinkingLevelEnvVar, "")
+	if cache {
+		t.Setenv(EnablePromptCacheEnvVar, "true")
+	} else {
+		t.Setenv(EnablePromptCacheEnvVar, "false")
+	}
+
+	dictPath := filepath.Join(t.TempDir(), "dictionary.txt")
+	tr, err := New(context.Background(), Config{APIKey: "test-key", BaseURL: srv.URL, DictionaryPath: dictPath})
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	return tr
+}
+
+func TestTranscribeFakeServer(t *testing.T) {
+	srv := geminitest.NewServer(PreferredModels...)
+	defer srv.Close()
+	srv.SetText("", "<text>ReactのuseStateを使う</text>\n")
+
+	tr := newFakeTranscriber(t, srv, false)
+	if tr.ModelName() != PreferredModels[0] {
+		t.Fatalf("ModelName() = %q, want %q", tr.ModelName(), PreferredModels[0])
+	}
+
+	text, err := tr.Transcribe(context.Background(), testAudio, Options{})
+	if err != nil {
+		t.Fatalf("Transcribe() error: %v", err)
+	}
+	if text != "ReactのuseStateを使う" {
+		t.Errorf("text = %q, want XML tags stripped", text)
+	}
+
+	calls := srv.GenerateCalls()
+	if len(calls) != 1 {
+		t.Fatalf("generateContent calls = %d, want 1", len(calls))
+	}
+	c := calls[0]
+	if c.AudioMIMEType != DefaultMIMEType || c.AudioBytes != len(testAudio) {
+		t.Errorf("audio = %s/%d bytes, want %s/%d", c.AudioMIMEType, c.AudioBytes, DefaultMIMEType, len(testAudio))
+	}
+	if c.SystemPrompt == "" {
+		t.Error("system instruction should be sent when prompt cache is disabled")
+	}
+	if c.ThinkingLevel != "MINIMAL" {
+		t.Errorf("ThinkingLevel = %q, want MINIMAL", c.ThinkingLevel)
+	}
+}
+
+func TestNewResolvesAvailableModel(t *testing.T) {
+	srv := geminitest.NewServer("gemini-2.0-flash", "gemini-exp")
+	defer srv.Close()
+
+	tr := newFakeTranscriber(t, srv, false)
+	if tr.ModelName() != "gemini-2.0-flash" {
+		t.Errorf("ModelName() = %q, want first available preferred model", tr.ModelName())
+	}
+}
+
+func TestNewListFailureUsesDefaultModel(t *testing.T) {
+	srv := geminitest.NewServer(DefaultModel)
+	defer srv.Close()
+	srv.FailList(geminitest.Unavailable())
+
+	tr := newFakeTranscriber(t, srv, false)
+	if tr.ModelName() != DefaultModel {
+		t.Errorf("ModelName() = %q, want %q", tr.ModelName(), DefaultModel)
+	}
+}
+
+func TestTranscribeRetriesTransientError(t *testing.T) {
+	srv := geminitest.NewServer(PreferredModels...)
+	defer srv.Close()
+
+	tr := newFakeTranscriber(t, srv, false)
+	srv.FailGenerate(tr.ModelName(), geminitest.RateLimited())
+
+	text, err := tr.Transcribe(context.Background(), testAudio, Options{})
+	if err != nil {
+		t.Fatalf("Transcribe() error: %v", err)
+	}
+	if text != geminitest.DefaultText {
+		t.Errorf("text = %q", text)
+	}
+	calls := srv.GenerateCalls()
+	if len(calls) != 2 || calls[0].Model != calls[1].Model {
+		t.Errorf("expected 2 calls to the same model, got %+v", calls)
+	}
+}
+
+func TestTranscribeFallsBackAfterPersistentTransientError(t *testing.T) {
+	srv := geminitest.NewServer(PreferredModels...)
+	defer srv.Close()
+
+	tr := newFakeTranscriber(t, srv, false)
+	primary := tr.ModelName()
+	srv.FailGenerate(primary, geminitest.Unavailable(), geminitest.Unavailable())
+
+	if _, err := tr.Transcribe(context.Background(), testAudio, Options{}); err != nil {
+		t.Fatalf("Transcribe() error: %v", err)
+	}
+	if tr.ModelName() == primary {
+		t.Errorf("ModelName() = %q, expected fallback away from primary", tr.ModelName())
+	}
+	calls := srv.GenerateCalls()
+	if last := calls[len(calls)-1]; last.Model != PreferredModels[1] || last.Status != http.StatusOK {
+		t.Errorf("last call = %+v, want success on %s", last, PreferredModels[1])
+	}
+}
+
+func TestTranscribeFallsBackWhenModelNotFound(t *testing.T) {
+	srv := geminitest.NewServer(PreferredModels...)
+	defer srv.Close()
+
+	tr := newFakeTranscriber(t, srv, false)
+	srv.FailGenerate(tr.ModelName(), geminitest.ModelNotFound(tr.ModelName()))
+	before := len(srv.Calls())
+
+	if _, err := tr.Transcribe(context.Background(), testAudio, Options{}); err != nil {
+		t.Fatalf("Transcribe() error: %v", err)
+	}
+	if got := len(srv.GenerateCalls()); got != 2 {
+		t.Errorf("generateContent calls = %d, want 2 (no retry on not-found)", got)
+	}
+	for _, c := range srv.Calls()[before:] {
+		if c.Method == "models.list" {
+			t.Errorf("fallback listed the models again; it should use the list resolved by New")
+		}
+	}
+	if tr.ModelName() != PreferredModels[1] {
+		t.Errorf("ModelName() = %q, want %q", tr.ModelName(), PreferredModels[1])
+	}
+}
+
+func TestTranscribeUsesConfiguredTimeout(t *testing.T) {
+	srv := geminitest.NewServer(PreferredModels...)
+	defer srv.Close()
+	t.Setenv("GOOGLE_API_KEY", "test-key")
+	t.Setenv(BaseURLEnvVar, srv.URL)
+	t.Setenv(ModelEnvVar, "")
+	t.Setenv(EnablePromptCacheEnvVar, "false")
+	t.Setenv("HOME", t.TempDir())
+
+	cfg := settings.Default()
+	cfg.TranscribeTimeoutSec = 0.2
+	b, err := NewBackend(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("NewBackend() error: %v", err)
+	}
+	defer b.Close()
+	for _, m := range PreferredModels {
+		srv.SetDelay(m, 2*time.Second)
+	}
+
+	start := time.Now()
+	if _, err := b.Transcribe(context.Background(), testAudio, Options{}); err == nil {
+		t.Fatal("Transcribe() should time out")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Transcribe took %s, want it bounded by transcribe_timeout_sec", elapsed)
+	}
+}
+
+func TestTranscribeNonTransientErrorFails(t *testing.T) {
+	srv := geminitest.NewServer(PreferredModels...)
+	defer srv.Close()
+
+	tr := newFakeTranscriber(t, srv, false)
+	srv.FailGenerate("", geminitest.InvalidAPIKey())
+
+	if _, err := tr.Transcribe(context.Background(), testAudio, Options{}); err == nil {
+		t.Fatal("Transcribe() should fail on invalid API key")
+	}
+	if got := len(srv.GenerateCalls()); got != 1 {
+		t.Errorf("generateContent calls = %d, want 1", got)
+	}
+}
+
+func TestTranscribeThinkingUnsupportedSwitchesToBudget(t *testing.T) {
+	srv := geminitest.NewServer(PreferredModels...)
+	defer srv.Close()
+
+	tr := newFakeTranscriber(t, srv, false)
+	srv.DisableThinkingLevel(tr.ModelName())
+
+	if _, err := tr.Transcribe(context.Background(), testAudio, Options{}); err != nil {
+		t.Fatalf("Transcribe() error: %v", err)
+	}
+	calls := srv.GenerateCalls()
+	if len(calls) != 2 {
+		t.Fatalf("generateContent calls = %d, want 2", len(calls))
+	}
+	if calls[1].ThinkingLevel != "" || calls[1].ThinkingBudget == nil || *calls[1].ThinkingBudget != 0 {
+		t.Errorf("retry should use thinking_budget=0, got %+v", calls[1])
+	}
+	if tr.thinkingModeFor(tr.ModelName()) != "budget0" {
+		t.Errorf("thinking mode = %q, want budget0", tr.thinkingModeFor(tr.ModelName()))
+	}
+}
+
+func TestTranscribeUsesPromptCache(t *testing.T) {
+	srv := geminitest.NewServer(PreferredModels...)
+	defer srv.Close()
+
+	tr := newFakeTranscriber(t, srv, true)
+	caches := srv.Caches()
+	if len(caches) != 1 {
+		t.Fatalf("caches = %v, want 1 created by New", caches)
+	}
+
+	if _, err := tr.Transcribe(context.Background(), testAudio, Options{}); err != nil {
+		t.Fatalf("Transcribe() error: %v", err)
+	}
+	c := srv.GenerateCalls()[0]
+	if c.CachedContent != caches[0] || c.SystemPrompt != "" {
+		t.Errorf("call should reference cache %s without system instruction, got %+v", caches[0], c)
+	}
+}
+
+func TestTranscribeSendsReferenceContext(t *testing.T) {
+	srv := geminitest.NewServer(PreferredModels...)
+	defer srv.Close()
+
+	tr := newFakeTranscriber(t, srv, true)
+	if _, err := tr.Transcribe(context.Background(), testAudio, Options{Context: "func parseConfig()"}); err != nil {
+		t.Fatalf("Transcribe() error: %v", err)
+	}
+	c := srv.GenerateCalls()[0]
+	if c.CachedContent == "" {
+		t.Errorf("reference context should not bypass the prompt cache, got %+v", c)
+	}
+	if len(c.Texts) != 2 || !strings.Contains(c.Texts[0], "<reference_context>") || !strings.Contains(c.Texts[0], "func parseConfig()") {
+		t.Errorf("Texts = %q, want the reference context before the transcribe prompt", c.Texts)
+	}
+}
+
+func TestTranscribeExpiredCacheFallsBackToSystemInstruction(t *testing.T) {
+	srv := geminitest.NewServer(PreferredModels...)
+	defer srv.Close()
+
+	tr := newFakeTranscriber(t, srv, true)
+	srv.ExpireCaches()
+
+	if _, err := tr.Transcribe(context.Background(), testAudio, Options{}); err != nil {
+		t.Fatalf("Transcribe() error: %v", err)
+	}
+	calls := srv.GenerateCalls()
+	if len(calls) != 2 {
+		t.Fatalf("generateContent calls = %d, want 2", len(calls))
+	}
+	if calls[1].CachedContent != "" || calls[1].SystemPrompt == "" {
+		t.Errorf("retry should send system instruction without cache, got %+v", calls[1])
+	}
+}
+
+func TestNewCacheCreateFailureUsesSystemInstruction(t *testing.T) {
+	srv := geminitest.NewServer(PreferredModels...)
+	defer srv.Close()
+	srv.FailCacheCreate(geminitest.Fault{Code: http.StatusBadRequest, Status: "INVALID_ARGUMENT", Message: "Cached content is too small."})
+
+	tr := newFakeTranscriber(t, srv, true)
+	if _, err := tr.Transcribe(context.Background(), testAudio, Options{}); err != nil {
+		t.Fatalf("Transcribe() error: %v", err)
+	}
+	if c := srv.GenerateCalls()[0]; c.CachedContent != "" || c.SystemPrompt == "" {
+		t.Errorf("expected system instruction fallback, got %+v", c)
+	}
+}
+
+func TestNewMergesUserDictionary(t *testing.T) {
+	srv := geminitest.NewServer(PreferredModels...)
+	defer srv.Close()
+	t.Setenv(ModelEnvVar, "")
+	t.Setenv(EnablePromptCacheEnvVar, "true")
+
+	dictPath := filepath.Join(t.TempDir(), "dictionary.txt")
+	if err := os.WriteFile(dictPath, []byte("クロードコード\tClaude Code\nsupabase\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tr, err := New(context.Background(), Config{APIKey: "test-key", BaseURL: srv.URL, DictionaryPath: dictPath})
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	var create *geminitest.Call
+	for _, c := range srv.Calls() {
+		if c.Method == "cachedContents.create" {
+			create = &c
+			break
+		}
+	}
+	if create == nil {
+		t.Fatal("expected a cachedContents.create call")
+	}
+	if !strings.Contains(create.SystemPrompt, `english="Claude Code"`) || !strings.Contains(create.SystemPrompt, "supabase") {
+		t.Error("cached system prompt should contain the user dictionary")
+	}
+	want := "vibescribe-system-prompt-cache-" + tr.dictionaryHash
+	if create.DisplayName != want || tr.dictionaryHash == "none" {
+		t.Errorf("DisplayName = %q, want %q with a dictionary hash", create.DisplayName, want)
+	}
+}
+
+func TestTranscribeModes(t *testing.T) {
+	srv := geminitest.NewServer(PreferredModels...)
+	defer srv.Close()
+	t.Setenv(ModelEnvVar, "")
+	t.Setenv(ThinkingLevelEnvVar, "")
+	t.Setenv(EnablePromptCacheEnvVar, "true")
+
+	dictPath := filepath.Join(t.TempDir(), "dictionary.txt")
+	if err := os.WriteFile(dictPath, []byte("クロードコード\tClaude Code\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	modes := []settings.Mode{{
+		Name:             "commit",
+		SystemPrompt:     "Write a git commit message.",
+		TranscribePrompt: "Summarize this audio as a commit message.",
+		Temperature:      floatPtr(0.5),
+		ThinkingLevel:    "high",
+	}}
+	tr, err := New(context.Background(), Config{APIKey: "test-key", BaseURL: srv.URL, DictionaryPath: dictPath, Modes: modes})
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	model := tr.ModelName()
+
+	creates := callsOf(srv, "cachedContents.create")
+	if len(creates) != 2 {
+		t.Fatalf("cachedContents.create calls = %d, want one per mode and the default", len(creates))
+	}
+	commitCache := tr.cacheNames[cacheKey{model, "commit"}]
+	defaultCache := tr.cacheNames[cacheKey{model: model}]
+	if commitCache == "" || defaultCache == "" || commitCache == defaultCache {
+		t.Fatalf("caches = default %q, commit %q; want separate caches", defaultCache, commitCache)
+	}
+	for _, c := range creates {
+		if c.DisplayName == "vibescribe-system-prompt-cache-"+tr.dictionaryHash+"-commit" &&
+			(!strings.HasPrefix(c.SystemPrompt, "Write a git commit message.") || !strings.Contains(c.SystemPrompt, `english="Claude Code"`)) {
+			t.Errorf("commit cache prompt = %q, want the mode prompt with the dictionary", c.SystemPrompt)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		opts        Options
+		cache       string
+		text        string
+		level       string
+		temperature float64
+	}{
+		{"default", Options{}, defaultCache, "", "MINIMAL", 0},
+		{"mode", Options{Mode: "commit"}, commitCache, "Summarize this audio as a commit message.", "HIGH", 0.5},
+		{"explicit thinking level wins", Options{Mode: "commit", ThinkingLevel: "low"}, commitCache, "", "LOW", 0.5},
+		{"unknown mode", Options{Mode: "missing"}, defaultCache, "", "MINIMAL", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tr.Transcribe(context.Background(), testAudio, tt.opts); err != nil {
+				t.Fatalf("Transcribe() error: %v", err)
+			}
+			calls := srv.GenerateCalls()
+			c := calls[len(calls)-1]
+			if c.CachedContent != tt.cache {
+				t.Errorf("CachedContent = %q, want %q", c.CachedContent, tt.cache)
+			}
+			if tt.text != "" && (len(c.Texts) == 0 || c.Texts[0] != tt.text) {
+				t.Errorf("Texts = %q, want %q first", c.Texts, tt.text)
+			}
+			if c.ThinkingLevel != tt.level {
+				t.Errorf("ThinkingLevel = %q, want %q", c.ThinkingLevel, tt.level)
+			}
+			if c.Temperature == nil || *c.Temperature != tt.temperature {
+				t.Errorf("Temperature = %v, want %v", c.Temperature, tt.temperature)
+			}
+		})
+	}
+}
+
+func floatPtr(v float64) *float64 { return &v }
@@ -0,0 +1,79 @@
+package transcriber
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/noricha-vr/voicecode/internal/core/prompt"
+	"github.com/noricha-vr/voicecode/internal/core/trace"
+	"google.golang.org/genai"
+)
+
+// Translator is implemented by backends that can translate a transcription,
+// e.g. to paste a Japanese utterance as an English prompt.
+type Translator interface {
+	// Translate returns text translated into the target language code.
+	Translate(ctx context.Context, text, target string) (string, error)
+}
+
+// ErrTranslationUnsupported is returned when no backend can translate.
+var ErrTranslationUnsupported = errors.New("translation requires the gemini backend")
+
+var (
+	_ Translator = (*Transcriber)(nil)
+	_ Translator = (*Chain)(nil)
+)
+
+// Translate translates text with a text-only request to the current model.
+// It makes a single attempt within the configured timeout.
+func (t *Transcriber) Translate(ctx context.Context, text, target string) (string, error) {
+	stepper := trace.FromContext(ctx)
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	start := time.Now()
+	model := t.selectModel(ctx)
+	config := &genai.GenerateContentConfig{
+		SystemInstruction: &genai.Content{
+			Parts: []*genai.Part{{Text: prompt.TranslateSystemPrompt(target)}},
+		},
+		ThinkingConfig: t.thinkingConfig(model, t.thinkingLevel),
+		Temperature:    genai.Ptr[float32](0.0),
+	}
+	contents := []*genai.Content{{Parts: []*genai.Part{{Text: text}}}}
+
+	done := stepper.Step("gemini.translate")
+	resp, err := t.client.Models.GenerateContent(ctx, model, contents, config)
+	done(err)
+	elapsed := time.Since(start).Seconds()
+	if err != nil {
+		log.Printf("[Gemini %.2fs] 翻訳に失敗しました(model=%s, target=%s): %v", elapsed, model, target, err)
+		return "", err
+	}
+	result := cleanText(resp.Text())
+	log.Printf("[Gemini %.2fs] 翻訳(%s): %s (model=%s)", elapsed, target, result, model)
+	return result, nil
+}
+
+// Translate uses the first backend of the chain that can translate.
+func (c *Chain) Translate(ctx context.Context, text, target string) (string, error) {
+	for _, b := range c.backends {
+		if tr, ok := b.(Translator); ok {
+			return tr.Translate(ctx, text, target)
+		}
+	}
+	return "", ErrTranslationUnsupported
+}
+
+// TranslateText translates text with b into target. It returns
+// ErrTranslationUnsupported when b cannot translate.
+func TranslateText(ctx context.Context, b Backend, text, target string) (string, error) {
+	tr, ok := b.(Translator)
+	if !ok {
+		return "", ErrTranslationUnsupported
+	}
+	return tr.Translate(ctx, strings.TrimSpace(text), target)
+}
@@ -0,0 +1,53 @@
+package transcriber
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/noricha-vr/voicecode/internal/core/transcriber/geminitest"
+)
+
+func TestTranslate(t *testing.T) {
+	srv := geminitest.NewServer(PreferredModels...)
+	defer srv.Close()
+	srv.SetText("", "Implement the useState hook in React.\n")
+
+	tr := newFakeTranscriber(t, srv, true)
+	got, err := TranslateText(context.Background(), tr, " ReactのuseStateを実装して ", "en")
+	if err != nil {
+		t.Fatalf("TranslateText() error: %v", err)
+	}
+	if got != "Implement the useState hook in React." {
+		t.Errorf("TranslateText() = %q", got)
+	}
+
+	c := srv.GenerateCalls()[0]
+	if c.CachedContent != "" || !strings.Contains(c.SystemPrompt, "英語に翻訳") {
+		t.Errorf("translation should send its own system instruction, got cache %q prompt %q", c.CachedContent, c.SystemPrompt)
+	}
+	if len(c.Texts) != 1 || c.Texts[0] != "ReactのuseStateを実装して" || c.AudioBytes != 0 {
+		t.Errorf("contents = %q (audio %d bytes), want only the trimmed text", c.Texts, c.AudioBytes)
+	}
+}
+
+func TestTranslateUnsupported(t *testing.T) {
+	if _, err := TranslateText(context.Background(), &stubBackend{}, "text", "en"); !errors.Is(err, ErrTranslationUnsupported) {
+		t.Errorf("TranslateText() error = %v, want ErrTranslationUnsupported", err)
+	}
+}
+
+func TestTranscribeLanguage(t *testing.T) {
+	srv := geminitest.NewServer(PreferredModels...)
+	defer srv.Close()
+
+	tr := newFakeTranscriber(t, srv, false)
+	tr.language = "en"
+	if _, err := tr.Transcribe(context.Background(), testAudio, Options{}); err != nil {
+		t.Fatalf("Transcribe() error: %v", err)
+	}
+	if c := srv.GenerateCalls()[0]; len(c.Texts) == 0 || !strings.Contains(c.Texts[0], "英語で") {
+		t.Errorf("Texts = %q, want the English transcription instruction", c.Texts)
+	}
+}
@@ -0,0 +1,384 @@
+package vocab
+
+import (
+	"bytes"
+	"go/scanner"
+	"go/token"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// extractor reports each candidate word in src.
+type extractor func(src []byte, add func(word string, kind Kind))
+
+// extractorFor picks the extractor by file name; nil means the file is not
+// scanned. Test files are skipped: their names (TestFoo, fixtures) are not
+// words anyone dictates.
+func extractorFor(name string) extractor {
+	switch {
+	case strings.HasSuffix(name, "_test.go"):
+		return nil
+	case strings.HasSuffix(name, ".go"):
+		return extractGo
+	case strings.Contains(name, ".test.") || strings.Contains(name, ".spec."):
+		return nil
+	case strings.HasSuffix(name, ".ts"), strings.HasSuffix(name, ".tsx"),
+		strings.HasSuffix(name, ".mts"), strings.HasSuffix(name, ".cts"):
+		return extractTypeScript
+	case strings.HasPrefix(name, "test_") && strings.HasSuffix(name, ".py"),
+		strings.HasSuffix(name, "_test.py"):
+		return nil
+	case strings.HasSuffix(name, ".py"):
+		return extractPython
+	case strings.HasSuffix(name, ".md"), strings.HasSuffix(name, ".markdown"):
+		return extractMarkdown
+	}
+	return nil
+}
+
+// stopWords are common enough that hinting them only costs prompt tokens.
+var stopWords = map[string]bool{
+	"TODO": true, "FIXME": true, "XXX": true, "NOTE": true, "OK": true, "ID": true,
+	"True": true, "False": true, "None": true, "README": true, "EOF": true,
+	"Println": true, "Printf": true, "Sprintf": true, "Errorf": true, "Fprintf": true,
+	"Fprintln": true, "Fatalf": true, "Sprint": true, "Logf": true,
+	"ReadFile": true, "WriteFile": true, "WriteString": true, "NewReader": true,
+	"Background": true, "WithCancel": true, "WithTimeout": true, "Unlock": true,
+	"RLock": true, "RUnlock": true, "TrimSpace": true, "HasPrefix": true, "HasSuffix": true,
+	"Contains": true, "ToLower": true, "ToUpper": true, "IsNotExist": true,
+	"useState": true, "useEffect": true, "console": true, "TypeError": true,
+	"ValueError": true, "KeyError": true, "Optional": true, "typeof": true, "instanceof": true,
+}
+
+// isCompound reports whether word is worth a hint as a code identifier:
+// camelCase/PascalCase with an inner capital (StreamReplacer, iOS, APIRouter)
+// or an acronym (VAD, HTTP2). Plain lower-case or single capitalized words are
+// left to the model; snake_case is skipped as it is rarely dictated.
+func isCompound(word string) bool {
+	if len(word) < 2 || len(word) > 40 || stopWords[word] || strings.ContainsAny(word, "_$") {
+		return false
+	}
+	if !isLetter(word[0]) {
+		return false
+	}
+	upper, lower, hump := 0, 0, false
+	for i := 0; i < len(word); i++ {
+		c := word[i]
+		switch {
+		case c >= 'A' && c <= 'Z':
+			upper++
+			// fooBar, or the end of a leading acronym as in APIRouter.
+			if i > 0 && word[i-1] >= 'a' && word[i-1] <= 'z' ||
+				i > 0 && i+1 < len(word) && word[i-1] >= 'A' && word[i-1] <= 'Z' && word[i+1] >= 'a' && word[i+1] <= 'z' {
+				hump = true
+			}
+		case c >= 'a' && c <= 'z':
+			lower++
+		}
+	}
+	if lower == 0 {
+		return upper >= 2 && len(word) <= 8
+	}
+	return hump && len(word) >= 3
+}
+
+func isLetter(c byte) bool {
+	return c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z'
+}
+
+func isIdentByte(c byte) bool {
+	return isLetter(c) || c >= '0' && c <= '9' || c == '_' || c == '$'
+}
+
+// isPackageName accepts import names that are plausible words.
+func isPackageName(name string) bool {
+	if len(name) < 3 || len(name) > 40 || name == "main" || !isLetter(name[0]) {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		if !isIdentByte(name[i]) && name[i] != '-' && name[i] != '.' {
+			return false
+		}
+	}
+	return true
+}
+
+// extractGo uses the Go scanner so comments and strings are skipped; import
+// paths yield the package name of non-standard-library imports.
+func extractGo(src []byte, add func(string, Kind)) {
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(src))
+	var s scanner.Scanner
+	s.Init(file, src, nil, 0)
+
+	const (
+		noImport = iota
+		importSpec
+		importGroup
+	)
+	state := noImport
+	prev := token.ILLEGAL
+	for {
+		_, tok, lit := s.Scan()
+		if tok == token.EOF {
+			return
+		}
+		switch tok {
+		case token.IDENT:
+			if prev == token.PACKAGE {
+				if isPackageName(lit) {
+					add(lit, KindPackage)
+				}
+			} else if state == noImport && isCompound(lit) {
+				add(lit, KindIdentifier)
+			}
+		case token.IMPORT:
+			state = importSpec
+		case token.LPAREN:
+			if state == importSpec {
+				state = importGroup
+			}
+		case token.RPAREN:
+			if state == importGroup {
+				state = noImport
+			}
+		case token.STRING:
+			if state != noImport {
+				if name := goPackageName(lit); name != "" {
+					add(name, KindPackage)
+				}
+				if state == importSpec {
+					state = noImport
+				}
+			}
+		}
+		prev = tok
+	}
+}
+
+// goPackageName returns the last element of a non-standard import path,
+// skipping a major version suffix (.../foo/v2 -> foo).
+func goPackageName(lit string) string {
+	path, err := strconv.Unquote(lit)
+	if err != nil {
+		return ""
+	}
+	first, _, _ := strings.Cut(path, "/")
+	if !strings.Contains(first, ".") {
+		return "" // standard library
+	}
+	elems := strings.Split(path, "/")
+	name := elems[len(elems)-1]
+	if len(elems) > 1 && len(name) > 1 && name[0] == 'v' && strings.Trim(name[1:], "0123456789") == "" {
+		name = elems[len(elems)-2]
+	}
+	if !isPackageName(name) {
+		return ""
+	}
+	return name
+}
+
+var tsImport = regexp.MustCompile(`(?:\bfrom|\bimport|\brequire\()\s*['"]([^'"]+)['"]`)
+
+func extractTypeScript(src []byte, add func(string, Kind)) {
+	for _, m := range tsImport.FindAllSubmatch(src, -1) {
+		if name := tsPackageName(string(m[1])); name != "" {
+			add(name, KindPackage)
+		}
+	}
+	codeIdentifiers(src, "//", true, func(w string) {
+		if isCompound(w) {
+			add(w, KindIdentifier)
+		}
+	})
+}
+
+// tsPackageName returns the npm package of a bare import specifier
+// (@scope/name/sub -> name, react-dom/client -> react-dom); relative and
+// node: imports are skipped.
+func tsPackageName(spec string) string {
+	if strings.HasPrefix(spec, ".") || strings.HasPrefix(spec, "/") || strings.HasPrefix(spec, "node:") {
+		return ""
+	}
+	elems := strings.Split(spec, "/")
+	name := elems[0]
+	if strings.HasPrefix(name, "@") && len(elems) > 1 {
+		name = elems[1]
+	}
+	if !isPackageName(name) {
+		return ""
+	}
+	return name
+}
+
+var pyImport = regexp.MustCompile(`(?m)^[ \t]*(?:from[ \t]+([\w.]+)[ \t]+import|import[ \t]+([\w., \t]+))`)
+
+// pyStdlib lists common standard library modules that make poor hints.
+var pyStdlib = map[string]bool{
+	"abc": true, "argparse": true, "asyncio": true, "collections": true, "contextlib": true,
+	"dataclasses": true, "datetime": true, "enum": true, "functools": true, "itertools": true,
+	"json": true, "logging": true, "math": true, "pathlib": true, "random": true,
+	"shutil": true, "subprocess": true, "sys": true, "tempfile": true, "time": true,
+	"typing": true, "unittest": true, "__future__": true,
+}
+
+func extractPython(src []byte, add func(string, Kind)) {
+	for _, m := range pyImport.FindAllSubmatch(src, -1) {
+		mods := string(m[1])
+		if mods == "" {
+			mods = string(m[2])
+		}
+		for _, mod := range strings.Split(mods, ",") {
+			mod, _, _ = strings.Cut(strings.TrimSpace(mod), " ")
+			top, _, _ := strings.Cut(mod, ".")
+			if top != "" && !pyStdlib[top] && isPackageName(top) {
+				add(top, KindPackage)
+			}
+		}
+	}
+	codeIdentifiers(src, "#", false, func(w string) {
+		if isCompound(w) {
+			add(w, KindIdentifier)
+		}
+	})
+}
+
+// codeIdentifiers calls fn with each identifier in src outside comments and
+// string literals. lineComment starts a comment to end of line; blockComments
+// enables /* ... */. Python triple-quoted strings are handled as strings.
+func codeIdentifiers(src []byte, lineComment string, blockComments bool, fn func(string)) {
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case bytes.HasPrefix(src[i:], []byte(lineComment)):
+			end := bytes.IndexByte(src[i:], '\n')
+			if end < 0 {
+				return
+			}
+			i += end
+		case blockComments && bytes.HasPrefix(src[i:], []byte("/*")):
+			end := bytes.Index(src[i+2:], []byte("*/"))
+			if end < 0 {
+				return
+			}
+			i += end + 4
+		case c == '"' || c == '\'' || c == '`':
+			i = skipString(src, i)
+		case isLetter(c) || c == '_' || c == '$':
+			j := i
+			for j < len(src) && isIdentByte(src[j]) {
+				j++
+			}
+			fn(string(src[i:j]))
+			i = j
+		case c >= '0' && c <= '9':
+			// Skip numeric literals so 0xFF is not read as an identifier.
+			for i < len(src) && isIdentByte(src[i]) {
+				i++
+			}
+		default:
+			i++
+		}
+	}
+}
+
+// skipString returns the index just past the string literal starting at i.
+func skipString(src []byte, i int) int {
+	q := src[i]
+	if q != '`' && bytes.HasPrefix(src[i:], []byte{q, q, q}) {
+		end := bytes.Index(src[i+3:], []byte{q, q, q})
+		if end < 0 {
+			return len(src)
+		}
+		return i + 3 + end + 3
+	}
+	for j := i + 1; j < len(src); j++ {
+		switch src[j] {
+		case '\\':
+			j++
+		case q:
+			return j + 1
+		case '\n':
+			if q != '`' {
+				return j + 1 // unterminated; resume on the next line
+			}
+		}
+	}
+	return len(src)
+}
+
+var (
+	mdLinkTarget = regexp.MustCompile(`\]\([^)]*\)|https?://\S+`)
+	mdCodeSpan   = regexp.MustCompile("`([^`]+)`")
+	mdLineMarker = regexp.MustCompile(`^\s*(?:[#>*+-]+|\d+\.|\|)?\s*`)
+	mdWord       = regexp.MustCompile(`[A-Za-z][A-Za-z0-9]*(?:[.+#-][A-Za-z0-9]+)*\+*`)
+)
+
+// extractMarkdown reads prose and code. Fenced blocks and `code spans` use
+// the identifier rules; prose contributes compound words, acronyms and
+// capitalized words that do not start a sentence (proper nouns such as
+// Kubernetes or Gemini).
+func extractMarkdown(src []byte, add func(string, Kind)) {
+	inFence := false
+	for _, line := range strings.Split(string(src), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			codeIdentifiers([]byte(line), "//", true, func(w string) {
+				if isCompound(w) {
+					add(w, KindIdentifier)
+				}
+			})
+			continue
+		}
+
+		line = mdLinkTarget.ReplaceAllString(line, " ")
+		for _, m := range mdCodeSpan.FindAllStringSubmatch(line, -1) {
+			codeIdentifiers([]byte(m[1]), "//", false, func(w string) {
+				if isCompound(w) {
+					add(w, KindIdentifier)
+				}
+			})
+		}
+		line = mdCodeSpan.ReplaceAllString(line, " ")
+		line = line[len(mdLineMarker.FindString(line)):]
+
+		for _, loc := range mdWord.FindAllStringIndex(line, -1) {
+			w := line[loc[0]:loc[1]]
+			if loc[0] > 0 && isIdentByte(line[loc[0]-1]) {
+				continue
+			}
+			if isCompound(w) || isProperNoun(w) && !sentenceStart(line[:loc[0]]) {
+				add(w, KindTerm)
+			}
+		}
+	}
+}
+
+// isProperNoun reports whether w is a capitalized word like Kubernetes.
+func isProperNoun(w string) bool {
+	if len(w) < 3 || stopWords[w] || w[0] < 'A' || w[0] > 'Z' {
+		return false
+	}
+	for i := 1; i < len(w); i++ {
+		if w[i] >= 'A' && w[i] <= 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// sentenceStart reports whether a word preceded by before begins a sentence.
+func sentenceStart(before string) bool {
+	before = strings.TrimRight(before, " \t*_(\"'")
+	if before == "" {
+		return true
+	}
+	return strings.HasSuffix(before, ".") || strings.HasSuffix(before, "!") ||
+		strings.HasSuffix(before, "?") || strings.HasSuffix(before, ":") ||
+		strings.HasSuffix(before, "|") || strings.HasSuffix(before, "。")
+}
@@ -0,0 +1,188 @@
+// Package vocab extracts project vocabulary (identifiers, package names and
+// technical terms) from a source tree for use as dictionary hint words.
+package vocab
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Kind is where a term was found.
+type Kind int
+
+const (
+	KindIdentifier Kind = iota // type, function or variable name in code
+	KindPackage                // package, module or import name
+	KindTerm                   // technical term in Markdown prose
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindPackage:
+		return "package"
+	case KindTerm:
+		return "term"
+	default:
+		return "identifier"
+	}
+}
+
+// Term is a ranked vocabulary word.
+type Term struct {
+	Word  string
+	Kind  Kind
+	Count int // occurrences across the tree
+	Files int // number of files containing it
+}
+
+const (
+	// DefaultLimit is the number of terms kept when Options.Limit is 0.
+	DefaultLimit = 200
+	// DefaultMinCount is the minimum number of occurrences when
+	// Options.MinCount is 0. Package names are kept regardless.
+	DefaultMinCount = 2
+	// maxFileSize skips generated or vendored blobs.
+	maxFileSize = 1 << 20
+)
+
+// Options controls Scan.
+type Options struct {
+	Limit    int
+	MinCount int
+}
+
+// skipDirs are never descended into.
+var skipDirs = map[string]bool{
+	"node_modules":  true,
+	"vendor":        true,
+	"testdata":      true,
+	"dist":          true,
+	"build":         true,
+	"__pycache__":   true,
+	"venv":          true,
+	"site-packages": true,
+}
+
+// Stats summarizes a scan.
+type Stats struct {
+	Files   int // source files read
+	Words   int // distinct candidate words before ranking
+	Skipped int // files and directories that could not be read
+}
+
+// readFile is overridable for testing.
+var readFile = os.ReadFile
+
+// Scan walks root and returns its vocabulary, most widespread first: terms
+// found in more files rank higher, then terms with more occurrences. Hidden
+// directories, dependency and build directories, and test files are skipped,
+// and so are files and directories that cannot be read.
+func Scan(root string, opts Options) ([]Term, Stats, error) {
+	if opts.Limit == 0 {
+		opts.Limit = DefaultLimit
+	}
+	if opts.MinCount == 0 {
+		opts.MinCount = DefaultMinCount
+	}
+
+	var stats Stats
+	terms := make(map[string]*Term)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			log.Printf("[Vocab] 読み込めないためスキップします: %v", err)
+			stats.Skipped++
+			if d != nil && d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if path != root && (strings.HasPrefix(name, ".") || skipDirs[name]) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		extract := extractorFor(name)
+		if extract == nil || !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || info.Size() > maxFileSize {
+			return nil
+		}
+		src, err := readFile(path)
+		if err != nil {
+			log.Printf("[Vocab] 読み込めないためスキップします: %v", err)
+			stats.Skipped++
+			return nil
+		}
+		stats.Files++
+
+		seen := make(map[string]bool)
+		extract(src, func(word string, kind Kind) {
+			t, ok := terms[word]
+			if !ok {
+				t = &Term{Word: word, Kind: kind}
+				terms[word] = t
+			}
+			// A word that is also a package name is reported as one.
+			if kind == KindPackage {
+				t.Kind = KindPackage
+			}
+			t.Count++
+			if !seen[word] {
+				seen[word] = true
+				t.Files++
+			}
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, stats, err
+	}
+	stats.Words = len(terms)
+
+	var out []Term
+	for _, t := range terms {
+		if t.Count >= opts.MinCount || t.Kind == KindPackage {
+			out = append(out, *t)
+		}
+	}
+	slices.SortFunc(out, func(a, b Term) int {
+		if c := cmp.Compare(b.Files, a.Files); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(b.Count, a.Count); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Word, b.Word)
+	})
+	if opts.Limit > 0 && len(out) > opts.Limit {
+		out = out[:opts.Limit]
+	}
+	return out, stats, nil
+}
+
+// WriteDictionary writes terms as hint words in the user dictionary format,
+// one per line in rank order, under a comment naming the scanned root.
+func WriteDictionary(w io.Writer, root string, terms []Term) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# Generated by voicecode vocab scan %s\n", root)
+	fmt.Fprintf(bw, "# %d hint words, most widespread first. Prefix reading<TAB> to turn one into a conversion.\n", len(terms))
+	for _, t := range terms {
+		fmt.Fprintln(bw, t.Word)
+	}
+	return bw.Flush()
+}
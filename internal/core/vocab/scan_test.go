@@ -0,0 +1,253 @@
+package vocab
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/noricha-vr/voicecode/internal/core/prompt"
+)
+
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func words(terms []Term) []string {
+	var out []string
+	for _, t := range terms {
+		out = append(out, t.Word)
+	}
+	return out
+}
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		src     string
+		want    []string
+		wantPkg []string
+	}{
+		{
+			name: "go skips comments, strings and standard imports",
+			file: "main.go",
+			src: `package voicecode
+
+import (
+	"fmt"
+	genai "google.golang.org/genai"
+	"github.com/acme/widget/v2"
+)
+
+// CommentOnly is not code.
+type StreamReplacer struct{ maxLen int }
+
+func NewStreamReplacer() *StreamReplacer { fmt.Println("QuotedName"); return nil }
+`,
+			want:    []string{"StreamReplacer", "maxLen", "NewStreamReplacer", "StreamReplacer"},
+			wantPkg: []string{"voicecode", "genai", "widget"},
+		},
+		{
+			name: "typescript",
+			file: "app.tsx",
+			src: `import { useQuery } from '@tanstack/react-query'
+import Foo from './Foo'
+const x = require("zustand")
+/* BlockComment */
+export function UserProfileCard() { return ` + "`TemplateText`" + ` } // LineComment
+`,
+			want:    []string{"useQuery", "UserProfileCard"},
+			wantPkg: []string{"react-query", "zustand"},
+		},
+		{
+			name: "python",
+			file: "svc.py",
+			src: `import os, numpy as np
+from fastapi.routing import APIRouter
+"""DocString here"""
+class VoiceCodeClient:  # TrailingComment
+    load_profile = 'QuotedName'
+`,
+			want:    []string{"APIRouter", "VoiceCodeClient"},
+			wantPkg: []string{"numpy", "fastapi"},
+		},
+		{
+			name: "markdown",
+			file: "README.md",
+			src:  "# Overview\n\nDeploy to Kubernetes with `helmfile` and `KubeConfig`. Gemini speaks JSON.\n\n```go\nvar clientSet = NewClientSet()\n```\n\nSee the [docs](https://example.com/SomePage).\n",
+			want: []string{"KubeConfig", "Kubernetes", "JSON", "clientSet", "NewClientSet"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got, gotPkg []string
+			extractorFor(tt.file)([]byte(tt.src), func(w string, k Kind) {
+				if k == KindPackage {
+					gotPkg = append(gotPkg, w)
+				} else {
+					got = append(got, w)
+				}
+			})
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("words = %q, want %q", got, tt.want)
+			}
+			if !slices.Equal(gotPkg, tt.wantPkg) {
+				t.Errorf("packages = %q, want %q", gotPkg, tt.wantPkg)
+			}
+		})
+	}
+}
+
+func TestIsCompound(t *testing.T) {
+	tests := map[string]bool{
+		"StreamReplacer": true,
+		"iOS":            true,
+		"VAD":            true,
+		"HTTP2":          true,
+		"APIRouter":      true,
+		"Config":         false,
+		"config":         false,
+		"load_profile":   false,
+		"TODO":           false,
+		"X":              false,
+		"ABCDEFGHIJ":     false,
+	}
+	for w, want := range tests {
+		if got := isCompound(w); got != want {
+			t.Errorf("isCompound(%q) = %v, want %v", w, got, want)
+		}
+	}
+}
+
+func TestScan(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"a.go":                    "package svc\n\nfunc LoadProfile() { LoadProfile() }\nvar OnceOnly = 1\n",
+		"b.go":                    "package svc\n\nvar _ = LoadProfile\nvar sampleRate, sampleRate2 = 1, 2\nvar _ = sampleRate\n",
+		"a_test.go":               "package svc\n\nfunc TestLoadProfile() { TestLoadProfile() }\n",
+		"node_modules/x/index.ts": "export const VendoredName = VendoredName\n",
+		".git/HEAD.md":            "HiddenName HiddenName\n",
+		"web/app.test.ts":         "const SpecName = SpecName\n",
+		"docs/guide.md":           "Use LoadProfile from the CLI.\n",
+	})
+
+	terms, stats, err := Scan(root, Options{})
+	if err != nil {
+		t.Fatalf("Scan() error: %v", err)
+	}
+	if stats.Files != 3 {
+		t.Errorf("stats.Files = %d, want 3", stats.Files)
+	}
+	// LoadProfile: 3 files; svc: package in 2 files; sampleRate: 2 hits in 1
+	// file. OnceOnly and sampleRate2 occur once and are dropped.
+	if want := []string{"LoadProfile", "svc", "sampleRate"}; !slices.Equal(words(terms), want) {
+		t.Errorf("Scan() = %q, want %q", words(terms), want)
+	}
+	if terms[0].Count != 4 || terms[0].Files != 3 {
+		t.Errorf("LoadProfile = %+v, want 4 occurrences in 3 files", terms[0])
+	}
+
+	limited, _, err := Scan(root, Options{Limit: 1, MinCount: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"LoadProfile"}; !slices.Equal(words(limited), want) {
+		t.Errorf("Scan(Limit: 1) = %q, want %q", words(limited), want)
+	}
+}
+
+func TestScanSkipsUnreadableFiles(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"a.go":      "package svc\n\nvar _ = LoadProfile\n",
+		"b.go":      "package svc\n\nvar _ = LoadProfile\n",
+		"secret.go": "package svc\n\nvar _ = HiddenName\nvar _ = HiddenName\n",
+	})
+	orig := readFile
+	t.Cleanup(func() { readFile = orig })
+	readFile = func(path string) ([]byte, error) {
+		if filepath.Base(path) == "secret.go" {
+			return nil, &fs.PathError{Op: "open", Path: path, Err: fs.ErrPermission}
+		}
+		return orig(path)
+	}
+
+	terms, stats, err := Scan(root, Options{})
+	if err != nil {
+		t.Fatalf("Scan() error: %v", err)
+	}
+	if stats.Files != 2 || stats.Skipped != 1 {
+		t.Errorf("stats = %+v, want 2 files read and 1 skipped", stats)
+	}
+	if want := []string{"LoadProfile", "svc"}; !slices.Equal(words(terms), want) {
+		t.Errorf("Scan() = %q, want %q", words(terms), want)
+	}
+}
+
+func TestScanSkipsUnreadableDirectories(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can read any directory")
+	}
+	root := writeTree(t, map[string]string{
+		"a.go":        "package svc\n\nvar _ = LoadProfile\nvar _ = LoadProfile\n",
+		"locked/b.go": "package locked\n",
+	})
+	locked := filepath.Join(root, "locked")
+	if err := os.Chmod(locked, 0); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(locked, 0o755) })
+
+	_, stats, err := Scan(root, Options{})
+	if err != nil {
+		t.Fatalf("Scan() error: %v", err)
+	}
+	if stats.Files != 1 || stats.Skipped != 1 {
+		t.Errorf("stats = %+v, want 1 file read and 1 skipped", stats)
+	}
+}
+
+func TestScanMissingRoot(t *testing.T) {
+	if _, _, err := Scan(filepath.Join(t.TempDir(), "missing"), Options{}); err == nil {
+		t.Error("Scan() should fail when the root cannot be read")
+	}
+}
+
+func TestWriteDictionaryParses(t *testing.T) {
+	terms := []Term{{Word: "LoadProfile"}, {Word: "react-query", Kind: KindPackage}, {Word: "Node.js", Kind: KindTerm}}
+	var buf bytes.Buffer
+	if err := WriteDictionary(&buf, "/src/app", terms); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "vocab.txt")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := prompt.ParseDictionary(path)
+	if err != nil {
+		t.Fatalf("ParseDictionary() error: %v", err)
+	}
+	var got []string
+	for _, e := range entries {
+		if !e.IsHint() {
+			t.Errorf("entry %q is not a hint word", e.Reading)
+		}
+		got = append(got, e.Reading)
+	}
+	if want := words(terms); !slices.Equal(got, want) {
+		t.Errorf("parsed hints = %q, want %q", got, want)
+	}
+}
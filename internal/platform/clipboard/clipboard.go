@@ -6,3 +6,9 @@ type Clipboard interface {
 	SetText(text string) error
 	Paste() error
 }
+
+// SelectionReader is implemented by clipboards that can read the primary
+// selection (the text currently highlighted, without copying it).
+type SelectionReader interface {
+	GetSelection() (string, error)
+}
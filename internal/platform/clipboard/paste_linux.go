@@ -40,3 +40,25 @@ func (c *clipboardImpl) Paste() error {
 
 	return fmt.Errorf("no paste tool found: install xdotool (X11) or wtype (Wayland)")
 }
+
+// GetSelection reads the primary selection with wl-paste (Wayland), xclip or
+// xsel (X11).
+func (c *clipboardImpl) GetSelection() (string, error) {
+	tools := [][]string{
+		{"wl-paste", "--primary", "--no-newline"},
+		{"xclip", "-o", "-selection", "primary"},
+		{"xsel", "--primary", "--output"},
+	}
+	for _, tool := range tools {
+		path, err := exec.LookPath(tool[0])
+		if err != nil {
+			continue
+		}
+		out, err := exec.Command(path, tool[1:]...).Output()
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", tool[0], err)
+		}
+		return string(out), nil
+	}
+	return "", fmt.Errorf("no selection tool found: install wl-clipboard (Wayland), xclip or xsel (X11)")
+}
@@ -2,6 +2,8 @@ package hotkey
 
 // Manager registers and manages global hotkeys.
 type Manager interface {
+	// Register adds a hotkey; it may be called once per key.
 	Register(key string, onPress func(), onRelease func()) error
+	// Unregister removes every registered hotkey.
 	Unregister() error
 }
@@ -1,6 +1,7 @@
 package hotkey
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 
@@ -8,7 +9,7 @@ import (
 )
 
 type hotkeyManager struct {
-	hk *xhotkey.Hotkey
+	hks []*xhotkey.Hotkey
 }
 
 // NewManager creates a new global hotkey manager.
@@ -40,20 +41,21 @@ func (m *hotkeyManager) Register(key string, onPress func(), onRelease func()) e
 	if err != nil {
 		return err
 	}
-	m.hk = xhotkey.New(mods, k)
-	if err := m.hk.Register(); err != nil {
-		return fmt.Errorf("register hotkey: %w", err)
+	hk := xhotkey.New(mods, k)
+	if err := hk.Register(); err != nil {
+		return fmt.Errorf("register hotkey %s: %w", key, err)
 	}
+	m.hks = append(m.hks, hk)
 
 	go func() {
-		for range m.hk.Keydown() {
+		for range hk.Keydown() {
 			if onPress != nil {
 				onPress()
 			}
 		}
 	}()
 	go func() {
-		for range m.hk.Keyup() {
+		for range hk.Keyup() {
 			if onRelease != nil {
 				onRelease()
 			}
@@ -64,8 +66,12 @@ func (m *hotkeyManager) Register(key string, onPress func(), onRelease func()) e
 }
 
 func (m *hotkeyManager) Unregister() error {
-	if m.hk != nil {
-		return m.hk.Unregister()
+	var errs []error
+	for _, hk := range m.hks {
+		if err := hk.Unregister(); err != nil {
+			errs = append(errs, err)
+		}
 	}
-	return nil
+	m.hks = nil
+	return errors.Join(errs...)
 }
@@ -6,3 +6,12 @@ type Recorder interface {
 	Stop() ([]int16, error)
 	IsRecording() bool
 }
+
+// Monitor is implemented by recorders that can pass captured audio to a
+// callback while recording, e.g. for voice activity detection.
+type Monitor interface {
+	// SetMonitor makes fn receive each captured buffer while recording; nil
+	// removes it. fn runs on the audio thread: it must return quickly, must
+	// not call Stop and must not retain samples.
+	SetMonitor(fn func(samples []int16))
+}
@@ -18,6 +18,7 @@ type portaudioRecorder struct {
 	stream    *portaudio.Stream
 	buffer    []int16
 	recording bool
+	monitor   func([]int16)
 }
 
 // NewRecorder creates a new audio recorder using PortAudio.
@@ -113,11 +114,23 @@ func (r *portaudioRecorder) IsRecording() bool {
 	return r.recording
 }
 
-func (r *portaudioRecorder) processAudio(in []int16) {
+func (r *portaudioRecorder) SetMonitor(fn func(samples []int16)) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	r.monitor = fn
+}
+
+func (r *portaudioRecorder) processAudio(in []int16) {
+	r.mu.Lock()
 	if !r.recording {
+		r.mu.Unlock()
 		return
 	}
 	r.buffer = append(r.buffer, in...)
+	monitor := r.monitor
+	r.mu.Unlock()
+
+	if monitor != nil {
+		monitor(in)
+	}
 }
@@ -9,6 +9,10 @@ const (
 	Success
 	Error
 	ProcessingTick
+	// AuthError signals a configuration problem such as an invalid API key.
+	AuthError
+	// Retry signals a temporary failure (rate limit, overload): try again.
+	Retry
 )
 
 // Player plays system sounds for audio feedback.
@@ -41,6 +41,8 @@ var soundData = map[SoundType][]byte{
 	Success:        nil,
 	Error:          nil,
 	ProcessingTick: nil,
+	AuthError:      nil,
+	Retry:          nil,
 }
 
 func init() {
@@ -49,6 +51,8 @@ func init() {
 	soundData[Success] = assets.SoundSuccess
 	soundData[Error] = assets.SoundError
 	soundData[ProcessingTick] = assets.SoundProcessingTick
+	soundData[AuthError] = assets.SoundAuthError
+	soundData[Retry] = assets.SoundRetry
 }
 
 type otoPlayer struct{}
@@ -14,6 +14,8 @@ type SettingsCallbacks struct {
 	OnHotkeyChange     func(key string)
 	OnDurationChange   func(seconds int)
 	OnPushToTalkToggle func(enabled bool)
+	OnProfileChange    func(profile string) // "" selects the base dictionary
+	OnModeChange       func(mode string)    // "" selects the default prompts
 }
 
 // Manager manages the system tray icon and menu.
@@ -22,4 +24,11 @@ type Manager interface {
 	SetState(state State) error
 	SetSettingsCallbacks(cb SettingsCallbacks)
 	UpdateSettings(hotkey string, maxDuration int, pushToTalk bool)
+	// SetStatus shows a short status line (e.g. the dictionary reload result)
+	// in the tray tooltip. An empty status restores the default tooltip.
+	SetStatus(status string)
+	// SetProfiles lists the dictionary profiles in the menu and checks current.
+	SetProfiles(profiles []string, current string)
+	// SetModes lists the transcription modes in the menu and checks current.
+	SetModes(modes []string, current string)
 }
@@ -2,6 +2,7 @@ package tray
 
 import (
 	"fmt"
+	"slices"
 
 	"fyne.io/systray"
 	"github.com/noricha-vr/voicecode/assets"
@@ -27,6 +28,16 @@ type systrayManager struct {
 	hkItems  []*systray.MenuItem
 	durItems []*systray.MenuItem
 	pttItem  *systray.MenuItem
+
+	mProfile  *systray.MenuItem
+	profItems []*systray.MenuItem
+	profNames []string      // profile of each item; "" is the base dictionary
+	profDone  chan struct{} // closed to stop the click listeners of profItems
+
+	mMode     *systray.MenuItem
+	modeItems []*systray.MenuItem
+	modeNames []string      // mode of each item; "" is the default
+	modeDone  chan struct{} // closed to stop the click listeners of modeItems
 }
 
 // NewManager creates a new system tray manager.
@@ -94,6 +105,12 @@ func (m *systrayManager) Run(onReady func(), onQuit func()) {
 		// Push-to-Talk toggle
 		m.pttItem = mSettings.AddSubMenuItemCheckbox("Push-to-Talk: Off", "Toggle push-to-talk mode", false)
 
+		// Dictionary profile submenu, filled by SetProfiles
+		m.mProfile = mSettings.AddSubMenuItem("Dictionary", "Dictionary profile")
+
+		// Transcription mode submenu, filled by SetModes
+		m.mMode = mSettings.AddSubMenuItem("Mode", "Transcription mode for the hotkey")
+
 		systray.AddSeparator()
 		m.mQuit = systray.AddMenuItem("Quit", "Quit VoiceCode")
 
@@ -145,6 +162,104 @@ func (m *systrayManager) Run(onReady func(), onQuit func()) {
 	})
 }
 
+func (m *systrayManager) SetStatus(status string) {
+	if status == "" {
+		systray.SetTooltip("VoiceCode")
+		return
+	}
+	systray.SetTooltip("VoiceCode - " + status)
+}
+
+func (m *systrayManager) SetProfiles(profiles []string, current string) {
+	if m.mProfile == nil {
+		return
+	}
+	names := append([]string{""}, profiles...)
+	if !slices.Equal(names, m.profNames) {
+		// Stop the old listeners first: Remove does not close ClickedCh.
+		if m.profDone != nil {
+			close(m.profDone)
+		}
+		for _, item := range m.profItems {
+			item.Remove()
+		}
+		m.profDone = make(chan struct{})
+		m.profItems = make([]*systray.MenuItem, len(names))
+		for i, name := range names {
+			label := name
+			if name == "" {
+				label = "Base only"
+			}
+			m.profItems[i] = m.mProfile.AddSubMenuItemCheckbox(label, "Use dictionary profile "+label, false)
+			go func(item *systray.MenuItem, profile string, done <-chan struct{}) {
+				for {
+					select {
+					case <-done:
+						return
+					case <-item.ClickedCh:
+						if m.cb.OnProfileChange != nil {
+							m.cb.OnProfileChange(profile)
+						}
+					}
+				}
+			}(m.profItems[i], name, m.profDone)
+		}
+		m.profNames = names
+	}
+	for i, name := range names {
+		if name == current {
+			m.profItems[i].Check()
+		} else {
+			m.profItems[i].Uncheck()
+		}
+	}
+}
+
+func (m *systrayManager) SetModes(modes []string, current string) {
+	if m.mMode == nil {
+		return
+	}
+	names := append([]string{""}, modes...)
+	if !slices.Equal(names, m.modeNames) {
+		// Stop the old listeners first: Remove does not close ClickedCh.
+		if m.modeDone != nil {
+			close(m.modeDone)
+		}
+		for _, item := range m.modeItems {
+			item.Remove()
+		}
+		m.modeDone = make(chan struct{})
+		m.modeItems = make([]*systray.MenuItem, len(names))
+		for i, name := range names {
+			label := name
+			if name == "" {
+				label = "Default"
+			}
+			m.modeItems[i] = m.mMode.AddSubMenuItemCheckbox(label, "Transcribe in mode "+label, false)
+			go func(item *systray.MenuItem, mode string, done <-chan struct{}) {
+				for {
+					select {
+					case <-done:
+						return
+					case <-item.ClickedCh:
+						if m.cb.OnModeChange != nil {
+							m.cb.OnModeChange(mode)
+						}
+					}
+				}
+			}(m.modeItems[i], name, m.modeDone)
+		}
+		m.modeNames = names
+	}
+	for i, name := range names {
+		if name == current {
+			m.modeItems[i].Check()
+		} else {
+			m.modeItems[i].Uncheck()
+		}
+	}
+}
+
 func (m *systrayManager) SetState(state State) error {
 	switch state {
 	case Idle: